@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -69,6 +70,22 @@ type Severity string
 // +kubebuilder:validation:Enum=DeleteAll;DeleteIfCreated;None
 type PruneObjectBehavior string
 
+// Provenance describes how objects that the configuration policy creates or updates are marked as
+// managed by the policy. When set to `Metadata`, the objects are labeled with the UID of the policy
+// and annotated with the name, namespace, and generation of the policy and the index of the object
+// template. When set to `OwnerReference`, the objects are also given an owner reference to the policy
+// when they are in the same namespace as the policy, so that they are garbage collected when the
+// policy is deleted. The default value is `None`.
+//
+// +kubebuilder:validation:Enum=None;Metadata;OwnerReference
+type Provenance string
+
+const (
+	ProvenanceNone           Provenance = "None"
+	ProvenanceMetadata       Provenance = "Metadata"
+	ProvenanceOwnerReference Provenance = "OwnerReference"
+)
+
 type Target struct {
 	*metav1.LabelSelector `json:",inline"`
 
@@ -110,25 +127,39 @@ func (t Target) String() string {
 // be evaluated regardless of the settings here.
 type EvaluationInterval struct {
 	// Compliant is the minimum elapsed time before a configuration policy is reevaluated when in the
-	// compliant state. Set this to `never` to disable reevaluation when in the compliant state. The default value is
+	// compliant state. Set this to `never` to disable reevaluation when in the compliant state. Set this to a cron
+	// expression prefixed with `cron:`, such as `cron:0 */6 * * *`, to reevaluate on a schedule. The default value is
 	// `watch`.
 	//
-	//+kubebuilder:validation:Pattern=`^(?:(?:(?:[0-9]+(?:.[0-9])?)(?:h|m|s|(?:ms)|(?:us)|(?:ns)))|never|watch)+$`
+	//+kubebuilder:validation:Pattern=`^(?:cron:.+|(?:(?:(?:[0-9]+(?:.[0-9])?)(?:h|m|s|(?:ms)|(?:us)|(?:ns)))|never|watch)+)$`
 	Compliant string `json:"compliant,omitempty"`
 	// NonCompliant is the minimum elapsed time before a configuration policy is reevaluated when in the noncompliant
-	// state. Set this to `never` to disable reevaluation when in the noncompliant state. The default value is `watch`.
+	// state. Set this to `never` to disable reevaluation when in the noncompliant state. Set this to a cron expression
+	// prefixed with `cron:`, such as `cron:*/15 * * * *`, to reevaluate on a schedule. The default value is `watch`.
 	//
-	//+kubebuilder:validation:Pattern=`^(?:(?:(?:[0-9]+(?:.[0-9])?)(?:h|m|s|(?:ms)|(?:us)|(?:ns)))|never|watch)+$`
+	//+kubebuilder:validation:Pattern=`^(?:cron:.+|(?:(?:(?:[0-9]+(?:.[0-9])?)(?:h|m|s|(?:ms)|(?:us)|(?:ns)))|never|watch)+)$`
 	NonCompliant string `json:"noncompliant,omitempty"`
+	// JitterPercent is the maximum percentage of the evaluation interval that the next evaluation is delayed by to
+	// spread out the evaluations of policies with the same interval. The delay is consistent for a given policy and
+	// evaluation time. When unset, the controller default from the `--evaluation-jitter-percent` flag is used.
+	//
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=100
+	JitterPercent *int32 `json:"jitterPercent,omitempty"`
 }
 
+// cronIntervalPrefix is the prefix of an evaluation interval that is a cron expression.
+const cronIntervalPrefix = "cron:"
+
 var (
 	ErrIsNever = errors.New("the interval is set to never")
 	ErrIsWatch = errors.New("the interval is set to watch")
+	ErrIsCron  = errors.New("the interval is set to a cron expression")
 )
 
 // parseInterval converts the input string to a duration. ErrIsNever is returned when the string is set to `never`.
-// ErrIsWatch is returned when the string is unset or set to `watch`.
+// ErrIsWatch is returned when the string is unset or set to `watch`. ErrIsCron is returned when the string is a valid
+// cron expression since it doesn't have a fixed duration.
 func (e EvaluationInterval) parseInterval(interval string) (time.Duration, error) {
 	if interval == "" || interval == "watch" {
 		return 0, ErrIsWatch
@@ -138,6 +169,14 @@ func (e EvaluationInterval) parseInterval(interval string) (time.Duration, error
 		return 0, ErrIsNever
 	}
 
+	if strings.HasPrefix(interval, cronIntervalPrefix) {
+		if _, err := parseCronInterval(interval); err != nil {
+			return 0, err
+		}
+
+		return 0, ErrIsCron
+	}
+
 	parsedInterval, err := time.ParseDuration(interval)
 	if err != nil {
 		return 0, err
@@ -166,6 +205,52 @@ func (e EvaluationInterval) GetNonCompliantInterval() (time.Duration, error) {
 	return e.parseInterval(e.NonCompliant)
 }
 
+// parseCronInterval parses an evaluation interval with the `cron:` prefix as a standard cron
+// expression.
+func parseCronInterval(interval string) (cron.Schedule, error) {
+	expression := strings.TrimSpace(strings.TrimPrefix(interval, cronIntervalPrefix))
+
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, fmt.Errorf("the cron expression %q is invalid: %w", expression, err)
+	}
+
+	return schedule, nil
+}
+
+// nextEvaluation returns the time of the next evaluation after the input time based on the
+// interval, which can either be a duration or a cron expression. ErrIsNever is returned when the
+// string is set to `never`, and ErrIsWatch is returned when the string is unset or set to `watch`.
+func (e EvaluationInterval) nextEvaluation(interval string, from time.Time) (time.Time, error) {
+	if strings.HasPrefix(interval, cronIntervalPrefix) {
+		schedule, err := parseCronInterval(interval)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return schedule.Next(from), nil
+	}
+
+	parsedInterval, err := e.parseInterval(interval)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return from.Add(parsedInterval), nil
+}
+
+// GetCompliantNextEvaluation returns the time of the next evaluation after the input time when in
+// the compliant state. ErrIsNever is returned when the interval is set to `never`.
+func (e EvaluationInterval) GetCompliantNextEvaluation(from time.Time) (time.Time, error) {
+	return e.nextEvaluation(e.Compliant, from)
+}
+
+// GetNonCompliantNextEvaluation returns the time of the next evaluation after the input time when
+// in the noncompliant state. ErrIsNever is returned when the interval is set to `never`.
+func (e EvaluationInterval) GetNonCompliantNextEvaluation(from time.Time) (time.Time, error) {
+	return e.nextEvaluation(e.NonCompliant, from)
+}
+
 type ComplianceType string
 
 const (
@@ -211,6 +296,28 @@ const (
 	Always     RecreateOption = "Always"
 )
 
+// +kubebuilder:validation:Enum=Skip;Inform;Enforce
+type GitOpsManagedBehavior string
+
+const (
+	GitOpsManagedSkip    GitOpsManagedBehavior = "Skip"
+	GitOpsManagedInform  GitOpsManagedBehavior = "Inform"
+	GitOpsManagedEnforce GitOpsManagedBehavior = "Enforce"
+)
+
+// +kubebuilder:validation:Enum=Background;Foreground;Orphan
+type DeletionPropagation string
+
+const (
+	DeletionPropagationBackground DeletionPropagation = "Background"
+	DeletionPropagationForeground DeletionPropagation = "Foreground"
+	DeletionPropagationOrphan     DeletionPropagation = "Orphan"
+)
+
+// DefaultDeletionTimeout is how long an object can be terminating before it is reported as stuck
+// when `waitForDeletion` is set and `deletionTimeout` is not.
+const DefaultDeletionTimeout = 10 * time.Minute
+
 // ObjectTemplate describes the desired state of an object on the cluster.
 type ObjectTemplate struct {
 	// ComplianceType describes how objects on the cluster should be compared with the object definition
@@ -228,6 +335,16 @@ type ObjectTemplate struct {
 	// +kubebuilder:validation:Enum=MustHave;Musthave;musthave;MustOnlyHave;Mustonlyhave;mustonlyhave
 	MetadataComplianceType ComplianceType `json:"metadataComplianceType,omitempty"`
 
+	// GitOpsManaged describes how to handle objects on the cluster that are managed by a GitOps tool
+	// such as Argo CD or Flux, which is detected from the tracking annotations, labels, and field
+	// managers that the tools set. Enforcing these objects can cause the policy and the GitOps tool to
+	// continuously revert each other's changes. When you set the parameter to `Skip`, the objects are
+	// not evaluated, except with the `mustnothave` compliance type, where the objects are reported as
+	// noncompliant but not deleted. When you set the parameter to `Inform`, the objects are evaluated but not
+	// modified, even when the `remediationAction` is `enforce`. The default value is `Enforce`, which
+	// handles the objects like any other object.
+	GitOpsManaged GitOpsManagedBehavior `json:"gitOpsManaged,omitempty"`
+
 	// RecreateOption describes when to delete and recreate an object when an update is required. When you set the
 	// object to `IfRequired`, the policy recreates the object when updating an immutable field. When you set the
 	// parameter to `Always`, the policy recreates the object on any update. When you set the `remediationAction` to
@@ -253,6 +370,40 @@ type ObjectTemplate struct {
 	// ObjectSelector defines the label selector for objects defined in the `objectDefinition`. If
 	// there is an object name defined in the `objectDefinition`, the `objectSelector` is ignored.
 	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+
+	// DeletionPropagation describes how the dependents of an object are handled when the object is
+	// deleted to enforce the `mustnothave` compliance type. The supported options are `Background`,
+	// `Foreground`, and `Orphan`. The default value is the default of the API server for the object
+	// kind, which is usually `Background`.
+	DeletionPropagation DeletionPropagation `json:"deletionPropagation,omitempty"`
+
+	// WaitForDeletion specifies whether an object deleted to enforce the `mustnothave` compliance type
+	// is only considered compliant once it is removed from the cluster. When you set the parameter to
+	// `true`, an object that is terminating, such as when finalizers are present, is noncompliant. The
+	// default value is `false`, which is compliant as soon as the deletion is requested.
+	WaitForDeletion bool `json:"waitForDeletion,omitempty"`
+
+	// DeletionTimeout is the duration an object can be terminating before it is reported as stuck,
+	// along with the finalizers blocking its deletion. This only applies when `waitForDeletion` is
+	// `true`. The default value is `10m`.
+	//
+	//+kubebuilder:validation:Pattern=`^(?:(?:[0-9]+(?:.[0-9])?)(?:h|m|s|(?:ms)|(?:us)|(?:ns)))+$`
+	DeletionTimeout string `json:"deletionTimeout,omitempty"`
+}
+
+// DeletionTimeoutWithDefault parses the `deletionTimeout` in the object template and returns the
+// default deletion timeout if it is not set or is invalid.
+func (o *ObjectTemplate) DeletionTimeoutWithDefault() time.Duration {
+	if o.DeletionTimeout == "" {
+		return DefaultDeletionTimeout
+	}
+
+	timeout, err := time.ParseDuration(o.DeletionTimeout)
+	if err != nil || timeout <= 0 {
+		return DefaultDeletionTimeout
+	}
+
+	return timeout
 }
 
 // RecordDiffWithDefault parses the `objectDefinition` in the policy for the kind and returns the
@@ -295,6 +446,7 @@ type ConfigurationPolicySpec struct {
 	EvaluationInterval EvaluationInterval `json:"evaluationInterval,omitempty"`
 	// +kubebuilder:default:=None
 	PruneObjectBehavior PruneObjectBehavior `json:"pruneObjectBehavior,omitempty"`
+	Provenance          Provenance          `json:"provenance,omitempty"`
 
 	// NamespaceSelector defines the list of namespaces to include or exclude for objects defined in
 	// `spec["object-templates"]`. All selector rules are combined. If 'include' is not provided but
@@ -317,6 +469,18 @@ type ConfigurationPolicySpec struct {
 	// `object-templates` and `object-templates-raw` can be set in a configuration policy. For more on
 	// the Go templates, see https://github.com/stolostron/go-template-utils/blob/main/README.md.
 	ObjectTemplatesRaw string `json:"object-templates-raw,omitempty"`
+
+	// Parameters declares the parameters that the Go templates in the configuration policy can
+	// reference with `{{ .Params.<name> }}`. When parameters are declared, only the declared
+	// parameters are available to the templates, and the values are validated against the declared
+	// types before the policy is evaluated.
+	Parameters []PolicyParameter `json:"parameters,omitempty"`
+
+	// ParametersFrom is a list of `ConfigurationPolicyParameters` or `ConfigMap` resources in the
+	// namespace of the configuration policy that provide the parameter values. When a parameter is
+	// set by multiple sources, the last source in the list takes precedence. Changes to the sources
+	// cause the configuration policy to be reevaluated.
+	ParametersFrom []ParametersReference `json:"parametersFrom,omitempty"`
 }
 
 // ComplianceState reports the observed status from the definitions of the policy.
@@ -331,6 +495,69 @@ const (
 	Terminating       ComplianceState = "Terminating"
 )
 
+// ReasonCode is a stable, machine-readable summary of why an object or an `object-template` has a
+// particular compliance. Unlike the reason, which is a human-readable message, the reason code doesn't
+// change between releases.
+//
+// +kubebuilder:validation:Enum=Found;NotFound;Missing;Mismatch;FoundNotWanted;NotApplicable;Created;Updated;Deleted;Deleting;DeletionStuck;CreateFailed;UpdateFailed;DeleteFailed;Immutable;Exempted;TemplateError;InvalidSpec;NoObjectTemplates;Installing;ApprovalRequired;NotApproved;Conflict;Unhealthy;Unknown;Error
+type ReasonCode string
+
+const (
+	// ReasonCodeFound means that the object exists as specified.
+	ReasonCodeFound ReasonCode = "Found"
+	// ReasonCodeNotFound means that the object doesn't exist, as expected.
+	ReasonCodeNotFound ReasonCode = "NotFound"
+	// ReasonCodeMissing means that the object doesn't exist but should.
+	ReasonCodeMissing ReasonCode = "Missing"
+	// ReasonCodeMismatch means that the object exists but doesn't match the definition.
+	ReasonCodeMismatch ReasonCode = "Mismatch"
+	// ReasonCodeFoundNotWanted means that the object exists but shouldn't.
+	ReasonCodeFoundNotWanted ReasonCode = "FoundNotWanted"
+	// ReasonCodeNotApplicable means that the object exists but isn't handled in `mustnothave` mode.
+	ReasonCodeNotApplicable ReasonCode = "NotApplicable"
+	// ReasonCodeCreated means that the object was created by the policy.
+	ReasonCodeCreated ReasonCode = "Created"
+	// ReasonCodeUpdated means that the object was updated by the policy.
+	ReasonCodeUpdated ReasonCode = "Updated"
+	// ReasonCodeDeleted means that the object was deleted by the policy.
+	ReasonCodeDeleted ReasonCode = "Deleted"
+	// ReasonCodeDeleting means that the object is being deleted but hasn't been removed yet.
+	ReasonCodeDeleting ReasonCode = "Deleting"
+	// ReasonCodeDeletionStuck means that the object has been deleting for too long.
+	ReasonCodeDeletionStuck ReasonCode = "DeletionStuck"
+	// ReasonCodeCreateFailed means that the policy failed to create the object.
+	ReasonCodeCreateFailed ReasonCode = "CreateFailed"
+	// ReasonCodeUpdateFailed means that the policy failed to update the object.
+	ReasonCodeUpdateFailed ReasonCode = "UpdateFailed"
+	// ReasonCodeDeleteFailed means that the policy failed to delete the object or its child objects.
+	ReasonCodeDeleteFailed ReasonCode = "DeleteFailed"
+	// ReasonCodeImmutable means that the object can't be updated because immutable fields don't match.
+	ReasonCodeImmutable ReasonCode = "Immutable"
+	// ReasonCodeExempted means that the object is exempted from the policy, such as when it is managed by
+	// GitOps or is kept when the policy is removed.
+	ReasonCodeExempted ReasonCode = "Exempted"
+	// ReasonCodeTemplateError means that the templates or the object definition couldn't be processed.
+	ReasonCodeTemplateError ReasonCode = "TemplateError"
+	// ReasonCodeInvalidSpec means that the policy specification is invalid.
+	ReasonCodeInvalidSpec ReasonCode = "InvalidSpec"
+	// ReasonCodeNoObjectTemplates means that the policy doesn't have any object templates.
+	ReasonCodeNoObjectTemplates ReasonCode = "NoObjectTemplates"
+	// ReasonCodeInstalling means that the operator resource is still being installed.
+	ReasonCodeInstalling ReasonCode = "Installing"
+	// ReasonCodeApprovalRequired means that the operator resource is waiting for an approval.
+	ReasonCodeApprovalRequired ReasonCode = "ApprovalRequired"
+	// ReasonCodeNotApproved means that the operator version isn't an approved version.
+	ReasonCodeNotApproved ReasonCode = "NotApproved"
+	// ReasonCodeConflict means that the object conflicts with other objects.
+	ReasonCodeConflict ReasonCode = "Conflict"
+	// ReasonCodeUnhealthy means that the object exists but isn't healthy.
+	ReasonCodeUnhealthy ReasonCode = "Unhealthy"
+	// ReasonCodeUnknown means that the state of the object couldn't be determined.
+	ReasonCodeUnknown ReasonCode = "Unknown"
+	// ReasonCodeError means that an error prevented the evaluation of the object.
+	ReasonCodeError ReasonCode = "Error"
+)
+
 // Condition contains the details of an evaluation of an `object-template`.
 type Condition struct {
 	// Type is the type of condition. The supported options are `violation` or `notification`.
@@ -349,6 +576,11 @@ type Condition struct {
 	// +optional
 	Reason string `json:"reason,omitempty" protobuf:"bytes,4,opt,name=reason"`
 
+	// ReasonCode is a machine-readable summary of the reason.
+	//
+	// +optional
+	ReasonCode ReasonCode `json:"reasonCode,omitempty"`
+
 	// Message is a human-readable message indicating details about the condition.
 	//
 	// +optional
@@ -431,6 +663,60 @@ type ObjectProperties struct {
 	// there was an initial mismatch between the policy and object, but the dry run update produced
 	// a compliant result.
 	MatchesAfterDryRun bool `json:"matchesAfterDryRun,omitempty"`
+
+	// ChangedBy lists the field managers that modified the mismatched fields of the object since it
+	// was last observed to be compliant with the policy. It is only set when the object doesn't match.
+	ChangedBy []FieldManagerChange `json:"changedBy,omitempty"`
+
+	// MismatchedFields lists the fields of the object that don't match the `objectDefinition` in the
+	// policy. Unlike the diff, it is recorded even when the values are censored. It is only set when
+	// the object doesn't match.
+	MismatchedFields []MismatchedField `json:"mismatchedFields,omitempty"`
+}
+
+// MismatchKind is how a field of an object differs from the `objectDefinition` in the policy.
+//
+// +kubebuilder:validation:Enum=missing;different;extra
+type MismatchKind string
+
+const (
+	// MismatchMissing is a field in the `objectDefinition` that is missing from the object.
+	MismatchMissing MismatchKind = "missing"
+	// MismatchDifferent is a field with a different value in the object and the `objectDefinition`.
+	MismatchDifferent MismatchKind = "different"
+	// MismatchExtra is a field in the object that is not in the `objectDefinition`, which is a mismatch
+	// with the `mustonlyhave` compliance type.
+	MismatchExtra MismatchKind = "extra"
+)
+
+// MismatchedField is a field of an object that doesn't match the `objectDefinition` in the policy.
+type MismatchedField struct {
+	// Path is the JSON path of the field, such as `data.password` or `spec.containers`. Lists are compared
+	// as a whole, so the path of a list is used when any of its items differ.
+	Path string `json:"path"`
+
+	// Kind is how the field differs, either `missing`, `different`, or `extra`.
+	Kind MismatchKind `json:"kind"`
+}
+
+// FieldManagerChange identifies a field manager, as recorded in the object's `managedFields`, that
+// changed an object so that it no longer matches the configuration policy.
+type FieldManagerChange struct {
+	// Manager is the name of the field manager, such as `kubectl-edit` or the name of a controller.
+	Manager string `json:"manager"`
+
+	// Operation is the type of operation the field manager performed, either `Apply` or `Update`.
+	Operation string `json:"operation,omitempty"`
+
+	// Subresource is the subresource the field manager changed, such as `status`. It is empty when
+	// the main resource was changed.
+	Subresource string `json:"subresource,omitempty"`
+
+	// Time is the timestamp of the most recent change to the object by the field manager.
+	Time *metav1.Time `json:"time,omitempty"`
+
+	// Fields is the list of mismatched field paths that are owned by the field manager.
+	Fields []string `json:"fields,omitempty"`
 }
 
 // RelatedObject contains the details of an object matched by the policy.
@@ -445,6 +731,9 @@ type RelatedObject struct {
 
 	// Reason is a human-readable message of why the related object has a particular compliance.
 	Reason string `json:"reason,omitempty"`
+
+	// ReasonCode is a machine-readable summary of why the related object has a particular compliance.
+	ReasonCode ReasonCode `json:"reasonCode,omitempty"`
 }
 
 // ConfigurationPolicyStatus is the observed status of the configuration policy from its object
@@ -463,6 +752,15 @@ type ConfigurationPolicyStatus struct {
 	// evaluated.
 	LastEvaluatedGeneration int64 `json:"lastEvaluatedGeneration,omitempty"`
 
+	// NextEvaluation is an ISO-8601 timestamp of the next scheduled evaluation of the policy based on
+	// the evaluation interval. It is unset when the policy is only reevaluated on watch events or is
+	// never reevaluated.
+	NextEvaluation string `json:"nextEvaluation,omitempty"`
+
+	// Shard is the controller replica that evaluates the policy when the policies are sharded across
+	// multiple active controller replicas.
+	Shard string `json:"shard,omitempty"`
+
 	// RelatedObjects is a list of objects processed by the configuration policy due to its
 	// `object-templates`.
 	RelatedObjects []RelatedObject `json:"relatedObjects,omitempty"`
@@ -3,8 +3,10 @@
 package v1
 
 import (
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -82,3 +84,81 @@ func TestRecordDiffWithDefault(t *testing.T) {
 		)
 	}
 }
+
+func TestGetNextEvaluation(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		interval    string
+		expected    time.Time
+		expectedErr error
+	}{
+		"duration": {
+			interval: "1h30m",
+			expected: from.Add(90 * time.Minute),
+		},
+		"cron": {
+			interval: "cron:0 */6 * * *",
+			expected: time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC),
+		},
+		"cron descriptor": {
+			interval: "cron: @daily",
+			expected: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"watch": {
+			interval:    "watch",
+			expectedErr: ErrIsWatch,
+		},
+		"unset": {
+			expectedErr: ErrIsWatch,
+		},
+		"never": {
+			interval:    "never",
+			expectedErr: ErrIsNever,
+		},
+	}
+
+	for testName, test := range tests {
+		t.Run(
+			testName,
+			func(t *testing.T) {
+				t.Parallel()
+
+				interval := EvaluationInterval{Compliant: test.interval, NonCompliant: test.interval}
+
+				for _, getNext := range []func(time.Time) (time.Time, error){
+					interval.GetCompliantNextEvaluation, interval.GetNonCompliantNextEvaluation,
+				} {
+					next, err := getNext(from)
+					if !errors.Is(err, test.expectedErr) {
+						t.Fatalf("Expected the error %v but got %v", test.expectedErr, err)
+					}
+
+					if !next.Equal(test.expected) {
+						t.Fatalf("Expected %s but got %s", test.expected, next)
+					}
+				}
+			},
+		)
+	}
+}
+
+func TestCronInterval(t *testing.T) {
+	t.Parallel()
+
+	interval := EvaluationInterval{Compliant: "cron:0 */6 * * *", NonCompliant: "cron:not a schedule"}
+
+	if _, err := interval.GetCompliantInterval(); !errors.Is(err, ErrIsCron) {
+		t.Fatalf("Expected ErrIsCron but got %v", err)
+	}
+
+	if _, err := interval.GetNonCompliantInterval(); err == nil || errors.Is(err, ErrIsCron) {
+		t.Fatalf("Expected an invalid cron expression error but got %v", err)
+	}
+
+	if _, err := interval.GetNonCompliantNextEvaluation(time.Now()); err == nil {
+		t.Fatal("Expected an invalid cron expression error")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package v1
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ParameterType is the type of the value of a policy parameter. The supported options are
+// `string`, `integer`, `number`, `boolean`, `array`, and `object`.
+//
+// +kubebuilder:validation:Enum=string;integer;number;boolean;array;object
+type ParameterType string
+
+const (
+	ParameterTypeString  ParameterType = "string"
+	ParameterTypeInteger ParameterType = "integer"
+	ParameterTypeNumber  ParameterType = "number"
+	ParameterTypeBoolean ParameterType = "boolean"
+	ParameterTypeArray   ParameterType = "array"
+	ParameterTypeObject  ParameterType = "object"
+)
+
+// PolicyParameter declares a parameter that the Go templates in the configuration policy can
+// reference with `{{ .Params.<name> }}`.
+type PolicyParameter struct {
+	// Name is the name of the parameter. It must be a valid Go template identifier.
+	//
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Type is the type of the parameter value. Values from a `ConfigMap` are converted to this type.
+	// The default value is `string`.
+	//
+	// +kubebuilder:default=string
+	Type ParameterType `json:"type,omitempty"`
+
+	// Required specifies whether the parameter must have a value from the `default` or from one of
+	// the `parametersFrom` sources.
+	Required bool `json:"required,omitempty"`
+
+	// Default is the value of the parameter when none of the `parametersFrom` sources set it.
+	//
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Default *apiextensionsv1.JSON `json:"default,omitempty"`
+}
+
+// ParametersReferenceKind is the kind of a parameters source. The supported options are
+// `ConfigurationPolicyParameters` and `ConfigMap`.
+//
+// +kubebuilder:validation:Enum=ConfigurationPolicyParameters;ConfigMap
+type ParametersReferenceKind string
+
+const (
+	ParametersReferenceConfigurationPolicyParameters ParametersReferenceKind = "ConfigurationPolicyParameters"
+	ParametersReferenceConfigMap                     ParametersReferenceKind = "ConfigMap"
+)
+
+// ParametersReference refers to a `ConfigurationPolicyParameters` or `ConfigMap` in the namespace of
+// the configuration policy that provides values for the policy parameters.
+type ParametersReference struct {
+	// Kind is the kind of the parameters source. The default value is `ConfigurationPolicyParameters`.
+	//
+	// +kubebuilder:default=ConfigurationPolicyParameters
+	Kind ParametersReferenceKind `json:"kind,omitempty"`
+
+	// Name is the name of the parameters source in the namespace of the configuration policy.
+	//
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Optional specifies whether the configuration policy can be evaluated when the parameters source
+	// doesn't exist. The default value is `false`.
+	Optional bool `json:"optional,omitempty"`
+}
+
+// ConfigurationPolicyParametersSpec defines the parameter values provided to the configuration
+// policies that reference it.
+type ConfigurationPolicyParametersSpec struct {
+	// Parameters is a map of parameter names to values of any type.
+	//
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	Parameters map[string]apiextensionsv1.JSON `json:"parameters,omitempty"`
+}
+
+// ConfigurationPolicyParameters provides parameter values to the configuration policies in the same
+// namespace that reference it in `spec.parametersFrom`. Changes to it cause the configuration
+// policies that reference it to be reevaluated.
+//
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=configpolicyparams
+type ConfigurationPolicyParameters struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ConfigurationPolicyParametersSpec `json:"spec,omitempty"`
+}
+
+// ConfigurationPolicyParametersList contains a list of configuration policy parameters.
+//
+// +kubebuilder:object:root=true
+type ConfigurationPolicyParametersList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ConfigurationPolicyParameters `json:"items"`
+}
@@ -27,6 +27,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(GroupVersion,
 		&ConfigurationPolicy{},
 		&ConfigurationPolicyList{},
+		&ConfigurationPolicyParameters{},
+		&ConfigurationPolicyParametersList{},
 	)
 	metav1.AddToGroupVersion(scheme, GroupVersion)
 
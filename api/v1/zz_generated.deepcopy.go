@@ -8,6 +8,7 @@
 package v1
 
 import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
@@ -87,11 +88,91 @@ func (in *ConfigurationPolicyList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationPolicyParameters) DeepCopyInto(out *ConfigurationPolicyParameters) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationPolicyParameters.
+func (in *ConfigurationPolicyParameters) DeepCopy() *ConfigurationPolicyParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationPolicyParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigurationPolicyParameters) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationPolicyParametersList) DeepCopyInto(out *ConfigurationPolicyParametersList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ConfigurationPolicyParameters, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationPolicyParametersList.
+func (in *ConfigurationPolicyParametersList) DeepCopy() *ConfigurationPolicyParametersList {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationPolicyParametersList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ConfigurationPolicyParametersList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConfigurationPolicyParametersSpec) DeepCopyInto(out *ConfigurationPolicyParametersSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]apiextensionsv1.JSON, len(*in))
+		for key, val := range *in {
+			(*out)[key] = *val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationPolicyParametersSpec.
+func (in *ConfigurationPolicyParametersSpec) DeepCopy() *ConfigurationPolicyParametersSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ConfigurationPolicyParametersSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigurationPolicySpec) DeepCopyInto(out *ConfigurationPolicySpec) {
 	*out = *in
 	out.CustomMessage = in.CustomMessage
-	out.EvaluationInterval = in.EvaluationInterval
+	in.EvaluationInterval.DeepCopyInto(&out.EvaluationInterval)
 	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
 	if in.ObjectTemplates != nil {
 		in, out := &in.ObjectTemplates, &out.ObjectTemplates
@@ -104,6 +185,18 @@ func (in *ConfigurationPolicySpec) DeepCopyInto(out *ConfigurationPolicySpec) {
 			}
 		}
 	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]PolicyParameter, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ParametersFrom != nil {
+		in, out := &in.ParametersFrom, &out.ParametersFrom
+		*out = make([]ParametersReference, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConfigurationPolicySpec.
@@ -170,6 +263,11 @@ func (in *CustomMessage) DeepCopy() *CustomMessage {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EvaluationInterval) DeepCopyInto(out *EvaluationInterval) {
 	*out = *in
+	if in.JitterPercent != nil {
+		in, out := &in.JitterPercent, &out.JitterPercent
+		*out = new(int32)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvaluationInterval.
@@ -182,6 +280,30 @@ func (in *EvaluationInterval) DeepCopy() *EvaluationInterval {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldManagerChange) DeepCopyInto(out *FieldManagerChange) {
+	*out = *in
+	if in.Time != nil {
+		in, out := &in.Time, &out.Time
+		*out = (*in).DeepCopy()
+	}
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldManagerChange.
+func (in *FieldManagerChange) DeepCopy() *FieldManagerChange {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldManagerChange)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HistoryEvent) DeepCopyInto(out *HistoryEvent) {
 	*out = *in
@@ -198,6 +320,21 @@ func (in *HistoryEvent) DeepCopy() *HistoryEvent {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MismatchedField) DeepCopyInto(out *MismatchedField) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MismatchedField.
+func (in *MismatchedField) DeepCopy() *MismatchedField {
+	if in == nil {
+		return nil
+	}
+	out := new(MismatchedField)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectMetadata) DeepCopyInto(out *ObjectMetadata) {
 	*out = *in
@@ -221,6 +358,18 @@ func (in *ObjectProperties) DeepCopyInto(out *ObjectProperties) {
 		*out = new(bool)
 		**out = **in
 	}
+	if in.ChangedBy != nil {
+		in, out := &in.ChangedBy, &out.ChangedBy
+		*out = make([]FieldManagerChange, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MismatchedFields != nil {
+		in, out := &in.MismatchedFields, &out.MismatchedFields
+		*out = make([]MismatchedField, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectProperties.
@@ -270,6 +419,41 @@ func (in *ObjectTemplate) DeepCopy() *ObjectTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ParametersReference) DeepCopyInto(out *ParametersReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ParametersReference.
+func (in *ParametersReference) DeepCopy() *ParametersReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ParametersReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyParameter) DeepCopyInto(out *PolicyParameter) {
+	*out = *in
+	if in.Default != nil {
+		in, out := &in.Default, &out.Default
+		*out = new(apiextensionsv1.JSON)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyParameter.
+func (in *PolicyParameter) DeepCopy() *PolicyParameter {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyParameter)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RelatedObject) DeepCopyInto(out *RelatedObject) {
 	*out = *in
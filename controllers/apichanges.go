@@ -0,0 +1,405 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+var (
+	crdGVR = schema.GroupVersionResource{
+		Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions",
+	}
+	apiServiceGVR = schema.GroupVersionResource{
+		Group: "apiregistration.k8s.io", Version: "v1", Resource: "apiservices",
+	}
+)
+
+// APIChangeWatcher watches the CustomResourceDefinitions and APIServices of the target cluster and notifies the
+// registered handlers of the API groups that changed, so that policies that failed on a missing API mapping are
+// evaluated again as soon as the API is available. Only the metadata of the objects is watched.
+type APIChangeWatcher struct {
+	client   metadata.Interface
+	lock     sync.RWMutex
+	handlers []func(ctx context.Context, group string)
+}
+
+// NewAPIChangeWatcher returns an APIChangeWatcher that is ready to be started with the Start method.
+func NewAPIChangeWatcher(client metadata.Interface) *APIChangeWatcher {
+	return &APIChangeWatcher{client: client}
+}
+
+// OnAPIChange registers a handler called with the API group of a CustomResourceDefinition or APIService that
+// was added, updated, or deleted. It must be called before Start.
+func (w *APIChangeWatcher) OnAPIChange(handler func(ctx context.Context, group string)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.handlers = append(w.handlers, handler)
+}
+
+// Start watches the CustomResourceDefinitions and APIServices until the context is canceled. The objects that
+// exist when starting don't notify the handlers.
+func (w *APIChangeWatcher) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("api-changes")
+
+	factory := metadatainformer.NewSharedInformerFactory(w.client, 0)
+
+	for _, gvr := range []schema.GroupVersionResource{crdGVR, apiServiceGVR} {
+		_, err := factory.ForResource(gvr).Informer().AddEventHandler(cache.ResourceEventHandlerDetailedFuncs{
+			AddFunc: func(obj any, isInInitialList bool) {
+				if !isInInitialList {
+					w.notify(ctx, gvr, obj)
+				}
+			},
+			UpdateFunc: func(oldObj, newObj any) {
+				oldMeta, oldOK := oldObj.(*metav1.PartialObjectMetadata)
+				newMeta, newOK := newObj.(*metav1.PartialObjectMetadata)
+
+				if oldOK && newOK && oldMeta.ResourceVersion == newMeta.ResourceVersion {
+					return
+				}
+
+				w.notify(ctx, gvr, newObj)
+			},
+			DeleteFunc: func(obj any) {
+				if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+					obj = tombstone.Obj
+				}
+
+				w.notify(ctx, gvr, obj)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch %s: %w", gvr.Resource, err)
+		}
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+
+	for gvr, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			log.Info("The watch did not sync before stopping", "resource", gvr.Resource)
+		}
+	}
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// notify calls the handlers with the API group of the CustomResourceDefinition or APIService.
+func (w *APIChangeWatcher) notify(ctx context.Context, gvr schema.GroupVersionResource, obj any) {
+	objMeta, ok := obj.(*metav1.PartialObjectMetadata)
+	if !ok {
+		return
+	}
+
+	group, ok := apiGroupFromName(gvr, objMeta.Name)
+	if !ok {
+		return
+	}
+
+	ctrl.LoggerFrom(ctx).WithName("api-changes").V(1).Info(
+		"Detected an API change", "resource", gvr.Resource, "name", objMeta.Name, "group", group,
+	)
+
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	for _, handler := range w.handlers {
+		handler(ctx, group)
+	}
+}
+
+// apiGroupFromName returns the API group from the name of a CustomResourceDefinition, which is <plural>.<group>,
+// or from the name of an APIService, which is <version>.<group>. The core API group is an empty string.
+func apiGroupFromName(gvr schema.GroupVersionResource, name string) (string, bool) {
+	_, group, found := strings.Cut(name, ".")
+	if !found {
+		return "", false
+	}
+
+	// A CustomResourceDefinition must have a group, so it must have a name with a group
+	if gvr == crdGVR && group == "" {
+		return "", false
+	}
+
+	return group, true
+}
+
+// missingAPITracker tracks the API groups that policies failed to find a mapping for during their last
+// evaluation, so that they can be evaluated again when an API of the group is added or updated.
+type missingAPITracker struct {
+	lock sync.Mutex
+	// groups has the policies as keys and the values are sets of the API groups with a missing mapping
+	groups map[types.NamespacedName]map[string]bool
+	// pending has the API groups with a missing mapping of the policies being evaluated, which replace the
+	// groups of the policies once the evaluation finishes
+	pending map[types.NamespacedName]map[string]bool
+	// available has the policies with a missing API group that changed since their last evaluation
+	available map[types.NamespacedName]bool
+	events    chan event.GenericEvent
+}
+
+func newMissingAPITracker() *missingAPITracker {
+	return &missingAPITracker{
+		groups:    map[types.NamespacedName]map[string]bool{},
+		pending:   map[types.NamespacedName]map[string]bool{},
+		available: map[types.NamespacedName]bool{},
+		events:    make(chan event.GenericEvent, 1024),
+	}
+}
+
+// source returns the source of the requests for the policies whose missing API group changed.
+func (t *missingAPITracker) source() source.Source {
+	return source.Channel(t.events, &handler.EnqueueRequestForObject{})
+}
+
+// start begins recording the missing API groups of the policy being evaluated. The missing API groups of the
+// last evaluation are kept until the evaluation finishes, so that an API change during the evaluation still
+// requeues the policy.
+func (t *missingAPITracker) start(policy types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.pending[policy] = map[string]bool{}
+}
+
+// record adds the API group to the missing API groups of the policy being evaluated.
+func (t *missingAPITracker) record(policy types.NamespacedName, group string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.pending[policy] == nil {
+		t.pending[policy] = map[string]bool{}
+	}
+
+	t.pending[policy][group] = true
+}
+
+// finish replaces the missing API groups of the policy with the ones recorded during its evaluation, so that an
+// API group is only cleared once the policy found a mapping for it.
+func (t *missingAPITracker) finish(policy types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.pending[policy]) == 0 {
+		delete(t.groups, policy)
+	} else {
+		t.groups[policy] = t.pending[policy]
+	}
+
+	delete(t.pending, policy)
+}
+
+// forget stops tracking a deleted policy.
+func (t *missingAPITracker) forget(policy types.NamespacedName) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.groups, policy)
+	delete(t.pending, policy)
+	delete(t.available, policy)
+}
+
+// hasUpdate returns true, once, if a missing API group of the policy changed since its last evaluation.
+func (t *missingAPITracker) hasUpdate(policy types.NamespacedName) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.available[policy] {
+		return false
+	}
+
+	delete(t.available, policy)
+
+	return true
+}
+
+// apiChanged enqueues the policies that are missing the API group, unless they are already waiting to be
+// evaluated again. The API group stays tracked until an evaluation finds its mapping, since a
+// CustomResourceDefinition is usually added before its API is established.
+func (t *missingAPITracker) apiChanged(ctx context.Context, group string) {
+	t.lock.Lock()
+
+	policies := []types.NamespacedName{}
+
+	for _, tracked := range []map[types.NamespacedName]map[string]bool{t.groups, t.pending} {
+		for policy, groups := range tracked {
+			if groups[group] && !t.available[policy] {
+				policies = append(policies, policy)
+				t.available[policy] = true
+			}
+		}
+	}
+
+	t.lock.Unlock()
+
+	if len(policies) == 0 {
+		return
+	}
+
+	ctrl.LoggerFrom(ctx).WithName("api-changes").Info(
+		"Requeuing the policies with a missing API mapping", "group", group, "policies", len(policies),
+	)
+
+	for _, policy := range policies {
+		select {
+		case t.events <- event.GenericEvent{Object: &policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Namespace: policy.Namespace, Name: policy.Name},
+		}}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// apiGroupInvalidator is implemented by the DynamicWatchers that cache API mappings per API group.
+type apiGroupInvalidator interface {
+	InvalidateAPIGroup(group string)
+}
+
+// NewDynamicWatcher returns a DynamicWatcher whose API mappings can be refreshed per API group with
+// InvalidateAPIGroup, so that a policy finds the mapping of an API as soon as it's available.
+func NewDynamicWatcher(
+	config *rest.Config, reconciler depclient.Reconciler, options *depclient.Options,
+) (depclient.DynamicWatcher, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a dynamic Kubernetes client: %w", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a discovery Kubernetes client: %w", err)
+	}
+
+	return newAPIGroupDynamicWatcher(dynamicClient, discoveryClient, reconciler, options, nil), nil
+}
+
+// apiGroupDynamicWatcher is a DynamicWatcher whose cached API mappings can be invalidated when an API group
+// changes.
+type apiGroupDynamicWatcher struct {
+	depclient.DynamicWatcher
+	discovery *apiGroupDiscovery
+	// kinds is set when the DynamicWatcher watches the metadata of objects
+	kinds *resourceKinds
+}
+
+// newAPIGroupDynamicWatcher returns an apiGroupDynamicWatcher with the clients. The DynamicWatcher caches the
+// GVK to GVR conversions for a minimal time so that the resources cached by the discovery client per API group
+// are used instead.
+func newAPIGroupDynamicWatcher(
+	dynamicClient dynamic.Interface,
+	discoveryClient discovery.DiscoveryInterface,
+	reconciler depclient.Reconciler,
+	options *depclient.Options,
+	kinds *resourceKinds,
+) *apiGroupDynamicWatcher {
+	watcherOptions := depclient.Options{}
+	if options != nil {
+		watcherOptions = *options
+	}
+
+	watcherOptions.ObjectCacheOptions.GVKToGVRCacheTTL = time.Nanosecond
+
+	groupDiscovery := &apiGroupDiscovery{DiscoveryInterface: discoveryClient}
+
+	return &apiGroupDynamicWatcher{
+		DynamicWatcher: depclient.NewWithClients(dynamicClient, groupDiscovery, reconciler, &watcherOptions),
+		discovery:      groupDiscovery,
+		kinds:          kinds,
+	}
+}
+
+func (w *apiGroupDynamicWatcher) InvalidateAPIGroup(group string) {
+	w.discovery.invalidate(group)
+
+	if w.kinds != nil {
+		w.kinds.invalidate(group)
+	}
+}
+
+// apiGroupDiscovery is a discovery client that caches the resources of the group versions that were found until
+// their API group is invalidated.
+type apiGroupDiscovery struct {
+	discovery.DiscoveryInterface
+	// resources has the group versions as keys and the values are the *metav1.APIResourceList of the group version
+	resources sync.Map
+}
+
+func (d *apiGroupDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*metav1.APIResourceList, error) {
+	if cached, ok := d.resources.Load(groupVersion); ok {
+		return cached.(*metav1.APIResourceList), nil
+	}
+
+	resources, err := d.DiscoveryInterface.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	d.resources.Store(groupVersion, resources)
+
+	return resources, nil
+}
+
+// invalidate drops the cached resources of the API group.
+func (d *apiGroupDiscovery) invalidate(group string) {
+	d.resources.Range(func(key, _ any) bool {
+		groupVersion, err := schema.ParseGroupVersion(key.(string))
+		if err != nil || groupVersion.Group == group {
+			d.resources.Delete(key)
+		}
+
+		return true
+	})
+}
+
+// handleAPIChange refreshes the cached API mappings of the API group and requeues the policies that failed on a
+// missing mapping in the API group.
+func (r *ConfigurationPolicyReconciler) handleAPIChange(ctx context.Context, group string) {
+	for _, dynamicWatcher := range r.policyWatchers() {
+		if invalidator, ok := dynamicWatcher.(apiGroupInvalidator); ok {
+			invalidator.InvalidateAPIGroup(group)
+		}
+	}
+
+	// The RESTMapper of the manager reloads an API group when a mapping is missing, but the mappings that changed
+	// are only refreshed when it can be reset
+	if resettable, ok := r.TargetRESTMapper.(meta.ResettableRESTMapper); ok {
+		resettable.Reset()
+	}
+
+	r.missingAPIs.apiChanged(ctx, group)
+}
+
+// recordMissingAPI records that the policy failed to find a mapping for the API group during its evaluation.
+func (r *ConfigurationPolicyReconciler) recordMissingAPI(policy *policyv1.ConfigurationPolicy, group string) {
+	if r.missingAPIs != nil {
+		r.missingAPIs.record(types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, group)
+	}
+}
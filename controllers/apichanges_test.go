@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestAPIGroupFromName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		gvrIsCRD bool
+		name     string
+		group    string
+		ok       bool
+	}{
+		"CRD":                {gvrIsCRD: true, name: "pizzas.example.com", group: "example.com", ok: true},
+		"CRD without group":  {gvrIsCRD: true, name: "pizzas."},
+		"APIService":         {name: "v1beta1.pizza.example.com", group: "pizza.example.com", ok: true},
+		"core APIService":    {name: "v1.", group: "", ok: true},
+		"invalid APIService": {name: "v1"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			gvr := apiServiceGVR
+			if test.gvrIsCRD {
+				gvr = crdGVR
+			}
+
+			group, ok := apiGroupFromName(gvr, test.name)
+			assert.Equal(t, test.ok, ok)
+			assert.Equal(t, test.group, group)
+		})
+	}
+}
+
+func TestMissingAPITracker(t *testing.T) {
+	t.Parallel()
+
+	tracker := newMissingAPITracker()
+	policyA := types.NamespacedName{Namespace: "managed", Name: "policy-a"}
+	policyB := types.NamespacedName{Namespace: "managed", Name: "policy-b"}
+
+	for policy, group := range map[types.NamespacedName]string{
+		policyA: "example.com", policyB: "other.example.com",
+	} {
+		tracker.start(policy)
+		tracker.record(policy, group)
+		tracker.finish(policy)
+	}
+
+	// Only the policies missing the changed API group are requeued
+	tracker.apiChanged(t.Context(), "example.com")
+
+	assert.Len(t, tracker.events, 1)
+	assert.Equal(t, policyA.Name, (<-tracker.events).Object.GetName())
+	assert.True(t, tracker.hasUpdate(policyA))
+	assert.False(t, tracker.hasUpdate(policyA), "the update is only reported once")
+	assert.False(t, tracker.hasUpdate(policyB))
+
+	// The missing APIs are kept until an evaluation finds their mapping
+	tracker.apiChanged(t.Context(), "example.com")
+	assert.Len(t, tracker.events, 1)
+	<-tracker.events
+
+	// A policy waiting to be evaluated again isn't requeued twice
+	tracker.apiChanged(t.Context(), "example.com")
+	assert.Empty(t, tracker.events)
+
+	// The missing APIs of the last evaluation are cleared when an evaluation doesn't record them
+	tracker.start(policyB)
+	tracker.finish(policyB)
+	tracker.apiChanged(t.Context(), "other.example.com")
+	assert.Empty(t, tracker.events)
+
+	tracker.start(policyB)
+	tracker.record(policyB, "other.example.com")
+	tracker.finish(policyB)
+	tracker.forget(policyB)
+	tracker.apiChanged(t.Context(), "other.example.com")
+	assert.Empty(t, tracker.events)
+}
+
+func TestMissingAPITrackerCRDEstablished(t *testing.T) {
+	t.Parallel()
+
+	tracker := newMissingAPITracker()
+	policy := types.NamespacedName{Namespace: "managed", Name: "policy"}
+
+	tracker.start(policy)
+	tracker.record(policy, "example.com")
+	tracker.finish(policy)
+
+	// The CustomResourceDefinition is added, which requeues the policy
+	tracker.apiChanged(t.Context(), "example.com")
+	assert.Len(t, tracker.events, 1)
+	<-tracker.events
+
+	// The policy is evaluated before the API is established, so the mapping is still missing, and the
+	// CustomResourceDefinition is established during the evaluation
+	assert.True(t, tracker.hasUpdate(policy))
+	tracker.start(policy)
+	tracker.record(policy, "example.com")
+	tracker.apiChanged(t.Context(), "example.com")
+	tracker.finish(policy)
+
+	assert.Len(t, tracker.events, 1, "the policy is requeued when the API is established")
+	<-tracker.events
+	assert.True(t, tracker.hasUpdate(policy))
+
+	// The mapping is found, so the API group is no longer tracked
+	tracker.start(policy)
+	tracker.finish(policy)
+	tracker.apiChanged(t.Context(), "example.com")
+	assert.Empty(t, tracker.events)
+}
+
+func TestAPIGroupDynamicWatcherInvalidate(t *testing.T) {
+	t.Parallel()
+
+	gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Pizza"}
+
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	watcher := newAPIGroupDynamicWatcher(
+		dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()), discoveryClient, nil, nil, nil,
+	)
+
+	_, err := watcher.GVKToGVR(gvk)
+	assert.ErrorIs(t, err, depclient.ErrNoVersionedResource)
+
+	// The API is available once the API group is invalidated
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "pizzas", Kind: "Pizza", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "watch"}},
+		},
+	}}
+
+	gvr, err := watcher.GVKToGVR(gvk)
+	assert.NoError(t, err)
+	assert.Equal(t, "pizzas", gvr.Resource)
+
+	// The resources of the API group are cached until the API group is invalidated
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "example.com/v1",
+		APIResources: []metav1.APIResource{
+			{Name: "pies", Kind: "Pizza", Namespaced: true, Verbs: metav1.Verbs{"get", "list", "watch"}},
+		},
+	}}
+
+	gvr, err = watcher.GVKToGVR(gvk)
+	assert.NoError(t, err)
+	assert.Equal(t, "pizzas", gvr.Resource)
+
+	watcher.InvalidateAPIGroup("other.example.com")
+
+	gvr, err = watcher.GVKToGVR(gvk)
+	assert.NoError(t, err)
+	assert.Equal(t, "pizzas", gvr.Resource)
+
+	watcher.InvalidateAPIGroup("example.com")
+
+	gvr, err = watcher.GVKToGVR(gvk)
+	assert.NoError(t, err)
+	assert.Equal(t, "pies", gvr.Resource)
+}
+
+func TestAPIChangeWatcher(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, metav1.AddMetaToScheme(scheme))
+
+	existingCRD := &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apiextensions.k8s.io/v1", Kind: "CustomResourceDefinition"},
+		ObjectMeta: metav1.ObjectMeta{Name: "existings.example.com", ResourceVersion: "1"},
+	}
+
+	client := metadatafake.NewSimpleMetadataClient(scheme, existingCRD)
+	watcher := NewAPIChangeWatcher(client)
+
+	groups := make(chan string, 10)
+	watcher.OnAPIChange(func(_ context.Context, group string) { groups <- group })
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- watcher.Start(ctx) }()
+
+	defer func() {
+		cancel()
+		assert.NoError(t, <-done)
+	}()
+
+	// Wait for the watches to start, since the fake client doesn't send the events created before the watch
+	assert.Eventually(t, func() bool {
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "watch" && action.GetResource() == apiServiceGVR {
+				return true
+			}
+		}
+
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err := client.Resource(apiServiceGVR).(metadatafake.MetadataClient).CreateFake(
+		&metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "apiregistration.k8s.io/v1", Kind: "APIService"},
+			ObjectMeta: metav1.ObjectMeta{Name: "v1beta1.pizza.example.com", ResourceVersion: "2"},
+		},
+		metav1.CreateOptions{},
+	)
+	assert.NoError(t, err)
+
+	select {
+	case group := <-groups:
+		// The existing CRD doesn't notify the handlers
+		assert.Equal(t, "pizza.example.com", group)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the API change")
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+// auditAction is the kind of change the controller made to an object.
+type auditAction string
+
+const (
+	auditActionCreate   auditAction = "create"
+	auditActionUpdate   auditAction = "update"
+	auditActionRecreate auditAction = "recreate"
+	auditActionDelete   auditAction = "delete"
+)
+
+// auditEntry is a line of the audit log.
+type auditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Policy    auditRef  `json:"policy"`
+	// TemplateIndex is the index of the object template of a ConfigurationPolicy that caused the change.
+	TemplateIndex *int        `json:"templateIndex,omitempty"`
+	Object        auditRef    `json:"object"`
+	Action        auditAction `json:"action"`
+	// Diff is the difference between the object before and after the change. It's empty when recordDiff is None
+	// and it only has the redaction message when the diff is censored.
+	Diff   string `json:"diff,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+type auditRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	UID        string `json:"uid,omitempty"`
+}
+
+// AuditLogger writes a JSON line for each change the controller makes to the objects on the cluster when
+// enforcing policies. It's independent of the controller log and its verbosity.
+type AuditLogger struct {
+	lock sync.Mutex
+	out  io.Writer
+	now  func() time.Time
+}
+
+// NewAuditLogger returns an AuditLogger that writes to the input writer, such as os.Stdout or a RotatingFile.
+func NewAuditLogger(out io.Writer) *AuditLogger {
+	return &AuditLogger{out: out, now: time.Now}
+}
+
+// record writes the entry to the audit log. Failures are logged since the change was already made.
+func (a *AuditLogger) record(ctx context.Context, entry auditEntry) {
+	entry.Timestamp = a.now().UTC()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to encode the audit log entry")
+
+		return
+	}
+
+	line = append(line, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, err := a.out.Write(line); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to write the audit log entry", "entry", string(line))
+	}
+}
+
+// RotatingFile is a file that is rotated when it reaches a maximum size. The rotated files have the suffixes .1
+// (the most recent) to .<max backups>, and older files are removed.
+type RotatingFile struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile opens the file at the path for appending, creating it if needed. A maxBytes of 0 disables the
+// rotation.
+func OpenRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	rotating := &RotatingFile{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+
+	if err := rotating.open(); err != nil {
+		return nil, err
+	}
+
+	return rotating, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open the file %s: %w", f.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("failed to read the size of the file %s: %w", f.path, err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+
+	return nil
+}
+
+// Write appends the data to the file, first rotating the file if the data would make it exceed the maximum size.
+func (f *RotatingFile) Write(data []byte) (int, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.maxBytes > 0 && f.size > 0 && f.size+int64(len(data)) > f.maxBytes {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	written, err := f.file.Write(data)
+	f.size += int64(written)
+
+	return written, err
+}
+
+// rotate renames the current file to the first backup, shifting the existing backups, and opens a new file.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close the file %s: %w", f.path, err)
+	}
+
+	if f.maxBackups < 1 {
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove the file %s: %w", f.path, err)
+		}
+
+		return f.open()
+	}
+
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		backup := f.path + "." + strconv.Itoa(i)
+
+		if err := os.Rename(backup, f.path+"."+strconv.Itoa(i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate the file %s: %w", backup, err)
+		}
+	}
+
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate the file %s: %w", f.path, err)
+	}
+
+	return f.open()
+}
+
+// Close closes the current file.
+func (f *RotatingFile) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.file.Close()
+}
+
+// countEnforcementAction increments the enforcement actions metric for the change made to an object of the kind.
+func countEnforcementAction(kind string, policy metav1.Object, objectKind string, action auditAction, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+
+	enforcementActionsCounter.WithLabelValues(
+		kind, policy.GetName(), policy.GetNamespace(), objectKind, string(action), result,
+	).Inc()
+}
+
+// auditResult sets the result and error message of the change in the audit log entry.
+func auditResult(entry *auditEntry, err error) {
+	entry.Result = "success"
+
+	if err != nil {
+		entry.Result = "failure"
+		entry.Error = err.Error()
+	}
+}
+
+// auditObjectRef returns the reference to the object, with the input kind since the kind of typed objects is not
+// always set.
+func auditObjectRef(obj metav1.Object, gvk schema.GroupVersionKind) auditRef {
+	return auditRef{
+		APIVersion: gvk.GroupVersion().String(),
+		Kind:       gvk.Kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        string(obj.GetUID()),
+	}
+}
+
+// auditDiff returns the difference between the object before and after the change, honoring the recordDiff of
+// the object template. The diff is generated even when recordDiff is Log, since the audit log is a log of its own.
+// A nil before object is a creation.
+func (r *ConfigurationPolicyReconciler) auditDiff(
+	recordDiff policyv1.RecordDiff, before *unstructured.Unstructured, after *unstructured.Unstructured,
+) string {
+	if after == nil {
+		return ""
+	}
+
+	if before == nil {
+		before = &unstructured.Unstructured{Object: map[string]any{}}
+	}
+
+	if recordDiff == policyv1.RecordDiffLog {
+		recordDiff = policyv1.RecordDiffInStatus
+	}
+
+	return handleDiff(logr.Discard(), recordDiff, before, after, r.FullDiffs)
+}
+
+// auditObjectChange counts the change the ConfigurationPolicy made to the object in the enforcement metrics, adds it
+// to the decision trace of the evaluation, and records it in the audit log, when the audit log is enabled. A
+// negative template index is for changes not caused by an object template, such as pruning.
+func (r *ConfigurationPolicyReconciler) auditObjectChange(
+	ctx context.Context,
+	plc *policyv1.ConfigurationPolicy,
+	templateIndex int,
+	action auditAction,
+	before *unstructured.Unstructured,
+	after *unstructured.Unstructured,
+	err error,
+) {
+	obj := after
+	if obj == nil {
+		obj = before
+	}
+
+	if obj == nil {
+		return
+	}
+
+	countEnforcementAction("ConfigurationPolicy", plc, obj.GetKind(), action, err)
+	decisionTraceFrom(ctx).actionTaken(templateIndex, obj, action, err)
+
+	if r.AuditLogger == nil {
+		return
+	}
+
+	entry := auditEntry{
+		Policy: auditObjectRef(plc, policyv1.GroupVersion.WithKind("ConfigurationPolicy")),
+		Object: auditObjectRef(obj, obj.GroupVersionKind()),
+		Action: action,
+	}
+
+	if templateIndex >= 0 {
+		entry.TemplateIndex = &templateIndex
+
+		if templateIndex < len(plc.Spec.ObjectTemplates) && action != auditActionDelete {
+			entry.Diff = r.auditDiff(plc.Spec.ObjectTemplates[templateIndex].RecordDiffWithDefault(), before, after)
+		}
+	}
+
+	auditResult(&entry, err)
+
+	r.AuditLogger.record(ctx, entry)
+}
+
+// auditObjectChange counts the change the OperatorPolicy made to the object in the enforcement metrics and records
+// it in the audit log, when the audit log is enabled. The diff is only set for updates when the object before the
+// update is known.
+func (r *OperatorPolicyReconciler) auditObjectChange(
+	ctx context.Context,
+	policy *policyv1beta1.OperatorPolicy,
+	action auditAction,
+	gvk schema.GroupVersionKind,
+	before client.Object,
+	after client.Object,
+	err error,
+) {
+	countEnforcementAction("OperatorPolicy", policy, gvk.Kind, action, err)
+
+	if r.AuditLogger == nil {
+		return
+	}
+
+	obj := after
+	if obj == nil {
+		obj = before
+	}
+
+	entry := auditEntry{
+		Policy: auditObjectRef(policy, policyv1beta1.GroupVersion.WithKind("OperatorPolicy")),
+		Object: auditObjectRef(obj, gvk),
+		Action: action,
+	}
+
+	if action == auditActionUpdate && before != nil && after != nil {
+		entry.Diff = operatorAuditDiff(before, after)
+	}
+
+	auditResult(&entry, err)
+
+	r.AuditLogger.record(ctx, entry)
+}
+
+// operatorAuditDiff returns the difference between the objects managed by an OperatorPolicy, which don't contain
+// sensitive data. The noisy fields, such as managedFields and the status, are removed.
+func operatorAuditDiff(before, after client.Object) string {
+	objects := make([]*unstructured.Unstructured, 0, 2)
+
+	for _, obj := range []client.Object{before, after} {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return ""
+		}
+
+		converted := &unstructured.Unstructured{Object: content}
+		removeFieldsForComparison(converted)
+		unstructured.RemoveNestedField(converted.Object, "status")
+
+		objects = append(objects, converted)
+	}
+
+	diff, err := generateDiff(objects[0], objects[1], false)
+	if err != nil {
+		return ""
+	}
+
+	return diff
+}
@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	file, err := OpenRotatingFile(path, 10, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := file.Write([]byte(line))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, file.Close())
+
+	expected := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+
+	for filePath, content := range expected {
+		data, err := os.ReadFile(filePath)
+		if assert.NoError(t, err) {
+			assert.Equal(t, content, string(data), filePath)
+		}
+	}
+
+	_, err = os.Stat(path + ".3")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Reopening the file continues from its current size
+	file, err = OpenRotatingFile(path, 10, 2)
+	if assert.NoError(t, err) {
+		_, err = file.Write([]byte("fifth\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+
+		data, err := os.ReadFile(path + ".1")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "fourth\n", string(data))
+		}
+	}
+}
+
+func auditEntries(t *testing.T, out *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	entries := []map[string]any{}
+
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		entry := map[string]any{}
+		if assert.NoError(t, json.Unmarshal([]byte(line), &entry), line) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+func TestConfigurationPolicyAuditObjectChange(t *testing.T) {
+	t.Parallel()
+
+	configMap := func(data string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": "app", "namespace": "default", "uid": "cm-uid"},
+			"data":       map[string]any{"key": data},
+		}}
+	}
+
+	tests := map[string]struct {
+		objectDefinition string
+		recordDiff       policyv1.RecordDiff
+		templateIndex    int
+		action           auditAction
+		before           *unstructured.Unstructured
+		after            *unstructured.Unstructured
+		err              error
+		expectedDiff     string
+		expectedResult   string
+	}{
+		"update with a diff logged": {
+			objectDefinition: `{"apiVersion": "v1", "kind": "ConfigMap"}`,
+			recordDiff:       policyv1.RecordDiffLog,
+			action:           auditActionUpdate,
+			before:           configMap("old"),
+			after:            configMap("new"),
+			expectedDiff:     "-  key: old\n+  key: new",
+			expectedResult:   "success",
+		},
+		"create of a censored kind": {
+			objectDefinition: `{"apiVersion": "v1", "kind": "Secret"}`,
+			action:           auditActionCreate,
+			after:            configMap("secret"),
+			expectedDiff:     "# The difference is redacted because it contains sensitive data.",
+			expectedResult:   "success",
+		},
+		"failed recreate without a diff": {
+			objectDefinition: `{"apiVersion": "v1", "kind": "ConfigMap"}`,
+			recordDiff:       policyv1.RecordDiffNone,
+			action:           auditActionRecreate,
+			before:           configMap("old"),
+			after:            configMap("new"),
+			err:              errors.New("the object is immutable"),
+			expectedResult:   "failure",
+		},
+		"prune": {
+			objectDefinition: `{"apiVersion": "v1", "kind": "ConfigMap"}`,
+			templateIndex:    -1,
+			action:           auditActionDelete,
+			before:           configMap("old"),
+			expectedResult:   "success",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			r := &ConfigurationPolicyReconciler{AuditLogger: NewAuditLogger(out)}
+			r.AuditLogger.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+
+			plc := &policyv1.ConfigurationPolicy{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "my-policy", UID: "policy-uid"},
+				Spec: policyv1.ConfigurationPolicySpec{
+					ObjectTemplates: []*policyv1.ObjectTemplate{{
+						ObjectDefinition: runtime.RawExtension{Raw: []byte(test.objectDefinition)},
+						RecordDiff:       test.recordDiff,
+					}},
+				},
+			}
+
+			r.auditObjectChange(
+				context.Background(), plc, test.templateIndex, test.action, test.before, test.after, test.err,
+			)
+
+			entries := auditEntries(t, out)
+			if !assert.Len(t, entries, 1) {
+				return
+			}
+
+			entry := entries[0]
+			assert.Equal(t, "2024-05-01T12:00:00Z", entry["timestamp"])
+			assert.Equal(t, map[string]any{
+				"apiVersion": "policy.open-cluster-management.io/v1",
+				"kind":       "ConfigurationPolicy",
+				"namespace":  "managed",
+				"name":       "my-policy",
+				"uid":        "policy-uid",
+			}, entry["policy"])
+			assert.Equal(t, map[string]any{
+				"apiVersion": "v1", "kind": "ConfigMap", "namespace": "default", "name": "app", "uid": "cm-uid",
+			}, entry["object"])
+			assert.Equal(t, string(test.action), entry["action"])
+			assert.Equal(t, test.expectedResult, entry["result"])
+
+			if test.templateIndex < 0 {
+				assert.NotContains(t, entry, "templateIndex")
+			} else {
+				assert.InDelta(t, float64(test.templateIndex), entry["templateIndex"], 0)
+			}
+
+			if test.expectedDiff == "" {
+				assert.NotContains(t, entry, "diff")
+			} else {
+				assert.Contains(t, entry["diff"], test.expectedDiff)
+			}
+
+			if test.err == nil {
+				assert.NotContains(t, entry, "error")
+			} else {
+				assert.Equal(t, test.err.Error(), entry["error"])
+			}
+		})
+	}
+}
+
+func TestOperatorPolicyAuditObjectChange(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	r := &OperatorPolicyReconciler{AuditLogger: NewAuditLogger(out)}
+
+	policy := &policyv1beta1.OperatorPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "install"}}
+	before := &operatorv1alpha1.Subscription{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "operators", Name: "my-operator"},
+		Spec:       &operatorv1alpha1.SubscriptionSpec{Channel: "stable-1"},
+	}
+	after := before.DeepCopy()
+	after.Spec.Channel = "stable-2"
+
+	r.auditObjectChange(context.Background(), policy, auditActionUpdate, subscriptionGVK, before, after, nil)
+	r.auditObjectChange(context.Background(), policy, auditActionDelete, subscriptionGVK, after, nil, nil)
+
+	entries := auditEntries(t, out)
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+
+	assert.Equal(t, "OperatorPolicy", entries[0]["policy"].(map[string]any)["kind"])
+	assert.Equal(t, map[string]any{
+		"apiVersion": "operators.coreos.com/v1alpha1",
+		"kind":       "Subscription",
+		"namespace":  "operators",
+		"name":       "my-operator",
+	}, entries[0]["object"])
+	assert.NotContains(t, entries[0], "templateIndex")
+	assert.Contains(t, entries[0]["diff"], "-  channel: stable-1\n+  channel: stable-2")
+	assert.Equal(t, "delete", entries[1]["action"])
+	assert.NotContains(t, entries[1], "diff")
+}
+
+func TestAuditObjectChangeDisabled(t *testing.T) {
+	t.Parallel()
+
+	r := &ConfigurationPolicyReconciler{}
+
+	// Nothing is recorded and nothing panics without an AuditLogger
+	r.auditObjectChange(context.Background(), &policyv1.ConfigurationPolicy{}, 0, auditActionCreate, nil, nil, nil)
+}
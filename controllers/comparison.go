@@ -0,0 +1,235 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// canonicalKey returns the canonical form of a value decoded from JSON. When sortLists is false, it's
+// identical to fmt.Sprint of the value, so values with the same key are the ones fmt.Sprint considers
+// equal. When sortLists is true, the items of every list are ordered by their own canonical form, so
+// lists with the same items in a different order have the same key. The key is built in a single
+// pass, which is much cheaper than formatting the value with fmt, and is meant to be computed once
+// per value and used as a map key or a sort key.
+func canonicalKey(item interface{}, sortLists bool) string {
+	var builder strings.Builder
+
+	writeCanonical(&builder, item, sortLists)
+
+	return builder.String()
+}
+
+// writeCanonical writes the canonical form of the item to the builder. The output matches the %v
+// formatting of the fmt package for the types produced by decoding JSON, and falls back to fmt for
+// any other type.
+func writeCanonical(builder *strings.Builder, item interface{}, sortLists bool) {
+	switch item := item.(type) {
+	case nil:
+		builder.WriteString("<nil>")
+	case string:
+		builder.WriteString(item)
+	case bool:
+		builder.WriteString(strconv.FormatBool(item))
+	case int64:
+		builder.WriteString(strconv.FormatInt(item, 10))
+	case int:
+		builder.WriteString(strconv.Itoa(item))
+	case float64:
+		builder.WriteString(strconv.FormatFloat(item, 'g', -1, 64))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(item))
+		for key := range item {
+			keys = append(keys, key)
+		}
+
+		slices.Sort(keys)
+
+		builder.WriteString("map[")
+
+		for i, key := range keys {
+			if i > 0 {
+				builder.WriteByte(' ')
+			}
+
+			builder.WriteString(key)
+			builder.WriteByte(':')
+			writeCanonical(builder, item[key], sortLists)
+		}
+
+		builder.WriteByte(']')
+	case []interface{}:
+		builder.WriteByte('[')
+
+		if sortLists {
+			sorted := make([]string, len(item))
+			for i, val := range item {
+				sorted[i] = canonicalKey(val, true)
+			}
+
+			slices.Sort(sorted)
+
+			builder.WriteString(strings.Join(sorted, " "))
+		} else {
+			for i, val := range item {
+				if i > 0 {
+					builder.WriteByte(' ')
+				}
+
+				writeCanonical(builder, val, false)
+			}
+		}
+
+		builder.WriteByte(']')
+	default:
+		builder.WriteString(fmt.Sprint(item))
+	}
+}
+
+// canonicalItem is a list item with its canonical key.
+type canonicalItem struct {
+	key   string
+	value interface{}
+}
+
+// sortByCanonicalKey returns the items of the list sorted by their canonical key with sorted lists,
+// without modifying the input list. The key of each item is computed once rather than on every
+// comparison.
+func sortByCanonicalKey(list []interface{}) []canonicalItem {
+	items := make([]canonicalItem, len(list))
+
+	for i, val := range list {
+		items[i] = canonicalItem{key: canonicalKey(val, true), value: val}
+	}
+
+	// This must remain sort.Slice rather than a stable sort since the order of items with the same key
+	// determines which items are compared when comparing lists.
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].key < items[j].key
+	})
+
+	return items
+}
+
+// scalarIndex maps the canonical key of each scalar item of a list to the indexes of the items with
+// that key, in ascending order. Items which are nil are tracked separately since a nil item can be
+// equivalent to a scalar with a different key when zero values are considered equal to nil.
+type scalarIndex struct {
+	byKey map[string][]int
+	nils  []int
+}
+
+// newScalarIndex indexes the scalar items of the list. Maps and lists are not indexed since they are
+// never equivalent to a scalar.
+func newScalarIndex(list []interface{}) *scalarIndex {
+	index := &scalarIndex{byKey: map[string][]int{}}
+
+	for i, val := range list {
+		switch val.(type) {
+		case map[string]interface{}, []interface{}:
+		case nil:
+			index.nils = append(index.nils, i)
+		default:
+			key := canonicalKey(val, false)
+			index.byKey[key] = append(index.byKey[key], i)
+		}
+	}
+
+	return index
+}
+
+// candidates returns the indexes of the items which are equivalent to the non-nil scalar with the
+// input canonical key, in ascending order. This matches the items for which deeplyEquivalent returns
+// true when comparing an item of the indexed list to the scalar.
+func (index *scalarIndex) candidates(scalar interface{}, key string, zeroValueEqualsNil bool) []int {
+	matches := index.byKey[key]
+
+	if len(index.nils) == 0 {
+		return matches
+	}
+
+	nilMatches := key == "<nil>"
+	if zeroValueEqualsNil {
+		nilMatches = canonicalKey(reflect.Zero(reflect.TypeOf(scalar)).Interface(), false) == key
+	}
+
+	if !nilMatches {
+		return matches
+	}
+
+	merged := make([]int, 0, len(matches)+len(index.nils))
+	merged = append(merged, matches...)
+	merged = append(merged, index.nils...)
+
+	slices.Sort(merged)
+
+	return merged
+}
+
+// copyJSONValue returns a deep copy of a value decoded from JSON, which is identical to the result of
+// encoding the value to JSON and decoding it again, but without the cost of the encoding. In
+// particular, integers become float64 values and empty maps and lists that are nil become nil. False
+// is returned if the value contains anything for which the result of the JSON round trip could
+// differ, such as invalid UTF-8 or types that are not produced by decoding JSON, in which case the
+// caller must fall back to the JSON round trip.
+func copyJSONValue(item interface{}) (interface{}, bool) {
+	switch item := item.(type) {
+	case nil:
+		return nil, true
+	case string:
+		return item, utf8.ValidString(item)
+	case bool:
+		return item, true
+	case int64:
+		return float64(item), true
+	case float64:
+		return item, !math.IsNaN(item) && !math.IsInf(item, 0)
+	case map[string]interface{}:
+		if item == nil {
+			return nil, true
+		}
+
+		copied := make(map[string]interface{}, len(item))
+
+		for key, val := range item {
+			if !utf8.ValidString(key) {
+				return nil, false
+			}
+
+			copiedVal, ok := copyJSONValue(val)
+			if !ok {
+				return nil, false
+			}
+
+			copied[key] = copiedVal
+		}
+
+		return copied, true
+	case []interface{}:
+		if item == nil {
+			return nil, true
+		}
+
+		copied := make([]interface{}, len(item))
+
+		for i, val := range item {
+			copiedVal, ok := copyJSONValue(val)
+			if !ok {
+				return nil, false
+			}
+
+			copied[i] = copiedVal
+		}
+
+		return copied, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,732 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiRes "k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// The differential tests compare the list comparison and merge functions to the legacy
+// implementations based on fmt.Sprint and comparing every pair of items, which are kept at the end of
+// this file as the reference for the expected behavior.
+
+var (
+	randomStrings = []string{"", "a", "b", "name", "1", "1Gi", "1024Mi", "100m", "0.1", "<nil>", "true", "map[]"}
+	randomNumbers = []interface{}{int64(0), int64(1), int64(1024), float64(0), float64(1), 1.5, 1e21, 1e-7}
+	randomKeys    = []string{"name", "a", "b", "c"}
+)
+
+// randomValue returns a random value as decoded from JSON, with a small set of possible keys and
+// values so that random values are often equal or equivalent.
+func randomValue(rnd *rand.Rand, depth int) interface{} {
+	kinds := 4
+	if depth > 0 {
+		kinds = 6
+	}
+
+	switch rnd.IntN(kinds) {
+	case 0:
+		return randomStrings[rnd.IntN(len(randomStrings))]
+	case 1:
+		return randomNumbers[rnd.IntN(len(randomNumbers))]
+	case 2:
+		return rnd.IntN(2) == 0
+	case 3:
+		if rnd.IntN(3) == 0 {
+			return nil
+		}
+
+		return randomStrings[rnd.IntN(len(randomStrings))]
+	case 4:
+		return randomMap(rnd, depth-1)
+	default:
+		return randomList(rnd, depth-1)
+	}
+}
+
+func randomMap(rnd *rand.Rand, depth int) map[string]interface{} {
+	item := map[string]interface{}{}
+
+	for _, key := range randomKeys {
+		if rnd.IntN(2) == 0 {
+			continue
+		}
+
+		if key == "name" {
+			item[key] = []string{"x", "y", ""}[rnd.IntN(3)]
+		} else {
+			item[key] = randomValue(rnd, depth)
+		}
+	}
+
+	return item
+}
+
+// randomList returns a list of either scalars or maps, like the lists in Kubernetes objects.
+func randomList(rnd *rand.Rand, depth int) []interface{} {
+	list := make([]interface{}, rnd.IntN(5))
+	maps := depth > 0 && rnd.IntN(2) == 0
+
+	for i := range list {
+		if maps {
+			list[i] = randomMap(rnd, depth)
+		} else {
+			list[i] = randomValue(rnd, 0)
+		}
+	}
+
+	return list
+}
+
+// relatedList returns a list derived from the input list by shuffling it, dropping, duplicating and
+// modifying items, and adding random items, so that the lists are often equivalent or nearly so.
+func relatedList(rnd *rand.Rand, list []interface{}, depth int) []interface{} {
+	related := make([]interface{}, 0, len(list)+2)
+
+	for _, item := range list {
+		switch rnd.IntN(8) {
+		case 0:
+		case 1:
+			related = append(related, runtime.DeepCopyJSONValue(item), runtime.DeepCopyJSONValue(item))
+		case 2:
+			if itemMap, ok := item.(map[string]interface{}); ok {
+				modified := runtime.DeepCopyJSONValue(itemMap).(map[string]interface{})
+				modified[randomKeys[rnd.IntN(len(randomKeys))]] = randomValue(rnd, depth)
+				related = append(related, modified)
+			} else {
+				related = append(related, randomValue(rnd, 0))
+			}
+		default:
+			related = append(related, runtime.DeepCopyJSONValue(item))
+		}
+	}
+
+	if rnd.IntN(3) == 0 {
+		related = append(related, randomList(rnd, depth)...)
+	}
+
+	if rnd.IntN(2) == 0 {
+		rnd.Shuffle(len(related), func(i, j int) { related[i], related[j] = related[j], related[i] })
+	}
+
+	return related
+}
+
+func copyList(list []interface{}) []interface{} {
+	return runtime.DeepCopyJSONValue(list).([]interface{})
+}
+
+// legacyReturnsMissingKey returns whether the legacy function can return the missingKey value. When
+// items don't match, the legacy functions return a missingKey value that depends on the order of
+// iteration through the maps, so the legacy function is called until it returns the value.
+func legacyReturnsMissingKey(missingKey bool, legacy func() bool) bool {
+	for range 200 {
+		if legacy() == missingKey {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestCanonicalKey(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	values := []interface{}{
+		nil, "", int64(-5), 3, 0.000001, 1e-5, 123456789.0, 1e20, 1e21, -0.0, true,
+		map[string]interface{}(nil), []interface{}(nil), map[string]interface{}{"a": nil}, []string{"a", "b"},
+	}
+
+	for range 2000 {
+		values = append(values, randomValue(rnd, 3))
+	}
+
+	for _, value := range values {
+		assert.Equal(t, fmt.Sprint(value), canonicalKey(value, false))
+		assert.Equal(t, legacySortAndSprint(value), canonicalKey(value, true))
+	}
+}
+
+func TestCopyJSONValue(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewPCG(3, 4))
+
+	for range 2000 {
+		value := randomValue(rnd, 3)
+
+		copied, ok := copyJSONValue(value)
+		assert.True(t, ok)
+
+		data, err := json.Marshal(value)
+		assert.NoError(t, err)
+
+		var expected interface{}
+
+		assert.NoError(t, json.Unmarshal(data, &expected))
+		assert.Equal(t, expected, copied)
+	}
+
+	for _, value := range []interface{}{"\xff", map[string]interface{}{"\xff": "a"}, []string{"a"}, int32(1)} {
+		_, ok := copyJSONValue(value)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestCheckListsAreEquivalentDifferential(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewPCG(5, 6))
+	matches := 0
+
+	for range 5000 {
+		oldVal := randomList(rnd, 3)
+		mergedVal := relatedList(rnd, oldVal, 2)
+
+		if rnd.IntN(4) == 0 {
+			mergedVal = copyList(oldVal)
+		}
+
+		match, missingKey := checkListsAreEquivalent(oldVal, mergedVal)
+		legacyMatch, _ := legacyCheckListsAreEquivalent(oldVal, mergedVal)
+
+		if !assert.Equal(t, legacyMatch, match, "old: %v, merged: %v", oldVal, mergedVal) {
+			continue
+		}
+
+		assert.True(t, legacyReturnsMissingKey(missingKey, func() bool {
+			_, legacyMissingKey := legacyCheckListsAreEquivalent(oldVal, mergedVal)
+
+			return legacyMissingKey
+		}), "old: %v, merged: %v", oldVal, mergedVal)
+
+		if match {
+			matches++
+		}
+	}
+
+	// Ensure the random lists exercise both results
+	assert.Greater(t, matches, 1000)
+}
+
+func TestMergeArraysDifferential(t *testing.T) {
+	t.Parallel()
+
+	rnd := rand.New(rand.NewPCG(7, 8))
+
+	for i := range 5000 {
+		desired := randomList(rnd, 3)
+		existing := relatedList(rnd, desired, 2)
+		zeroValueEqualsNil := i%2 == 0
+
+		result, missingKey := mergeArrays(copyList(desired), existing, policyv1.MustHave, zeroValueEqualsNil)
+		legacyResult, _ := legacyMergeArrays(copyList(desired), existing, policyv1.MustHave, zeroValueEqualsNil)
+
+		if !assert.Equal(t, legacyResult, result, "desired: %v, existing: %v", desired, existing) {
+			continue
+		}
+
+		assert.True(t, legacyReturnsMissingKey(missingKey, func() bool {
+			_, legacyMissingKey := legacyMergeArrays(
+				copyList(desired), existing, policyv1.MustHave, zeroValueEqualsNil,
+			)
+
+			return legacyMissingKey
+		}), "desired: %v, existing: %v", desired, existing)
+	}
+}
+
+// benchmarkLists returns lists like the lists of rules in RBAC roles, the peers in network policies,
+// and long lists of strings. The existing list has the same items as the desired list in a different
+// order, except for one extra item.
+func benchmarkLists(size int) map[string][2][]interface{} {
+	rules := make([]interface{}, size)
+	peers := make([]interface{}, size)
+	names := make([]interface{}, size)
+
+	for i := range size {
+		rules[i] = map[string]interface{}{
+			"apiGroups": []interface{}{fmt.Sprintf("group%d.example.com", i)},
+			"resources": []interface{}{"widgets", "widgets/status", "gadgets"},
+			"verbs":     []interface{}{"get", "list", "watch", "update"},
+		}
+		peers[i] = map[string]interface{}{
+			"ipBlock": map[string]interface{}{
+				"cidr": fmt.Sprintf("10.%d.%d.0/24", i/256, i%256), "except": []interface{}{"10.0.0.1/32"},
+			},
+		}
+		names[i] = fmt.Sprintf("name-%05d", i)
+	}
+
+	lists := map[string][2][]interface{}{}
+
+	for name, desired := range map[string][]interface{}{"rules": rules, "peers": peers, "strings": names} {
+		existing := copyList(desired)
+		rand.New(rand.NewPCG(1, 1)).Shuffle(len(existing), func(i, j int) {
+			existing[i], existing[j] = existing[j], existing[i]
+		})
+
+		lists[name] = [2][]interface{}{desired, append(existing, runtime.DeepCopyJSONValue(desired[0]))}
+	}
+
+	return lists
+}
+
+// BenchmarkMergeArrays compares merging lists with the current and the legacy implementations.
+func BenchmarkMergeArrays(b *testing.B) {
+	lists := benchmarkLists(500)
+
+	for _, name := range []string{"peers", "rules", "strings"} {
+		desired, existing := lists[name][0], lists[name][1]
+
+		b.Run(name+"/canonical", func(b *testing.B) {
+			for b.Loop() {
+				mergeArrays(slices.Clone(desired), existing, policyv1.MustHave, true)
+			}
+		})
+
+		b.Run(name+"/legacy", func(b *testing.B) {
+			for b.Loop() {
+				legacyMergeArrays(slices.Clone(desired), existing, policyv1.MustHave, true)
+			}
+		})
+	}
+}
+
+// BenchmarkCheckListsAreEquivalent compares comparing lists in a different order with the current and
+// the legacy implementations.
+func BenchmarkCheckListsAreEquivalent(b *testing.B) {
+	lists := benchmarkLists(2000)
+
+	for _, name := range []string{"peers", "rules", "strings"} {
+		oldVal, mergedVal := lists[name][0], lists[name][1][:len(lists[name][0])]
+
+		b.Run(name+"/canonical", func(b *testing.B) {
+			for b.Loop() {
+				checkListsAreEquivalent(oldVal, mergedVal)
+			}
+		})
+
+		b.Run(name+"/legacy", func(b *testing.B) {
+			for b.Loop() {
+				legacyCheckListsAreEquivalent(oldVal, mergedVal)
+			}
+		})
+	}
+}
+
+// The legacy implementations as of before the comparisons used canonical keys.
+
+func legacyDeeplyEquivalent(
+	mergedObj interface{}, oldObj interface{}, zeroValueEqualsNil bool,
+) (areEqual, missingKey bool) {
+	switch mergedObj := mergedObj.(type) {
+	case map[string]interface{}:
+		if oldObjMap, ok := oldObj.(map[string]interface{}); ok {
+			return legacyCheckFieldsAreEquivalent(mergedObj, oldObjMap, zeroValueEqualsNil)
+		}
+		// this includes the case where oldObj is nil
+		return false, false
+	case []interface{}:
+		if len(mergedObj) == 0 && oldObj == nil {
+			return true, false
+		}
+
+		if oldObjList, ok := oldObj.([]interface{}); ok {
+			return legacyCheckListsAreEquivalent(mergedObj, oldObjList)
+		}
+
+		return false, false
+	default: // when mergedObj's type is string, int, bool, or nil
+		if zeroValueEqualsNil {
+			if oldObj == nil && mergedObj != nil {
+				// compare the zero value of mergedObj's type to mergedObj
+				ref := reflect.ValueOf(mergedObj)
+				zero := reflect.Zero(ref.Type()).Interface()
+
+				return fmt.Sprint(zero) == fmt.Sprint(mergedObj), true
+			}
+
+			if mergedObj == nil && oldObj != nil {
+				// compare the zero value of oldObj's type to oldObj
+				ref := reflect.ValueOf(oldObj)
+				zero := reflect.Zero(ref.Type()).Interface()
+
+				return fmt.Sprint(zero) == fmt.Sprint(oldObj), false
+			}
+		}
+
+		return fmt.Sprint(mergedObj) == fmt.Sprint(oldObj), false
+	}
+}
+
+func legacyCheckFieldsAreEquivalent(
+	mergedObj map[string]interface{}, oldObj map[string]interface{}, zeroValueEqualsNil bool,
+) (matches, missingKey bool) {
+	// needed to compare lists, since merge messes up the order
+	if len(mergedObj) < len(oldObj) {
+		return false, false
+	}
+
+	for i, mVal := range mergedObj {
+		switch mVal := mVal.(type) {
+		case map[string]interface{}:
+			// if field is a map, recurse to check for a match
+			oVal, ok := oldObj[i].(map[string]interface{})
+			if !ok {
+				if zeroValueEqualsNil && len(mVal) == 0 {
+					break
+				}
+
+				return false, missingKey
+			}
+
+			match, missing := legacyCheckFieldsAreEquivalent(mVal, oVal, zeroValueEqualsNil)
+			missingKey = missingKey || missing
+
+			if !match {
+				return false, missingKey
+			}
+		case []interface{}:
+			// if field is a generic list, sort and iterate through them to make sure each value matches
+			oVal, ok := oldObj[i].([]interface{})
+			if !ok {
+				if len(mVal) == 0 {
+					break
+				}
+
+				return false, missingKey
+			}
+
+			if len(mVal) != len(oVal) {
+				return false, missingKey
+			}
+
+			match, miss := legacyCheckListsAreEquivalent(oVal, mVal)
+			missingKey = missingKey || miss
+
+			if !match {
+				return false, missingKey
+			}
+		case string:
+			// extra check to see if value is a byte value
+			mQty, err := apiRes.ParseQuantity(mVal)
+			if err != nil {
+				oVal, ok := oldObj[i]
+				if !ok {
+					return false, missingKey
+				}
+
+				// An error indicates the value is a regular string, so check equality normally
+				if fmt.Sprint(oVal) != mVal {
+					return false, missingKey
+				}
+			} else {
+				// if the value is a quantity of bytes, convert original
+				oVal, ok := oldObj[i].(string)
+				if !ok {
+					return false, missingKey
+				}
+
+				oQty, err := apiRes.ParseQuantity(oVal)
+				if err != nil || !oQty.Equal(mQty) {
+					return false, missingKey
+				}
+			}
+		default:
+			// if field is not an object, just do a basic compare to check for a match
+			oVal := oldObj[i]
+			// When oVal value omitted because of omitempty
+			if oVal == nil && mVal != nil {
+				ref := reflect.ValueOf(mVal)
+				oVal = reflect.Zero(ref.Type()).Interface()
+				missingKey = true
+			}
+
+			if fmt.Sprint(oVal) != fmt.Sprint(mVal) {
+				return false, missingKey
+			}
+		}
+	}
+
+	return true, missingKey
+}
+
+func legacySortAndSprint(item interface{}) string {
+	switch item := item.(type) {
+	case map[string]interface{}:
+		sorted := make(map[string]string, len(item))
+
+		for key, val := range item {
+			sorted[key] = legacySortAndSprint(val)
+		}
+
+		return fmt.Sprintf("%v", sorted)
+	case []interface{}:
+		sorted := make([]string, len(item))
+
+		for i, val := range item {
+			sorted[i] = legacySortAndSprint(val)
+		}
+
+		sort.Slice(sorted, func(x, y int) bool {
+			return sorted[x] < sorted[y]
+		})
+
+		return fmt.Sprintf("%v", sorted)
+	default:
+		return fmt.Sprintf("%v", item)
+	}
+}
+
+func legacyCheckListsAreEquivalent(
+	oldVal []interface{}, mergedVal []interface{},
+) (matches, missingKey bool) {
+	if (oldVal == nil && mergedVal != nil) || (oldVal != nil && mergedVal == nil) {
+		return false, false
+	}
+
+	if len(mergedVal) != len(oldVal) {
+		return false, false
+	}
+
+	// Make copies of the lists, so we can sort them without mutating this function's inputs
+	oVal := append([]interface{}{}, oldVal...)
+	mVal := append([]interface{}{}, mergedVal...)
+
+	sort.Slice(oVal, func(i, j int) bool {
+		return legacySortAndSprint(oVal[i]) < legacySortAndSprint(oVal[j])
+	})
+	sort.Slice(mVal, func(x, y int) bool {
+		return legacySortAndSprint(mVal[x]) < legacySortAndSprint(mVal[y])
+	})
+
+	for idx, oNestedVal := range oVal {
+		switch oNestedVal := oNestedVal.(type) {
+		case map[string]interface{}:
+			// if list contains maps, recurse on those maps to check for a match
+			if mVal, ok := mVal[idx].(map[string]interface{}); ok {
+				match, miss := legacyCheckFieldsAreEquivalent(mVal, oNestedVal, true)
+				missingKey = missingKey || miss
+
+				if !match {
+					return false, missingKey
+				}
+
+				continue
+			}
+
+			return false, missingKey
+		default:
+			// otherwise, just do a generic check
+			if fmt.Sprint(oNestedVal) != fmt.Sprint(mVal[idx]) {
+				return false, missingKey
+			}
+		}
+	}
+
+	return true, missingKey
+}
+
+func legacyMergeSpecs(
+	templateVal, existingVal interface{}, ctype policyv1.ComplianceType, zeroValueEqualsNil bool,
+) (interface{}, bool, error) {
+	// Copy templateVal since it will be modified in mergeSpecsHelper
+	data1, err := json.Marshal(templateVal)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var j1 interface{}
+
+	err = json.Unmarshal(data1, &j1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	merged, missing := legacyMergeSpecsHelper(j1, existingVal, ctype, zeroValueEqualsNil)
+
+	return merged, missing, nil
+}
+
+func legacyMergeSpecsHelper(
+	templateVal, existingVal interface{}, ctype policyv1.ComplianceType, zeroValueEqualsNil bool,
+) (merged interface{}, missingKey bool) {
+	switch templateVal := templateVal.(type) {
+	case map[string]interface{}:
+		existingVal, ok := existingVal.(map[string]interface{})
+		if !ok {
+			// if one field is a map and the other isn't, don't bother merging -
+			// just returning the template value will still generate noncompliant
+			return templateVal, false
+		}
+		// otherwise, iterate through all fields in the template object and
+		// merge in missing values from the existing object
+		for k, v2 := range existingVal {
+			var missing bool
+
+			if v1, ok := templateVal[k]; ok {
+				templateVal[k], missing = legacyMergeSpecsHelper(v1, v2, ctype, zeroValueEqualsNil)
+				missingKey = missingKey || missing
+			} else {
+				templateVal[k] = v2
+			}
+		}
+
+		if len(templateVal) > len(existingVal) {
+			// template specifies something that isn't in the current object
+			missingKey = true
+		}
+	case []interface{}: // list nested in map
+		existingVal, ok := existingVal.([]interface{})
+		if !ok {
+			// if one field is a list and the other isn't, don't bother merging
+			return templateVal, false
+		}
+
+		if len(existingVal) > 0 {
+			// if both values are non-empty lists, we need to merge in the extra data in the existing
+			// object to do a proper compare
+			return legacyMergeArrays(templateVal, existingVal, ctype, zeroValueEqualsNil)
+		}
+	case nil:
+		// if template value is nil, pull data from existing, since the template does not care about it
+		existingVal, ok := existingVal.(map[string]interface{})
+		if ok {
+			return existingVal, false
+		}
+	}
+
+	_, ok := templateVal.(string)
+	if !ok {
+		return templateVal, missingKey
+	}
+
+	return templateVal.(string), missingKey
+}
+
+func legacyMergeArrays(
+	desiredArr []interface{}, existingArr []interface{}, ctype policyv1.ComplianceType, zeroValueEqualsNil bool,
+) (result []interface{}, missingKey bool) {
+	if ctype.IsMustOnlyHave() {
+		return desiredArr, false
+	}
+
+	desiredArrCopy := append([]interface{}{}, desiredArr...)
+	idxWritten := map[int]bool{}
+
+	for i := range desiredArrCopy {
+		idxWritten[i] = false
+	}
+
+	// create a set with a key for each unique item in the list
+	oldItemSet := make(map[string]*countedVal)
+
+	for _, val2 := range existingArr {
+		key := fmt.Sprint(val2)
+
+		if entry, ok := oldItemSet[key]; ok {
+			entry.count++
+		} else {
+			oldItemSet[key] = &countedVal{value: val2, count: 1}
+		}
+	}
+
+	seen := map[string]bool{}
+
+	// Iterate both arrays in order to favor the case when the object is already compliant.
+	for _, val2 := range existingArr {
+		key := fmt.Sprint(val2)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		count := 0
+		val2 := oldItemSet[key].value
+		// for each list item in the existing array, iterate through the template array and try to find a match
+		for desiredArrIdx, val1 := range desiredArrCopy {
+			if idxWritten[desiredArrIdx] {
+				continue
+			}
+
+			var mergedObj interface{}
+			// Stores if val1 and val2 are maps with the same "name" key value. In the case of the containers array
+			// in a Deployment object, the value should be merged and not appended if the name is the same in both.
+			var sameNamedObjects bool
+
+			switch val2 := val2.(type) {
+			case map[string]interface{}:
+				// If the policy value and the current value are different types, use the same logic
+				// as the default case.
+				val1, ok := val1.(map[string]interface{})
+				if !ok {
+					mergedObj = val1
+
+					break
+				}
+
+				if name2, ok := val2["name"].(string); ok && name2 != "" {
+					if name1, ok := val1["name"].(string); ok && name1 == name2 {
+						sameNamedObjects = true
+					}
+				}
+
+				// use map compare helper function to check equality on lists of maps
+				mergedObj, missingKey, _ = legacyMergeMaps(val1, val2, ctype, zeroValueEqualsNil)
+			default:
+				mergedObj = val1
+			}
+			// if a match is found, this field is already in the template, so we can skip it in future checks
+			equal, missing := legacyDeeplyEquivalent(mergedObj, val2, zeroValueEqualsNil)
+			missingKey = missingKey || missing
+
+			if sameNamedObjects || equal {
+				count++
+
+				desiredArr[desiredArrIdx] = mergedObj
+				idxWritten[desiredArrIdx] = true
+			}
+
+			// If the result of merging val1 (template) into val2 (existing value) matched val2 for the required count,
+			// move on to the next existing value.
+			if count == oldItemSet[key].count {
+				break
+			}
+		}
+		// if an item in the existing object cannot be found in the template, we add it to the template array
+		// to produce the merged array
+		if count < oldItemSet[key].count {
+			for range oldItemSet[key].count - count {
+				desiredArr = append(desiredArr, val2)
+			}
+		}
+	}
+
+	return desiredArr, missingKey
+}
+
+func legacyMergeMaps(
+	newSpec, oldSpec map[string]interface{}, ctype policyv1.ComplianceType, zeroValueEqualsNil bool,
+) (updatedSpec map[string]interface{}, missingKey bool, err error) {
+	if ctype.IsMustOnlyHave() {
+		return newSpec, false, nil
+	}
+	// if compliance type is musthave, create merged object to compare on
+	merged, missing, err := legacyMergeSpecs(newSpec, oldSpec, ctype, zeroValueEqualsNil)
+
+	return merged.(map[string]interface{}), missing, err
+}
@@ -0,0 +1,229 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+// ComplianceAPIPath is the path of the compliance API on the metrics server.
+const ComplianceAPIPath = "/compliance"
+
+// ComplianceAPI is a read-only HTTP handler that returns a JSON summary of the compliance of the policies, so that
+// clients without access to the Kubernetes API can get it. The policies can be filtered with the namespace, kind,
+// and severity query parameters, which can be repeated or have comma-separated values. For example:
+//
+//	GET /compliance?namespace=local-cluster&kind=ConfigurationPolicy&severity=high,critical
+//
+// The handler doesn't authenticate the requests, so it's meant to be served on the metrics server with its
+// authentication and authorization filter.
+type ComplianceAPI struct {
+	client client.Reader
+	// operatorPolicies determines if the OperatorPolicies are listed, which requires their CRD to be installed.
+	operatorPolicies bool
+}
+
+// NewComplianceAPI returns a ComplianceAPI that lists the policies with the input reader, which is usually the
+// cached client of the manager.
+func NewComplianceAPI(reader client.Reader, operatorPolicies bool) *ComplianceAPI {
+	return &ComplianceAPI{client: reader, operatorPolicies: operatorPolicies}
+}
+
+// complianceAPIResponse is the body of the responses of the compliance API.
+type complianceAPIResponse struct {
+	Policies []policyComplianceSummary `json:"policies"`
+}
+
+type policyComplianceSummary struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Severity  string `json:"severity,omitempty"`
+	Compliant string `json:"compliant,omitempty"`
+	// LastEvaluated is the time of the last evaluation of a ConfigurationPolicy. OperatorPolicies don't record
+	// their evaluations, so it is the time of their latest compliance message instead.
+	LastEvaluated       string                      `json:"lastEvaluated,omitempty"`
+	NoncompliantObjects []noncompliantObjectSummary `json:"noncompliantObjects,omitempty"`
+	// Messages are the latest compliance messages, from the most recent to the oldest.
+	Messages []policyv1.HistoryEvent `json:"messages,omitempty"`
+}
+
+type noncompliantObjectSummary struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Reason     string `json:"reason,omitempty"`
+	ReasonCode string `json:"reasonCode,omitempty"`
+}
+
+// complianceAPIFilter has the accepted values of each filter, where an empty list accepts everything.
+type complianceAPIFilter struct {
+	namespaces []string
+	kinds      []string
+	severities []string
+}
+
+func (a *ComplianceAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "only GET requests are supported", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	log := ctrl.LoggerFrom(r.Context()).WithName("compliance-api")
+
+	query := r.URL.Query()
+	filter := complianceAPIFilter{
+		namespaces: queryValues(query["namespace"]),
+		kinds:      queryValues(query["kind"]),
+		severities: queryValues(query["severity"]),
+	}
+
+	response := complianceAPIResponse{Policies: []policyComplianceSummary{}}
+
+	if filter.accepts(filter.kinds, "ConfigurationPolicy") {
+		policies := policyv1.ConfigurationPolicyList{}
+
+		if err := a.client.List(r.Context(), &policies); err != nil {
+			log.Error(err, "Failed to list the ConfigurationPolicies")
+			http.Error(w, "failed to list the ConfigurationPolicies", http.StatusInternalServerError)
+
+			return
+		}
+
+		for i := range policies.Items {
+			summary := configPolicyComplianceSummary(&policies.Items[i])
+			if filter.matches(summary) {
+				response.Policies = append(response.Policies, summary)
+			}
+		}
+	}
+
+	if a.operatorPolicies && filter.accepts(filter.kinds, "OperatorPolicy") {
+		policies := policyv1beta1.OperatorPolicyList{}
+
+		if err := a.client.List(r.Context(), &policies); err != nil {
+			log.Error(err, "Failed to list the OperatorPolicies")
+			http.Error(w, "failed to list the OperatorPolicies", http.StatusInternalServerError)
+
+			return
+		}
+
+		for i := range policies.Items {
+			summary := operatorPolicyComplianceSummary(&policies.Items[i])
+			if filter.matches(summary) {
+				response.Policies = append(response.Policies, summary)
+			}
+		}
+	}
+
+	slices.SortFunc(response.Policies, func(a, b policyComplianceSummary) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error(err, "Failed to write the compliance API response")
+	}
+}
+
+// queryValues splits the comma-separated values of a query parameter and drops the empty ones.
+func queryValues(params []string) []string {
+	values := []string{}
+
+	for _, param := range params {
+		for value := range strings.SplitSeq(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values
+}
+
+// accepts returns true if the value is one of the accepted values, ignoring the case, or if all values are
+// accepted.
+func (f complianceAPIFilter) accepts(accepted []string, value string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(accepted, func(acceptedValue string) bool {
+		return strings.EqualFold(acceptedValue, value)
+	})
+}
+
+func (f complianceAPIFilter) matches(summary policyComplianceSummary) bool {
+	return f.accepts(f.kinds, summary.Kind) &&
+		(len(f.namespaces) == 0 || slices.Contains(f.namespaces, summary.Namespace)) &&
+		f.accepts(f.severities, summary.Severity)
+}
+
+func configPolicyComplianceSummary(policy *policyv1.ConfigurationPolicy) policyComplianceSummary {
+	return policyComplianceSummary{
+		Kind:                "ConfigurationPolicy",
+		Namespace:           policy.Namespace,
+		Name:                policy.Name,
+		Severity:            string(policy.Spec.Severity),
+		Compliant:           string(policy.Status.ComplianceState),
+		LastEvaluated:       policy.Status.LastEvaluated,
+		NoncompliantObjects: noncompliantObjectSummaries(policy.Status.RelatedObjects),
+		Messages:            policy.Status.History,
+	}
+}
+
+func operatorPolicyComplianceSummary(policy *policyv1beta1.OperatorPolicy) policyComplianceSummary {
+	summary := policyComplianceSummary{
+		Kind:                "OperatorPolicy",
+		Namespace:           policy.Namespace,
+		Name:                policy.Name,
+		Severity:            string(policy.Spec.Severity),
+		Compliant:           string(policy.Status.ComplianceState),
+		NoncompliantObjects: noncompliantObjectSummaries(policy.Status.RelatedObjects),
+		Messages:            policy.Status.History,
+	}
+
+	if len(policy.Status.History) != 0 {
+		summary.LastEvaluated = policy.Status.History[0].LastTimestamp.UTC().Format(time.RFC3339)
+	}
+
+	return summary
+}
+
+func noncompliantObjectSummaries(related []policyv1.RelatedObject) []noncompliantObjectSummary {
+	var summaries []noncompliantObjectSummary
+
+	for _, obj := range related {
+		if obj.Compliant != string(policyv1.NonCompliant) {
+			continue
+		}
+
+		summaries = append(summaries, noncompliantObjectSummary{
+			APIVersion: obj.Object.APIVersion,
+			Kind:       obj.Object.Kind,
+			Namespace:  obj.Object.Metadata.Namespace,
+			Name:       obj.Object.Metadata.Name,
+			Reason:     obj.Reason,
+			ReasonCode: string(obj.ReasonCode),
+		})
+	}
+
+	return summaries
+}
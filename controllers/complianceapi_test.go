@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+func TestComplianceAPI(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, policyv1.AddToScheme(scheme))
+	assert.NoError(t, policyv1beta1.AddToScheme(scheme))
+
+	evaluated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	configPolicy := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "config"},
+		Spec:       policyv1.ConfigurationPolicySpec{Severity: "high"},
+		Status: policyv1.ConfigurationPolicyStatus{
+			ComplianceState: policyv1.NonCompliant,
+			LastEvaluated:   evaluated.Format(time.RFC3339),
+			RelatedObjects: []policyv1.RelatedObject{
+				{
+					Object: policyv1.ObjectResource{
+						APIVersion: "v1", Kind: "ConfigMap",
+						Metadata: policyv1.ObjectMetadata{Namespace: "default", Name: "missing"},
+					},
+					Compliant:  string(policyv1.NonCompliant),
+					Reason:     reasonWantFoundDNE,
+					ReasonCode: policyv1.ReasonCodeMissing,
+				},
+				{
+					Object: policyv1.ObjectResource{
+						APIVersion: "v1", Kind: "ConfigMap",
+						Metadata: policyv1.ObjectMetadata{Namespace: "default", Name: "found"},
+					},
+					Compliant: string(policyv1.Compliant),
+				},
+			},
+			History: []policyv1.HistoryEvent{
+				historyEvent("NonCompliant; violation - configmaps [missing] not found", evaluated),
+			},
+		},
+	}
+
+	operatorPolicy := &policyv1beta1.OperatorPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "operator"},
+		Spec:       policyv1beta1.OperatorPolicySpec{Severity: "low"},
+		Status: policyv1beta1.OperatorPolicyStatus{
+			ComplianceState: policyv1.Compliant,
+			History: []policyv1.HistoryEvent{
+				historyEvent("Compliant; the ClusterServiceVersion is installed", evaluated.Add(time.Minute)),
+			},
+		},
+	}
+
+	api := NewComplianceAPI(
+		fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(configPolicy, operatorPolicy).Build(), true,
+	)
+
+	request := func(target string) (int, complianceAPIResponse) {
+		recorder := httptest.NewRecorder()
+		api.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+		response := complianceAPIResponse{}
+		if recorder.Code == http.StatusOK {
+			assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		}
+
+		return recorder.Code, response
+	}
+
+	code, response := request("/compliance")
+	assert.Equal(t, http.StatusOK, code)
+
+	if assert.Len(t, response.Policies, 2) {
+		assert.Equal(t, "ConfigurationPolicy", response.Policies[0].Kind)
+		assert.Equal(t, "high", response.Policies[0].Severity)
+		assert.Equal(t, "NonCompliant", response.Policies[0].Compliant)
+		assert.Equal(t, "2024-05-01T12:00:00Z", response.Policies[0].LastEvaluated)
+		assert.Equal(t, []noncompliantObjectSummary{
+			{
+				APIVersion: "v1", Kind: "ConfigMap", Namespace: "default", Name: "missing",
+				Reason: reasonWantFoundDNE, ReasonCode: "Missing",
+			},
+		}, response.Policies[0].NoncompliantObjects)
+		assert.Len(t, response.Policies[0].Messages, 1)
+
+		assert.Equal(t, "OperatorPolicy", response.Policies[1].Kind)
+		assert.Equal(t, "2024-05-01T12:01:00Z", response.Policies[1].LastEvaluated)
+		assert.Empty(t, response.Policies[1].NoncompliantObjects)
+	}
+
+	filters := map[string]string{
+		"/compliance?namespace=other":                   "operator",
+		"/compliance?kind=configurationpolicy":          "config",
+		"/compliance?severity=critical,high":            "config",
+		"/compliance?severity=critical&severity=low":    "operator",
+		"/compliance?namespace=managed&kind=Operator":   "",
+		"/compliance?kind=OperatorPolicy&namespace=all": "",
+	}
+
+	for target, expected := range filters {
+		code, response := request(target)
+		assert.Equal(t, http.StatusOK, code, target)
+
+		if expected == "" {
+			assert.Empty(t, response.Policies, target)
+		} else if assert.Len(t, response.Policies, 1, target) {
+			assert.Equal(t, expected, response.Policies[0].Name, target)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	api.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/compliance", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
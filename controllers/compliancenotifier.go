@@ -0,0 +1,453 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/yaml"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+const (
+	// ComplianceChangedEventType is the CloudEvents type of the compliance notifications.
+	ComplianceChangedEventType = "io.open-cluster-management.policy.compliance.changed"
+
+	DefaultNotificationQueueSize      = 1000
+	DefaultNotificationMaxAttempts    = 5
+	DefaultNotificationInitialBackoff = time.Second
+	DefaultNotificationMaxBackoff     = 30 * time.Second
+	defaultNotificationTimeout        = 10 * time.Second
+)
+
+// ComplianceNotifier sends a CloudEvent in the structured JSON format to HTTP endpoints, called sinks, when the
+// compliance of a policy changes. The sinks are configured in a ConfigMap where each key is the name of a sink and
+// each value is a YAML object with the url of the sink, and optionally the headers to send and the timeout of the
+// requests. For example:
+//
+//	incidents: |
+//	  url: https://incidents.example.com/hooks/policies
+//	  headers:
+//	    X-Team: platform
+//	  timeout: 5s
+//
+// The notifications are queued in a bounded queue and sent in order by a single worker, so that a slow sink delays
+// but doesn't reorder the notifications. When the queue is full, new notifications are dropped.
+type ComplianceNotifier struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	source    string
+	// HTTPClient sends the requests to the sinks.
+	HTTPClient *http.Client
+	// MaxAttempts is the number of times a notification is sent to a sink before giving up.
+	MaxAttempts int
+	// InitialBackoff is the time before the first retry, which is doubled after each attempt up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	queue chan queuedNotification
+	sinks atomic.Pointer[[]notificationSink]
+}
+
+// NewComplianceNotifier returns a ComplianceNotifier that reads the sinks from the ConfigMap and sends the
+// notifications with the input CloudEvents source. It must be started with the Start method.
+func NewComplianceNotifier(
+	client kubernetes.Interface, namespace, name, source string, queueSize int,
+) *ComplianceNotifier {
+	if queueSize < 1 {
+		queueSize = DefaultNotificationQueueSize
+	}
+
+	notifier := &ComplianceNotifier{
+		client:         client,
+		namespace:      namespace,
+		name:           name,
+		source:         source,
+		HTTPClient:     &http.Client{},
+		MaxAttempts:    DefaultNotificationMaxAttempts,
+		InitialBackoff: DefaultNotificationInitialBackoff,
+		MaxBackoff:     DefaultNotificationMaxBackoff,
+		queue:          make(chan queuedNotification, queueSize),
+	}
+
+	notifier.sinks.Store(&[]notificationSink{})
+
+	return notifier
+}
+
+// ComplianceNotification is a change of the compliance of a policy.
+type ComplianceNotification struct {
+	APIVersion      string
+	Kind            string
+	Policy          metav1.Object
+	ComplianceState policyv1.ComplianceState
+	Severity        policyv1.Severity
+	// Message is the message of the compliance history event.
+	Message string
+	// RelatedObjects are the related objects from the status of the policy. Their diffs are only set when the
+	// recordDiff of the object template is InStatus, and censored diffs only have the redaction message.
+	RelatedObjects []policyv1.RelatedObject
+	Time           time.Time
+}
+
+// notificationSink is an HTTP endpoint that receives the notifications.
+type notificationSink struct {
+	Name    string            `json:"-"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Timeout metav1.Duration   `json:"timeout,omitempty"`
+}
+
+// queuedNotification is an encoded CloudEvent waiting to be sent.
+type queuedNotification struct {
+	id     string
+	policy types.NamespacedName
+	body   []byte
+}
+
+// cloudEvent is a CloudEvent in the structured JSON format.
+type cloudEvent struct {
+	SpecVersion     string                     `json:"specversion"`
+	ID              string                     `json:"id"`
+	Source          string                     `json:"source"`
+	Type            string                     `json:"type"`
+	Subject         string                     `json:"subject"`
+	Time            string                     `json:"time"`
+	DataContentType string                     `json:"datacontenttype"`
+	Data            complianceNotificationData `json:"data"`
+}
+
+type complianceNotificationData struct {
+	Policy          notificationPolicy       `json:"policy"`
+	ComplianceState policyv1.ComplianceState `json:"complianceState"`
+	Severity        string                   `json:"severity,omitempty"`
+	Message         string                   `json:"message"`
+	RelatedObjects  []policyv1.RelatedObject `json:"relatedObjects,omitempty"`
+}
+
+type notificationPolicy struct {
+	APIVersion string    `json:"apiVersion"`
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid"`
+}
+
+// Notify queues the notification to be sent to the sinks. It doesn't block, and the notification is dropped when
+// the queue is full or when there are no sinks.
+func (n *ComplianceNotifier) Notify(ctx context.Context, notification ComplianceNotification) {
+	if len(*n.sinks.Load()) == 0 {
+		return
+	}
+
+	log := ctrl.LoggerFrom(ctx).WithName("compliance-notifier")
+	policy := types.NamespacedName{Namespace: notification.Policy.GetNamespace(), Name: notification.Policy.GetName()}
+
+	event := cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              string(uuid.NewUUID()),
+		Source:          n.source,
+		Type:            ComplianceChangedEventType,
+		Subject:         policy.String(),
+		Time:            notification.Time.UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		Data: complianceNotificationData{
+			Policy: notificationPolicy{
+				APIVersion: notification.APIVersion,
+				Kind:       notification.Kind,
+				Namespace:  policy.Namespace,
+				Name:       policy.Name,
+				UID:        notification.Policy.GetUID(),
+			},
+			ComplianceState: notification.ComplianceState,
+			Severity:        string(notification.Severity),
+			Message:         notification.Message,
+			RelatedObjects:  notification.RelatedObjects,
+		},
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Error(err, "Failed to encode the compliance notification")
+
+		return
+	}
+
+	queued := queuedNotification{id: event.ID, policy: policy, body: body}
+
+	select {
+	case n.queue <- queued:
+		notificationQueueGauge.Set(float64(len(n.queue)))
+	default:
+		notificationsDroppedCounter.Inc()
+		log.Info("The compliance notification queue is full. Dropping the notification.",
+			"policy", queued.policy.String())
+	}
+}
+
+// Start watches the ConfigMap of the sinks and sends the queued notifications until the context is canceled.
+func (n *ComplianceNotifier) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("compliance-notifier")
+
+	factory := informers.NewSharedInformerFactoryWithOptions(
+		n.client, 0, informers.WithNamespace(n.namespace), informers.WithTweakListOptions(
+			func(options *metav1.ListOptions) {
+				options.FieldSelector = fields.OneTermEqualSelector("metadata.name", n.name).String()
+			},
+		),
+	)
+
+	_, err := factory.Core().V1().ConfigMaps().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			n.loadSinks(ctx, obj)
+		},
+		UpdateFunc: func(_, newObj any) {
+			n.loadSinks(ctx, newObj)
+		},
+		DeleteFunc: func(any) {
+			log.Info("The compliance notification sinks ConfigMap was deleted")
+			n.sinks.Store(&[]notificationSink{})
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch the compliance notification sinks ConfigMap: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case queued := <-n.queue:
+			notificationQueueGauge.Set(float64(len(n.queue)))
+
+			n.send(ctx, queued)
+		}
+	}
+}
+
+// loadSinks replaces the sinks with the ones in the ConfigMap. Invalid sinks are logged and skipped.
+func (n *ComplianceNotifier) loadSinks(ctx context.Context, obj any) {
+	log := ctrl.LoggerFrom(ctx).WithName("compliance-notifier")
+
+	configMap, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return
+	}
+
+	sinks, err := parseNotificationSinks(configMap.Data)
+	if err != nil {
+		log.Error(err, "Some compliance notification sinks are invalid and are ignored")
+	}
+
+	log.Info("Loaded the compliance notification sinks", "sinks", len(sinks))
+
+	n.sinks.Store(&sinks)
+}
+
+// parseNotificationSinks returns the valid sinks in the ConfigMap data, sorted by name, and an error for the
+// invalid ones.
+func parseNotificationSinks(data map[string]string) ([]notificationSink, error) {
+	sinks := make([]notificationSink, 0, len(data))
+
+	var errs []error
+
+	for name, value := range data {
+		sink := notificationSink{}
+
+		if err := yaml.UnmarshalStrict([]byte(value), &sink); err != nil {
+			errs = append(errs, fmt.Errorf("the sink %s is invalid: %w", name, err))
+
+			continue
+		}
+
+		parsedURL, err := url.Parse(sink.URL)
+		if err != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			errs = append(errs, fmt.Errorf("the sink %s must have an http or https url", name))
+
+			continue
+		}
+
+		sink.Name = name
+		sinks = append(sinks, sink)
+	}
+
+	slices.SortFunc(sinks, func(a, b notificationSink) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return sinks, errors.Join(errs...)
+}
+
+// send sends the notification to all of the sinks in parallel and waits for the deliveries to finish.
+func (n *ComplianceNotifier) send(ctx context.Context, queued queuedNotification) {
+	var wg sync.WaitGroup
+
+	for _, sink := range *n.sinks.Load() {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			n.deliver(ctx, sink, queued)
+		}()
+	}
+
+	wg.Wait()
+}
+
+// deliver sends the notification to the sink, retrying with an exponential backoff on network errors, throttling,
+// and server errors.
+func (n *ComplianceNotifier) deliver(ctx context.Context, sink notificationSink, queued queuedNotification) {
+	log := ctrl.LoggerFrom(ctx).WithName("compliance-notifier").WithValues(
+		"sink", sink.Name, "policy", queued.policy.String(), "id", queued.id,
+	)
+
+	start := time.Now()
+	backoff := n.InitialBackoff
+
+	defer func() {
+		notificationDeliverySeconds.WithLabelValues(sink.Name).Observe(time.Since(start).Seconds())
+	}()
+
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, sink, queued.body)
+		if err == nil {
+			notificationsCounter.WithLabelValues(sink.Name, "delivered").Inc()
+			log.V(2).Info("Sent the compliance notification", "attempts", attempt)
+
+			return
+		}
+
+		if !retry || attempt >= n.MaxAttempts || ctx.Err() != nil {
+			notificationsCounter.WithLabelValues(sink.Name, "failed").Inc()
+			log.Error(err, "Failed to send the compliance notification", "attempts", attempt)
+
+			return
+		}
+
+		notificationRetriesCounter.WithLabelValues(sink.Name).Inc()
+		log.V(1).Info("Failed to send the compliance notification. Retrying.",
+			"attempt", attempt, "backoff", backoff.String(), "error", err.Error())
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			notificationsCounter.WithLabelValues(sink.Name, "failed").Inc()
+
+			return
+		}
+
+		backoff = min(backoff*2, n.MaxBackoff)
+	}
+}
+
+// post sends the CloudEvent to the sink and returns whether a failure should be retried.
+func (n *ComplianceNotifier) post(ctx context.Context, sink notificationSink, body []byte) (bool, error) {
+	timeout := sink.Timeout.Duration
+	if timeout <= 0 {
+		timeout = defaultNotificationTimeout
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, sink.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	for header, value := range sink.Headers {
+		req.Header.Set(header, value)
+	}
+
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+
+	resp, err := n.HTTPClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer resp.Body.Close()
+
+	// Read the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+
+	return retry, fmt.Errorf("the sink responded with the status %s", resp.Status)
+}
+
+// notifyCompliance queues a compliance notification for the policy when the ComplianceNotifier is set, with the
+// message and time of the latest compliance history event.
+func (r *ConfigurationPolicyReconciler) notifyCompliance(ctx context.Context, policy *policyv1.ConfigurationPolicy) {
+	if r.ComplianceNotifier == nil || len(policy.Status.History) == 0 {
+		return
+	}
+
+	message := policy.Status.History[0].Message
+	timestamp := policy.Status.History[0].LastTimestamp.Time
+
+	r.ComplianceNotifier.Notify(ctx, ComplianceNotification{
+		APIVersion:      policyv1.GroupVersion.String(),
+		Kind:            "ConfigurationPolicy",
+		Policy:          policy,
+		ComplianceState: policy.Status.ComplianceState,
+		Severity:        policy.Spec.Severity,
+		Message:         message,
+		RelatedObjects:  policy.Status.RelatedObjects,
+		Time:            timestamp,
+	})
+}
+
+// notifyCompliance queues a compliance notification for the policy when the ComplianceNotifier is set, with the
+// message and time of the latest compliance history event.
+func (r *OperatorPolicyReconciler) notifyCompliance(ctx context.Context, policy *policyv1beta1.OperatorPolicy) {
+	if r.ComplianceNotifier == nil || len(policy.Status.History) == 0 {
+		return
+	}
+
+	message := policy.Status.History[0].Message
+	timestamp := policy.Status.History[0].LastTimestamp.Time
+
+	r.ComplianceNotifier.Notify(ctx, ComplianceNotification{
+		APIVersion:      policyv1beta1.GroupVersion.String(),
+		Kind:            "OperatorPolicy",
+		Policy:          policy,
+		ComplianceState: policy.Status.ComplianceState,
+		Severity:        policy.Spec.Severity,
+		Message:         message,
+		RelatedObjects:  policy.Status.RelatedObjects,
+		Time:            timestamp,
+	})
+}
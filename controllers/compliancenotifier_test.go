@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// notificationSinkServer is a local HTTP sink that responds with the configured statuses in order, and then
+// with 200.
+type notificationSinkServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newNotificationSinkServer(t *testing.T, statuses ...int) *notificationSinkServer {
+	t.Helper()
+
+	sink := &notificationSinkServer{statuses: statuses}
+	sink.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		sink.lock.Lock()
+		defer sink.lock.Unlock()
+
+		sink.requests = append(sink.requests, req)
+		sink.bodies = append(sink.bodies, body)
+
+		status := http.StatusOK
+		if len(sink.statuses) != 0 {
+			status = sink.statuses[0]
+			sink.statuses = sink.statuses[1:]
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(sink.Close)
+
+	return sink
+}
+
+func (s *notificationSinkServer) requestCount() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.requests)
+}
+
+func counterValue(t *testing.T, counter interface{ Write(*dto.Metric) error }) float64 {
+	t.Helper()
+
+	metric := &dto.Metric{}
+	if err := counter.Write(metric); err != nil {
+		t.Fatal(err)
+	}
+
+	return metric.GetCounter().GetValue()
+}
+
+func startNotifier(t *testing.T, sinkName string, sinkURL string, queueSize int) *ComplianceNotifier {
+	t.Helper()
+
+	client := fake.NewClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ocm", Name: "sinks"},
+		Data:       map[string]string{sinkName: "url: " + sinkURL + "\nheaders:\n  X-Test: " + sinkName},
+	})
+
+	notifier := NewComplianceNotifier(client, "ocm", "sinks", "/clusters/local/config-policy-controller", queueSize)
+	notifier.MaxAttempts = 3
+	notifier.InitialBackoff = time.Millisecond
+	notifier.MaxBackoff = 5 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		_ = notifier.Start(ctx)
+	}()
+
+	assert.Eventually(t, func() bool {
+		return len(*notifier.sinks.Load()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return notifier
+}
+
+func testNotification() ComplianceNotification {
+	return ComplianceNotification{
+		APIVersion: policyv1.GroupVersion.String(),
+		Kind:       "ConfigurationPolicy",
+		Policy: &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{
+			Namespace: "managed", Name: "my-policy", UID: "policy-uid",
+		}},
+		ComplianceState: policyv1.NonCompliant,
+		Severity:        "high",
+		Message:         "NonCompliant; violation - configmaps [app] found but not as specified in namespace default",
+		RelatedObjects: []policyv1.RelatedObject{{
+			Object: policyv1.ObjectResource{
+				APIVersion: "v1", Kind: "ConfigMap",
+				Metadata: policyv1.ObjectMetadata{Name: "app", Namespace: "default"},
+			},
+			Compliant:  string(policyv1.NonCompliant),
+			Reason:     "Resource found but does not match",
+			Properties: &policyv1.ObjectProperties{Diff: "-  a: b\n+  a: c"},
+		}},
+		Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestComplianceNotifierDelivery(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		statuses         []int
+		expectedRequests int
+		expectedResult   string
+	}{
+		"delivered on the first attempt": {
+			expectedRequests: 1,
+			expectedResult:   "delivered",
+		},
+		"server errors are retried": {
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			expectedRequests: 3,
+			expectedResult:   "delivered",
+		},
+		"client errors are not retried": {
+			statuses:         []int{http.StatusBadRequest},
+			expectedRequests: 1,
+			expectedResult:   "failed",
+		},
+		"gives up after the maximum attempts": {
+			statuses:         []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			expectedRequests: 3,
+			expectedResult:   "failed",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sinkName := "sink-" + name
+			server := newNotificationSinkServer(t, test.statuses...)
+			notifier := startNotifier(t, sinkName, server.URL, 10)
+
+			notifier.Notify(context.Background(), testNotification())
+
+			result := notificationsCounter.WithLabelValues(sinkName, test.expectedResult)
+
+			assert.Eventually(t, func() bool {
+				return counterValue(t, result) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+			assert.Equal(t, test.expectedRequests, server.requestCount())
+		})
+	}
+}
+
+func TestComplianceNotifierPayload(t *testing.T) {
+	t.Parallel()
+
+	server := newNotificationSinkServer(t)
+	notifier := startNotifier(t, "payload", server.URL, 10)
+
+	notifier.Notify(context.Background(), testNotification())
+
+	assert.Eventually(t, func() bool {
+		return server.requestCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	server.lock.Lock()
+	defer server.lock.Unlock()
+
+	assert.Equal(t, "application/cloudevents+json", server.requests[0].Header.Get("Content-Type"))
+	assert.Equal(t, "payload", server.requests[0].Header.Get("X-Test"))
+
+	event := map[string]any{}
+	if !assert.NoError(t, json.Unmarshal(server.bodies[0], &event)) {
+		return
+	}
+
+	assert.Equal(t, "1.0", event["specversion"])
+	assert.Equal(t, ComplianceChangedEventType, event["type"])
+	assert.Equal(t, "/clusters/local/config-policy-controller", event["source"])
+	assert.Equal(t, "managed/my-policy", event["subject"])
+	assert.Equal(t, "2024-05-01T12:00:00Z", event["time"])
+	assert.NotEmpty(t, event["id"])
+
+	data := event["data"].(map[string]any)
+	assert.Equal(t, map[string]any{
+		"apiVersion": "policy.open-cluster-management.io/v1",
+		"kind":       "ConfigurationPolicy",
+		"namespace":  "managed",
+		"name":       "my-policy",
+		"uid":        "policy-uid",
+	}, data["policy"])
+	assert.Equal(t, "NonCompliant", data["complianceState"])
+	assert.Equal(t, "high", data["severity"])
+	assert.Contains(t, data["message"], "found but not as specified")
+
+	related := data["relatedObjects"].([]any)
+	if assert.Len(t, related, 1) {
+		properties := related[0].(map[string]any)["properties"].(map[string]any)
+		assert.Equal(t, "-  a: b\n+  a: c", properties["diff"])
+	}
+}
+
+func TestComplianceNotifierQueueFull(t *testing.T) {
+	t.Parallel()
+
+	// The notifier isn't started, so the queue isn't drained
+	notifier := NewComplianceNotifier(fake.NewClientset(), "ocm", "sinks", "test", 1)
+	notifier.sinks.Store(&[]notificationSink{{Name: "queue-full", URL: "http://localhost"}})
+
+	dropped := counterValue(t, notificationsDroppedCounter)
+
+	notifier.Notify(context.Background(), testNotification())
+	notifier.Notify(context.Background(), testNotification())
+
+	assert.Len(t, notifier.queue, 1)
+	assert.GreaterOrEqual(t, counterValue(t, notificationsDroppedCounter), dropped+1)
+}
+
+func TestComplianceNotifierNoSinks(t *testing.T) {
+	t.Parallel()
+
+	notifier := NewComplianceNotifier(fake.NewClientset(), "ocm", "sinks", "test", 1)
+
+	notifier.Notify(context.Background(), testNotification())
+
+	assert.Empty(t, notifier.queue)
+}
+
+func TestParseNotificationSinks(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		data          map[string]string
+		expectedNames []string
+		expectedError string
+	}{
+		"valid sinks are sorted": {
+			data: map[string]string{
+				"b": "url: https://b.example.com/hook\ntimeout: 5s",
+				"a": "url: http://a.example.com",
+			},
+			expectedNames: []string{"a", "b"},
+		},
+		"invalid sinks are skipped": {
+			data: map[string]string{
+				"good":    "url: https://example.com",
+				"scheme":  "url: ftp://example.com",
+				"unknown": "url: https://example.com\nmethod: PUT",
+				"empty":   "",
+			},
+			expectedNames: []string{"good"},
+			expectedError: "the sink scheme must have an http or https url",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			sinks, err := parseNotificationSinks(test.data)
+
+			names := make([]string, 0, len(sinks))
+			for _, sink := range sinks {
+				names = append(names, sink.Name)
+			}
+
+			assert.Equal(t, test.expectedNames, names)
+
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, test.expectedError)
+				assert.ErrorContains(t, err, "the sink unknown is invalid")
+				assert.ErrorContains(t, err, "the sink empty must have an http or https url")
+			}
+		})
+	}
+}
@@ -24,10 +24,12 @@ import (
 	gocmp "github.com/google/go-cmp/cmp"
 	templates "github.com/stolostron/go-template-utils/v7/pkg/templates"
 	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
@@ -44,6 +46,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
@@ -51,6 +54,7 @@ import (
 
 	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
 	common "open-cluster-management.io/config-policy-controller/pkg/common"
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
 )
 
 const (
@@ -67,6 +71,9 @@ const (
 	reasonWantFoundDNE       = "Resource not found but should exist"
 	reasonWantNotFoundExists = "Resource found but should not exist"
 	reasonWantNotFoundDNE    = "Resource not found as expected"
+	reasonGitOpsSkipped      = "Resource skipped"
+	reasonDeleteInProgress   = "K8s deletion in progress"
+	reasonDeleteStuck        = "K8s deletion stuck"
 	reasonCleanupError       = "Error cleaning up child objects"
 	reasonFoundNotApplicable = "Resource found but will not be handled in mustnothave mode"
 	reasonTemplateError      = "Error processing template"
@@ -103,6 +110,9 @@ func (r *ConfigurationPolicyReconciler) SetupWithManager(
 		Named(ControllerName).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: int(evaluationConcurrency),
+			NewQueue: newEvaluationQueue(
+				configPolicyPriorityInfo(mgr.GetClient(), r.evaluationCacheStale),
+			),
 		}).
 		For(&policyv1.ConfigurationPolicy{}, builder.WithPredicates(
 			predicate.Funcs{
@@ -146,6 +156,8 @@ func (r *ConfigurationPolicyReconciler) SetupWithManager(
 					// of a hack but it works.
 					r.lastEvaluatedCache.Delete(event.Object.GetUID())
 					r.processedPolicyCache.Delete(event.Object.GetUID())
+					r.lastCompliantCache.Delete(event.Object.GetUID())
+					r.deletionDeadlines.Delete(event.Object.GetUID())
 
 					return true
 				},
@@ -159,6 +171,46 @@ func (r *ConfigurationPolicyReconciler) SetupWithManager(
 		}
 	}
 
+	if r.Shards != nil {
+		r.shards = newShardTracker(r.Shards, "ConfigurationPolicy",
+			func(ctx context.Context) ([]types.NamespacedName, error) {
+				policies := &policyv1.ConfigurationPolicyList{}
+				if err := r.List(ctx, policies); err != nil {
+					return nil, err
+				}
+
+				names := make([]types.NamespacedName, 0, len(policies.Items))
+				for _, policy := range policies.Items {
+					names = append(names, client.ObjectKeyFromObject(&policy))
+				}
+
+				return names, nil
+			},
+		)
+
+		builder = builder.WatchesRawSource(r.shards.source())
+	}
+
+	if r.EvaluationCacheStore != nil {
+		err := mgr.Add(manager.RunnableFunc(r.saveEvaluationCachePeriodically))
+		if err != nil {
+			return err
+		}
+	}
+
+	if r.APIChanges != nil {
+		r.missingAPIs = newMissingAPITracker()
+		r.APIChanges.OnAPIChange(r.handleAPIChange)
+
+		builder = builder.WatchesRawSource(r.missingAPIs.source())
+	}
+
+	if r.EnableMetrics && r.DynamicWatcher != nil {
+		if err := mgr.Add(manager.RunnableFunc(r.reportWatchCacheUsage)); err != nil {
+			return err
+		}
+	}
+
 	return builder.Complete(r)
 }
 
@@ -172,8 +224,11 @@ type ConfigurationPolicyReconciler struct {
 	client.Client
 	DecryptionConcurrency uint8
 	DynamicWatcher        depclient.DynamicWatcher
-	Scheme                *runtime.Scheme
-	Recorder              events.EventRecorder
+	// When set, the objects of object templates that only need the metadata of the objects are watched with this
+	// DynamicWatcher, which only caches the metadata of the objects
+	MetadataDynamicWatcher depclient.DynamicWatcher
+	Scheme                 *runtime.Scheme
+	Recorder               events.EventRecorder
 	// processedPolicyCache has the ConfigurationPolicy UID as the key and the values are a *sync.Map with the keys
 	// as object UIDs and the values as cachedEvaluationResult objects.
 	processedPolicyCache sync.Map
@@ -192,10 +247,31 @@ type ConfigurationPolicyReconciler struct {
 	// policies)
 	EvalBackoffSeconds uint32
 	ItemLimiters       *PerItemRateLimiter[reconcile.Request]
+	// The default maximum percentage of the evaluation interval that the next evaluation of a policy is delayed by,
+	// when not set on the policy
+	EvaluationJitterPercent uint8
+	// The max number of independent object templates of a policy that are handled concurrently
+	ObjectTemplateConcurrency uint8
+	// When set, the cache of evaluated objects is persisted to this store so that unchanged objects aren't
+	// compared again after the controller restarts
+	EvaluationCacheStore EvaluationCacheStore
+	// How often the cache of evaluated objects is saved to the EvaluationCacheStore
+	EvaluationCacheSaveInterval time.Duration
+	evaluationCache             evaluationCacheState
 	// lastEvaluatedCache contains the value of the last known ConfigurationPolicy resourceVersion per UID.
 	// This is a workaround to account for race conditions where the status is updated but the controller-runtime cache
 	// has not updated yet.
 	lastEvaluatedCache sync.Map
+	// lastCompliantCache has the ConfigurationPolicy UID as the key and the values are a *sync.Map with the keys
+	// from getEvalObjKey and the values as compliantObservation objects. It is used to determine which field
+	// managers caused an object to drift from the policy.
+	lastCompliantCache sync.Map
+	// deletionDeadlines has the ConfigurationPolicy UID as the key and the values are the earliest time.Time when
+	// an object the policy is waiting to be deleted should be reported as stuck terminating.
+	deletionDeadlines sync.Map
+	// pendingStatuses has the ConfigurationPolicy UID as the key and the values are *pendingStatus objects for the
+	// policies being evaluated, whose status is written once at the end of the evaluation.
+	pendingStatuses sync.Map
 	// for standalone hub templating
 	HubDynamicWatcher depclient.DynamicWatcher
 	HubClient         *kubernetes.Clientset
@@ -206,12 +282,44 @@ type ConfigurationPolicyReconciler struct {
 	FullDiffs bool
 	// List of additional template functions to deny
 	TemplateFuncDenylist []string
+	// When set, the policies are sharded across the active controller replicas and this replica only handles
+	// the policies it owns
+	Shards *sharding.Coordinator
+	shards *shardTracker
+	// When set, the policies that failed on a missing API mapping are evaluated again as soon as an API of the
+	// missing API group is added or updated
+	APIChanges  *APIChangeWatcher
+	missingAPIs *missingAPITracker
+	// When set, the RESTMapper of the target cluster is reset when an API group changes
+	TargetRESTMapper meta.RESTMapper
+	// When set, the related objects of the policies are reported in PolicyReports and ClusterPolicyReports
+	PolicyReporter *PolicyReporter
+	// When set, the compliance changes of the policies are sent to the configured HTTP sinks
+	ComplianceNotifier *ComplianceNotifier
+	// When set, the changes made to the objects on the cluster are recorded in the audit log
+	AuditLogger *AuditLogger
+	// When set, the compare_objects_* metrics only have the resource of the objects instead of their namespace and
+	// name, which limits the number of series to one per policy and resource
+	DropObjectMetricLabels bool
+	// When set, the decisions made during the last evaluations of each policy are recorded
+	DecisionTracer *DecisionTracer
 }
 
 //+kubebuilder:rbac:groups=*,resources=*,verbs=*
 
 // Reconcile is responsible for evaluating and rescheduling ConfigurationPolicy evaluations.
 func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
+	ctx, span := startPolicySpan(ctx, "ConfigurationPolicy.Reconcile", "ConfigurationPolicy", request.NamespacedName)
+
+	result, err := r.reconcile(ctx, request)
+
+	endSpan(span, err)
+
+	return result, err
+}
+
+// reconcile evaluates the ConfigurationPolicy and determines when it should be evaluated next.
+func (r *ConfigurationPolicyReconciler) reconcile(ctx context.Context, request ctrl.Request) (ctrl.Result, error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	if r.ItemLimiters != nil {
@@ -242,6 +350,27 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 
 		log.V(1).Info("Handling a deleted policy")
 		removeConfigPolicyMetrics(request)
+
+		owned := true
+
+		if r.shards != nil {
+			owned = r.shards.forget(request)
+		}
+
+		if r.missingAPIs != nil {
+			r.missingAPIs.forget(request.NamespacedName)
+		}
+
+		r.DecisionTracer.forget(request.NamespacedName)
+
+		// The reports are only removed by the replica that owned the policy since every replica gets the deletion
+		if r.PolicyReporter != nil && owned {
+			err := r.PolicyReporter.Remove(ctx, "ConfigurationPolicy", request.NamespacedName)
+			if err != nil {
+				log.Error(err, "Failed to delete the policy reports of the deleted ConfigurationPolicy. Will ignore.")
+			}
+		}
+
 		r.SelectorReconciler.Stop(request.Namespace, request.Name)
 
 		objID := depclient.ObjectIdentifier{
@@ -252,7 +381,7 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 			Name:      request.Name,
 		}
 
-		err := r.DynamicWatcher.RemoveWatcher(objID)
+		err := r.removePolicyWatches(objID)
 		if err != nil {
 			log.Error(err, "Failed to remove any watches from this deleted ConfigurationPolicy. Will ignore.")
 		}
@@ -264,6 +393,31 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 		return reconcile.Result{}, err
 	}
 
+	if r.shards != nil {
+		switch action, wait := r.shards.action(request); action {
+		case shardHandle:
+		case shardWait:
+			log.V(2).Info(
+				"Waiting for the controller replicas to be known or for the previous replica to release the policy",
+				"requeueAfter", wait,
+			)
+
+			return reconcile.Result{RequeueAfter: wait}, nil
+		case shardRelease:
+			log.Info("Releasing the policy to another controller replica",
+				"replica", r.Shards.Owner(sharding.Key(request.Namespace, request.Name)))
+			r.releasePolicy(ctx, policy)
+
+			return reconcile.Result{}, nil
+		case shardSkip:
+			return reconcile.Result{}, nil
+		}
+	}
+
+	// The status updates during the evaluation are written once when it's done
+	r.beginStatusUpdates(policy)
+	defer r.flushStatusUpdates(ctx, policy)
+
 	// Account for a change in evaluation interval either due to a spec change or compliance state change.
 	defer func() {
 		compliantWithWatch := policy.Status.ComplianceState == policyv1.Compliant &&
@@ -272,7 +426,7 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 			policy.Spec.EvaluationInterval.IsWatchForNonCompliant()
 
 		if !(compliantWithWatch || nonCompliantWithWatch) && !cleanup {
-			err := r.DynamicWatcher.RemoveWatcher(policy.ObjectIdentifier())
+			err := r.removePolicyWatches(policy.ObjectIdentifier())
 			if err != nil {
 				log.Error(err, "Failed to remove any watches related to this ConfigurationPolicy. Will ignore.")
 			}
@@ -289,7 +443,9 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 	// But when `cleanup` is true, we must skip resolving the hub templates.
 	if !cleanup {
 		if err := r.resolveHubTemplates(ctx, policy); err != nil {
-			statusChanged := addConditionToStatus(policy, -1, false, "Hub template resolution failure", err.Error())
+			statusChanged := addConditionToStatus(
+				policy, -1, false, "Hub template resolution failure", policyv1.ReasonCodeTemplateError, err.Error(),
+			)
 
 			if statusChanged {
 				r.recordInfoEvent(policy, true)
@@ -317,7 +473,18 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 
 	before := time.Now().UTC()
 
-	handleErr := r.handleObjectTemplates(ctx, policy)
+	// This is set again during the evaluation if the policy is waiting for objects to be deleted
+	r.deletionDeadlines.Delete(policy.GetUID())
+
+	evalCtx, span := startSpan(ctx, "ConfigurationPolicy.handleObjectTemplates")
+	evalCtx, evalTrace := r.DecisionTracer.start(evalCtx, policy)
+
+	handleErr := r.handleObjectTemplates(evalCtx, policy)
+
+	r.DecisionTracer.finish(policy, evalTrace, handleErr)
+	endSpan(span, handleErr)
+
+	trace.SpanFromContext(ctx).SetAttributes(policyComplianceKey.String(string(policy.Status.ComplianceState)))
 
 	duration := time.Now().UTC().Sub(before)
 	seconds := float64(duration) / float64(time.Second)
@@ -327,6 +494,7 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 	).Set(
 		getStatusValue(policy.Status.ComplianceState),
 	)
+	setNoncompliantObjectsMetric("ConfigurationPolicy", policy, policy.Status.RelatedObjects)
 	policyEvalSecondsCounter.WithLabelValues(policy.Name).Add(seconds)
 	policyEvalCounter.WithLabelValues(policy.Name).Inc()
 
@@ -334,7 +502,7 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 		// If the policy is invalid, don't bother requeueing since we need to wait for a spec change.
 		if errors.Is(handleErr, ErrPolicyInvalid) {
 			// Remove any watches on the policy in case the policy used to be valid and specified watches.
-			err := r.DynamicWatcher.RemoveWatcher(policy.ObjectIdentifier())
+			err := r.removePolicyWatches(policy.ObjectIdentifier())
 			if err != nil {
 				log.Error(err, "Failed to remove any watches related to this ConfigurationPolicy. Will ignore.")
 			}
@@ -342,7 +510,8 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 			return reconcile.Result{}, nil
 		}
 
-		// If a mapping error occurred, try again in 10 seconds to see if the CRD is available
+		// If a mapping error occurred, try again in 10 seconds to see if the CRD is available. This is a fallback
+		// for when the policy isn't requeued by a change of the API.
 		if errors.Is(handleErr, depclient.ErrNoVersionedResource) &&
 			policy.Spec.EvaluationInterval.IsWatchForNonCompliant() {
 			log.Info("Requeuing the policy to be reevalauted in 10 seconds due to a mapping error")
@@ -354,7 +523,6 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 	}
 
 	var requeueAfter time.Duration
-	var getIntervalErr error
 
 	if policy.Status.ComplianceState == policyv1.Compliant {
 		if policy.Spec.EvaluationInterval.IsWatchForCompliant() {
@@ -362,25 +530,45 @@ func (r *ConfigurationPolicyReconciler) Reconcile(ctx context.Context, request c
 
 			return reconcile.Result{}, nil
 		}
-
-		requeueAfter, getIntervalErr = policy.Spec.EvaluationInterval.GetCompliantInterval()
 	} else {
 		// If the policy is not compliant (i.e. noncompliant or unknown), fall back to the noncompliant evaluation
 		// interval. This is a court of guilty until proven innocent.
 		if policy.Spec.EvaluationInterval.IsWatchForNonCompliant() {
+			// An object blocked by its finalizers won't cause a watch event, so reevaluate the policy when the
+			// object should be reported as stuck terminating.
+			if deadline, ok := r.deletionDeadlines.Load(policy.GetUID()); ok {
+				requeueAfter = time.Until(deadline.(time.Time)) + time.Second
+				log.V(2).Info(
+					"The policy is waiting for an object to be deleted. Will reevaluate after the deletion timeout.",
+					"untilNextEvaluation", requeueAfter.String(),
+				)
+
+				return reconcile.Result{RequeueAfter: requeueAfter}, nil
+			}
+
 			log.V(2).Info(
 				"The policy is not compliant and has the evaluation interval set to watch. Will not schedule.",
 			)
 
 			return reconcile.Result{}, nil
 		}
+	}
+
+	// The status.lastEvaluated field was set during the evaluation. If it can't be parsed, the next evaluation is
+	// scheduled relative to now.
+	lastEvaluated, lastEvaluatedErr := time.Parse(time.RFC3339, policy.Status.LastEvaluated)
+	if lastEvaluatedErr != nil {
+		lastEvaluated = time.Now().UTC()
+	}
 
-		requeueAfter, getIntervalErr = policy.Spec.EvaluationInterval.GetNonCompliantInterval()
+	nextEvaluation, getIntervalErr := r.getNextEvaluation(policy, lastEvaluated)
+	if getIntervalErr == nil {
+		requeueAfter = time.Until(nextEvaluation)
 	}
 
 	// At this point, we know the evaluation interval isn't set to watch so remove any potential watches for this
 	// policy.
-	removeWatcherErr := r.DynamicWatcher.RemoveWatcher(policy.ObjectIdentifier())
+	removeWatcherErr := r.removePolicyWatches(policy.ObjectIdentifier())
 	if removeWatcherErr != nil {
 		log.Error(err, "Failed to remove any watches related to this ConfigurationPolicy. Will ignore.")
 	}
@@ -489,21 +677,22 @@ func (r *ConfigurationPolicyReconciler) shouldEvaluatePolicy(
 		return true, 0
 	}
 
-	var interval time.Duration
-	var getIntervalErr error
+	if r.missingAPIs != nil && r.missingAPIs.hasUpdate(client.ObjectKeyFromObject(policy)) {
+		log.V(1).Info("An API that was missing during the last evaluation changed. Will evaluate it now.")
+
+		return true, 0
+	}
 
 	switch policy.Status.ComplianceState {
-	case policyv1.Compliant:
-		interval, getIntervalErr = policy.Spec.EvaluationInterval.GetCompliantInterval()
-	case policyv1.NonCompliant:
-		interval, getIntervalErr = policy.Spec.EvaluationInterval.GetNonCompliantInterval()
+	case policyv1.Compliant, policyv1.NonCompliant:
 	case policyv1.UnknownCompliancy, policyv1.Terminating:
 		log.V(1).Info("The policy has an unknown compliance. Will evaluate it now.")
 
 		return true, 0
 	}
 
-	now := time.Now().UTC()
+	lastEvaluated, lastEvaluatedErr := time.Parse(time.RFC3339, policy.Status.LastEvaluated)
+	nextEvaluation, getIntervalErr := r.getNextEvaluation(policy, lastEvaluated)
 
 	switch {
 	case errors.Is(getIntervalErr, policyv1.ErrIsNever):
@@ -529,15 +718,13 @@ func (r *ConfigurationPolicyReconciler) shouldEvaluatePolicy(
 	// At this point, we have a valid evaluation interval, we can now determine
 	// how long we need to wait (if at all).
 
-	lastEvaluated, err := time.Parse(time.RFC3339, policy.Status.LastEvaluated)
-	if err != nil {
-		log.Error(err, "The policy has an invalid status.lastEvaluated value. Will evaluate it now.")
+	if lastEvaluatedErr != nil {
+		log.Error(lastEvaluatedErr, "The policy has an invalid status.lastEvaluated value. Will evaluate it now.")
 
 		return true, 0
 	}
 
-	nextEvaluation := lastEvaluated.Add(interval)
-	durationLeft := nextEvaluation.Sub(now)
+	durationLeft := nextEvaluation.Sub(time.Now().UTC())
 
 	if durationLeft > 0 {
 		log.V(1).Info("Skipping the policy evaluation due to the policy not reaching the evaluation interval")
@@ -567,6 +754,10 @@ func (r *ConfigurationPolicyReconciler) cleanUpChildObjects(
 	}
 
 	objsToDelete := plc.Status.RelatedObjects
+	if len(objsToDelete) == 0 {
+		// The status may have been lost, so also rely on the provenance label
+		objsToDelete = r.provenanceRelatedObjects(ctx, plc)
+	}
 
 	// When spec is updated and new related objects are created
 	if len(newRelated) != 0 {
@@ -664,6 +855,9 @@ func (r *ConfigurationPolicyReconciler) cleanUpChildObjects(
 				*object.Properties.CreatedByPolicy &&
 				object.Properties.UID == string(existing.GetUID()) {
 				needsDelete = true
+			} else if createdByPolicyStamp(plc, existing.GetAnnotations()) {
+				// The status may have been lost, so also rely on the provenance annotations
+				needsDelete = true
 			}
 		}
 
@@ -689,7 +883,12 @@ func (r *ConfigurationPolicyReconciler) cleanUpChildObjects(
 				res = r.TargetK8sDynamicClient.Resource(scopedGVR.GroupVersionResource)
 			}
 
-			deleted, err := deleteObject(ctx, res, object.Object.Metadata.Name, object.Object.Metadata.Namespace)
+			deleted, err := deleteObject(
+				ctx, res, object.Object.Metadata.Name, object.Object.Metadata.Namespace, "",
+			)
+			if !k8serrors.IsNotFound(err) {
+				r.auditObjectChange(ctx, plc, -1, auditActionDelete, existing, nil, err)
+			}
 			if !deleted {
 				deletionFailures = append(deletionFailures, gvk.String()+fmt.Sprintf(` "%s" in namespace %s`,
 					object.Object.Metadata.Name, object.Object.Metadata.Namespace))
@@ -808,6 +1007,7 @@ func (r *ConfigurationPolicyReconciler) resolveObjectTemplatesRaw(
 	plc *policyv1.ConfigurationPolicy,
 	tmplResolver *templates.TemplateResolver,
 	resolveOptions *templates.ResolveOptions,
+	params map[string]any,
 ) error {
 	objRawBytes := []byte(plc.Spec.ObjectTemplatesRaw)
 	plc.Spec.ObjectTemplates = []*policyv1.ObjectTemplate{}
@@ -819,7 +1019,9 @@ func (r *ConfigurationPolicyReconciler) resolveObjectTemplatesRaw(
 		if err != nil {
 			complianceMsg := fmt.Sprintf("Error parsing object-templates-raw YAML: %v", err)
 
-			statusChanged := addConditionToStatus(plc, -1, false, "Error processing template", complianceMsg)
+			statusChanged := addConditionToStatus(
+				plc, -1, false, reasonTemplateError, policyv1.ReasonCodeTemplateError, complianceMsg,
+			)
 			if statusChanged {
 				r.recordInfoEvent(plc, true)
 			}
@@ -839,7 +1041,12 @@ func (r *ConfigurationPolicyReconciler) resolveObjectTemplatesRaw(
 	// If there's a template, we can't rely on the cache results.
 	r.processedPolicyCache.Delete(plc.GetUID())
 
-	resolvedTemplate, err := tmplResolver.ResolveTemplate(objRawBytes, nil, resolveOptions)
+	var templateContext any
+	if params != nil {
+		templateContext = getTemplateContext(nil, "", "", params)
+	}
+
+	resolvedTemplate, err := tmplResolver.ResolveTemplate(objRawBytes, templateContext, resolveOptions)
 	if err == nil {
 		err = json.Unmarshal(resolvedTemplate.ResolvedJSON, &plc.Spec.ObjectTemplates)
 		if err != nil {
@@ -850,7 +1057,9 @@ func (r *ConfigurationPolicyReconciler) resolveObjectTemplatesRaw(
 	if err != nil {
 		complianceMsg, formattedErr := getFormattedTemplateErr(err)
 
-		statusChanged := addConditionToStatus(plc, -1, false, "Error processing template", complianceMsg)
+		statusChanged := addConditionToStatus(
+			plc, -1, false, reasonTemplateError, policyv1.ReasonCodeTemplateError, complianceMsg,
+		)
 		if statusChanged {
 			r.recordInfoEvent(plc, true)
 		}
@@ -920,7 +1129,12 @@ func (r *ConfigurationPolicyReconciler) getTemplateResolver(ctx context.Context,
 		encryptionConfig, err = r.getEncryptionConfig(ctx, plc)
 		if err != nil {
 			statusChanged := addConditionToStatus(
-				plc, -1, false, "Template encryption configuration error", err.Error(),
+				plc,
+				-1,
+				false,
+				"Template encryption configuration error",
+				policyv1.ReasonCodeTemplateError,
+				err.Error(),
 			)
 			if statusChanged {
 				r.recordInfoEvent(plc, true)
@@ -953,28 +1167,37 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 		return err
 	}
 
+	if r.missingAPIs != nil {
+		policyKey := client.ObjectKeyFromObject(plc)
+
+		r.missingAPIs.start(policyKey)
+		defer r.missingAPIs.finish(policyKey)
+	}
+
 	usingWatch := currentlyUsingWatch(plc)
 
 	if usingWatch && r.DynamicWatcher != nil {
 		watcherObj := plc.ObjectIdentifier()
 
-		err := r.DynamicWatcher.StartQueryBatch(watcherObj)
-		if err != nil {
-			log.Error(
-				err,
-				"Failed to start a query batch using the dynamic watcher. Will try again on the next evaluation.",
-				"watcher", watcherObj,
-			)
-
-			return err
-		}
-
-		defer func() {
-			err := r.DynamicWatcher.EndQueryBatch(watcherObj)
+		for _, dynamicWatcher := range r.policyWatchers() {
+			err := dynamicWatcher.StartQueryBatch(watcherObj)
 			if err != nil {
-				log.Error(err, "Failed to stop the query batch using the dynamic watcher", "watcher", watcherObj)
+				log.Error(
+					err,
+					"Failed to start a query batch using the dynamic watcher. Will try again on the next evaluation.",
+					"watcher", watcherObj,
+				)
+
+				return err
 			}
-		}()
+
+			defer func() {
+				err := dynamicWatcher.EndQueryBatch(watcherObj)
+				if err != nil {
+					log.Error(err, "Failed to stop the query batch using the dynamic watcher", "watcher", watcherObj)
+				}
+			}()
+		}
 	}
 
 	if plc.ObjectMeta.DeletionTimestamp != nil {
@@ -998,9 +1221,13 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 	var tmplResolver *templates.TemplateResolver
 	var resolveOptions *templates.ResolveOptions
 
+	var params map[string]any
+
 	relatedObjects := []policyv1.RelatedObject{}
 
-	if !disableTemplates {
+	if disableTemplates {
+		r.warnParametersIgnored(ctx, plc)
+	} else {
 		var err error
 
 		tmplResolver, resolveOptions, err = r.getTemplateResolver(ctx, plc)
@@ -1008,8 +1235,20 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 			return err
 		}
 
+		params, err = r.resolveParameters(ctx, plc, usingWatch)
+		if err != nil {
+			log.Info("Failed to resolve the policy parameters", "error", err.Error())
+
+			return r.handleParametersErr(ctx, plc, err)
+		}
+
 		if plc.Spec.ObjectTemplatesRaw != "" {
-			err := r.resolveObjectTemplatesRaw(ctx, plc, tmplResolver, resolveOptions)
+			tmplCtx, span := startSpan(ctx, "ConfigurationPolicy.resolveObjectTemplatesRaw")
+
+			err := r.resolveObjectTemplatesRaw(tmplCtx, plc, tmplResolver, resolveOptions, params)
+
+			endSpan(span, err)
+
 			if err != nil {
 				return err
 			}
@@ -1032,7 +1271,7 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 		msg := fmt.Sprintf("%v contains no object templates to check, and thus has no violations",
 			plc.GetName())
 
-		statusUpdateNeeded := addConditionToStatus(plc, -1, true, reason, msg)
+		statusUpdateNeeded := addConditionToStatus(plc, -1, true, reason, policyv1.ReasonCodeNoObjectTemplates, msg)
 
 		if statusUpdateNeeded {
 			r.recordInfoEvent(plc, false)
@@ -1041,6 +1280,7 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 		updatedRelated := r.updatedRelatedObjects(plc, relatedObjects)
 		if !gocmp.Equal(updatedRelated, plc.Status.RelatedObjects) {
 			r.cleanUpChildObjects(ctx, plc, updatedRelated, usingWatch)
+			r.pruneCompliantObservations(plc, updatedRelated)
 
 			plc.Status.RelatedObjects = updatedRelated
 		}
@@ -1052,50 +1292,77 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 
 	errs := []error{}
 	var skipCleanupChildObjects bool
+	relatedIndexes := map[policyv1.ObjectResource]int{}
 
-	for index, objectT := range plc.Spec.ObjectTemplates {
-		nsNameToResults := map[string]objectTmplEvalResult{}
+	// Template resolution is not safe for concurrent use, so when object templates are handled concurrently,
+	// the desired objects of every object template are determined in order before any are handled. Otherwise,
+	// each object template is handled before the next is resolved.
+	evaluations := make([]objectTemplateEvaluation, len(plc.Spec.ObjectTemplates))
+	concurrent := r.ObjectTemplateConcurrency > 1 && !mustHandleInOrder(plc.Spec.ObjectTemplates, !disableTemplates)
 
+	for index, objectT := range plc.Spec.ObjectTemplates {
 		var resolverToUse *templates.TemplateResolver
 
 		if !disableTemplates {
 			resolverToUse = tmplResolver
 		}
 
+		tmplCtx, span := startSpan(ctx, "ConfigurationPolicy.determineDesiredObjects", templateIndexKey.Int(index))
+
 		desiredObjects, scopedGVR, determinedRelatedObjects, errEvent, err := r.determineDesiredObjects(
-			ctx, plc, index, objectT, resolverToUse, resolveOptions,
+			tmplCtx, plc, index, objectT, resolverToUse, resolveOptions, params,
 		)
 
+		endSpan(span, err)
+
+		decisionTraceFrom(ctx).templateResolved(index, objectT, desiredObjects, err)
+
+		evaluations[index] = objectTemplateEvaluation{
+			desiredObjects: desiredObjects,
+			scopedGVR:      scopedGVR,
+			relatedObjects: determinedRelatedObjects,
+			errEvent:       errEvent,
+			err:            err,
+		}
+
+		if !concurrent {
+			r.handleTemplateDesiredObjects(ctx, plc, index, &evaluations[index], usingWatch)
+		}
+	}
+
+	if concurrent {
+		r.handleDesiredObjects(ctx, plc, evaluations, usingWatch)
+	}
+
+	for index := range plc.Spec.ObjectTemplates {
+		nsNameToResults := map[string]objectTmplEvalResult{}
+		evaluation := &evaluations[index]
+		scopedGVR := evaluation.scopedGVR
+
 		// Merge the related objects returned from determineDesiredObjects into the outer relatedObjects
-		for _, object := range determinedRelatedObjects {
-			relatedObjects = addOrUpdateRelatedObject(relatedObjects, object)
+		for _, object := range evaluation.relatedObjects {
+			relatedObjects = addOrUpdateIndexedRelatedObject(relatedObjects, relatedIndexes, object)
 		}
 
-		if err != nil {
+		if evaluation.err != nil {
 			// Return all mapping and templating errors encountered and let the caller decide if the errors should be
 			// retried
-			errs = append(errs, err)
+			errs = append(errs, evaluation.err)
 			// Don't clean up child objects if there is a templating or system error.
 			skipCleanupChildObjects = true
 		}
 
-		if errEvent != nil {
+		if evaluation.errEvent != nil {
 			nsNameToResults["ns"] = objectTmplEvalResult{
-				events: []objectTmplEvalEvent{*errEvent},
+				events: []objectTmplEvalEvent{*evaluation.errEvent},
 			}
-		} else if err != nil {
+		} else if evaluation.err != nil {
 			continue
 		}
 
-		for _, desiredObj := range desiredObjects {
-			ns := desiredObj.GetNamespace()
-			name := desiredObj.GetName()
-			resultKey := fmt.Sprintf("%s/%s", ns, name)
-
-			log.V(1).Info("Handling the object template for the relevant namespace",
-				"namespace", ns, "desiredName", name, "index", index)
-
-			related, result := r.handleObjects(ctx, objectT, desiredObj, index, plc, *scopedGVR, usingWatch)
+		for i, desiredObj := range evaluation.desiredObjects {
+			resultKey := fmt.Sprintf("%s/%s", desiredObj.GetNamespace(), desiredObj.GetName())
+			result := evaluation.results[i].result
 
 			if result.apiErr != nil {
 				errs = append(errs, result.apiErr)
@@ -1103,8 +1370,10 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 
 			nsNameToResults[resultKey] = result
 
-			for _, object := range related {
-				relatedObjects = addOrUpdateRelatedObject(relatedObjects, object)
+			decisionTraceFrom(ctx).objectsEvaluated(index, result)
+
+			for _, object := range evaluation.results[i].relatedObjects {
+				relatedObjects = addOrUpdateIndexedRelatedObject(relatedObjects, relatedIndexes, object)
 			}
 		}
 
@@ -1124,10 +1393,12 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 		if len(eventBatches) > 1 {
 			lastBatch := eventBatches[len(eventBatches)-1]
 
-			compliant, reason, msg := createStatus(resourceName, lastBatch)
+			compliant, reason, msg, reasonCode := createStatus(resourceName, lastBatch)
 
 			if !compliant {
-				statusUpdateNeeded := addConditionToStatus(plc.DeepCopy(), index, compliant, reason, msg)
+				statusUpdateNeeded := addConditionToStatus(
+					plc.DeepCopy(), index, compliant, reason, reasonCode, msg,
+				)
 
 				if !statusUpdateNeeded {
 					log.V(2).Info("Skipping status update because the last batch already matches")
@@ -1138,9 +1409,9 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 		}
 
 		for i, batch := range eventBatches {
-			compliant, reason, msg := createStatus(resourceName, batch)
+			compliant, reason, msg, reasonCode := createStatus(resourceName, batch)
 
-			statusUpdateNeeded := addConditionToStatus(plc, index, compliant, reason, msg)
+			statusUpdateNeeded := addConditionToStatus(plc, index, compliant, reason, reasonCode, msg)
 
 			if statusUpdateNeeded {
 				parentStatusUpdateNeeded = true
@@ -1161,6 +1432,7 @@ func (r *ConfigurationPolicyReconciler) handleObjectTemplates(
 	if !gocmp.Equal(updatedRelated, plc.Status.RelatedObjects) {
 		if !skipCleanupChildObjects {
 			r.cleanUpChildObjects(ctx, plc, updatedRelated, usingWatch)
+			r.pruneCompliantObservations(plc, updatedRelated)
 		}
 
 		plc.Status.RelatedObjects = updatedRelated
@@ -1182,7 +1454,9 @@ func (r *ConfigurationPolicyReconciler) validateConfigPolicy(
 	}
 
 	invalidMessage := "Policy does not have a RemediationAction specified"
-	statusChanged := addConditionToStatus(plc, -1, false, "Invalid spec", invalidMessage)
+	statusChanged := addConditionToStatus(
+		plc, -1, false, "Invalid spec", policyv1.ReasonCodeInvalidSpec, invalidMessage,
+	)
 
 	if statusChanged {
 		r.recordInfoEvent(plc, true)
@@ -1280,7 +1554,7 @@ func (r *ConfigurationPolicyReconciler) handleDeletion(
 
 		failuresStr := strings.Join(failures, ", ")
 
-		statusChanged := addConditionToStatus(plc, -1, false, reasonCleanupError,
+		statusChanged := addConditionToStatus(plc, -1, false, reasonCleanupError, policyv1.ReasonCodeDeleteFailed,
 			"Failed to delete objects: "+failuresStr)
 		if statusChanged {
 			parentStatusUpdateNeeded = true
@@ -1341,6 +1615,7 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 	objectT *policyv1.ObjectTemplate,
 	tmplResolver *templates.TemplateResolver,
 	resolveOptions *templates.ResolveOptions,
+	params map[string]any,
 ) (
 	[]*unstructured.Unstructured,
 	*depclient.ScopedGVR,
@@ -1360,9 +1635,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 		log.Error(err, "Could not parse the namespace from the objectDefinition")
 
 		errEvent := &objectTmplEvalEvent{
-			compliant: false,
-			reason:    "K8s decode object definition error",
-			message:   "Error parsing the namespace from the object definition",
+			compliant:  false,
+			reason:     "K8s decode object definition error",
+			reasonCode: policyv1.ReasonCodeInvalidSpec,
+			message:    "Error parsing the namespace from the object definition",
 		}
 
 		return nil, nil, nil, errEvent, err
@@ -1372,8 +1648,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 
 	if objGVK.Kind == "" || objGVK.Version == "" {
 		errEvent := &objectTmplEvalEvent{
-			compliant: false,
-			reason:    "K8s decode object definition error",
+			compliant:  false,
+			reason:     "K8s decode object definition error",
+			reasonCode: policyv1.ReasonCodeInvalidSpec,
 			message: fmt.Sprintf(
 				"The kind and apiVersion fields are required on the object template at index %d in policy %s",
 				index, plc.Name,
@@ -1394,7 +1671,8 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 				Object          map[string]any
 				ObjectNamespace string
 				ObjectName      string
-			}{Object: map[string]any{}, ObjectNamespace: "", ObjectName: ""}
+				Params          map[string]any
+			}{Object: map[string]any{}, ObjectNamespace: "", ObjectName: "", Params: params}
 
 			_, skipObject, _ := resolveGoTemplates(
 				objectT.ObjectDefinition.Raw,
@@ -1405,9 +1683,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 
 			if skipObject {
 				event := &objectTmplEvalEvent{
-					compliant: true,
-					reason:    "",
-					message:   fmt.Sprintf(skippedObjMsg, objGVK.Kind),
+					compliant:  true,
+					reason:     "",
+					reasonCode: policyv1.ReasonCodeNotFound,
+					message:    fmt.Sprintf(skippedObjMsg, objGVK.Kind),
 				}
 
 				return []*unstructured.Unstructured{}, nil, nil, event, nil
@@ -1415,9 +1694,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 		}
 
 		errEvent := &objectTmplEvalEvent{
-			compliant: false,
-			reason:    "K8s error",
-			message:   err.Error(),
+			compliant:  false,
+			reason:     "K8s error",
+			reasonCode: policyv1.ReasonCodeError,
+			message:    err.Error(),
 		}
 
 		return nil, nil, nil, errEvent, err
@@ -1472,9 +1752,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 			log.Error(err, "Failed to select the namespaces", "namespaceSelector", nsSelector.String())
 			msg := fmt.Sprintf("Error filtering namespaces with provided namespaceSelector: %v", err)
 			errEvent := &objectTmplEvalEvent{
-				compliant: false,
-				reason:    "namespaceSelector error",
-				message:   msg,
+				compliant:  false,
+				reason:     "namespaceSelector error",
+				reasonCode: policyv1.ReasonCodeInvalidSpec,
+				message:    msg,
 			}
 
 			return nil, &scopedGVR, nil, errEvent, err
@@ -1540,7 +1821,7 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 			space, desiredName, objGVK.Kind,
 		)
 
-		errEvent := &objectTmplEvalEvent{false, "K8s missing namespace", msg}
+		errEvent := &objectTmplEvalEvent{false, "K8s missing namespace", policyv1.ReasonCodeInvalidSpec, msg}
 
 		return nil, &scopedGVR, nil, errEvent, nil
 	}
@@ -1561,9 +1842,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 					"it may require evaluationInterval to be set: %v", index, err)
 
 				errEvent := &objectTmplEvalEvent{
-					compliant: false,
-					reason:    "unwatchable resource",
-					message:   msg,
+					compliant:  false,
+					reason:     "unwatchable resource",
+					reasonCode: policyv1.ReasonCodeError,
+					message:    msg,
 				}
 
 				return nil, &scopedGVR, nil, errEvent, err
@@ -1590,9 +1872,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 			)
 
 			errEvent := &objectTmplEvalEvent{
-				compliant: false,
-				reason:    "objectSelector error",
-				message:   msg,
+				compliant:  false,
+				reason:     "objectSelector error",
+				reasonCode: policyv1.ReasonCodeInvalidSpec,
+				message:    msg,
 			}
 
 			return nil, &scopedGVR, nil, errEvent, err
@@ -1609,7 +1892,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 
 			// If watch is enabled, use the dynamic watcher, otherwise use the controller dynamic client
 			if usingWatch {
-				filteredObjects, err = r.DynamicWatcher.List(plc.ObjectIdentifier(), objGVK, ns, objSelector)
+				filteredObjects, err = r.templateWatcher(plc, objectT).List(
+					plc.ObjectIdentifier(), objGVK, ns, objSelector,
+				)
 			} else {
 				var filteredObjectList *unstructured.UnstructuredList
 				filteredObjectList, err = r.TargetK8sDynamicClient.Resource(
@@ -1633,9 +1918,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 				}
 
 				errEvent := &objectTmplEvalEvent{
-					compliant: false,
-					reason:    "objectSelector error",
-					message:   msg,
+					compliant:  false,
+					reason:     "objectSelector error",
+					reasonCode: policyv1.ReasonCodeInvalidSpec,
+					message:    msg,
 				}
 
 				return nil, &scopedGVR, nil, errEvent, err
@@ -1710,7 +1996,7 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 				unstructured.RemoveNestedField(obj.Object, "metadata", "managedFields")
 
 				// Get the template context for resolving Go templates
-				templateContext := getTemplateContext(obj.Object, name, ns)
+				templateContext := getTemplateContext(obj.Object, name, ns, params)
 
 				// Resolve the Go templates
 				resolvedTemplate, skipObject, err := resolveGoTemplates(
@@ -1734,9 +2020,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 					complianceMsg, err := getFormattedTemplateErr(err)
 
 					errEvent := &objectTmplEvalEvent{
-						compliant: false,
-						reason:    reasonTemplateError,
-						message:   complianceMsg,
+						compliant:  false,
+						reason:     reasonTemplateError,
+						reasonCode: policyv1.ReasonCodeTemplateError,
+						message:    complianceMsg,
 					}
 
 					return nil, &scopedGVR, nil, errEvent, err
@@ -1772,8 +2059,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 				log.Error(err, "Could not decode the objectDefinition", "index", index)
 
 				errEvent := &objectTmplEvalEvent{
-					compliant: false,
-					reason:    "K8s decode object definition error",
+					compliant:  false,
+					reason:     "K8s decode object definition error",
+					reasonCode: policyv1.ReasonCodeInvalidSpec,
 					message: fmt.Sprintf("Decoding error, please check your policy file!"+
 						" Aborting handling the object template at index [%v] in policy `%v` with error = `%v`",
 						index, plc.Name, err),
@@ -1800,8 +2088,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 			// Error if the namespace doesn't match the parsed namespace from the namespaceSelector
 			if !plc.Spec.NamespaceSelector.IsEmpty() && desiredObj.GetNamespace() != ns {
 				errEvent := &objectTmplEvalEvent{
-					compliant: false,
-					reason:    reasonTemplateError,
+					compliant:  false,
+					reason:     reasonTemplateError,
+					reasonCode: policyv1.ReasonCodeTemplateError,
 					message: "The object definition's namespace must match the result " +
 						"from the namespace selector after template resolution",
 				}
@@ -1817,8 +2106,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 				}
 
 				errEvent := &objectTmplEvalEvent{
-					compliant: false,
-					reason:    reasonTemplateError,
+					compliant:  false,
+					reason:     reasonTemplateError,
+					reasonCode: policyv1.ReasonCodeTemplateError,
 					message: fmt.Sprintf("namespaced object%s%s of kind %s has no namespace specified "+
 						"after template resolution",
 						space, desiredName, objGVK.Kind,
@@ -1831,8 +2121,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 			// Error if the name doesn't match the parsed name from the objectSelector
 			if objectSelector != nil && desiredObj.GetName() != name {
 				errEvent := &objectTmplEvalEvent{
-					compliant: false,
-					reason:    reasonTemplateError,
+					compliant:  false,
+					reason:     reasonTemplateError,
+					reasonCode: policyv1.ReasonCodeTemplateError,
 					message: "The object definition's name must match the result " +
 						"from the object selector after template resolution",
 				}
@@ -1860,9 +2151,10 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 		}
 
 		event := &objectTmplEvalEvent{
-			compliant: true,
-			reason:    "",
-			message:   fmt.Sprintf(msg, objGVK.Kind),
+			compliant:  true,
+			reason:     "",
+			reasonCode: policyv1.ReasonCodeNotFound,
+			message:    fmt.Sprintf(msg, objGVK.Kind),
 		}
 
 		return nil, &scopedGVR, nil, event, nil
@@ -1907,6 +2199,7 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 						ns,
 						[]string{name},
 						"",
+						policyv1.ReasonCodeNotFound,
 						nil,
 					)
 				}
@@ -1920,8 +2213,9 @@ func (r *ConfigurationPolicyReconciler) determineDesiredObjects(
 			}
 
 			event := &objectTmplEvalEvent{
-				compliant: true,
-				reason:    "",
+				compliant:  true,
+				reason:     "",
+				reasonCode: policyv1.ReasonCodeNotFound,
 				message: fmt.Sprintf(
 					"%s missing as expected in %s %s",
 					scopedGVR.Resource,
@@ -1992,20 +2286,26 @@ func (r *ConfigurationPolicyReconciler) updatedRelatedObjects(
 		return related[i].Object.Metadata.Name < related[j].Object.Metadata.Name
 	})
 
+	// Index the old related objects by object to avoid a quadratic search when there are many related objects
+	oldIndexes := make(map[policyv1.ObjectResource][]int, len(oldRelated))
+
+	for i, oldEntry := range oldRelated {
+		oldIndexes[oldEntry.Object] = append(oldIndexes[oldEntry.Object], i)
+	}
+
 	for i, newEntry := range related {
-		for _, oldEntry := range oldRelated {
-			// Get matching objects
-			if gocmp.Equal(newEntry.Object, oldEntry.Object) {
-				if oldEntry.Properties != nil &&
-					newEntry.Properties != nil &&
-					newEntry.Properties.CreatedByPolicy != nil &&
-					!(*newEntry.Properties.CreatedByPolicy) {
-					// Use the old properties if they existed and this is not a newly created resource
-					related[i].Properties.CreatedByPolicy = oldEntry.Properties.CreatedByPolicy
-					related[i].Properties.UID = oldEntry.Properties.UID
+		for _, oldIndex := range oldIndexes[newEntry.Object] {
+			oldEntry := oldRelated[oldIndex]
 
-					break
-				}
+			if oldEntry.Properties != nil &&
+				newEntry.Properties != nil &&
+				newEntry.Properties.CreatedByPolicy != nil &&
+				!(*newEntry.Properties.CreatedByPolicy) {
+				// Use the old properties if they existed and this is not a newly created resource
+				related[i].Properties.CreatedByPolicy = oldEntry.Properties.CreatedByPolicy
+				related[i].Properties.UID = oldEntry.Properties.UID
+
+				break
 			}
 		}
 	}
@@ -2016,12 +2316,18 @@ func (r *ConfigurationPolicyReconciler) updatedRelatedObjects(
 // helper function that appends a condition (violation or compliant) to the status of a configurationpolicy
 // Set the index to -1 to signal that the status should be cleared.
 func addConditionToStatus(
-	plc *policyv1.ConfigurationPolicy, index int, compliant bool, reason string, message string,
+	plc *policyv1.ConfigurationPolicy,
+	index int,
+	compliant bool,
+	reason string,
+	reasonCode policyv1.ReasonCode,
+	message string,
 ) (updateNeeded bool) {
 	newCond := &policyv1.Condition{
 		Status:             corev1.ConditionTrue,
 		LastTransitionTime: metav1.Now(),
 		Reason:             reason,
+		ReasonCode:         reasonCode,
 		Message:            message,
 	}
 
@@ -2090,6 +2396,7 @@ func addConditionToStatus(
 		oldCond := currentConds[len(currentConds)-1]
 		newConditionIsSame := oldCond.Status == newCond.Status &&
 			oldCond.Reason == newCond.Reason &&
+			oldCond.ReasonCode == newCond.ReasonCode &&
 			oldCond.Message == newCond.Message &&
 			oldCond.Type == newCond.Type
 
@@ -2150,7 +2457,9 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 				Kind:    desiredObjKind,
 			}
 
-			existingObj, getErr = r.getObjectFromCache(policy, log, desiredObjNamespace, desiredObjName, objGVK)
+			existingObj, getErr = r.getTemplateObjectFromCache(
+				policy, objectT, log, desiredObjNamespace, desiredObjName, objGVK,
+			)
 
 			// This error is handled specially - others are handled later
 			if errors.Is(getErr, depclient.ErrResourceUnwatchable) {
@@ -2161,9 +2470,10 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 					objectNames: []string{desiredObjName},
 					namespace:   desiredObjNamespace, // may be empty
 					events: []objectTmplEvalEvent{{
-						compliant: false,
-						reason:    "unwatchable resource",
-						message:   msg,
+						compliant:  false,
+						reason:     "unwatchable resource",
+						reasonCode: policyv1.ReasonCodeError,
+						message:    msg,
 					}},
 				}
 
@@ -2213,9 +2523,10 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 			objectNames: objNames,
 			namespace:   desiredObjNamespace, // may be empty
 			events: []objectTmplEvalEvent{{
-				compliant: false,
-				reason:    "api error",
-				message:   msg,
+				compliant:  false,
+				reason:     "api error",
+				reasonCode: policyv1.ReasonCodeError,
+				message:    msg,
 			}},
 			apiErr: getErr,
 		}
@@ -2229,6 +2540,24 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 
 	shouldAddCondensedRelatedObj := false
 
+	// In watch mode, reuse the result of the previous evaluation of a named object when neither the policy,
+	// the object, nor the resolved object template changed so that a watch event for one of many objects
+	// selected by the object template only causes the changed object to be evaluated again.
+	var resultKey, resourceVersion, desiredHash string
+
+	if useCache && desiredObjName != "" && existingObj != nil {
+		resultKey = getObjectResultKey(index, desiredObjNamespace, desiredObjName)
+		resourceVersion = existingObj.GetResourceVersion()
+		desiredHash = getDesiredHash(desiredObj)
+
+		cachedRelated, cachedResult, found := r.getObjectResult(policy, resultKey, resourceVersion, desiredHash)
+		if found {
+			log.V(2).Info("Reusing the previous result since the object and object template are unchanged")
+
+			return cachedRelated, cachedResult
+		}
+	}
+
 	if len(objNames) == 1 {
 		name := objNames[0]
 		singObj := singleObject{
@@ -2250,16 +2579,27 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 
 		if len(result.events) != 0 {
 			event := result.events[len(result.events)-1]
+
+			reason := event.reason
+			if result.gitOpsOwner != "" {
+				reason += " (managed by " + result.gitOpsOwner + ")"
+			}
+
 			relatedObjects = addRelatedObjects(
 				event.compliant,
 				scopedGVR,
 				desiredObjKind,
 				desiredObjNamespace,
 				result.objectNames,
-				event.reason,
+				reason,
+				event.reasonCode,
 				objectProperties,
 			)
 		}
+
+		if resultKey != "" {
+			r.setObjectResult(policy, resultKey, resourceVersion, desiredHash, relatedObjects, result, remediation)
+		}
 	} else { // This case only occurs when the desired object is not named
 		resultEvent := objectTmplEvalEvent{}
 
@@ -2267,9 +2607,11 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 			if exists {
 				resultEvent.compliant = true
 				resultEvent.reason = reasonWantFoundExists
+				resultEvent.reasonCode = policyv1.ReasonCodeFound
 			} else {
 				resultEvent.compliant = false
 				resultEvent.reason = reasonWantFoundDNE
+				resultEvent.reasonCode = policyv1.ReasonCodeMissing
 				// Length of objNames = 0, complianceType == musthave or mustonlyhave
 				// Find Noncompliant resources to add to the status.relatedObjects for debugging purpose
 				shouldAddCondensedRelatedObj = true
@@ -2278,6 +2620,7 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 					// Change reason to Resource found but does not match
 					if len(allResourceNames) > 0 {
 						resultEvent.reason = reasonWantFoundNoMatch
+						resultEvent.reasonCode = policyv1.ReasonCodeMismatch
 					}
 				}
 			}
@@ -2285,9 +2628,11 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 			if exists {
 				resultEvent.compliant = false
 				resultEvent.reason = reasonWantNotFoundExists
+				resultEvent.reasonCode = policyv1.ReasonCodeFoundNotWanted
 			} else {
 				resultEvent.compliant = true
 				resultEvent.reason = reasonWantNotFoundDNE
+				resultEvent.reasonCode = policyv1.ReasonCodeNotFound
 				// Compliant, complianceType == mustnothave
 				// Find resources in the same namespace to add to the status.relatedObjects for debugging purpose
 				shouldAddCondensedRelatedObj = true
@@ -2307,6 +2652,7 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 				desiredObjKind,
 				desiredObjNamespace,
 				resultEvent.reason,
+				resultEvent.reasonCode,
 			)
 		} else {
 			relatedObjects = addRelatedObjects(
@@ -2316,6 +2662,7 @@ func (r *ConfigurationPolicyReconciler) handleObjects(
 				desiredObjNamespace,
 				objNames,
 				resultEvent.reason,
+				resultEvent.reasonCode,
 				nil,
 			)
 		}
@@ -2340,12 +2687,18 @@ type objectTmplEvalResult struct {
 	namespace   string
 	events      []objectTmplEvalEvent
 	apiErr      error
+	// changedBy lists the field managers that caused the object to no longer match the object template
+	changedBy []policyv1.FieldManagerChange
+	// gitOpsOwner describes the GitOps tool managing the object when that caused the object to not be enforced
+	gitOpsOwner string
 }
 
 type objectTmplEvalEvent struct {
 	compliant bool
 	reason    string
-	message   string
+	// reasonCode is the machine-readable summary of the reason, which is set with the reason
+	reasonCode policyv1.ReasonCode
+	message    string
 }
 
 type objectTmplEvalResultWithEvent struct {
@@ -2356,19 +2709,23 @@ type objectTmplEvalResultWithEvent struct {
 // uidWasCreatedByPolicy reports whether the configuration policy's status lists the given object UID
 // with createdByPolicy set. The UID should be the cluster object's metadata.uid from before an
 // operation that replaces the object (for example enforce-mode recreate). Callers use this to
-// preserve createdByPolicy across a UID change when pruneObjectBehavior is DeleteIfCreated.
-func uidWasCreatedByPolicy(plc *policyv1.ConfigurationPolicy, objectUID string) bool {
+// preserve createdByPolicy across a UID change when pruneObjectBehavior is DeleteIfCreated. If the
+// status doesn't list the UID, such as when the status was lost, the provenance annotations of the
+// object are used instead.
+func uidWasCreatedByPolicy(
+	plc *policyv1.ConfigurationPolicy, objectUID string, annotations map[string]string,
+) bool {
 	for _, related := range plc.Status.RelatedObjects {
 		if related.Properties == nil || related.Properties.UID != objectUID {
 			continue
 		}
 
 		if related.Properties.CreatedByPolicy != nil {
-			return *related.Properties.CreatedByPolicy
+			return *related.Properties.CreatedByPolicy || createdByPolicyStamp(plc, annotations)
 		}
 	}
 
-	return false
+	return createdByPolicyStamp(plc, annotations)
 }
 
 // handleSingleObj takes in an object template (for a named object) and its data and determines whether
@@ -2391,15 +2748,51 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 		events:      []objectTmplEvalEvent{},
 	}
 
+	if exists {
+		if owner := getGitOpsOwner(obj.existingObj); owner != nil {
+			switch {
+			case objectT.GitOpsManaged == policyv1.GitOpsManagedSkip && obj.shouldExist:
+				objLog.V(1).Info("Skipping the object since it is managed by GitOps", "gitOpsOwner", owner.String())
+
+				result.gitOpsOwner = owner.String()
+				result.events = append(result.events, objectTmplEvalEvent{
+					true,
+					reasonGitOpsSkipped,
+					policyv1.ReasonCodeExempted,
+					getMsgPrefix(&obj) + " is managed by " + owner.String() + " and was not evaluated",
+				})
+
+				// Retain whether the object was created by the policy so that it's still pruned
+				uid := string(obj.existingObj.GetUID())
+				created := uidWasCreatedByPolicy(obj.policy, uid, obj.existingObj.GetAnnotations())
+
+				return result, &policyv1.ObjectProperties{CreatedByPolicy: &created, UID: uid}
+			// Skipping an object that shouldn't exist would hide it, so it's reported but not deleted instead
+			case objectT.GitOpsManaged == policyv1.GitOpsManagedSkip,
+				objectT.GitOpsManaged == policyv1.GitOpsManagedInform:
+				if remediation.IsEnforce() {
+					objLog.V(1).Info(
+						"Not enforcing the object since it is managed by GitOps", "gitOpsOwner", owner.String(),
+					)
+
+					result.gitOpsOwner = owner.String()
+					remediation = policyv1.Inform
+				}
+			}
+		}
+	}
+
 	if !exists && obj.shouldExist {
 		// object is missing and will be created, so send noncompliant "does not exist" event regardless of the
 		// remediation action
-		result.events = append(result.events, objectTmplEvalEvent{false, reasonWantFoundDNE, ""})
+		result.events = append(result.events, objectTmplEvalEvent{
+			false, reasonWantFoundDNE, policyv1.ReasonCodeMissing, "",
+		})
 
 		// it is a musthave and it does not exist, so it must be created
 		if remediation.IsEnforce() {
 			var uid string
-			completed, reason, msg, uid, err := r.enforceByCreating(ctx, obj)
+			completed, reason, reasonCode, msg, uid, err := r.enforceByCreating(ctx, obj)
 
 			hasStatus := false
 			var unstruct unstructured.Unstructured
@@ -2413,9 +2806,9 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 			if completed && hasStatus {
 				msg += ", the status of the object will be verified in the next evaluation"
 				reason += ", status unchecked"
-				result.events = append(result.events, objectTmplEvalEvent{false, reason, msg})
+				result.events = append(result.events, objectTmplEvalEvent{false, reason, reasonCode, msg})
 			} else {
-				result.events = append(result.events, objectTmplEvalEvent{completed, reason, msg})
+				result.events = append(result.events, objectTmplEvalEvent{completed, reason, reasonCode, msg})
 			}
 
 			if err != nil {
@@ -2437,15 +2830,17 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 	if exists && !obj.shouldExist {
 		// it is a mustnothave but it exist, so it must be deleted
 		if remediation.IsEnforce() {
-			completed, reason, msg, err := r.enforceByDeleting(ctx, obj)
+			completed, reason, reasonCode, msg, err := r.enforceByDeleting(ctx, obj, objectT)
 			if err != nil {
 				objLog.Error(err, "Could not handle existing mustnothave object")
 				result.apiErr = err
 			}
 
-			result.events = append(result.events, objectTmplEvalEvent{completed, reason, msg})
+			result.events = append(result.events, objectTmplEvalEvent{completed, reason, reasonCode, msg})
 		} else { // inform
-			result.events = append(result.events, objectTmplEvalEvent{false, reasonWantNotFoundExists, ""})
+			result.events = append(result.events, objectTmplEvalEvent{
+				false, reasonWantNotFoundExists, policyv1.ReasonCodeFoundNotWanted, "",
+			})
 		}
 
 		return result, objectProperties
@@ -2454,7 +2849,9 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 	if !exists && !obj.shouldExist {
 		objLog.V(1).Info("The object does not exist and is compliant with the mustnothave compliance type")
 		// it is a must not have and it does not exist, so it is compliant
-		result.events = append(result.events, objectTmplEvalEvent{true, reasonWantNotFoundDNE, ""})
+		result.events = append(result.events, objectTmplEvalEvent{
+			true, reasonWantNotFoundDNE, policyv1.ReasonCodeNotFound, "",
+		})
 
 		return result, objectProperties
 	}
@@ -2465,19 +2862,27 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 
 		var violation, triedUpdate, matchesAfterDryRun bool
 		var msg, diff string
+		var failureCode policyv1.ReasonCode
+		var mismatches []policyv1.MismatchedField
 		var updatedObj *unstructured.Unstructured
+		var changedBy []policyv1.FieldManagerChange
 
-		created := false
+		// The annotations are retrieved before the comparison since it removes the provenance annotations
+		existingAnnotations := obj.existingObj.GetAnnotations()
+		created := createdByPolicyStamp(obj.policy, existingAnnotations)
 		uid := string(obj.existingObj.GetUID())
 
-		if evaluated, compliant, cachedMsg := r.alreadyEvaluated(obj.policy, obj.existingObj, objectT); evaluated {
+		evaluated, compliant, cachedMsg, cachedCode := r.alreadyEvaluated(obj.policy, obj.existingObj, objectT)
+		if evaluated {
 			objLog.V(1).Info("Skipping object comparison since the resourceVersion hasn't changed")
 
 			for _, relatedObj := range obj.policy.Status.RelatedObjects {
 				if relatedObj.Properties != nil && relatedObj.Properties.UID == uid {
 					// Retain the properties from the previous evaluation
 					diff = relatedObj.Properties.Diff
+					mismatches = relatedObj.Properties.MismatchedFields
 					matchesAfterDryRun = relatedObj.Properties.MatchesAfterDryRun
+					changedBy = relatedObj.Properties.ChangedBy
 
 					break
 				}
@@ -2485,51 +2890,79 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 
 			violation = !compliant
 			msg = cachedMsg
+			failureCode = cachedCode
+
+			if compliant {
+				r.recordCompliantObservation(obj.policy, obj.existingObj, objectT)
+			}
 		} else {
 			var recreated bool
 
-			violation, msg, diff, triedUpdate, updatedObj, matchesAfterDryRun, recreated = r.checkAndUpdateResource(
-				ctx, obj, objectT, remediation,
-			)
+			violation, msg, failureCode, diff, mismatches, triedUpdate, updatedObj, matchesAfterDryRun, recreated =
+				r.checkAndUpdateResource(ctx, obj, objectT, remediation)
 
 			if updatedObj != nil && string(updatedObj.GetUID()) != uid {
 				oldUID := uid
 				uid = string(updatedObj.GetUID())
 
 				if recreated {
-					created = uidWasCreatedByPolicy(obj.policy, oldUID) // preserve the previous setting
+					// preserve the previous setting
+					created = uidWasCreatedByPolicy(obj.policy, oldUID, existingAnnotations)
 				} else {
 					created = true
 				}
 			}
+
+			switch {
+			case !violation && updatedObj != nil:
+				r.recordCompliantObservation(obj.policy, updatedObj, objectT)
+			case !violation:
+				r.recordCompliantObservation(obj.policy, obj.existingObj, objectT)
+			case msg == "":
+				changedBy = r.driftChangedBy(ctx, objLog, obj, objectT)
+			}
 		}
 
 		if triedUpdate && !strings.Contains(msg, "Error validating the object") {
 			// The object was mismatched and was potentially fixed depending on the remediation action
-			result.events = append(result.events, objectTmplEvalEvent{false, reasonWantFoundNoMatch, ""})
+			result.events = append(result.events, objectTmplEvalEvent{
+				false, reasonWantFoundNoMatch, policyv1.ReasonCodeMismatch, "",
+			})
 		}
 
 		if violation {
-			var resultReason, resultMsg string
+			resultEvent := objectTmplEvalEvent{false, reasonWantFoundNoMatch, policyv1.ReasonCodeMismatch, ""}
 
 			if msg != "" {
-				resultReason = "K8s update template error"
-				resultMsg = msg
+				resultEvent.reason = "K8s update template error"
+				resultEvent.message = msg
+				resultEvent.reasonCode = failureCode
+
+				// The evaluation cache saved by older versions doesn't have the reason code
+				if resultEvent.reasonCode == "" {
+					resultEvent.reasonCode = policyv1.ReasonCodeUpdateFailed
+				}
 			} else {
-				resultReason = reasonWantFoundNoMatch
+				result.changedBy = changedBy
 			}
 
-			result.events = append(result.events, objectTmplEvalEvent{false, resultReason, resultMsg})
+			result.events = append(result.events, resultEvent)
 		} else {
 			// it is a must have and it does exist, so it is compliant
 			if remediation.IsEnforce() {
 				if updatedObj != nil {
-					result.events = append(result.events, objectTmplEvalEvent{true, reasonUpdateSuccess, ""})
+					result.events = append(result.events, objectTmplEvalEvent{
+						true, reasonUpdateSuccess, policyv1.ReasonCodeUpdated, "",
+					})
 				} else {
-					result.events = append(result.events, objectTmplEvalEvent{true, reasonWantFoundExists, ""})
+					result.events = append(result.events, objectTmplEvalEvent{
+						true, reasonWantFoundExists, policyv1.ReasonCodeFound, "",
+					})
 				}
 			} else {
-				result.events = append(result.events, objectTmplEvalEvent{true, reasonWantFoundExists, ""})
+				result.events = append(result.events, objectTmplEvalEvent{
+					true, reasonWantFoundExists, policyv1.ReasonCodeFound, "",
+				})
 			}
 		}
 
@@ -2539,6 +2972,11 @@ func (r *ConfigurationPolicyReconciler) handleSingleObj(
 			Diff:               diff,
 			MatchesAfterDryRun: matchesAfterDryRun,
 		}
+
+		if violation {
+			objectProperties.ChangedBy = changedBy
+			objectProperties.MismatchedFields = mismatches
+		}
 	}
 
 	return result, objectProperties
@@ -2569,6 +3007,8 @@ func (r *ConfigurationPolicyReconciler) getMapping(
 
 		log.Error(err, "Could not map resource, do you have the CRD deployed?", "kind", gvk.Kind)
 
+		r.recordMissingAPI(policy, gvk.Group)
+
 		parent := ""
 		if len(policy.OwnerReferences) > 0 {
 			parent = policy.OwnerReferences[0].Name
@@ -2650,7 +3090,9 @@ func (r *ConfigurationPolicyReconciler) getMatchingNames(
 	switch {
 	case currentlyUsingWatch(plc):
 		var returnedItems []unstructured.Unstructured
-		returnedItems, err = r.DynamicWatcher.List(plc.ObjectIdentifier(), desiredObj.GroupVersionKind(), ns, sel)
+		returnedItems, err = r.templateWatcher(plc, objectT).List(
+			plc.ObjectIdentifier(), desiredObj.GroupVersionKind(), ns, sel,
+		)
 		resList = &unstructured.UnstructuredList{Items: returnedItems}
 	case scopedGVR.Namespaced:
 		res := r.TargetK8sDynamicClient.Resource(scopedGVR.GroupVersionResource).Namespace(ns)
@@ -2678,7 +3120,7 @@ func (r *ConfigurationPolicyReconciler) getMatchingNames(
 // enforceByCreating handles the situation where a musthave or mustonlyhave object is
 // completely missing (as opposed to existing, but not matching the desired state)
 func (r *ConfigurationPolicyReconciler) enforceByCreating(ctx context.Context, obj singleObject) (
-	completed bool, reason string, msg string, uid string, err error,
+	completed bool, reason string, reasonCode policyv1.ReasonCode, msg string, uid string, err error,
 ) {
 	log := ctrl.LoggerFrom(ctx,
 		"objName", obj.name,
@@ -2695,10 +3137,24 @@ func (r *ConfigurationPolicyReconciler) enforceByCreating(ctx context.Context, o
 
 	log.Info("Enforcing the policy by creating the object")
 
+	desiredObj := obj.desiredObj.DeepCopy()
+	stampProvenance(obj.policy, desiredObj, obj.index, obj.scopedGVR.Namespaced, true)
+
 	var createdObj *unstructured.Unstructured
 
-	if createdObj, err = r.createObject(ctx, res, obj.desiredObj); createdObj == nil {
+	createdObj, err = r.createObject(ctx, res, desiredObj)
+
+	auditedObj := desiredObj
+	if createdObj != nil {
+		auditedObj = desiredObj.DeepCopy()
+		auditedObj.SetUID(createdObj.GetUID())
+	}
+
+	r.auditObjectChange(ctx, obj.policy, obj.index, auditActionCreate, nil, auditedObj, err)
+
+	if createdObj == nil {
 		reason = "K8s creation error"
+		reasonCode = policyv1.ReasonCodeCreateFailed
 		msg = fmt.Sprintf(
 			"%v %v is missing, and cannot be created, reason: `%v`", obj.scopedGVR.Resource, idStr, err,
 		)
@@ -2729,18 +3185,22 @@ func (r *ConfigurationPolicyReconciler) enforceByCreating(ctx context.Context, o
 		)
 
 		reason = reasonWantFoundCreated
+		reasonCode = policyv1.ReasonCodeCreated
 		msg = fmt.Sprintf("%v %v was created successfully", obj.scopedGVR.Resource, idStr)
 
 		uid = string(createdObj.GetUID())
 		completed = true
 	}
 
-	return completed, reason, msg, uid, err
+	return completed, reason, reasonCode, msg, uid, err
 }
 
-// enforceByDeleting handles the case where a mustnothave object exists.
-func (r *ConfigurationPolicyReconciler) enforceByDeleting(ctx context.Context, obj singleObject) (
-	completed bool, reason string, msg string, err error,
+// enforceByDeleting handles the case where a mustnothave object exists. When the object template
+// sets waitForDeletion, the deletion is only completed once the object is removed from the cluster.
+func (r *ConfigurationPolicyReconciler) enforceByDeleting(
+	ctx context.Context, obj singleObject, objectT *policyv1.ObjectTemplate,
+) (
+	completed bool, reason string, reasonCode policyv1.ReasonCode, msg string, err error,
 ) {
 	log := ctrl.LoggerFrom(ctx,
 		"objName", obj.name,
@@ -2755,19 +3215,95 @@ func (r *ConfigurationPolicyReconciler) enforceByDeleting(ctx context.Context, o
 		res = r.TargetK8sDynamicClient.Resource(obj.scopedGVR.GroupVersionResource)
 	}
 
-	log.Info("Enforcing the policy by deleting the object")
+	current := obj.existingObj
 
-	if completed, err = deleteObject(ctx, res, obj.name, obj.namespace); !completed {
-		reason = "K8s deletion error"
-		msg = fmt.Sprintf(
-			"%v %v exists, and cannot be deleted, reason: `%v`", obj.scopedGVR.Resource, idStr, err,
-		)
-	} else {
+	// If the object is already terminating and the deletion will be waited on, don't redo the delete request
+	if !objectT.WaitForDeletion || current.GetDeletionTimestamp() == nil {
+		log.Info("Enforcing the policy by deleting the object")
+
+		completed, err = deleteObject(ctx, res, obj.name, obj.namespace, objectT.DeletionPropagation)
+		if !k8serrors.IsNotFound(err) {
+			r.auditObjectChange(ctx, obj.policy, obj.index, auditActionDelete, current, nil, err)
+		}
+
+		if !completed {
+			reason = "K8s deletion error"
+			msg = fmt.Sprintf(
+				"%v %v exists, and cannot be deleted, reason: `%v`", obj.scopedGVR.Resource, idStr, err,
+			)
+
+			return completed, reason, policyv1.ReasonCodeDeleteFailed, msg, err
+		}
+
+		if objectT.WaitForDeletion && err == nil {
+			// Don't use the cache here since the watch may not have been updated yet.
+			current, err = getObject(ctx, obj.namespace, obj.name, obj.scopedGVR, r.TargetK8sDynamicClient)
+			if err != nil {
+				reason = "K8s deletion error"
+				msg = fmt.Sprintf(
+					"%v %v was deleted, but it could not be verified that it is removed, reason: `%v`",
+					obj.scopedGVR.Resource, idStr, err,
+				)
+
+				return false, reason, policyv1.ReasonCodeDeleteFailed, msg, err
+			}
+		}
+	}
+
+	if !objectT.WaitForDeletion || current == nil {
 		reason = reasonDeleteSuccess
 		msg = fmt.Sprintf("%v %v was deleted successfully", obj.scopedGVR.Resource, idStr)
+
+		return true, reason, policyv1.ReasonCodeDeleted, msg, err
+	}
+
+	reason, reasonCode, msg = r.deletionInProgressStatus(obj, current, objectT.DeletionTimeoutWithDefault())
+
+	return false, reason, reasonCode, msg, nil
+}
+
+// deletionInProgressStatus returns the reason, reason code, and message for an object that is still terminating
+// after it was deleted to enforce a mustnothave object template. If the object has been terminating for longer than
+// the timeout, it is reported as stuck. Otherwise, the policy is scheduled to be reevaluated when the timeout is
+// reached since no watch event is expected if the object is blocked by its finalizers.
+func (r *ConfigurationPolicyReconciler) deletionInProgressStatus(
+	obj singleObject, current *unstructured.Unstructured, timeout time.Duration,
+) (reason string, reasonCode policyv1.ReasonCode, msg string) {
+	idStr := identifierStr([]string{obj.name}, obj.namespace)
+
+	var finalizersMsg string
+	if finalizers := current.GetFinalizers(); len(finalizers) > 0 {
+		finalizersMsg = ", blocked by the finalizers: " + strings.Join(finalizers, ", ")
+	}
+
+	deletedAt := time.Now().UTC()
+	if deletionTimestamp := current.GetDeletionTimestamp(); deletionTimestamp != nil {
+		deletedAt = deletionTimestamp.UTC()
+	}
+
+	deadline := deletedAt.Add(timeout)
+
+	if !time.Now().Before(deadline) {
+		msg = fmt.Sprintf(
+			"%v %v has been terminating since %s, which is longer than the deletion timeout of %s%s",
+			obj.scopedGVR.Resource, idStr, deletedAt.Format(time.RFC3339), timeout, finalizersMsg,
+		)
+
+		return reasonDeleteStuck, policyv1.ReasonCodeDeletionStuck, msg
+	}
+
+	// Object templates can be handled concurrently, so only replace the deadline if it wasn't changed since
+	for {
+		previous, loaded := r.deletionDeadlines.LoadOrStore(obj.policy.GetUID(), deadline)
+		if !loaded || !deadline.Before(previous.(time.Time)) ||
+			r.deletionDeadlines.CompareAndSwap(obj.policy.GetUID(), previous, deadline) {
+			break
+		}
 	}
 
-	return completed, reason, msg, err
+	msg = fmt.Sprintf("%v %v is being deleted%s", obj.scopedGVR.Resource, idStr, finalizersMsg)
+
+	return reasonDeleteInProgress, policyv1.ReasonCodeDeleting, msg
 }
 
 // getObject gets the object with the dynamic client and returns the object if found.
@@ -2816,13 +3352,27 @@ func (r *ConfigurationPolicyReconciler) getObjectFromCache(
 	objNamespace string,
 	objName string,
 	objGVK schema.GroupVersionKind,
+) (*unstructured.Unstructured, error) {
+	return r.getTemplateObjectFromCache(plc, nil, log, objNamespace, objName, objGVK)
+}
+
+// getTemplateObjectFromCache gets the object of the object template with the caching dependency watcher client
+// and returns the object if found. Only the metadata of the object is returned when the object template doesn't
+// need the full object.
+func (r *ConfigurationPolicyReconciler) getTemplateObjectFromCache(
+	plc *policyv1.ConfigurationPolicy,
+	objectT *policyv1.ObjectTemplate,
+	log logr.Logger,
+	objNamespace string,
+	objName string,
+	objGVK schema.GroupVersionKind,
 ) (*unstructured.Unstructured, error) {
 	objLog := log.WithValues("name", objName, "namespace", objNamespace)
 	objLog.V(2).Info("Checking if the object exists")
 
 	watcher := plc.ObjectIdentifier()
 
-	rv, err := r.DynamicWatcher.Get(watcher, objGVK, objNamespace, objName)
+	rv, err := r.templateWatcher(plc, objectT).Get(watcher, objGVK, objNamespace, objName)
 	if err != nil {
 		objLog.V(2).Error(err, "Could not retrieve object from the API server")
 
@@ -2871,11 +3421,19 @@ func deleteObject(
 	res dynamic.ResourceInterface,
 	name string,
 	namespace string,
+	propagation policyv1.DeletionPropagation,
 ) (deleted bool, err error) {
 	objLog := ctrl.LoggerFrom(ctx, "objName", name, "objNamespace", namespace)
 	objLog.V(2).Info("Entered deleteObject")
 
-	err = res.Delete(ctx, name, metav1.DeleteOptions{})
+	deleteOptions := metav1.DeleteOptions{}
+
+	if propagation != "" {
+		propagationPolicy := metav1.DeletionPropagation(propagation)
+		deleteOptions.PropagationPolicy = &propagationPolicy
+	}
+
+	err = res.Delete(ctx, name, deleteOptions)
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
 			objLog.V(2).Info("Got 'Not Found' response while deleting object")
@@ -2898,16 +3456,17 @@ func mergeSpecs(
 	templateVal, existingVal interface{}, ctype policyv1.ComplianceType, zeroValueEqualsNil bool,
 ) (interface{}, bool, error) {
 	// Copy templateVal since it will be modified in mergeSpecsHelper
-	data1, err := json.Marshal(templateVal)
-	if err != nil {
-		return nil, false, err
-	}
-
-	var j1 interface{}
+	j1, ok := copyJSONValue(templateVal)
+	if !ok {
+		data1, err := json.Marshal(templateVal)
+		if err != nil {
+			return nil, false, err
+		}
 
-	err = json.Unmarshal(data1, &j1)
-	if err != nil {
-		return nil, false, err
+		err = json.Unmarshal(data1, &j1)
+		if err != nil {
+			return nil, false, err
+		}
 	}
 
 	merged, missing := mergeSpecsHelper(j1, existingVal, ctype, zeroValueEqualsNil)
@@ -2988,6 +3547,11 @@ type countedVal struct {
 // items or nested items which are maps, the `zeroValueEqualsNil` parameter
 // determines how to handle certain "zero value" cases (see `deeplyEquivalent`).
 //
+// Existing items are identified by their canonical key, and scalar items are matched to the
+// template items with the same key with a lookup rather than comparing every pair. Items which are
+// maps are still merged with each template item in order since which template item they are merged
+// with depends on the merge.
+//
 // It returns the merged list, and indicates whether any of the nested maps were
 // considered equivalent due to "zero values".
 func mergeArrays(
@@ -2998,17 +3562,16 @@ func mergeArrays(
 	}
 
 	desiredArrCopy := append([]interface{}{}, desiredArr...)
-	idxWritten := map[int]bool{}
+	idxWritten := make([]bool, len(desiredArrCopy))
 
-	for i := range desiredArrCopy {
-		idxWritten[i] = false
-	}
-
-	// create a set with a key for each unique item in the list
+	// create a set with a key for each unique item in the list, where items are unique when they are
+	// formatted differently by fmt.Sprint
 	oldItemSet := make(map[string]*countedVal)
+	existingKeys := make([]string, len(existingArr))
 
-	for _, val2 := range existingArr {
-		key := fmt.Sprint(val2)
+	for i, val2 := range existingArr {
+		key := canonicalKey(val2, false)
+		existingKeys[i] = key
 
 		if entry, ok := oldItemSet[key]; ok {
 			entry.count++
@@ -3019,9 +3582,13 @@ func mergeArrays(
 
 	seen := map[string]bool{}
 
+	// The template items equivalent to an existing scalar are looked up by key rather than compared one
+	// by one, so that merging lists of strings doesn't compare every pair of items.
+	var desiredScalars *scalarIndex
+
 	// Iterate both arrays in order to favor the case when the object is already compliant.
-	for _, val2 := range existingArr {
-		key := fmt.Sprint(val2)
+	for i := range existingArr {
+		key := existingKeys[i]
 		if seen[key] {
 			continue
 		}
@@ -3030,6 +3597,37 @@ func mergeArrays(
 
 		count := 0
 		val2 := oldItemSet[key].value
+
+		switch val2.(type) {
+		case map[string]interface{}, []interface{}, nil:
+		default:
+			if desiredScalars == nil {
+				desiredScalars = newScalarIndex(desiredArrCopy)
+			}
+
+			// The matches are in the same order as when iterating through the template array below
+			for _, desiredArrIdx := range desiredScalars.candidates(val2, key, zeroValueEqualsNil) {
+				if idxWritten[desiredArrIdx] {
+					continue
+				}
+
+				count++
+
+				desiredArr[desiredArrIdx] = desiredArrCopy[desiredArrIdx]
+				idxWritten[desiredArrIdx] = true
+
+				if count == oldItemSet[key].count {
+					break
+				}
+			}
+
+			for range oldItemSet[key].count - count {
+				desiredArr = append(desiredArr, val2)
+			}
+
+			continue
+		}
+
 		// for each list item in the existing array, iterate through the template array and try to find a match
 		for desiredArrIdx, val1 := range desiredArrCopy {
 			if idxWritten[desiredArrIdx] {
@@ -3237,9 +3835,13 @@ func handleSingleKey(
 }
 
 type cachedEvaluationResult struct {
+	// generation is the policy generation the object was evaluated with
+	generation      int64
 	resourceVersion string
 	compliant       bool
 	msg             string
+	// reasonCode is the reason code of the failure in msg
+	reasonCode policyv1.ReasonCode
 }
 
 // checkAndUpdateResource compares the live object to the template using handleKeys, runs a server-side
@@ -3247,7 +3849,9 @@ type cachedEvaluationResult struct {
 // delete-and-create when recreate is allowed. It returns:
 //   - throwViolation: true when the object should be reported as non-compliant (including API errors).
 //   - message: a human-readable error or status detail when throwViolation is true.
+//   - failureCode: the reason code of the error in message, such as when an immutable field can't be updated.
 //   - diff: a rendered diff when recordDiff allows it and a mismatch was analyzed.
+//   - mismatches: the mismatched fields when a diff was analyzed, even when the diff is censored.
 //   - updateNeeded: true when handleKeys reported that a spec update may be required (also true on some error paths).
 //   - updatedObj: the object returned from a successful live Update or Create, otherwise nil.
 //   - matchesAfterDryRun: true when a mismatch was detected but the dry-run update produced no spec change
@@ -3259,7 +3863,9 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 ) (
 	throwViolation bool,
 	message string,
+	failureCode policyv1.ReasonCode,
 	diff string,
+	mismatches []policyv1.MismatchedField,
 	updateNeeded bool,
 	updatedObj *unstructured.Unstructured,
 	matchesAfterDryRun bool,
@@ -3267,27 +3873,38 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 ) {
 	log := ctrl.LoggerFrom(ctx, "objName", obj.name, "objNamespace", obj.namespace, "resource", obj.scopedGVR.Resource)
 
+	spanAttrs := objectSpanAttributes(obj.desiredObj.GroupVersionKind(), obj.namespace, obj.name)
+
+	ctx, span := startSpan(
+		ctx, "ConfigurationPolicy.checkAndUpdateResource", append(spanAttrs, templateIndexKey.Int(obj.index))...,
+	)
+	defer func() {
+		span.SetAttributes(objectViolationKey.Bool(throwViolation), objectUpdateNeededKey.Bool(updateNeeded))
+		span.End()
+	}()
+
 	// Time the function, and record it in a metric
 	before := time.Now().UTC()
 	defer func() {
 		duration := time.Now().UTC().Sub(before)
 		seconds := float64(duration) / float64(time.Second)
-		compareObjSecondsCounter.WithLabelValues(
-			obj.policy.Name,
-			obj.namespace,
-			fmt.Sprintf("%s.%s", obj.scopedGVR.Resource, obj.name),
-		).Add(seconds)
-		compareObjEvalCounter.WithLabelValues(
-			obj.policy.Name,
-			obj.namespace,
-			fmt.Sprintf("%s.%s", obj.scopedGVR.Resource, obj.name),
-		).Inc()
+
+		metricNamespace := obj.namespace
+		metricObject := fmt.Sprintf("%s.%s", obj.scopedGVR.Resource, obj.name)
+
+		if r.DropObjectMetricLabels {
+			metricNamespace = ""
+			metricObject = obj.scopedGVR.Resource
+		}
+
+		compareObjSecondsCounter.WithLabelValues(obj.policy.Name, metricNamespace, metricObject).Add(seconds)
+		compareObjEvalCounter.WithLabelValues(obj.policy.Name, metricNamespace, metricObject).Inc()
 	}()
 
 	if obj.existingObj == nil {
 		log.Info("Skipping update: Previous object retrieval from the API server failed")
 
-		return false, "", "", false, nil, false, false
+		return false, "", "", "", nil, false, nil, false, false
 	}
 
 	var res dynamic.ResourceInterface
@@ -3297,6 +3914,13 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 		res = r.TargetK8sDynamicClient.Resource(obj.scopedGVR.GroupVersionResource)
 	}
 
+	// The provenance labels and annotations are set by the controller, so they aren't compared with the object
+	// template. They are set again if the object is updated.
+	createdByPolicy := uidWasCreatedByPolicy(
+		obj.policy, string(obj.existingObj.GetUID()), obj.existingObj.GetAnnotations(),
+	)
+	removeProvenance(obj.existingObj)
+
 	// Use a copy since some values can be directly assigned to mergedObj in handleSingleKey.
 	existingObjectCopy := obj.existingObj.DeepCopy()
 	removeFieldsForComparison(existingObjectCopy)
@@ -3310,12 +3934,19 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 		objectT.MetadataComplianceType,
 	)
 	if errMsg != "" {
-		return true, errMsg, "", true, nil, false, false
+		return true, errMsg, policyv1.ReasonCodeUpdateFailed, "", nil, true, nil, false, false
 	}
 
+	decisionTraceFrom(ctx).objectCompared(
+		obj.index, existingObjectCopy, obj.existingObj, !updateNeeded && !missingKey && !statusMismatch,
+	)
+
 	recordDiff := objectT.RecordDiffWithDefault()
 	var needsRecreate bool
 
+	// auditedObj is the object after the update to record in the audit log when the object is enforced
+	var auditedObj *unstructured.Unstructured
+
 	isInform := remediation.IsInform()
 
 	if statusMismatch && recordDiff != policyv1.RecordDiffNone {
@@ -3325,20 +3956,21 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 		removeFieldsForComparison(mergedObjCopy)
 
 		diff = handleDiff(log, recordDiff, existingObjectCopy, mergedObjCopy, r.FullDiffs)
+		mismatches = mismatchedFields(recordDiff, existingObjectCopy, mergedObjCopy, r.FullDiffs)
 	}
 
 	if !updateNeeded && !missingKey {
 		if !statusMismatch {
 			// No spec changes needed, and no status mismatch, so it's Compliant.
-			r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, true, "")
+			r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, true, "", "")
 
-			return false, "", "", updateNeeded, updatedObj, false, false
+			return false, "", "", "", nil, updateNeeded, updatedObj, false, false
 		}
 
 		// No spec changes needed, but the status mismatches, so it's NonCompliant.
-		r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, "")
+		r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, "", "")
 
-		return true, "", diff, updateNeeded, updatedObj, false, false
+		return true, "", "", diff, mismatches, updateNeeded, updatedObj, false, false
 	}
 
 	if updateNeeded {
@@ -3379,10 +4011,12 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 
 			// If the user specifies an unknown or invalid field, it comes back as a bad request.
 			if k8serrors.IsBadRequest(err) {
-				r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, message)
+				r.setEvaluatedObject(
+					obj.policy, obj.existingObj, objectT, false, message, policyv1.ReasonCodeUpdateFailed,
+				)
 			}
 
-			return true, message, "", updateNeeded, nil, false, false
+			return true, message, policyv1.ReasonCodeUpdateFailed, "", nil, updateNeeded, nil, false, false
 		}
 
 		// If an update is invalid (i.e. modifying Pod spec fields), then return noncompliant since that
@@ -3398,6 +4032,7 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 			removeFieldsForComparison(obj.existingObj)
 
 			diff = handleDiff(log, recordDiff, existingObjectCopy, obj.existingObj, r.FullDiffs)
+			mismatches = mismatchedFields(recordDiff, existingObjectCopy, obj.existingObj, r.FullDiffs)
 
 			if !isInform {
 				// Don't include the error message in the compliance status because that can be very long. The
@@ -3406,40 +4041,46 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 					`you may set spec["object-templates"][].recreateOption to recreate the object`
 			}
 
-			r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, message)
+			r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, message, policyv1.ReasonCodeImmutable)
 
-			return true, message, diff, false, nil, false, false
+			return true, message, policyv1.ReasonCodeImmutable, diff, mismatches, false, nil, false, false
 		}
 
 		mergedObjCopy := obj.existingObj.DeepCopy()
 		removeFieldsForComparison(mergedObjCopy)
 		diff = handleDiff(log, recordDiff, existingObjectCopy, mergedObjCopy, r.FullDiffs)
+		mismatches = mismatchedFields(recordDiff, existingObjectCopy, mergedObjCopy, r.FullDiffs)
+		auditedObj = mergedObjCopy
 	} else {
 		removeFieldsForComparison(dryRunUpdatedObj)
 
 		if reflect.DeepEqual(dryRunUpdatedObj.Object, existingObjectCopy.Object) {
 			log.Info("A mismatch was detected but a dry run update didn't make any changes.")
 
+			decisionTraceFrom(ctx).objectCompared(obj.index, existingObjectCopy, dryRunUpdatedObj, !statusMismatch)
+
 			if !statusMismatch {
-				r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, true, "")
+				r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, true, "", "")
 
-				return false, "", "", false, updatedObj, true, false
+				return false, "", "", "", nil, false, updatedObj, true, false
 			}
 
 			// No spec changes needed, but the status is incorrect, so it's NonCompliant.
-			r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, "")
+			r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, "", "")
 
-			return true, "", diff, updateNeeded, updatedObj, false, false
+			return true, "", "", diff, mismatches, updateNeeded, updatedObj, false, false
 		}
 
 		diff = handleDiff(log, recordDiff, existingObjectCopy, dryRunUpdatedObj, r.FullDiffs)
+		mismatches = mismatchedFields(recordDiff, existingObjectCopy, dryRunUpdatedObj, r.FullDiffs)
+		auditedObj = dryRunUpdatedObj
 	}
 
 	// The object would have been updated, so if it's inform, return as noncompliant.
 	if isInform {
-		r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, "")
+		r.setEvaluatedObject(obj.policy, obj.existingObj, objectT, false, "", "")
 
-		return true, "", diff, false, nil, false, false
+		return true, "", "", diff, mismatches, false, nil, false, false
 	}
 
 	// If it's not inform (i.e. enforce), update the object
@@ -3459,13 +4100,20 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 		if err != nil && !k8serrors.IsNotFound(err) {
 			message = fmt.Sprintf(`%s failed to delete when recreating with the error %v`, getMsgPrefix(&obj), err)
 
-			return true, message, "", updateNeeded, nil, false, false
+			r.auditObjectChange(
+				ctx, obj.policy, obj.index, auditActionRecreate, existingObjectCopy, auditedObj, errors.New(message),
+			)
+
+			return true, message, policyv1.ReasonCodeUpdateFailed, "", nil, updateNeeded, nil, false, false
 		}
 
 		attempts := 0
 
+		recreatedObj := obj.desiredObj.DeepCopy()
+		stampProvenance(obj.policy, recreatedObj, obj.index, obj.scopedGVR.Namespaced, createdByPolicy)
+
 		for {
-			updatedObj, err = res.Create(ctx, obj.desiredObj, metav1.CreateOptions{})
+			updatedObj, err = res.Create(ctx, recreatedObj, metav1.CreateOptions{})
 			if !k8serrors.IsAlreadyExists(err) {
 				// If there is no error or the error is unexpected, break for the error handling below
 				break
@@ -3477,7 +4125,10 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 				message = getMsgPrefix(&obj) + " timed out waiting for the object to delete during recreate, " +
 					"will retry on the next policy evaluation"
 
-				return true, message, "", updateNeeded, nil, false, false
+				r.auditObjectChange(ctx, obj.policy, obj.index, auditActionRecreate,
+					existingObjectCopy, auditedObj, errors.New(message))
+
+				return true, message, policyv1.ReasonCodeUpdateFailed, "", nil, updateNeeded, nil, false, false
 			}
 
 			time.Sleep(time.Second)
@@ -3485,6 +4136,8 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 	} else {
 		log.Info("Updating the object based on the template definition")
 
+		stampProvenance(obj.policy, obj.existingObj, obj.index, obj.scopedGVR.Namespaced, createdByPolicy)
+
 		updatedObj, err = res.Update(ctx, obj.existingObj, metav1.UpdateOptions{
 			FieldValidation: metav1.FieldValidationStrict,
 		})
@@ -3502,19 +4155,23 @@ func (r *ConfigurationPolicyReconciler) checkAndUpdateResource(
 			}
 		}
 
+		r.auditObjectChange(ctx, obj.policy, obj.index, auditAction(action), existingObjectCopy, auditedObj, err)
+
 		message := getUpdateErrorMsg(err, obj.existingObj.GetKind(), obj.name)
 		if message == "" {
 			message = fmt.Sprintf("%s failed to %s with the error `%v`", getMsgPrefix(&obj), action, err)
 		}
 
-		return true, message, diff, updateNeeded, nil, false, false
+		return true, message, policyv1.ReasonCodeUpdateFailed, diff, mismatches, updateNeeded, nil, false, false
 	}
 
+	r.auditObjectChange(ctx, obj.policy, obj.index, auditAction(action), existingObjectCopy, auditedObj, nil)
+
 	if !statusMismatch {
-		r.setEvaluatedObject(obj.policy, updatedObj, objectT, true, message)
+		r.setEvaluatedObject(obj.policy, updatedObj, objectT, true, message, "")
 	}
 
-	return throwViolation, "", diff, updateNeeded, updatedObj, false, action == "recreate"
+	return throwViolation, "", "", diff, mismatches, updateNeeded, updatedObj, false, action == "recreate"
 }
 
 func getMsgPrefix(obj *singleObject) string {
@@ -3567,6 +4224,38 @@ func handleDiff(
 	return ""
 }
 
+// mismatchedFields returns the fields that differ between the existing object and the merged object, which is the
+// existing object with the object template merged into it. The fields don't include the values, so unlike the diff,
+// they are returned when recordDiff is Censored or Log. Nothing is returned when recordDiff is set to None. A maximum
+// of 50 fields are returned unless fullDiffs is set, like the diff lines. Lists are compared as a whole, so a list
+// with added or changed items is returned once.
+func mismatchedFields(
+	recordDiff policyv1.RecordDiff,
+	existingObject *unstructured.Unstructured,
+	mergedObject *unstructured.Unstructured,
+	fullDiffs bool,
+) []policyv1.MismatchedField {
+	if recordDiff == policyv1.RecordDiffNone {
+		return nil
+	}
+
+	mismatches := mismatchedFieldPaths(nil, existingObject.Object, mergedObject.Object)
+	if len(mismatches) > 50 && !fullDiffs {
+		mismatches = mismatches[:50]
+	}
+
+	fields := make([]policyv1.MismatchedField, 0, len(mismatches))
+
+	for _, mismatch := range mismatches {
+		fields = append(fields, policyv1.MismatchedField{
+			Path: formatFieldPath(mismatch.path),
+			Kind: policyv1.MismatchKind(mismatch.outcome),
+		})
+	}
+
+	return fields
+}
+
 // handleKeys goes through all of the fields in the desired object and checks if the existing object
 // matches. When a field is a map or slice, the value in the existing object will be updated with
 // the result of merging its current value with the desired value.
@@ -3704,6 +4393,7 @@ func (r *ConfigurationPolicyReconciler) setEvaluatedObject(
 	objectT *policyv1.ObjectTemplate,
 	compliant bool,
 	msg string,
+	reasonCode policyv1.ReasonCode,
 ) {
 	policyMap := &sync.Map{}
 
@@ -3715,9 +4405,11 @@ func (r *ConfigurationPolicyReconciler) setEvaluatedObject(
 	policyMap.Store(
 		getEvalObjKey(currentObject.GetUID(), objectT),
 		cachedEvaluationResult{
+			generation:      policy.GetGeneration(),
 			resourceVersion: currentObject.GetResourceVersion(),
 			compliant:       compliant,
 			msg:             msg,
+			reasonCode:      reasonCode,
 		},
 	)
 }
@@ -3728,29 +4420,30 @@ func (r *ConfigurationPolicyReconciler) alreadyEvaluated(
 	policy *policyv1.ConfigurationPolicy,
 	currentObject *unstructured.Unstructured,
 	objectT *policyv1.ObjectTemplate,
-) (evaluated bool, compliant bool, msg string) {
+) (evaluated bool, compliant bool, msg string, reasonCode policyv1.ReasonCode) {
 	if policy == nil || currentObject == nil {
-		return false, false, ""
+		return false, false, "", ""
 	}
 
 	loadedPolicyMap, loaded := r.processedPolicyCache.Load(policy.GetUID())
 	if !loaded {
-		return false, false, ""
+		return false, false, "", ""
 	}
 
 	policyMap := loadedPolicyMap.(*sync.Map)
 
 	result, loaded := policyMap.Load(getEvalObjKey(currentObject.GetUID(), objectT))
 	if !loaded {
-		return false, false, ""
+		return false, false, "", ""
 	}
 
 	resultTyped := result.(cachedEvaluationResult)
 
 	alreadyEvaluated := resultTyped.resourceVersion != "" &&
-		resultTyped.resourceVersion == currentObject.GetResourceVersion()
+		resultTyped.resourceVersion == currentObject.GetResourceVersion() &&
+		resultTyped.generation == policy.GetGeneration()
 
-	return alreadyEvaluated, resultTyped.compliant, resultTyped.msg
+	return alreadyEvaluated, resultTyped.compliant, resultTyped.msg, resultTyped.reasonCode
 }
 
 // getEvalObjKey returns a key for the cached policy map based on the
@@ -3785,7 +4478,8 @@ func getUpdateErrorMsg(err error, kind string, name string) string {
 
 // addForUpdate calculates the compliance status of a configurationPolicy and updates the status field. The sendEvent
 // argument determines if a status update event should be sent on the parent policy and configuration policy.
-// Regardless of the sendEvent parameter, events will be sent if the compliance or policy generation changes.
+// Regardless of the sendEvent parameter, events will be sent if the compliance or policy generation changes. During
+// a policy evaluation, the status is only written to the API server at the end of the evaluation.
 func (r *ConfigurationPolicyReconciler) addForUpdate(
 	ctx context.Context,
 	policy *policyv1.ConfigurationPolicy,
@@ -3819,6 +4513,11 @@ func (r *ConfigurationPolicyReconciler) addForUpdate(
 		sendEvent = true
 	}
 
+	recordComplianceTransition(
+		"ConfigurationPolicy", policy, previousComplianceState, policy.Status.ComplianceState,
+		policy.Status.History, time.Now(),
+	)
+
 	// Always try to send an event when the generation changes
 	if policy.Status.LastEvaluatedGeneration != policy.Generation {
 		sendEvent = true
@@ -3826,35 +4525,63 @@ func (r *ConfigurationPolicyReconciler) addForUpdate(
 
 	policy.Status.LastEvaluated = time.Now().UTC().Format(time.RFC3339)
 	policy.Status.LastEvaluatedGeneration = policy.Generation
+	policy.Status.NextEvaluation = r.getNextEvaluationStatus(policy)
 
-	err := r.updatePolicyStatus(ctx, policy, sendEvent)
-	if err != nil {
-		log := ctrl.LoggerFrom(ctx)
+	if r.Shards != nil {
+		policy.Status.Shard = r.Shards.Identity()
+	} else {
+		policy.Status.Shard = ""
+	}
 
-		if k8serrors.IsConflict(err) {
-			log.Error(err, "Tried to re-update status before previous update could be applied, retrying next loop")
+	if err := r.recordPolicyStatus(ctx, policy, sendEvent); err != nil {
+		// The status isn't written so that everything is retried next loop
+		r.markStatusUpdateFailed(policy)
+		r.statusUpdateFailed(ctx, policy, err)
 
-			return
-		}
+		return
+	}
 
-		log.Error(err, "Could not update status, will retry")
+	// During a policy evaluation, the status is written once at the end of the evaluation
+	if r.deferStatusWrite(policy) {
+		return
+	}
 
-		parent := ""
-		if len(policy.OwnerReferences) > 0 {
-			parent = policy.OwnerReferences[0].Name
-		}
+	if err := r.writePolicyStatus(ctx, policy); err != nil {
+		r.statusUpdateFailed(ctx, policy, err)
+
+		return
+	}
+
+	if previousComplianceState != policy.Status.ComplianceState {
+		r.notifyCompliance(ctx, policy)
+	}
+}
+
+// statusUpdateFailed logs the failure to update the status of the policy and increments the error metric.
+func (r *ConfigurationPolicyReconciler) statusUpdateFailed(
+	ctx context.Context, policy *policyv1.ConfigurationPolicy, err error,
+) {
+	log := ctrl.LoggerFrom(ctx)
 
-		policySystemErrorsCounter.WithLabelValues(parent, policy.GetName(), "status-update-failed").Add(1)
+	if k8serrors.IsConflict(err) {
+		log.Error(err, "Tried to re-update status before previous update could be applied, retrying next loop")
 
 		return
 	}
 
-	r.lastEvaluatedCache.Store(policy.UID, policy.GetResourceVersion())
+	log.Error(err, "Could not update status, will retry")
+
+	parent := ""
+	if len(policy.OwnerReferences) > 0 {
+		parent = policy.OwnerReferences[0].Name
+	}
+
+	policySystemErrorsCounter.WithLabelValues(parent, policy.GetName(), "status-update-failed").Add(1)
 }
 
-// updatePolicyStatus updates the status of the configurationPolicy if new conditions are added and generates an event
-// on the parent policy and configuration policy with the compliance decision if the sendEvent argument is true.
-func (r *ConfigurationPolicyReconciler) updatePolicyStatus(
+// recordPolicyStatus adds the compliance message to the status history and generates an event on the parent
+// policy and configuration policy with the compliance decision if the sendEvent argument is true.
+func (r *ConfigurationPolicyReconciler) recordPolicyStatus(
 	ctx context.Context, policy *policyv1.ConfigurationPolicy, sendEvent bool,
 ) error {
 	log := ctrl.LoggerFrom(ctx)
@@ -3879,10 +4606,6 @@ func (r *ConfigurationPolicyReconciler) updatePolicyStatus(
 		}
 	}
 
-	log.V(1).Info(
-		"Updating configurationPolicy status", "status", policy.Status.ComplianceState, "policy", policy.GetName(),
-	)
-
 	var latestEvent policyv1.HistoryEvent
 
 	if len(policy.Status.History) > 0 {
@@ -3904,43 +4627,6 @@ func (r *ConfigurationPolicyReconciler) updatePolicyStatus(
 		}
 	}
 
-	evaluatedUID := policy.UID
-	updatedStatus := policy.Status
-
-	maxRetries := 3
-	for i := 1; i <= maxRetries; i++ {
-		err := r.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, policy)
-		if err != nil {
-			log.Info(fmt.Sprintf("Failed to refresh policy; using previously fetched version: %s", err))
-		} else {
-			policy.Status = updatedStatus
-
-			// If the UID has changed, then the policy has been deleted and created again. Do not update the status,
-			// because it was calculated based on a previous version. If sendEvent is true, that event might be useful
-			// and it can be emitted. By leaving the status blank, the policy will be reevaluated and send a new event.
-			if evaluatedUID != policy.UID {
-				log.Info("The ConfigurationPolicy was recreated after it was evaluated. Skipping the status update.")
-
-				// Reset the original UID so that if there are more status updates (i.e. batches), the status on the
-				// API server is never updated.
-				policy.UID = evaluatedUID
-
-				break
-			}
-		}
-
-		err = r.Status().Update(ctx, policy)
-		if err != nil {
-			if i == maxRetries {
-				return err
-			}
-
-			log.Info(fmt.Sprintf("Failed to update policy status. Retrying (attempt %d/%d): %s", i, maxRetries, err))
-		} else {
-			break
-		}
-	}
-
 	if sendEvent {
 		log.V(1).Info("Sending policy status update event")
 
@@ -3976,6 +4662,65 @@ func (r *ConfigurationPolicyReconciler) updatePolicyStatus(
 	return nil
 }
 
+// writePolicyStatus updates the status of the configurationPolicy on the API server, retrying on failures.
+func (r *ConfigurationPolicyReconciler) writePolicyStatus(
+	ctx context.Context, policy *policyv1.ConfigurationPolicy,
+) (err error) {
+	ctx, span := startSpan(ctx, "ConfigurationPolicy.writePolicyStatus")
+	defer func() { endSpan(span, err) }()
+
+	log := ctrl.LoggerFrom(ctx)
+
+	log.V(1).Info(
+		"Updating configurationPolicy status", "status", policy.Status.ComplianceState, "policy", policy.GetName(),
+	)
+
+	evaluatedUID := policy.UID
+	updatedStatus := policy.Status
+
+	maxRetries := 3
+	for i := 1; i <= maxRetries; i++ {
+		err := r.Get(ctx, types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}, policy)
+		if err != nil {
+			log.Info(fmt.Sprintf("Failed to refresh policy; using previously fetched version: %s", err))
+		} else {
+			policy.Status = updatedStatus
+
+			// If the UID has changed, then the policy has been deleted and created again. Do not update the status,
+			// because it was calculated based on a previous version. If sendEvent is true, that event might be useful
+			// and it can be emitted. By leaving the status blank, the policy will be reevaluated and send a new event.
+			if evaluatedUID != policy.UID {
+				log.Info("The ConfigurationPolicy was recreated after it was evaluated. Skipping the status update.")
+
+				// Reset the original UID so that if there are more status updates, the status on the API server is
+				// never updated.
+				policy.UID = evaluatedUID
+
+				return nil
+			}
+		}
+
+		err = r.Status().Update(ctx, policy)
+		if err == nil {
+			policyStatusWritesCounter.WithLabelValues(policy.Name, "written").Inc()
+			r.lastEvaluatedCache.Store(policy.UID, policy.GetResourceVersion())
+			r.reportPolicy(ctx, policy)
+
+			return nil
+		}
+
+		if i == maxRetries {
+			policyStatusWritesCounter.WithLabelValues(policy.Name, "failed").Inc()
+
+			return err
+		}
+
+		log.Info(fmt.Sprintf("Failed to update policy status. Retrying (attempt %d/%d): %s", i, maxRetries, err))
+	}
+
+	return nil
+}
+
 // recordInfoEvent adds an informational event to the queue to be emitted (it does not emit it
 // synchronously). This event is not used for compliance, but may be used by other tools.
 func (r *ConfigurationPolicyReconciler) recordInfoEvent(plc *policyv1.ConfigurationPolicy, violation bool) {
@@ -4,6 +4,7 @@
 package controllers
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"sync"
@@ -16,8 +17,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/uuid"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -609,13 +612,14 @@ func TestAddRelatedObject(t *testing.T) {
 	name := "foo"
 	reason := "reason"
 	relatedList := addRelatedObjects(
-		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, nil,
+		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, policyv1.ReasonCodeFound, nil,
 	)
 	related := relatedList[0]
 
 	// get the related object and validate what we added is in the status
 	assert.Equal(t, string(policyv1.Compliant), related.Compliant)
 	assert.Equal(t, "reason", related.Reason)
+	assert.Equal(t, policyv1.ReasonCodeFound, related.ReasonCode)
 	assert.Equal(t, scopedGVR.GroupVersion().String(), related.Object.APIVersion)
 	assert.Equal(t, "ConfigurationPolicy", related.Object.Kind)
 	assert.Equal(t, name, related.Object.Metadata.Name)
@@ -624,18 +628,25 @@ func TestAddRelatedObject(t *testing.T) {
 	// add the same object and make sure the existing one is overwritten
 	reason = "new"
 	compliant = false
-	relatedList = addRelatedObjects(compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, nil)
+	relatedList = addRelatedObjects(
+		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, policyv1.ReasonCodeMismatch,
+		nil,
+	)
 	related = relatedList[0]
 
 	assert.Len(t, relatedList, 1)
 	assert.Equal(t, string(policyv1.NonCompliant), related.Compliant)
 	assert.Equal(t, "new", related.Reason)
+	assert.Equal(t, policyv1.ReasonCodeMismatch, related.ReasonCode)
 
 	// add a new related object and make sure the entry is appended
 	name = "bar"
 	relatedList = append(
 		relatedList,
-		addRelatedObjects(compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, nil)...,
+		addRelatedObjects(
+			compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, policyv1.ReasonCodeMismatch,
+			nil,
+		)...,
 	)
 
 	assert.Len(t, relatedList, 2)
@@ -673,12 +684,14 @@ func TestUpdatedRelatedObjects(t *testing.T) {
 		Namespaced:           true,
 	}
 	name := "foo"
-	relatedList := addRelatedObjects(true, scopedGVR, "ConfigurationPolicy", "default", []string{name}, "reason", nil)
+	relatedList := addRelatedObjects(
+		true, scopedGVR, "ConfigurationPolicy", "default", []string{name}, "reason", policyv1.ReasonCodeFound, nil,
+	)
 
 	// add the same object but after sorting it should be first
 	name = "bar"
 	relatedList = append(relatedList, addRelatedObjects(
-		true, scopedGVR, "ConfigurationPolicy", "default", []string{name}, "reason", nil)...,
+		true, scopedGVR, "ConfigurationPolicy", "default", []string{name}, "reason", policyv1.ReasonCodeFound, nil)...,
 	)
 
 	r.updatedRelatedObjects(policy, relatedList)
@@ -686,7 +699,7 @@ func TestUpdatedRelatedObjects(t *testing.T) {
 
 	// append another object named bar but also with namespace bar
 	relatedList = append(relatedList, addRelatedObjects(
-		true, scopedGVR, "ConfigurationPolicy", "bar", []string{name}, "reason", nil)...,
+		true, scopedGVR, "ConfigurationPolicy", "bar", []string{name}, "reason", policyv1.ReasonCodeFound, nil)...,
 	)
 
 	r.updatedRelatedObjects(policy, relatedList)
@@ -695,10 +708,12 @@ func TestUpdatedRelatedObjects(t *testing.T) {
 	// clear related objects and test sorting with no namespace
 	scopedGVR.Namespaced = false
 	name = "foo"
-	relatedList = addRelatedObjects(true, scopedGVR, "ConfigurationPolicy", "", []string{name}, "reason", nil)
+	relatedList = addRelatedObjects(
+		true, scopedGVR, "ConfigurationPolicy", "", []string{name}, "reason", policyv1.ReasonCodeFound, nil,
+	)
 	name = "bar"
 	relatedList = append(relatedList, addRelatedObjects(
-		true, scopedGVR, "ConfigurationPolicy", "", []string{name}, "reason", nil)...,
+		true, scopedGVR, "ConfigurationPolicy", "", []string{name}, "reason", policyv1.ReasonCodeFound, nil)...,
 	)
 
 	r.updatedRelatedObjects(policy, relatedList)
@@ -729,7 +744,8 @@ func TestAddRelatedObjectProperties(t *testing.T) {
 	}
 
 	relatedList := addRelatedObjects(
-		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, creationInfo,
+		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, policyv1.ReasonCodeFound,
+		creationInfo,
 	)
 	related := relatedList[0]
 
@@ -758,7 +774,8 @@ func TestAddRelatedObjectProperties(t *testing.T) {
 	}
 
 	relatedList = addRelatedObjects(
-		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, newCreationInfo)
+		compliant, scopedGVR, "ConfigurationPolicy", namespace, []string{name}, reason, policyv1.ReasonCodeMismatch,
+		newCreationInfo)
 	related = relatedList[0]
 
 	assert.Len(t, relatedList, 1)
@@ -781,6 +798,7 @@ func TestCreateStatus(t *testing.T) {
 		namespaceToEvent  map[string]*objectTmplEvalResultWithEvent
 		expectedCompliant bool
 		expectedReason    string
+		expectedCode      policyv1.ReasonCode
 		expectedMsg       string
 	}{
 		{
@@ -792,13 +810,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 			},
 			true,
 			"K8s `must have` object already exists",
+			policyv1.ReasonCodeFound,
 			"configmaps [buzz] found as specified in namespace toy-story",
 		},
 		{
@@ -810,13 +830,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"movies"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 			},
 			true,
 			"K8s `must have` object already exists",
+			policyv1.ReasonCodeFound,
 			"namespaces [movies] found as specified",
 		},
 		{
@@ -828,8 +850,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 				"toy-story3": {
@@ -837,13 +860,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 			},
 			true,
 			"K8s `must have` object already exists",
+			policyv1.ReasonCodeFound,
 			"configmaps [buzz] found as specified in namespaces: toy-story, toy-story3",
 		},
 		{
@@ -855,8 +880,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 				"toy-story4": {
@@ -864,13 +890,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"bo-peep"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 			},
 			true,
 			"K8s `must have` object already exists",
+			policyv1.ReasonCodeFound,
 			"secrets [buzz] found as specified in namespace toy-story; " +
 				"secrets [bo-peep] found as specified in namespace toy-story4",
 		},
@@ -883,13 +911,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundCreated,
+						compliant:  true,
+						reason:     reasonWantFoundCreated,
+						reasonCode: policyv1.ReasonCodeCreated,
 					},
 				},
 			},
 			true,
 			"K8s creation success",
+			policyv1.ReasonCodeCreated,
 			"configmaps [buzz] was created successfully in namespace toy-story",
 		},
 		{
@@ -901,8 +931,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundCreated,
+						compliant:  true,
+						reason:     reasonWantFoundCreated,
+						reasonCode: policyv1.ReasonCodeCreated,
 					},
 				},
 				"toy-story4": {
@@ -910,13 +941,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 			},
 			true,
 			"K8s `must have` object already exists; K8s creation success",
+			policyv1.ReasonCodeFound,
 			"configmaps [buzz] found as specified in namespace toy-story4; configmaps [buzz] was created " +
 				"successfully in namespace toy-story",
 		},
@@ -929,8 +962,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantFoundExists,
+						compliant:  true,
+						reason:     reasonWantFoundExists,
+						reasonCode: policyv1.ReasonCodeFound,
 					},
 				},
 				"toy-story4": {
@@ -938,13 +972,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    reasonWantFoundDNE,
+						compliant:  false,
+						reason:     reasonWantFoundDNE,
+						reasonCode: policyv1.ReasonCodeMissing,
 					},
 				},
 			},
 			false,
 			"K8s does not have a `must have` object",
+			policyv1.ReasonCodeMissing,
 			"configmaps [buzz] not found in namespace toy-story4",
 		},
 		{
@@ -956,13 +992,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    reasonWantFoundNoMatch,
+						compliant:  false,
+						reason:     reasonWantFoundNoMatch,
+						reasonCode: policyv1.ReasonCodeMismatch,
 					},
 				},
 			},
 			false,
 			"K8s does not have a `must have` object",
+			policyv1.ReasonCodeMismatch,
 			"configmaps [buzz] found but not as specified in namespace toy-story",
 		},
 		{
@@ -974,13 +1012,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    reasonWantNotFoundExists,
+						compliant:  false,
+						reason:     reasonWantNotFoundExists,
+						reasonCode: policyv1.ReasonCodeFoundNotWanted,
 					},
 				},
 			},
 			false,
 			"K8s has a `must not have` object",
+			policyv1.ReasonCodeFoundNotWanted,
 			"configmaps [buzz] found in namespace toy-story",
 		},
 		{
@@ -992,13 +1032,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonWantNotFoundDNE,
+						compliant:  true,
+						reason:     reasonWantNotFoundDNE,
+						reasonCode: policyv1.ReasonCodeNotFound,
 					},
 				},
 			},
 			true,
 			"K8s `must not have` object already missing",
+			policyv1.ReasonCodeNotFound,
 			"configmaps [buzz] missing as expected in namespace toy-story",
 		},
 		{
@@ -1010,13 +1052,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: true,
-						reason:    reasonDeleteSuccess,
+						compliant:  true,
+						reason:     reasonDeleteSuccess,
+						reasonCode: policyv1.ReasonCodeDeleted,
 					},
 				},
 			},
 			true,
 			"K8s deletion success",
+			policyv1.ReasonCodeDeleted,
 			"configmaps [buzz] was deleted successfully in namespace toy-story",
 		},
 		{
@@ -1028,8 +1072,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{""},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    "K8s missing namespace",
+						compliant:  false,
+						reason:     "K8s missing namespace",
+						reasonCode: policyv1.ReasonCodeInvalidSpec,
 						message: "namespaced object of kind ConfigMap has no namespace specified " +
 							"from the policy namespaceSelector nor the object metadata",
 					},
@@ -1037,6 +1082,7 @@ func TestCreateStatus(t *testing.T) {
 			},
 			false,
 			"K8s missing namespace",
+			policyv1.ReasonCodeInvalidSpec,
 			"namespaced object of kind ConfigMap has no namespace specified from the policy namespaceSelector " +
 				"nor the object metadata",
 		},
@@ -1049,8 +1095,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"rex", "woody"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    reasonWantFoundNoMatch,
+						compliant:  false,
+						reason:     reasonWantFoundNoMatch,
+						reasonCode: policyv1.ReasonCodeMismatch,
 					},
 				},
 				"toy-story2": {
@@ -1058,13 +1105,15 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz", "potato"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    reasonWantFoundNoMatch,
+						compliant:  false,
+						reason:     reasonWantFoundNoMatch,
+						reasonCode: policyv1.ReasonCodeMismatch,
 					},
 				},
 			},
 			false,
 			"K8s does not have a `must have` object",
+			policyv1.ReasonCodeMismatch,
 			"configmaps [rex, woody] found but not as specified in namespace toy-story1; " +
 				"configmaps [buzz, potato] found but not as specified in namespace toy-story2",
 		},
@@ -1077,8 +1126,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    "K8s missing namespace",
+						compliant:  false,
+						reason:     "K8s missing namespace",
+						reasonCode: policyv1.ReasonCodeInvalidSpec,
 						message: "namespaced object buzz of kind ConfigMap has no namespace specified " +
 							"from the policy namespaceSelector nor the object metadata",
 					},
@@ -1088,8 +1138,9 @@ func TestCreateStatus(t *testing.T) {
 						objectNames: []string{"buzz"},
 					},
 					event: objectTmplEvalEvent{
-						compliant: false,
-						reason:    "K8s decode object definition error",
+						compliant:  false,
+						reason:     "K8s decode object definition error",
+						reasonCode: policyv1.ReasonCodeInvalidSpec,
 						message: "Decoding error, please check your policy file! Aborting handling the object " +
 							"template at index [0] in policy `create-configmaps` with error = `some error`",
 					},
@@ -1097,6 +1148,7 @@ func TestCreateStatus(t *testing.T) {
 			},
 			false,
 			"K8s decode object definition error; K8s missing namespace",
+			policyv1.ReasonCodeInvalidSpec,
 			"Decoding error, please check your policy file! Aborting handling the object template at index [0] in " +
 				"policy `create-configmaps` with error = `some error`; namespaced object buzz of kind ConfigMap has " +
 				"no namespace specified from the policy namespaceSelector nor the object metadata",
@@ -1105,15 +1157,72 @@ func TestCreateStatus(t *testing.T) {
 
 	for _, test := range testcases {
 		t.Run(test.testName, func(t *testing.T) {
-			compliant, reason, msg := createStatus(test.resourceName, test.namespaceToEvent)
+			compliant, reason, msg, reasonCode := createStatus(test.resourceName, test.namespaceToEvent)
 
 			assert.Equal(t, test.expectedCompliant, compliant)
 			assert.Equal(t, test.expectedReason, reason)
+			assert.Equal(t, test.expectedCode, reasonCode)
 			assert.Equal(t, test.expectedMsg, msg)
 		})
 	}
 }
 
+func TestMismatchedFields(t *testing.T) {
+	t.Parallel()
+
+	existing := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]any{"name": "creds", "namespace": "default"},
+		"data":       map[string]any{"password": "b2xk", "username": "YWRtaW4="},
+	}}
+	merged := existing.DeepCopy()
+	merged.Object["data"] = map[string]any{"password": "bmV3", "token": "dG9rZW4="}
+
+	expected := []policyv1.MismatchedField{
+		{Path: "data.password", Kind: policyv1.MismatchDifferent},
+		{Path: "data.token", Kind: policyv1.MismatchMissing},
+		{Path: "data.username", Kind: policyv1.MismatchExtra},
+	}
+
+	// The paths are recorded even when the diff is censored since they don't include the values
+	assert.Equal(t, expected, mismatchedFields(policyv1.RecordDiffCensored, existing, merged, false))
+	assert.Equal(t, expected, mismatchedFields(policyv1.RecordDiffInStatus, existing, merged, false))
+	assert.Nil(t, mismatchedFields(policyv1.RecordDiffNone, existing, merged, false))
+
+	// A list with an appended item is reported once and a reordered list isn't reported
+	existing.Object["spec"] = map[string]any{
+		"ports": []any{map[string]any{"port": int64(80)}, map[string]any{"port": int64(443)}},
+		"hosts": []any{"a", "b", "c"},
+	}
+	merged = existing.DeepCopy()
+	merged.Object["spec"] = map[string]any{
+		"ports": []any{
+			map[string]any{"port": int64(80)}, map[string]any{"port": int64(443)}, map[string]any{"port": int64(8080)},
+		},
+		"hosts": []any{"c", "a", "b"},
+	}
+
+	assert.Equal(t, []policyv1.MismatchedField{
+		{Path: "spec.ports", Kind: policyv1.MismatchDifferent},
+	}, mismatchedFields(policyv1.RecordDiffInStatus, existing, merged, false))
+
+	delete(existing.Object, "spec")
+	delete(merged.Object, "spec")
+
+	manyKeys := map[string]any{}
+	for i := range 60 {
+		manyKeys[fmt.Sprintf("key%02d", i)] = "value"
+	}
+
+	merged.Object["data"] = manyKeys
+
+	truncated := mismatchedFields(policyv1.RecordDiffInStatus, existing, merged, false)
+	assert.Len(t, truncated, 50)
+	assert.Equal(t, policyv1.MismatchedField{Path: "data.key00", Kind: policyv1.MismatchMissing}, truncated[0])
+	assert.Len(t, mismatchedFields(policyv1.RecordDiffInStatus, existing, merged, true), 62)
+}
+
 func TestHandleKeysServiceAccount(t *testing.T) {
 	t.Parallel()
 
@@ -1438,6 +1547,32 @@ func TestShouldEvaluatePolicy(t *testing.T) {
 			[]string{},
 			lastEvaluatedTwelveSecsAgo,
 		},
+		{
+			"Cron evaluation interval that hasn't past yet when compliant",
+			futureResourceVersion,
+			twelveSecsAgo,
+			2,
+			policyv1.EvaluationInterval{Compliant: "cron:0 0 1 1 *"},
+			policyv1.Compliant,
+			false,
+			true,
+			nil,
+			[]string{},
+			lastEvaluatedTwelveSecsAgo,
+		},
+		{
+			"Cron evaluation interval that has past when noncompliant",
+			futureResourceVersion,
+			twelveHoursAgo,
+			2,
+			policyv1.EvaluationInterval{NonCompliant: "cron:*/5 * * * *"},
+			policyv1.NonCompliant,
+			true,
+			false,
+			nil,
+			[]string{},
+			lastEvaluatedTwelveHoursAgo,
+		},
 		{
 			"Deletion timestamp is non nil",
 			futureResourceVersion,
@@ -1613,3 +1748,103 @@ func TestShouldHandleSingleKeyFalse(t *testing.T) {
 		assert.False(t, skip)
 	}
 }
+
+func TestEnforceByDeletingWaitForDeletion(t *testing.T) {
+	t.Parallel()
+
+	configMapGVR := depclient.ScopedGVR{
+		GroupVersionResource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		Namespaced:           true,
+	}
+
+	newConfigMap := func(deletedAgo time.Duration) *unstructured.Unstructured {
+		configMap := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":      "unwanted",
+				"namespace": "default",
+			},
+		}}
+
+		if deletedAgo != 0 {
+			deletionTimestamp := metav1.NewTime(time.Now().Add(-deletedAgo))
+			configMap.SetDeletionTimestamp(&deletionTimestamp)
+			configMap.SetFinalizers([]string{"example.com/cleanup"})
+		}
+
+		return configMap
+	}
+
+	tests := map[string]struct {
+		existing          *unstructured.Unstructured
+		objectT           *policyv1.ObjectTemplate
+		expectedCompleted bool
+		expectedReason    string
+		expectedCode      policyv1.ReasonCode
+		expectedMsg       string
+		expectedDeadline  bool
+	}{
+		"deleted without waiting": {
+			existing:          newConfigMap(0),
+			objectT:           &policyv1.ObjectTemplate{DeletionPropagation: policyv1.DeletionPropagationForeground},
+			expectedCompleted: true,
+			expectedReason:    reasonDeleteSuccess,
+			expectedCode:      policyv1.ReasonCodeDeleted,
+			expectedMsg:       "configmaps [unwanted] in namespace default was deleted successfully",
+		},
+		"deleted and removed": {
+			existing:          newConfigMap(0),
+			objectT:           &policyv1.ObjectTemplate{WaitForDeletion: true},
+			expectedCompleted: true,
+			expectedReason:    reasonDeleteSuccess,
+			expectedCode:      policyv1.ReasonCodeDeleted,
+			expectedMsg:       "configmaps [unwanted] in namespace default was deleted successfully",
+		},
+		"terminating": {
+			existing:       newConfigMap(time.Minute),
+			objectT:        &policyv1.ObjectTemplate{WaitForDeletion: true},
+			expectedReason: reasonDeleteInProgress,
+			expectedCode:   policyv1.ReasonCodeDeleting,
+			expectedMsg: "configmaps [unwanted] in namespace default is being deleted, blocked by the " +
+				"finalizers: example.com/cleanup",
+			expectedDeadline: true,
+		},
+		"stuck terminating": {
+			existing:       newConfigMap(time.Hour),
+			objectT:        &policyv1.ObjectTemplate{WaitForDeletion: true, DeletionTimeout: "30m"},
+			expectedReason: reasonDeleteStuck,
+			expectedCode:   policyv1.ReasonCodeDeletionStuck,
+			expectedMsg: "which is longer than the deletion timeout of 30m0s, blocked by the finalizers: " +
+				"example.com/cleanup",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{UID: uuid.NewUUID()}}
+			r := &ConfigurationPolicyReconciler{
+				TargetK8sDynamicClient: dynamicfake.NewSimpleDynamicClient(scheme.Scheme, test.existing.DeepCopy()),
+			}
+
+			completed, reason, reasonCode, msg, err := r.enforceByDeleting(context.TODO(), singleObject{
+				policy:      policy,
+				scopedGVR:   configMapGVR,
+				existingObj: test.existing,
+				name:        "unwanted",
+				namespace:   "default",
+			}, test.objectT)
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedCompleted, completed)
+			assert.Equal(t, test.expectedReason, reason)
+			assert.Equal(t, test.expectedCode, reasonCode)
+			assert.Contains(t, msg, test.expectedMsg)
+
+			_, hasDeadline := r.deletionDeadlines.Load(policy.GetUID())
+			assert.Equal(t, test.expectedDeadline, hasDeadline)
+		})
+	}
+}
@@ -36,6 +36,7 @@ func addRelatedObjects(
 	namespace string,
 	objNames []string,
 	reason string,
+	reasonCode policyv1.ReasonCode,
 	creationInfo *policyv1.ObjectProperties,
 ) (relatedObjects []policyv1.RelatedObject) {
 	for _, name := range objNames {
@@ -52,6 +53,7 @@ func addRelatedObjects(
 		}
 
 		relatedObject.Reason = reason
+		relatedObject.ReasonCode = reasonCode
 		metadata := policyv1.ObjectMetadata{}
 		metadata.Name = name
 
@@ -78,6 +80,7 @@ func addCondensedRelatedObjs(
 	kind string,
 	namespace string,
 	reason string,
+	reasonCode policyv1.ReasonCode,
 ) (relatedObjects []policyv1.RelatedObject) {
 	metadata := policyv1.ObjectMetadata{Name: "-"}
 
@@ -89,7 +92,8 @@ func addCondensedRelatedObjs(
 
 	// Initialize the related object from the object handling
 	relatedObject := policyv1.RelatedObject{
-		Reason: reason,
+		Reason:     reason,
+		ReasonCode: reasonCode,
 		Object: policyv1.ObjectResource{
 			APIVersion: scopedGVR.GroupVersion().String(),
 			Kind:       kind,
@@ -136,6 +140,27 @@ func addOrUpdateRelatedObject(
 	return list
 }
 
+// addOrUpdateIndexedRelatedObject is like addOrUpdateRelatedObject but uses the index of the list by
+// object instead of a linear search, which matters when an object template selects many objects. The
+// index is updated when the related object is added.
+func addOrUpdateIndexedRelatedObject(
+	list []policyv1.RelatedObject, indexes map[policyv1.ObjectResource]int, relatedObject policyv1.RelatedObject,
+) []policyv1.RelatedObject {
+	index, present := indexes[relatedObject.Object]
+	if !present {
+		indexes[relatedObject.Object] = len(list)
+
+		return append(list, relatedObject)
+	}
+
+	if list[index].Compliant != relatedObject.Compliant ||
+		!reflect.DeepEqual(list[index].Properties, relatedObject.Properties) {
+		list[index] = relatedObject
+	}
+
+	return list
+}
+
 // deeplyEquivalent deeply compares the first two inputs, considering them
 // equivalent even if they have lists which are in different orders. When the
 // `zeroValueEqualsNil` parameter is true, it will allow nested maps to have
@@ -286,30 +311,7 @@ func checkFieldsAreEquivalent(
 
 // sortAndSprint sorts any lists in the input, and formats the resulting object as a string
 func sortAndSprint(item interface{}) string {
-	switch item := item.(type) {
-	case map[string]interface{}:
-		sorted := make(map[string]string, len(item))
-
-		for key, val := range item {
-			sorted[key] = sortAndSprint(val)
-		}
-
-		return fmt.Sprintf("%v", sorted)
-	case []interface{}:
-		sorted := make([]string, len(item))
-
-		for i, val := range item {
-			sorted[i] = sortAndSprint(val)
-		}
-
-		sort.Slice(sorted, func(x, y int) bool {
-			return sorted[x] < sorted[y]
-		})
-
-		return fmt.Sprintf("%v", sorted)
-	default:
-		return fmt.Sprintf("%v", item)
-	}
+	return canonicalKey(item, true)
 }
 
 // checkListsAreEquivalent deeply compares two lists and determines whether they
@@ -328,22 +330,21 @@ func checkListsAreEquivalent(oldVal []interface{}, mergedVal []interface{}) (mat
 		return false, false
 	}
 
-	// Make copies of the lists, so we can sort them without mutating this function's inputs
-	oVal := append([]interface{}{}, oldVal...)
-	mVal := append([]interface{}{}, mergedVal...)
+	// Identical lists are equivalent regardless of the sort order, which is the common case of a
+	// compliant object, so skip computing the canonical keys
+	if reflect.DeepEqual(oldVal, mergedVal) {
+		return true, false
+	}
 
-	sort.Slice(oVal, func(i, j int) bool {
-		return sortAndSprint(oVal[i]) < sortAndSprint(oVal[j])
-	})
-	sort.Slice(mVal, func(x, y int) bool {
-		return sortAndSprint(mVal[x]) < sortAndSprint(mVal[y])
-	})
+	// Sort copies of the lists, so this function's inputs are not mutated
+	oVal := sortByCanonicalKey(oldVal)
+	mVal := sortByCanonicalKey(mergedVal)
 
-	for idx, oNestedVal := range oVal {
-		switch oNestedVal := oNestedVal.(type) {
+	for idx, oNestedItem := range oVal {
+		switch oNestedVal := oNestedItem.value.(type) {
 		case map[string]interface{}:
 			// if list contains maps, recurse on those maps to check for a match
-			if mVal, ok := mVal[idx].(map[string]interface{}); ok {
+			if mVal, ok := mVal[idx].value.(map[string]interface{}); ok {
 				match, miss := checkFieldsAreEquivalent(mVal, oNestedVal, true)
 				missingKey = missingKey || miss
 
@@ -357,7 +358,7 @@ func checkListsAreEquivalent(oldVal []interface{}, mergedVal []interface{}) (mat
 			return false, missingKey
 		default:
 			// otherwise, just do a generic check
-			if fmt.Sprint(oNestedVal) != fmt.Sprint(mVal[idx]) {
+			if canonicalKey(oNestedVal, false) != canonicalKey(mVal[idx].value, false) {
 				return false, missingKey
 			}
 		}
@@ -495,11 +496,12 @@ func identifierStr(names []string, namespace string) (nameStr string) {
 }
 
 // createStatus generates the status reason and message for the object template after processing. resourceName indicates
-// the name of the resource (e.g. namespaces), and not the kind (e.g. Namespace).
+// the name of the resource (e.g. namespaces), and not the kind (e.g. Namespace). The reason code is the one of the
+// first reason in the generated reason.
 func createStatus(
 	resourceName string, nsNameToEvent map[string]*objectTmplEvalResultWithEvent,
 ) (
-	compliant bool, compliancyDetailsReason, compliancyDetailsMsg string,
+	compliant bool, compliancyDetailsReason, compliancyDetailsMsg string, reasonCode policyv1.ReasonCode,
 ) {
 	reasonToNsNameToEvent := map[string]map[string]*objectTmplEvalResultWithEvent{}
 	compliant = true
@@ -576,6 +578,10 @@ func createStatus(
 
 		slices.Sort(sortedNsNames)
 
+		if reasonCode == "" {
+			reasonCode = nsNameToEvent[sortedNsNames[0]].event.reasonCode
+		}
+
 		// If the object template was unnamed, then the object names can be different per namespace. If it was named,
 		// all will be the same, but this accounts for both.
 		sortedObjectNamesStrs := []string{}
@@ -607,7 +613,7 @@ func createStatus(
 		compliancyDetailsMsg = getCombinedCompliancyDetailsMsg(msgMap, resourceName, compliancyDetailsMsg)
 	}
 
-	return compliant, compliancyDetailsReason, compliancyDetailsMsg
+	return compliant, compliancyDetailsReason, compliancyDetailsMsg, reasonCode
 }
 
 func setCompliancyDetailsMsgEnd(compliancyDetailsMsg string) string {
@@ -751,6 +757,43 @@ func generateMessageWithReason(sortedObjectNamesStrs []string,
 			msgTemplate += strings.Join(nsList, ", ")
 		}
 
+		if reason == reasonWantFoundNoMatch {
+			changes := []policyv1.FieldManagerChange{}
+
+			for _, nsName := range objectNameStrsToNsNames[namesStr] {
+				for _, change := range nsNameToEvent[nsName].result.changedBy {
+					if !slices.ContainsFunc(changes, func(c policyv1.FieldManagerChange) bool {
+						return c.Manager == change.Manager && c.Operation == change.Operation &&
+							c.Subresource == change.Subresource
+					}) {
+						changes = append(changes, change)
+					}
+				}
+			}
+
+			if len(changes) > 0 {
+				// The message template is used as a format string, so escape any percent signs
+				msgTemplate += ", " + strings.ReplaceAll(changedByMsg(changes), "%", "%%")
+			}
+		}
+
+		if reason == reasonWantFoundNoMatch || reason == reasonWantNotFoundExists {
+			owners := []string{}
+
+			for _, nsName := range objectNameStrsToNsNames[namesStr] {
+				owner := nsNameToEvent[nsName].result.gitOpsOwner
+				if owner != "" && !slices.Contains(owners, owner) {
+					owners = append(owners, owner)
+				}
+			}
+
+			if len(owners) > 0 {
+				slices.Sort(owners)
+				msgTemplate += ", not enforced since it is managed by " +
+					strings.ReplaceAll(strings.Join(owners, ", "), "%", "%%")
+			}
+		}
+
 		if _, ok := msgMap[msgTemplate]; !ok {
 			msgMap[msgTemplate] = []string{}
 		}
@@ -840,37 +883,52 @@ func generateDiff(existingObj, updatedObj *unstructured.Unstructured, fullDiffs
 
 // getTemplateContext constructs a context object for Go template resolution. It
 // selectively includes the object, name, and namespace depending on what
-// information is available.
+// information is available. The policy parameters are included as Params when
+// the policy has parameters. Only the available fields are in the context so that
+// templates referencing the others fail to resolve.
 func getTemplateContext(
 	obj map[string]any,
 	name string,
 	ns string,
+	params map[string]any,
 ) (templateContext any) {
+	fields := []reflect.StructField{}
+	values := []reflect.Value{}
+
+	addField := func(fieldName string, value any) {
+		fields = append(fields, reflect.StructField{Name: fieldName, Type: reflect.TypeOf(value)})
+		values = append(values, reflect.ValueOf(value))
+	}
+
 	// Only populate context variables as they are available:
 	switch {
 	case name != "" && ns != "":
 		// - Namespaced object with metadata.name or objectSelector
-		return struct {
-			Object          map[string]any
-			ObjectNamespace string
-			ObjectName      string
-		}{Object: obj, ObjectNamespace: ns, ObjectName: name}
+		addField("Object", obj)
+		addField("ObjectNamespace", ns)
+		addField("ObjectName", name)
 
 	case name != "":
 		// - Cluster-scoped object with metadata.name or objectSelector
-		return struct {
-			Object     map[string]any
-			ObjectName string
-		}{Object: obj, ObjectName: name}
+		addField("Object", obj)
+		addField("ObjectName", name)
 
 	case ns != "":
 		// - Unnamed namespaced object
-		return struct {
-			ObjectNamespace string
-		}{ObjectNamespace: ns}
+		addField("ObjectNamespace", ns)
+	}
+
+	if params != nil {
+		addField("Params", params)
+	}
+
+	context := reflect.New(reflect.StructOf(fields)).Elem()
+
+	for i, value := range values {
+		context.Field(i).Set(value)
 	}
 
-	return struct{}{}
+	return context.Interface()
 }
 
 // resolveGoTemplates resolves Go templates in the given raw object using the
@@ -92,7 +92,8 @@ func TestAddConditionToStatusNeverEvalInterval(t *testing.T) {
 					},
 				}
 
-				addConditionToStatus(policy, 0, test.compliancy == policyv1.Compliant, "Some reason", "Some message")
+				compliant := test.compliancy == policyv1.Compliant
+				addConditionToStatus(policy, 0, compliant, "Some reason", policyv1.ReasonCodeError, "Some message")
 
 				details := policy.Status.CompliancyDetails
 				assert.Len(t, details, 1)
@@ -107,11 +108,24 @@ func TestAddConditionToStatusNeverEvalInterval(t *testing.T) {
 					fmt.Sprintf(`spec.evaluationInterval.%s being set to "never".`, lowercaseCompliance)
 
 				assert.Equal(t, expectedMsg, condition.Message)
+				assert.Equal(t, policyv1.ReasonCodeError, condition.ReasonCode)
 			},
 		)
 	}
 }
 
+func TestAddConditionToStatusReasonCode(t *testing.T) {
+	t.Parallel()
+
+	policy := &policyv1.ConfigurationPolicy{}
+
+	assert.True(t, addConditionToStatus(policy, 0, false, "Some reason", policyv1.ReasonCodeUpdateFailed, "msg"))
+	assert.False(t, addConditionToStatus(policy, 0, false, "Some reason", policyv1.ReasonCodeUpdateFailed, "msg"))
+	// Only the reason code differs, such as from a status set by an older version of the controller
+	assert.True(t, addConditionToStatus(policy, 0, false, "Some reason", policyv1.ReasonCodeImmutable, "msg"))
+	assert.Equal(t, policyv1.ReasonCodeImmutable, policy.Status.CompliancyDetails[0].Conditions[0].ReasonCode)
+}
+
 func TestCheckFieldsAreEquivalentEmptyMap(t *testing.T) {
 	oldObj := map[string]interface{}{
 		"spec": map[string]interface{}{
@@ -327,6 +341,7 @@ func TestGetTemplateContext(t *testing.T) {
 		obj      map[string]any
 		name     string
 		ns       string
+		params   map[string]any
 		expected any
 	}{
 		"namespaced object with name and namespace": {
@@ -385,6 +400,32 @@ func TestGetTemplateContext(t *testing.T) {
 				ObjectName:      "empty-obj",
 			},
 		},
+		"parameters with name and namespace": {
+			obj:    testObj,
+			name:   "my-configmap",
+			ns:     "default",
+			params: map[string]any{"team": "a"},
+			expected: struct {
+				Object          map[string]any
+				ObjectNamespace string
+				ObjectName      string
+				Params          map[string]any
+			}{
+				Object:          testObj,
+				ObjectNamespace: "default",
+				ObjectName:      "my-configmap",
+				Params:          map[string]any{"team": "a"},
+			},
+		},
+		"parameters with no name and no namespace": {
+			obj:    testObj,
+			params: map[string]any{},
+			expected: struct {
+				Params map[string]any
+			}{
+				Params: map[string]any{},
+			},
+		},
 		"nil object with name and namespace": {
 			obj:  nil,
 			name: "nil-obj",
@@ -405,7 +446,7 @@ func TestGetTemplateContext(t *testing.T) {
 		t.Run(testName, func(t *testing.T) {
 			t.Parallel()
 
-			result := getTemplateContext(test.obj, test.name, test.ns)
+			result := getTemplateContext(test.obj, test.name, test.ns, test.params)
 
 			assert.Equal(t, test.expected, result)
 		})
@@ -449,6 +490,22 @@ metadata:
 				`"name":"test",` +
 				`"namespace":"test-namespace"}}`,
 		},
+		"template with parameters": {
+			rawObj: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: '{{ .Params.prefix }}-config'
+  namespace: '{{ .ObjectNamespace }}'
+data:
+  replicas: '{{ .Params.replicas }}'`,
+			templateContext: getTemplateContext(
+				nil, "", "test-namespace", map[string]any{"prefix": "team-a", "replicas": int64(3)},
+			),
+			expectedResolved: `{"apiVersion":"v1",` +
+				`"kind":"ConfigMap",` +
+				`"metadata":{"name":"team-a-config","namespace":"test-namespace"},` +
+				`"data":{"replicas":"3"}}`,
+		},
 		"template with skipObject no arguments": {
 			rawObj: `apiVersion: v1
 kind: ConfigMap
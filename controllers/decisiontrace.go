@@ -0,0 +1,444 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+const (
+	// DecisionTracePath is the path of the decision traces on the metrics server.
+	DecisionTracePath = "/debug/decisions"
+	// redactedValue replaces the values of the resolved objects in the decision traces when they can't be shown.
+	redactedValue = "REDACTED"
+)
+
+// The outcomes of the comparison of a field path of an object with its object template. They match the kinds of the
+// mismatched fields in the related object properties.
+const (
+	// fieldMissing is a field of the object template that is not in the object.
+	fieldMissing = string(policyv1.MismatchMissing)
+	// fieldDifferent is a field with a different value in the object than in the object template.
+	fieldDifferent = string(policyv1.MismatchDifferent)
+	// fieldExtra is a field of the object that is not allowed by a mustonlyhave object template.
+	fieldExtra = string(policyv1.MismatchExtra)
+)
+
+// DecisionTracer records what was decided during the last evaluations of each ConfigurationPolicy: the resolved
+// object templates, the objects that were considered, the result of comparing each object with its object
+// template, and the actions taken on the objects. The traces are kept in memory and served as JSON by its
+// ServeHTTP method, so that users can find out why a policy is noncompliant without raising the log level.
+//
+// The values of the resolved objects are redacted unless the recordDiff of the object template allows them in the
+// policy status, and the comparison results only have the field paths, not the values.
+type DecisionTracer struct {
+	maxEvaluations int
+	lock           sync.RWMutex
+	// traces has the traces of each policy, from the most recent to the oldest
+	traces map[types.NamespacedName][]*evaluationTrace
+}
+
+// NewDecisionTracer returns a DecisionTracer that keeps the traces of the input number of evaluations per policy.
+func NewDecisionTracer(maxEvaluations int) *DecisionTracer {
+	return &DecisionTracer{
+		maxEvaluations: maxEvaluations,
+		traces:         map[types.NamespacedName][]*evaluationTrace{},
+	}
+}
+
+// evaluationTrace is the trace of a single evaluation of a policy. The objects are handled concurrently, so
+// everything is recorded while holding the lock.
+type evaluationTrace struct {
+	lock       sync.Mutex
+	Start      time.Time       `json:"start"`
+	Duration   string          `json:"duration"`
+	Generation int64           `json:"generation"`
+	Compliant  string          `json:"compliant,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Templates  []templateTrace `json:"templates"`
+}
+
+type templateTrace struct {
+	// Index is the index of the object template, or -1 for the changes not caused by an object template, such as
+	// pruning
+	Index int    `json:"index"`
+	Error string `json:"error,omitempty"`
+	// ResolvedObjects are the desired objects after resolving the templates
+	ResolvedObjects []map[string]any `json:"resolvedObjects,omitempty"`
+	// Results are the results of each desired object, as used in the compliance messages
+	Results []objectResultTrace `json:"results,omitempty"`
+	// Objects are the objects on the cluster that were compared or changed
+	Objects []*objectTrace `json:"objects,omitempty"`
+}
+
+type objectResultTrace struct {
+	Namespace string   `json:"namespace,omitempty"`
+	Names     []string `json:"names,omitempty"`
+	Compliant bool     `json:"compliant"`
+	Reason    string   `json:"reason,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+type objectTrace struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	// Matches is set when the object was compared with the object template
+	Matches     *bool             `json:"matches,omitempty"`
+	Differences []fieldDifference `json:"differences,omitempty"`
+	Actions     []actionTrace     `json:"actions,omitempty"`
+}
+
+type fieldDifference struct {
+	Path    string `json:"path"`
+	Outcome string `json:"outcome"`
+}
+
+type actionTrace struct {
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+type decisionTraceCtxKey struct{}
+
+// start begins the trace of an evaluation of the policy and returns a context with the trace, which is recorded
+// by finish. When the tracer is nil, the context is returned as is and nothing is recorded.
+func (d *DecisionTracer) start(
+	ctx context.Context, policy *policyv1.ConfigurationPolicy,
+) (context.Context, *evaluationTrace) {
+	if d == nil {
+		return ctx, nil
+	}
+
+	evalTrace := &evaluationTrace{
+		Start:      time.Now().UTC(),
+		Generation: policy.Generation,
+		Templates:  []templateTrace{},
+	}
+
+	return context.WithValue(ctx, decisionTraceCtxKey{}, evalTrace), evalTrace
+}
+
+// finish records the trace of the evaluation of the policy, dropping the oldest trace of the policy if there are
+// too many.
+func (d *DecisionTracer) finish(policy *policyv1.ConfigurationPolicy, evalTrace *evaluationTrace, err error) {
+	if d == nil || evalTrace == nil {
+		return
+	}
+
+	evalTrace.lock.Lock()
+
+	evalTrace.Duration = time.Since(evalTrace.Start).String()
+	evalTrace.Compliant = string(policy.Status.ComplianceState)
+
+	if err != nil {
+		evalTrace.Error = err.Error()
+	}
+
+	slices.SortFunc(evalTrace.Templates, func(a, b templateTrace) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+
+	for i := range evalTrace.Templates {
+		slices.SortFunc(evalTrace.Templates[i].Objects, func(a, b *objectTrace) int {
+			return cmp.Or(
+				cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name),
+			)
+		})
+	}
+
+	evalTrace.lock.Unlock()
+
+	key := types.NamespacedName{Namespace: policy.Namespace, Name: policy.Name}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	traces := append([]*evaluationTrace{evalTrace}, d.traces[key]...)
+	if len(traces) > d.maxEvaluations {
+		traces = traces[:d.maxEvaluations]
+	}
+
+	d.traces[key] = traces
+}
+
+// forget removes the traces of a policy that was deleted or released to another replica.
+func (d *DecisionTracer) forget(policy types.NamespacedName) {
+	if d == nil {
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.traces, policy)
+}
+
+// decisionTraceFrom returns the trace of the evaluation in the context, or nil if the evaluation isn't traced. The
+// methods of the trace do nothing on a nil trace.
+func decisionTraceFrom(ctx context.Context) *evaluationTrace {
+	evalTrace, _ := ctx.Value(decisionTraceCtxKey{}).(*evaluationTrace)
+
+	return evalTrace
+}
+
+// template returns the trace of the object template at the index, creating it if needed. The lock must be held.
+func (e *evaluationTrace) template(index int) *templateTrace {
+	for i := range e.Templates {
+		if e.Templates[i].Index == index {
+			return &e.Templates[i]
+		}
+	}
+
+	e.Templates = append(e.Templates, templateTrace{Index: index})
+
+	return &e.Templates[len(e.Templates)-1]
+}
+
+// object returns the trace of the object of the object template at the index, creating it if needed. The lock
+// must be held.
+func (e *evaluationTrace) object(index int, obj *unstructured.Unstructured) *objectTrace {
+	tmplTrace := e.template(index)
+
+	for _, objTrace := range tmplTrace.Objects {
+		if objTrace.Kind == obj.GetKind() && objTrace.Namespace == obj.GetNamespace() &&
+			objTrace.Name == obj.GetName() {
+			return objTrace
+		}
+	}
+
+	objTrace := &objectTrace{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+
+	tmplTrace.Objects = append(tmplTrace.Objects, objTrace)
+
+	return objTrace
+}
+
+// templateResolved records the desired objects of the object template at the index, or the error resolving them.
+// The values of the objects are redacted unless the recordDiff of the object template allows them in the status.
+func (e *evaluationTrace) templateResolved(
+	index int, objectT *policyv1.ObjectTemplate, desiredObjects []*unstructured.Unstructured, err error,
+) {
+	if e == nil {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	tmplTrace := e.template(index)
+
+	if err != nil {
+		tmplTrace.Error = err.Error()
+	}
+
+	redact := objectT.RecordDiffWithDefault() != policyv1.RecordDiffInStatus
+
+	for _, desiredObj := range desiredObjects {
+		resolved := desiredObj.DeepCopy().Object
+
+		if redact {
+			resolved = redactedObject(resolved)
+		}
+
+		tmplTrace.ResolvedObjects = append(tmplTrace.ResolvedObjects, resolved)
+	}
+}
+
+// objectsEvaluated records the result of handling a desired object of the object template at the index.
+func (e *evaluationTrace) objectsEvaluated(index int, result objectTmplEvalResult) {
+	if e == nil {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	tmplTrace := e.template(index)
+
+	for _, event := range result.events {
+		tmplTrace.Results = append(tmplTrace.Results, objectResultTrace{
+			Namespace: result.namespace,
+			Names:     result.objectNames,
+			Compliant: event.compliant,
+			Reason:    event.reason,
+			Message:   event.message,
+		})
+	}
+}
+
+// objectCompared records the result of comparing the object on the cluster with the object template at the
+// index. The existing object is the object before the comparison and the merged object is the object with the
+// object template merged into it.
+func (e *evaluationTrace) objectCompared(index int, existing, merged *unstructured.Unstructured, matches bool) {
+	if e == nil {
+		return
+	}
+
+	var differences []fieldDifference
+
+	if !matches {
+		mergedCopy := merged.DeepCopy()
+		removeFieldsForComparison(mergedCopy)
+
+		differences = fieldDifferences(existing.Object, mergedCopy.Object)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	objTrace := e.object(index, existing)
+	objTrace.Matches = &matches
+	objTrace.Differences = differences
+}
+
+// actionTaken records a change made to an object of the object template at the index. A negative index is for
+// changes not caused by an object template, such as pruning.
+func (e *evaluationTrace) actionTaken(index int, obj *unstructured.Unstructured, action auditAction, err error) {
+	if e == nil {
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	actTrace := actionTrace{Action: string(action)}
+	if err != nil {
+		actTrace.Error = err.Error()
+	}
+
+	objTrace := e.object(index, obj)
+	objTrace.Actions = append(objTrace.Actions, actTrace)
+}
+
+// redactedObject returns a copy of the object with all values replaced, except for the fields that identify it.
+func redactedObject(obj map[string]any) map[string]any {
+	redacted, _ := redactedValues(obj).(map[string]any)
+
+	for _, field := range []string{"apiVersion", "kind"} {
+		if value, ok := obj[field]; ok {
+			redacted[field] = value
+		}
+	}
+
+	for _, field := range []string{"name", "namespace"} {
+		if value, found, _ := unstructured.NestedString(obj, "metadata", field); found {
+			_ = unstructured.SetNestedField(redacted, value, "metadata", field)
+		}
+	}
+
+	return redacted
+}
+
+func redactedValues(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(value))
+
+		for key, item := range value {
+			redacted[key] = redactedValues(item)
+		}
+
+		return redacted
+	case []any:
+		redacted := make([]any, len(value))
+
+		for i, item := range value {
+			redacted[i] = redactedValues(item)
+		}
+
+		return redacted
+	default:
+		return redactedValue
+	}
+}
+
+// fieldDifferences returns the fields that differ between the existing object and the merged object, with their
+// paths formatted for the trace.
+func fieldDifferences(existing, merged map[string]any) []fieldDifference {
+	mismatches := mismatchedFieldPaths(nil, existing, merged)
+	differences := make([]fieldDifference, 0, len(mismatches))
+
+	for _, mismatch := range mismatches {
+		differences = append(differences, fieldDifference{
+			Path:    formatFieldPath(mismatch.path),
+			Outcome: mismatch.outcome,
+		})
+	}
+
+	return differences
+}
+
+// decisionTraceResponse is the body of the responses of the decision traces.
+type decisionTraceResponse struct {
+	Policies []policyDecisionTraces `json:"policies"`
+}
+
+type policyDecisionTraces struct {
+	Namespace   string             `json:"namespace"`
+	Name        string             `json:"name"`
+	Evaluations []*evaluationTrace `json:"evaluations"`
+}
+
+// ServeHTTP returns the decision traces of the ConfigurationPolicies as JSON, from the most recent evaluation to
+// the oldest. The namespace and name query parameters limit the traces to the matching policies. The handler
+// doesn't authenticate the requests, so it's meant to be served on the metrics server with its authentication
+// and authorization filter.
+func (d *DecisionTracer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "only GET requests are supported", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	namespace := r.URL.Query().Get("namespace")
+	name := r.URL.Query().Get("name")
+
+	response := decisionTraceResponse{Policies: []policyDecisionTraces{}}
+
+	d.lock.RLock()
+
+	for key, traces := range d.traces {
+		if (namespace != "" && key.Namespace != namespace) || (name != "" && key.Name != name) {
+			continue
+		}
+
+		response.Policies = append(response.Policies, policyDecisionTraces{
+			Namespace: key.Namespace, Name: key.Name, Evaluations: traces,
+		})
+	}
+
+	slices.SortFunc(response.Policies, func(a, b policyDecisionTraces) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+
+	// The finished traces aren't modified, so they can be encoded while holding the read lock
+	err := json.NewEncoder(w).Encode(response)
+
+	d.lock.RUnlock()
+
+	if err != nil {
+		ctrl.LoggerFrom(r.Context()).WithName("decision-trace").Error(err, "Failed to write the decision traces")
+	}
+}
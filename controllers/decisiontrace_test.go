@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestFieldDifferences(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		existing map[string]any
+		desired  map[string]any
+		expected []fieldDifference
+	}{
+		"equal": {
+			existing: map[string]any{"data": map[string]any{"a": "1"}},
+			desired:  map[string]any{"data": map[string]any{"a": "1"}},
+			expected: []fieldDifference{},
+		},
+		"nested": {
+			existing: map[string]any{
+				"data":     map[string]any{"a": "1", "c": "3"},
+				"metadata": map[string]any{"labels": map[string]any{"app.kubernetes.io/name": "old"}},
+			},
+			desired: map[string]any{
+				"data":     map[string]any{"a": "2", "b": "2"},
+				"metadata": map[string]any{"labels": map[string]any{"app.kubernetes.io/name": "new"}},
+			},
+			expected: []fieldDifference{
+				{Path: "data.a", Outcome: fieldDifferent},
+				{Path: "data.b", Outcome: fieldMissing},
+				{Path: "data.c", Outcome: fieldExtra},
+				{Path: `metadata.labels["app.kubernetes.io/name"]`, Outcome: fieldDifferent},
+			},
+		},
+		"lists": {
+			existing: map[string]any{"items": []any{"a", "b", "c"}, "ports": []any{map[string]any{"port": 80}}},
+			desired:  map[string]any{"items": []any{"a", "x"}, "ports": []any{map[string]any{"port": 80}, "443"}},
+			expected: []fieldDifference{
+				{Path: "items", Outcome: fieldDifferent},
+				{Path: "ports", Outcome: fieldDifferent},
+			},
+		},
+		"reordered list": {
+			existing: map[string]any{"items": []any{"a", "b"}},
+			desired:  map[string]any{"items": []any{"b", "a"}},
+			expected: []fieldDifference{},
+		},
+		"type change": {
+			existing: map[string]any{"spec": "value"},
+			desired:  map[string]any{"spec": map[string]any{"replicas": 2}},
+			expected: []fieldDifference{{Path: "spec", Outcome: fieldDifferent}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, fieldDifferences(test.existing, test.desired))
+		})
+	}
+}
+
+func TestRedactedObject(t *testing.T) {
+	t.Parallel()
+
+	obj := map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]any{"name": "creds", "namespace": "default", "labels": map[string]any{"a": "b"}},
+		"data":       map[string]any{"password": "c2VjcmV0"},
+		"list":       []any{"x", int64(1)},
+	}
+
+	assert.Equal(t, map[string]any{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]any{
+			"name": "creds", "namespace": "default", "labels": map[string]any{"a": redactedValue},
+		},
+		"data": map[string]any{"password": redactedValue},
+		"list": []any{redactedValue, redactedValue},
+	}, redactedObject(obj))
+
+	// The input object isn't modified
+	assert.Equal(t, "c2VjcmV0", obj["data"].(map[string]any)["password"])
+}
+
+func TestDecisionTracer(t *testing.T) {
+	t.Parallel()
+
+	tracer := NewDecisionTracer(2)
+	policy := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "traced"},
+	}
+
+	configMap := func(data map[string]any) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": "settings", "namespace": "default"},
+			"data":       data,
+		}}
+	}
+
+	objectT := &policyv1.ObjectTemplate{
+		ComplianceType: policyv1.MustHave,
+		RecordDiff:     policyv1.RecordDiffInStatus,
+		ObjectDefinition: runtime.RawExtension{
+			Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"settings"}}`),
+		},
+	}
+
+	for i := range 3 {
+		policy.Generation = int64(i)
+
+		ctx, evalTrace := tracer.start(context.Background(), policy)
+		assert.Same(t, evalTrace, decisionTraceFrom(ctx))
+
+		decisionTraceFrom(ctx).templateResolved(0, objectT, []*unstructured.Unstructured{
+			configMap(map[string]any{"level": "debug"}),
+		}, nil)
+		decisionTraceFrom(ctx).objectsEvaluated(0, objectTmplEvalResult{
+			objectNames: []string{"settings"},
+			namespace:   "default",
+			events: []objectTmplEvalEvent{
+				{compliant: true, reason: reasonWantFoundCreated, reasonCode: policyv1.ReasonCodeCreated},
+			},
+		})
+		decisionTraceFrom(ctx).objectCompared(
+			0, configMap(map[string]any{"level": "info"}), configMap(map[string]any{"level": "debug"}), false,
+		)
+		decisionTraceFrom(ctx).actionTaken(0, configMap(nil), auditActionUpdate, nil)
+		decisionTraceFrom(ctx).actionTaken(-1, configMap(nil), auditActionDelete, errors.New("forbidden"))
+
+		policy.Status.ComplianceState = policyv1.Compliant
+
+		tracer.finish(policy, evalTrace, nil)
+	}
+
+	request := func(target string) decisionTraceResponse {
+		recorder := httptest.NewRecorder()
+		tracer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+
+		response := decisionTraceResponse{}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+		return response
+	}
+
+	response := request(DecisionTracePath + "?namespace=managed&name=traced")
+	if !assert.Len(t, response.Policies, 1) {
+		return
+	}
+
+	// Only the last two evaluations are kept, from the most recent
+	evaluations := response.Policies[0].Evaluations
+	if !assert.Len(t, evaluations, 2) {
+		return
+	}
+
+	assert.Equal(t, int64(2), evaluations[0].Generation)
+	assert.Equal(t, int64(1), evaluations[1].Generation)
+	assert.Equal(t, "Compliant", evaluations[0].Compliant)
+
+	if assert.Len(t, evaluations[0].Templates, 2) {
+		pruned := evaluations[0].Templates[0]
+		assert.Equal(t, -1, pruned.Index)
+
+		if assert.Len(t, pruned.Objects, 1) {
+			assert.Equal(t, []actionTrace{{Action: "delete", Error: "forbidden"}}, pruned.Objects[0].Actions)
+		}
+
+		tmplTrace := evaluations[0].Templates[1]
+		assert.Equal(t, 0, tmplTrace.Index)
+		assert.Equal(
+			t, []map[string]any{configMap(map[string]any{"level": "debug"}).Object}, tmplTrace.ResolvedObjects,
+		)
+		assert.Equal(t, []objectResultTrace{
+			{Namespace: "default", Names: []string{"settings"}, Compliant: true, Reason: reasonWantFoundCreated},
+		}, tmplTrace.Results)
+
+		if assert.Len(t, tmplTrace.Objects, 1) {
+			assert.Equal(t, "settings", tmplTrace.Objects[0].Name)
+			assert.False(t, *tmplTrace.Objects[0].Matches)
+			assert.Equal(
+				t, []fieldDifference{{Path: "data.level", Outcome: fieldDifferent}}, tmplTrace.Objects[0].Differences,
+			)
+			assert.Equal(t, []actionTrace{{Action: "update"}}, tmplTrace.Objects[0].Actions)
+		}
+	}
+
+	assert.Empty(t, request(DecisionTracePath+"?namespace=other").Policies)
+
+	tracer.forget(types.NamespacedName{Namespace: "managed", Name: "traced"})
+	assert.Empty(t, request(DecisionTracePath).Policies)
+}
+
+func TestDecisionTracerDisabled(t *testing.T) {
+	t.Parallel()
+
+	var tracer *DecisionTracer
+
+	ctx, evalTrace := tracer.start(context.Background(), &policyv1.ConfigurationPolicy{})
+	assert.Nil(t, evalTrace)
+	assert.Nil(t, decisionTraceFrom(ctx))
+
+	// Recording on a nil trace does nothing
+	decisionTraceFrom(ctx).templateResolved(0, &policyv1.ObjectTemplate{}, nil, errors.New("template error"))
+	decisionTraceFrom(ctx).actionTaken(0, &unstructured.Unstructured{}, auditActionCreate, nil)
+	tracer.finish(&policyv1.ConfigurationPolicy{}, evalTrace, nil)
+}
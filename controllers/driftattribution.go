@@ -0,0 +1,478 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// plainPathKey matches map keys that can be represented in a dotted path without quoting.
+var plainPathKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// compliantObservation is a snapshot of an object from the last time it was observed to be
+// compliant with an object template. It is used to determine which field managers changed the
+// object after that point.
+type compliantObservation struct {
+	uid             string
+	resourceVersion string
+	observed        time.Time
+	// managedFields is nil when the object was observed through the watch cache, which strips them.
+	managedFields []metav1.ManagedFieldsEntry
+	// attributedVersion is the resourceVersion of the drifted object that changedBy was determined for.
+	attributedVersion string
+	changedBy         []policyv1.FieldManagerChange
+}
+
+// recordCompliantObservation stores a snapshot of the object's field managers since the object is
+// compliant with the object template. The snapshot is only replaced when the object changes.
+func (r *ConfigurationPolicyReconciler) recordCompliantObservation(
+	policy *policyv1.ConfigurationPolicy,
+	obj *unstructured.Unstructured,
+	objectT *policyv1.ObjectTemplate,
+) {
+	if policy == nil || obj == nil {
+		return
+	}
+
+	policyMap := &sync.Map{}
+
+	loadedPolicyMap, loaded := r.lastCompliantCache.LoadOrStore(policy.GetUID(), policyMap)
+	if loaded {
+		policyMap = loadedPolicyMap.(*sync.Map)
+	}
+
+	key := getEvalObjKey(obj.GetUID(), objectT)
+
+	if cached, ok := policyMap.Load(key); ok {
+		if cached.(compliantObservation).resourceVersion == obj.GetResourceVersion() {
+			return
+		}
+	}
+
+	policyMap.Store(key, compliantObservation{
+		uid:             string(obj.GetUID()),
+		resourceVersion: obj.GetResourceVersion(),
+		observed:        time.Now().UTC(),
+		managedFields:   obj.GetManagedFields(),
+	})
+}
+
+// getCompliantObservation returns the last compliant observation of the object for the object
+// template, if there is one.
+func (r *ConfigurationPolicyReconciler) getCompliantObservation(
+	policy *policyv1.ConfigurationPolicy,
+	obj *unstructured.Unstructured,
+	objectT *policyv1.ObjectTemplate,
+) *compliantObservation {
+	loadedPolicyMap, loaded := r.lastCompliantCache.Load(policy.GetUID())
+	if !loaded {
+		return nil
+	}
+
+	cached, loaded := loadedPolicyMap.(*sync.Map).Load(getEvalObjKey(obj.GetUID(), objectT))
+	if !loaded {
+		return nil
+	}
+
+	observation := cached.(compliantObservation)
+
+	return &observation
+}
+
+// pruneCompliantObservations removes the compliant observations of the objects that are no longer related to the
+// policy, such as when they no longer match the object selector of the object template.
+func (r *ConfigurationPolicyReconciler) pruneCompliantObservations(
+	policy *policyv1.ConfigurationPolicy, related []policyv1.RelatedObject,
+) {
+	loadedPolicyMap, loaded := r.lastCompliantCache.Load(policy.GetUID())
+	if !loaded {
+		return
+	}
+
+	uids := make(map[string]bool, len(related))
+
+	for _, relatedObj := range related {
+		if relatedObj.Properties != nil && relatedObj.Properties.UID != "" {
+			uids[relatedObj.Properties.UID] = true
+		}
+	}
+
+	loadedPolicyMap.(*sync.Map).Range(func(key, value any) bool {
+		if !uids[value.(compliantObservation).uid] {
+			loadedPolicyMap.(*sync.Map).Delete(key)
+		}
+
+		return true
+	})
+}
+
+// driftChangedBy returns the field managers that changed the object since it was last observed to be compliant
+// with the object template. Nothing is returned when the object was never observed to be compliant, or when it
+// didn't change since then, in which case the object template changed instead. The watch cache doesn't include
+// the managedFields, so the object is fetched from the API server, but only once per resourceVersion of the
+// object since the result is cached with the compliant observation.
+func (r *ConfigurationPolicyReconciler) driftChangedBy(
+	ctx context.Context,
+	log logr.Logger,
+	obj singleObject,
+	objectT *policyv1.ObjectTemplate,
+) []policyv1.FieldManagerChange {
+	observation := r.getCompliantObservation(obj.policy, obj.existingObj, objectT)
+	if observation == nil || observation.resourceVersion == obj.existingObj.GetResourceVersion() {
+		return nil
+	}
+
+	if observation.attributedVersion == obj.existingObj.GetResourceVersion() {
+		return observation.changedBy
+	}
+
+	current, err := getObject(ctx, obj.namespace, obj.name, obj.scopedGVR, r.TargetK8sDynamicClient)
+	if err != nil {
+		return nil
+	}
+
+	changedBy := r.attributeDrift(log, obj, current, objectT)
+
+	if loadedPolicyMap, loaded := r.lastCompliantCache.Load(obj.policy.GetUID()); loaded {
+		observation.attributedVersion = obj.existingObj.GetResourceVersion()
+		observation.changedBy = changedBy
+
+		loadedPolicyMap.(*sync.Map).Store(getEvalObjKey(obj.existingObj.GetUID(), objectT), *observation)
+	}
+
+	return changedBy
+}
+
+// attributeDrift determines which field managers changed the fields of the object that don't match
+// the object template since the object was last observed to be compliant. The current object must
+// include its managedFields, which means it must not come from the watch cache.
+func (r *ConfigurationPolicyReconciler) attributeDrift(
+	log logr.Logger,
+	obj singleObject,
+	current *unstructured.Unstructured,
+	objectT *policyv1.ObjectTemplate,
+) []policyv1.FieldManagerChange {
+	if current == nil || obj.desiredObj == nil {
+		return nil
+	}
+
+	existing := current.DeepCopy()
+	removeFieldsForComparison(existing)
+	removeProvenance(existing)
+
+	merged := existing.DeepCopy()
+
+	_, errMsg, _, _, _ := handleKeys(
+		log, obj.desiredObj, merged, existing.DeepCopy(), objectT.ComplianceType, objectT.MetadataComplianceType,
+	)
+	if errMsg != "" {
+		return nil
+	}
+
+	removeFieldsForComparison(merged)
+
+	mismatches := mismatchedFieldPaths(nil, existing.Object, merged.Object)
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	paths := make([][]string, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		paths = append(paths, mismatch.path)
+	}
+
+	return attributeManagedFields(
+		current.GetManagedFields(), r.getCompliantObservation(obj.policy, current, objectT), paths,
+	)
+}
+
+// fieldMismatch is a field that differs between an existing object and the object merged with an object
+// template. The outcome is fieldMissing, fieldExtra, or fieldDifferent.
+type fieldMismatch struct {
+	path    []string
+	outcome string
+}
+
+// mismatchedFieldPaths returns the fields that differ between the existing object and the merged object, sorted
+// by their paths. Maps are compared key by key, but lists are compared as a whole like checkListsAreEquivalent
+// compares them, since list items can't be reliably correlated by their index or with the managed fields.
+func mismatchedFieldPaths(prefix []string, existing, merged map[string]any) []fieldMismatch {
+	keys := make([]string, 0, len(existing)+len(merged))
+
+	for key := range existing {
+		keys = append(keys, key)
+	}
+
+	for key := range merged {
+		if _, ok := existing[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	mismatches := []fieldMismatch{}
+
+	for _, key := range keys {
+		path := append(slices.Clone(prefix), key)
+
+		existingVal, existingOk := existing[key]
+		mergedVal, mergedOk := merged[key]
+
+		switch {
+		case !existingOk:
+			mismatches = append(mismatches, fieldMismatch{path: path, outcome: fieldMissing})
+
+			continue
+		case !mergedOk:
+			mismatches = append(mismatches, fieldMismatch{path: path, outcome: fieldExtra})
+
+			continue
+		}
+
+		existingMap, existingIsMap := existingVal.(map[string]any)
+		mergedMap, mergedIsMap := mergedVal.(map[string]any)
+
+		if existingIsMap && mergedIsMap {
+			mismatches = append(mismatches, mismatchedFieldPaths(path, existingMap, mergedMap)...)
+
+			continue
+		}
+
+		if equal, _ := deeplyEquivalent(mergedVal, existingVal, false); !equal {
+			mismatches = append(mismatches, fieldMismatch{path: path, outcome: fieldDifferent})
+		}
+	}
+
+	return mismatches
+}
+
+// formatFieldPath formats a field path as a dotted path, quoting keys that contain characters such
+// as dots or slashes. For example: metadata.labels["app.kubernetes.io/name"]
+func formatFieldPath(path []string) string {
+	var sb strings.Builder
+
+	for i, key := range path {
+		if !plainPathKey.MatchString(key) {
+			fmt.Fprintf(&sb, "[%q]", key)
+
+			continue
+		}
+
+		if i != 0 {
+			sb.WriteString(".")
+		}
+
+		sb.WriteString(key)
+	}
+
+	return sb.String()
+}
+
+// managedFieldPaths parses the FieldsV1 JSON of a managedFields entry and returns the paths of the
+// fields it owns. List items (the `k:`, `v:`, and `i:` keys) are represented as a "*" wildcard.
+func managedFieldPaths(fields *metav1.FieldsV1) [][]string {
+	if fields == nil || len(fields.Raw) == 0 {
+		return nil
+	}
+
+	parsed := map[string]any{}
+
+	if err := json.Unmarshal(fields.Raw, &parsed); err != nil {
+		return nil
+	}
+
+	paths := [][]string{}
+
+	var walk func(prefix []string, node map[string]any)
+
+	walk = func(prefix []string, node map[string]any) {
+		hasChildren := false
+
+		for key, child := range node {
+			var segment string
+
+			switch {
+			case key == ".":
+				continue
+			case strings.HasPrefix(key, "f:"):
+				segment = strings.TrimPrefix(key, "f:")
+			case strings.HasPrefix(key, "k:"), strings.HasPrefix(key, "v:"), strings.HasPrefix(key, "i:"):
+				segment = "*"
+			default:
+				continue
+			}
+
+			hasChildren = true
+			path := append(slices.Clone(prefix), segment)
+
+			if childMap, ok := child.(map[string]any); ok && len(childMap) > 0 {
+				walk(path, childMap)
+			} else {
+				paths = append(paths, path)
+			}
+		}
+
+		if !hasChildren && len(prefix) > 0 {
+			paths = append(paths, prefix)
+		}
+	}
+
+	walk(nil, parsed)
+
+	return paths
+}
+
+// fieldPathsOverlap reports whether one path is a prefix of the other, where "*" matches any key.
+func fieldPathsOverlap(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] && a[i] != "*" && b[i] != "*" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// managedFieldsEntryChanged reports whether the managedFields entry is new or was modified since the
+// compliant observation.
+func managedFieldsEntryChanged(entry metav1.ManagedFieldsEntry, observation *compliantObservation) bool {
+	if observation == nil {
+		return true
+	}
+
+	if observation.managedFields == nil {
+		// The managedFields weren't available when the object was observed, so fall back to the timestamps.
+		// The managedFields timestamps have a resolution of seconds.
+		return entry.Time != nil && !entry.Time.Time.Before(observation.observed.Truncate(time.Second))
+	}
+
+	for _, previous := range observation.managedFields {
+		if previous.Manager != entry.Manager || previous.Operation != entry.Operation ||
+			previous.Subresource != entry.Subresource {
+			continue
+		}
+
+		return !previous.Time.Equal(entry.Time) || !reflect.DeepEqual(previous.FieldsV1, entry.FieldsV1)
+	}
+
+	return true
+}
+
+// attributeManagedFields returns the field managers that changed the object since the compliant
+// observation and own the mismatched paths. If none of the changed field managers own a mismatched
+// path, such as when a field was removed, all field managers that changed the object since the
+// compliant observation are returned without fields. The result is sorted with the most recent
+// change first.
+func attributeManagedFields(
+	managedFields []metav1.ManagedFieldsEntry, observation *compliantObservation, mismatches [][]string,
+) []policyv1.FieldManagerChange {
+	owners := []policyv1.FieldManagerChange{}
+	changed := []policyv1.FieldManagerChange{}
+
+	for _, entry := range managedFields {
+		if !managedFieldsEntryChanged(entry, observation) {
+			continue
+		}
+
+		change := policyv1.FieldManagerChange{
+			Manager:     entry.Manager,
+			Operation:   string(entry.Operation),
+			Subresource: entry.Subresource,
+		}
+
+		if entry.Time != nil {
+			change.Time = entry.Time.DeepCopy()
+		}
+
+		changed = append(changed, change)
+
+		owned := managedFieldPaths(entry.FieldsV1)
+
+		for _, mismatch := range mismatches {
+			for _, ownedPath := range owned {
+				if fieldPathsOverlap(mismatch, ownedPath) {
+					change.Fields = append(change.Fields, formatFieldPath(mismatch))
+
+					break
+				}
+			}
+		}
+
+		if len(change.Fields) != 0 {
+			owners = append(owners, change)
+		}
+	}
+
+	if len(owners) == 0 {
+		// Without a compliant observation, every field manager would be reported, which isn't useful.
+		if observation == nil {
+			return nil
+		}
+
+		owners = changed
+	}
+
+	slices.SortStableFunc(owners, func(a, b policyv1.FieldManagerChange) int {
+		if !a.Time.Equal(b.Time) {
+			if a.Time == nil {
+				return 1
+			}
+
+			if b.Time == nil {
+				return -1
+			}
+
+			return b.Time.Compare(a.Time.Time)
+		}
+
+		return strings.Compare(a.Manager, b.Manager)
+	})
+
+	return owners
+}
+
+// changedByMsg formats the field managers that changed an object for the compliance message, for
+// example: `changed by kubectl-edit (Update at 2024-01-01T00:00:00Z)`.
+func changedByMsg(changes []policyv1.FieldManagerChange) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(changes))
+
+	for _, change := range changes {
+		part := change.Manager
+
+		details := change.Operation
+		if change.Subresource != "" {
+			details = strings.TrimSpace(details + " " + change.Subresource)
+		}
+
+		if change.Time != nil {
+			details = strings.TrimSpace(details + " at " + change.Time.UTC().Format(time.RFC3339))
+		}
+
+		if details != "" {
+			part += " (" + details + ")"
+		}
+
+		parts = append(parts, part)
+	}
+
+	return "changed by " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestMismatchedFieldPaths(t *testing.T) {
+	t.Parallel()
+
+	existing := map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{"app.kubernetes.io/name": "other", "same": "value"},
+		},
+		"data": map[string]any{"extra": "value", "key": "old"},
+		"list": []any{"a", "b"},
+	}
+	merged := map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{"app.kubernetes.io/name": "mine", "same": "value"},
+		},
+		"data": map[string]any{"key": "new", "missing": "value"},
+		"list": []any{"a", "c"},
+	}
+
+	mismatches := mismatchedFieldPaths(nil, existing, merged)
+
+	formatted := make(map[string]string, len(mismatches))
+	for _, mismatch := range mismatches {
+		formatted[formatFieldPath(mismatch.path)] = mismatch.outcome
+	}
+
+	assert.Equal(t, map[string]string{
+		"data.extra":   fieldExtra,
+		"data.key":     fieldDifferent,
+		"data.missing": fieldMissing,
+		"list":         fieldDifferent,
+		`metadata.labels["app.kubernetes.io/name"]`: fieldDifferent,
+	}, formatted)
+}
+
+func TestManagedFieldPaths(t *testing.T) {
+	t.Parallel()
+
+	fields := &metav1.FieldsV1{Raw: []byte(
+		`{"f:data":{".":{},"f:key":{}},"f:spec":{"f:containers":{"k:{\"name\":\"app\"}":{".":{},"f:image":{}}}}}`,
+	)}
+
+	paths := managedFieldPaths(fields)
+
+	assert.ElementsMatch(t, [][]string{
+		{"data", "key"},
+		{"spec", "containers", "*", "image"},
+	}, paths)
+
+	assert.True(t, fieldPathsOverlap([]string{"spec", "containers"}, paths[0]) ||
+		fieldPathsOverlap([]string{"spec", "containers"}, paths[1]))
+	assert.False(t, fieldPathsOverlap([]string{"data", "other"}, []string{"data", "key"}))
+}
+
+func TestAttributeManagedFields(t *testing.T) {
+	t.Parallel()
+
+	observed := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	before := metav1.NewTime(observed.Add(-time.Hour))
+	after := metav1.NewTime(observed.Add(time.Minute))
+	later := metav1.NewTime(observed.Add(time.Hour))
+
+	managedFields := []metav1.ManagedFieldsEntry{
+		{
+			Manager:   "config-policy-controller",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			Time:      &before,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:key":{}}}`)},
+		},
+		{
+			Manager:   "kubectl-edit",
+			Operation: metav1.ManagedFieldsOperationUpdate,
+			Time:      &after,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:key":{}}}`)},
+		},
+		{
+			Manager:   "other-controller",
+			Operation: metav1.ManagedFieldsOperationApply,
+			Time:      &later,
+			FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:team":{}}}}`)},
+		},
+	}
+
+	mismatches := [][]string{{"data", "key"}}
+
+	// The managedFields weren't available in the observation, so the timestamps are used
+	changes := attributeManagedFields(managedFields, &compliantObservation{observed: observed}, mismatches)
+	assert.Equal(t, []policyv1.FieldManagerChange{
+		{Manager: "kubectl-edit", Operation: "Update", Time: &after, Fields: []string{"data.key"}},
+	}, changes)
+
+	// Only the entry that differs from the observation is reported
+	observation := &compliantObservation{observed: observed, managedFields: managedFields[:1]}
+	changes = attributeManagedFields(managedFields, observation, mismatches)
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "kubectl-edit", changes[0].Manager)
+
+	// When no changed field manager owns the mismatched field, all changed field managers are reported
+	changes = attributeManagedFields(managedFields, observation, [][]string{{"data", "removed"}})
+	assert.Equal(t, []string{"other-controller", "kubectl-edit"}, []string{changes[0].Manager, changes[1].Manager})
+	assert.Empty(t, changes[0].Fields)
+
+	// Without an observation, only field managers that own a mismatched field are reported
+	changes = attributeManagedFields(managedFields, nil, [][]string{{"data", "removed"}})
+	assert.Empty(t, changes)
+}
+
+func TestAttributeDrift(t *testing.T) {
+	t.Parallel()
+
+	editTime := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	current := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":      "drifted",
+			"namespace": "default",
+			"uid":       "1234",
+		},
+		"data": map[string]any{"key": "edited", "other": "value"},
+	}}
+	current.SetManagedFields([]metav1.ManagedFieldsEntry{{
+		Manager:   "kubectl-edit",
+		Operation: metav1.ManagedFieldsOperationUpdate,
+		Time:      &editTime,
+		FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:key":{}}}`)},
+	}})
+
+	desired := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "drifted", "namespace": "default"},
+		"data":       map[string]any{"key": "desired"},
+	}}
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{UID: "policy-uid"}}
+	objectT := &policyv1.ObjectTemplate{ComplianceType: policyv1.MustHave}
+	r := &ConfigurationPolicyReconciler{}
+
+	compliant := current.DeepCopy()
+	compliant.SetResourceVersion("1")
+	compliant.SetManagedFields(nil)
+	r.recordCompliantObservation(policy, compliant, objectT)
+
+	// Simulate the observation happening before the edit
+	policyMap, _ := r.lastCompliantCache.Load(policy.GetUID())
+	key := getEvalObjKey(compliant.GetUID(), objectT)
+	cached, _ := policyMap.(*sync.Map).Load(key)
+	observation := cached.(compliantObservation)
+	observation.observed = editTime.Add(-time.Minute)
+	policyMap.(*sync.Map).Store(key, observation)
+
+	changes := r.attributeDrift(
+		logr.Discard(), singleObject{policy: policy, desiredObj: desired}, current, objectT,
+	)
+
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "kubectl-edit", changes[0].Manager)
+		assert.Equal(t, "Update", changes[0].Operation)
+		assert.True(t, editTime.Equal(changes[0].Time))
+		assert.Equal(t, []string{"data.key"}, changes[0].Fields)
+	}
+
+	assert.Equal(t, "changed by kubectl-edit (Update at 2024-01-01T12:00:00Z)", changedByMsg(changes))
+}
+
+func TestDriftChangedBy(t *testing.T) {
+	t.Parallel()
+
+	editTime := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	current := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]any{
+			"name":            "drifted",
+			"namespace":       "default",
+			"uid":             "1234",
+			"resourceVersion": "2",
+		},
+		"data": map[string]any{"key": "edited"},
+	}}
+	current.SetManagedFields([]metav1.ManagedFieldsEntry{{
+		Manager:   "kubectl-edit",
+		Operation: metav1.ManagedFieldsOperationUpdate,
+		Time:      &editTime,
+		FieldsV1:  &metav1.FieldsV1{Raw: []byte(`{"f:data":{"f:key":{}}}`)},
+	}})
+
+	desired := &unstructured.Unstructured{Object: map[string]any{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]any{"name": "drifted", "namespace": "default"},
+		"data":       map[string]any{"key": "desired"},
+	}}
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme, current.DeepCopy())
+	gets := 0
+
+	dynamicClient.PrependReactor("get", "configmaps", func(clienttesting.Action) (bool, runtime.Object, error) {
+		gets++
+
+		return false, nil, nil
+	})
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{UID: "policy-uid"}}
+	objectT := &policyv1.ObjectTemplate{ComplianceType: policyv1.MustHave}
+	r := &ConfigurationPolicyReconciler{TargetK8sDynamicClient: dynamicClient}
+
+	// The watch cache strips the managedFields
+	existing := current.DeepCopy()
+	existing.SetManagedFields(nil)
+
+	obj := singleObject{
+		policy:      policy,
+		existingObj: existing,
+		desiredObj:  desired,
+		name:        "drifted",
+		namespace:   "default",
+		scopedGVR: depclient.ScopedGVR{
+			GroupVersionResource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			Namespaced:           true,
+		},
+	}
+
+	// The object was never observed to be compliant
+	assert.Nil(t, r.driftChangedBy(t.Context(), logr.Discard(), obj, objectT))
+	assert.Equal(t, 0, gets)
+
+	// The object didn't change since it was compliant, so the object template changed instead
+	r.recordCompliantObservation(policy, existing, objectT)
+	assert.Nil(t, r.driftChangedBy(t.Context(), logr.Discard(), obj, objectT))
+	assert.Equal(t, 0, gets)
+
+	compliant := existing.DeepCopy()
+	compliant.SetResourceVersion("1")
+	r.lastCompliantCache.Delete(policy.GetUID())
+	r.recordCompliantObservation(policy, compliant, objectT)
+
+	// Simulate the observation happening before the edit
+	policyMap, _ := r.lastCompliantCache.Load(policy.GetUID())
+	key := getEvalObjKey(compliant.GetUID(), objectT)
+	cached, _ := policyMap.(*sync.Map).Load(key)
+	observation := cached.(compliantObservation)
+	observation.observed = editTime.Add(-time.Minute)
+	policyMap.(*sync.Map).Store(key, observation)
+
+	changes := r.driftChangedBy(t.Context(), logr.Discard(), obj, objectT)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "kubectl-edit", changes[0].Manager)
+	}
+
+	assert.Equal(t, 1, gets)
+
+	// The attribution is cached for the resourceVersion of the object
+	assert.Equal(t, changes, r.driftChangedBy(t.Context(), logr.Discard(), obj, objectT))
+	assert.Equal(t, 1, gets)
+
+	obj.existingObj = existing.DeepCopy()
+	obj.existingObj.SetResourceVersion("3")
+	r.driftChangedBy(t.Context(), logr.Discard(), obj, objectT)
+	assert.Equal(t, 2, gets)
+}
+
+func TestPruneCompliantObservations(t *testing.T) {
+	t.Parallel()
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{UID: "policy-uid"}}
+	objectT := &policyv1.ObjectTemplate{ComplianceType: policyv1.MustHave}
+	r := &ConfigurationPolicyReconciler{}
+
+	newObject := func(uid types.UID) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetUID(uid)
+		obj.SetResourceVersion("1")
+
+		return obj
+	}
+
+	inScope := newObject("in-scope")
+	outOfScope := newObject("out-of-scope")
+
+	r.recordCompliantObservation(policy, inScope, objectT)
+	r.recordCompliantObservation(policy, outOfScope, objectT)
+
+	r.pruneCompliantObservations(policy, []policyv1.RelatedObject{
+		{Properties: &policyv1.ObjectProperties{UID: "in-scope"}},
+		{Object: policyv1.ObjectResource{Kind: "ConfigMap"}},
+	})
+
+	assert.NotNil(t, r.getCompliantObservation(policy, inScope, objectT))
+	assert.Nil(t, r.getCompliantObservation(policy, outOfScope, objectT))
+}
+
+func TestCreateStatusChangedBy(t *testing.T) {
+	t.Parallel()
+
+	editTime := metav1.NewTime(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	_, _, msg, _ := createStatus("configmaps", map[string]*objectTmplEvalResultWithEvent{
+		"default/drifted": {
+			result: objectTmplEvalResult{
+				objectNames: []string{"drifted"},
+				namespace:   "default",
+				changedBy: []policyv1.FieldManagerChange{
+					{Manager: "100%-manager", Operation: "Update", Time: &editTime},
+				},
+			},
+			event: objectTmplEvalEvent{false, reasonWantFoundNoMatch, policyv1.ReasonCodeMismatch, ""},
+		},
+	})
+
+	assert.Equal(t,
+		"configmaps [drifted] found but not as specified in namespace default, "+
+			"changed by 100%-manager (Update at 2024-01-01T12:00:00Z)",
+		msg,
+	)
+}
@@ -0,0 +1,564 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
+)
+
+const (
+	// evaluationCacheVersion is the version of the format of the persisted evaluation cache. A persisted
+	// cache with a different version is ignored.
+	evaluationCacheVersion = 1
+	// evaluationCacheKey is the key of the compressed evaluation cache in the ConfigMap binaryData.
+	evaluationCacheKey = "evaluation-cache.json.gz"
+	// evaluationCachePartitionPrefix is the prefix of the ConfigMap binaryData key of the evaluation cache
+	// of a partition, which is followed by the partition and the evaluationCachePartitionSuffix.
+	evaluationCachePartitionPrefix = "evaluation-cache-"
+	evaluationCachePartitionSuffix = ".json.gz"
+)
+
+// EvaluationCacheStore stores the persisted evaluation cache, which is compressed JSON. The evaluation
+// cache is split in partitions so that when the policies are sharded, each replica only writes the
+// results of the policies it owns to its own partition. The partition is empty when the policies aren't
+// sharded.
+type EvaluationCacheStore interface {
+	// Load returns the stored evaluation cache of every partition, which is empty if nothing was stored
+	// yet.
+	Load(ctx context.Context) (map[string][]byte, error)
+	// Save replaces the stored evaluation cache of the partition, and removes the stored evaluation caches
+	// of the partitions that aren't in activePartitions.
+	Save(ctx context.Context, partition string, data []byte, activePartitions []string) error
+}
+
+// FileEvaluationCacheStore stores the evaluation cache in a file, which should be on a persistent volume.
+// The evaluation cache of a partition is stored in the file with the partition appended to the path.
+type FileEvaluationCacheStore struct {
+	Path string
+}
+
+func (s *FileEvaluationCacheStore) partitionPath(partition string) string {
+	if partition == "" {
+		return s.Path
+	}
+
+	return s.Path + "." + partition
+}
+
+// partitions returns the partitions of the files in the directory of the path.
+func (s *FileEvaluationCacheStore) partitions() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(s.Path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	base := filepath.Base(s.Path)
+	partitions := []string{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		switch {
+		case entry.IsDir():
+			continue
+		case name == base:
+			partitions = append(partitions, "")
+		case strings.HasPrefix(name, base+".") && !strings.HasPrefix(name, base+".tmp-"):
+			partitions = append(partitions, strings.TrimPrefix(name, base+"."))
+		}
+	}
+
+	return partitions, nil
+}
+
+func (s *FileEvaluationCacheStore) Load(_ context.Context) (map[string][]byte, error) {
+	partitions, err := s.partitions()
+	if err != nil {
+		return nil, err
+	}
+
+	caches := make(map[string][]byte, len(partitions))
+
+	for _, partition := range partitions {
+		data, err := os.ReadFile(s.partitionPath(partition))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		caches[partition] = data
+	}
+
+	return caches, nil
+}
+
+// Save writes the evaluation cache to a temporary file which is renamed, so that the file is never
+// partially written if the controller is stopped while saving.
+func (s *FileEvaluationCacheStore) Save(
+	_ context.Context, partition string, data []byte, activePartitions []string,
+) error {
+	path := s.partitionPath(partition)
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(s.Path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmpFile.Name())
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpFile.Name(), path); err != nil {
+		return err
+	}
+
+	partitions, err := s.partitions()
+	if err != nil {
+		return err
+	}
+
+	for _, stored := range partitions {
+		if stored == partition || slices.Contains(activePartitions, stored) {
+			continue
+		}
+
+		if err := os.Remove(s.partitionPath(stored)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ConfigMapEvaluationCacheStore stores the evaluation cache in a ConfigMap. Note that a ConfigMap is
+// limited to 1 MiB, so a volume is preferred for a large number of policies and objects.
+type ConfigMapEvaluationCacheStore struct {
+	Client    kubernetes.Interface
+	Namespace string
+	Name      string
+}
+
+func evaluationCachePartitionKey(partition string) string {
+	if partition == "" {
+		return evaluationCacheKey
+	}
+
+	return evaluationCachePartitionPrefix + partition + evaluationCachePartitionSuffix
+}
+
+// evaluationCacheKeyPartition returns the partition of the ConfigMap binaryData key, and false if the key
+// isn't for an evaluation cache.
+func evaluationCacheKeyPartition(key string) (string, bool) {
+	if key == evaluationCacheKey {
+		return "", true
+	}
+
+	partition, found := strings.CutPrefix(key, evaluationCachePartitionPrefix)
+	if !found {
+		return "", false
+	}
+
+	partition, found = strings.CutSuffix(partition, evaluationCachePartitionSuffix)
+
+	return partition, found && partition != ""
+}
+
+func (s *ConfigMapEvaluationCacheStore) Load(ctx context.Context) (map[string][]byte, error) {
+	configMap, err := s.Client.CoreV1().ConfigMaps(s.Namespace).Get(ctx, s.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	caches := map[string][]byte{}
+
+	for key, data := range configMap.BinaryData {
+		if partition, ok := evaluationCacheKeyPartition(key); ok {
+			caches[partition] = data
+		}
+	}
+
+	return caches, nil
+}
+
+// Save updates the ConfigMap with the resourceVersion it was read at, so that a concurrent save of
+// another partition causes a conflict error rather than being overwritten.
+func (s *ConfigMapEvaluationCacheStore) Save(
+	ctx context.Context, partition string, data []byte, activePartitions []string,
+) error {
+	configMaps := s.Client.CoreV1().ConfigMaps(s.Namespace)
+	key := evaluationCachePartitionKey(partition)
+
+	configMap, err := configMaps.Get(ctx, s.Name, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.Name, Namespace: s.Namespace},
+			BinaryData: map[string][]byte{key: data},
+		}
+
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+
+		return err
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = map[string][]byte{}
+	}
+
+	for storedKey := range configMap.BinaryData {
+		stored, ok := evaluationCacheKeyPartition(storedKey)
+		if ok && stored != partition && !slices.Contains(activePartitions, stored) {
+			delete(configMap.BinaryData, storedKey)
+		}
+	}
+
+	configMap.BinaryData[key] = data
+
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+
+	return err
+}
+
+// persistedEvaluationCache is the format of the persisted evaluation cache. It has the results of
+// comparing objects to object templates from processedPolicyCache, which lets the controller skip the
+// comparisons of unchanged objects after it restarts. The other caches aren't persisted since they're
+// either cheap to rebuild or only relevant while the controller is running.
+type persistedEvaluationCache struct {
+	Version  int                                     `json:"version"`
+	Policies map[types.UID]persistedPolicyEvaluation `json:"policies"`
+}
+
+// persistedPolicyEvaluation has the evaluation results for a generation of a policy, keyed by the key
+// from getEvalObjKey, which is based on the object UID and the object template.
+type persistedPolicyEvaluation struct {
+	Generation int64                                `json:"generation"`
+	Objects    map[string]persistedEvaluationResult `json:"objects"`
+}
+
+type persistedEvaluationResult struct {
+	ResourceVersion string              `json:"resourceVersion"`
+	Compliant       bool                `json:"compliant,omitempty"`
+	Message         string              `json:"message,omitempty"`
+	ReasonCode      policyv1.ReasonCode `json:"reasonCode,omitempty"`
+}
+
+// evaluationCacheState is the state of persisting the evaluation cache of the reconciler.
+type evaluationCacheState struct {
+	lock sync.RWMutex
+	// restoredGenerations is the generation of each policy in the restored evaluation cache, and is nil
+	// when no evaluation cache was restored.
+	restoredGenerations map[types.UID]int64
+	// lastSavedHash is the hash of the last saved evaluation cache to skip saving an unchanged cache.
+	lastSavedHash [sha256.Size]byte
+	// lastSavedPartitions are the active partitions of the last save, since the evaluation cache must be
+	// saved again to remove the partitions that are no longer active.
+	lastSavedPartitions []string
+}
+
+// RestoreEvaluationCache loads the persisted evaluation cache from the EvaluationCacheStore into the
+// cache of evaluated objects. It must be called before the controller starts. Restored results are only
+// used while the policy generation and the object resourceVersion are unchanged.
+// Partitions that can't be decoded are skipped.
+func (r *ConfigurationPolicyReconciler) RestoreEvaluationCache(ctx context.Context) error {
+	if r.EvaluationCacheStore == nil {
+		return nil
+	}
+
+	caches, err := r.EvaluationCacheStore.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load the evaluation cache: %w", err)
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+
+	// A policy can be in several partitions if it moved between replicas, so the results of the latest
+	// generation are used
+	latest := map[types.UID]persistedPolicyEvaluation{}
+
+	for partition, data := range caches {
+		cache, err := decodeEvaluationCache(data)
+		if err != nil {
+			log.Error(err, "Skipping a corrupt partition of the evaluation cache", "partition", partition)
+
+			continue
+		}
+
+		for uid, policyEvaluation := range cache.Policies {
+			if restored, ok := latest[uid]; ok && restored.Generation >= policyEvaluation.Generation {
+				continue
+			}
+
+			latest[uid] = policyEvaluation
+		}
+	}
+
+	restoredGenerations := make(map[types.UID]int64, len(latest))
+
+	for uid, policyEvaluation := range latest {
+		policyMap := &sync.Map{}
+
+		for key, result := range policyEvaluation.Objects {
+			policyMap.Store(key, cachedEvaluationResult{
+				generation:      policyEvaluation.Generation,
+				resourceVersion: result.ResourceVersion,
+				compliant:       result.Compliant,
+				msg:             result.Message,
+				reasonCode:      result.ReasonCode,
+			})
+		}
+
+		r.processedPolicyCache.Store(uid, policyMap)
+		restoredGenerations[uid] = policyEvaluation.Generation
+	}
+
+	r.evaluationCache.lock.Lock()
+	r.evaluationCache.restoredGenerations = restoredGenerations
+	r.evaluationCache.lastSavedHash = sha256.Sum256(caches[r.evaluationCachePartition()])
+	r.evaluationCache.lock.Unlock()
+
+	log.Info("Restored the evaluation cache", "policies", len(restoredGenerations))
+
+	return nil
+}
+
+// evaluationCacheStale returns whether an evaluation cache was restored without results for the current
+// generation of the policy, which means the policy changed or was created while the controller was
+// stopped.
+func (r *ConfigurationPolicyReconciler) evaluationCacheStale(policy *policyv1.ConfigurationPolicy) bool {
+	r.evaluationCache.lock.RLock()
+	defer r.evaluationCache.lock.RUnlock()
+
+	if r.evaluationCache.restoredGenerations == nil {
+		return false
+	}
+
+	generation, ok := r.evaluationCache.restoredGenerations[policy.GetUID()]
+
+	return !ok || generation != policy.GetGeneration()
+}
+
+// evaluationCachePartition returns the partition of the EvaluationCacheStore this replica writes to, which
+// is the identity of the replica when the policies are sharded.
+func (r *ConfigurationPolicyReconciler) evaluationCachePartition() string {
+	if r.Shards == nil {
+		return ""
+	}
+
+	return r.Shards.Identity()
+}
+
+// saveEvaluationCache saves the results of the current generation of existing policies to the
+// EvaluationCacheStore if they changed since the last save. When the policies are sharded, only the
+// results of the policies owned by this replica are saved, to the partition of this replica, and the
+// partitions of replicas that are no longer active are removed.
+func (r *ConfigurationPolicyReconciler) saveEvaluationCache(ctx context.Context) error {
+	activePartitions := []string{""}
+
+	if r.Shards != nil {
+		// This replica doesn't own any policies until the replicas are known
+		if !r.Shards.IsReady() {
+			return nil
+		}
+
+		activePartitions = r.Shards.Members()
+	}
+
+	policies := &policyv1.ConfigurationPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		return fmt.Errorf("failed to list the policies to save the evaluation cache: %w", err)
+	}
+
+	generations := make(map[types.UID]int64, len(policies.Items))
+
+	for _, policy := range policies.Items {
+		if r.Shards != nil && !r.Shards.Owns(sharding.Key(policy.Namespace, policy.Name)) {
+			continue
+		}
+
+		generations[policy.GetUID()] = policy.GetGeneration()
+	}
+
+	cache := persistedEvaluationCache{
+		Version:  evaluationCacheVersion,
+		Policies: map[types.UID]persistedPolicyEvaluation{},
+	}
+
+	r.processedPolicyCache.Range(func(key, value any) bool {
+		uid, _ := key.(types.UID)
+
+		// Skip deleted policies so that the policies deleted while the controller was stopped are pruned, and
+		// the policies owned by other replicas
+		generation, ok := generations[uid]
+		if !ok {
+			return true
+		}
+
+		objects := map[string]persistedEvaluationResult{}
+
+		value.(*sync.Map).Range(func(objKey, objValue any) bool {
+			result, ok := objValue.(cachedEvaluationResult)
+			if ok && result.generation == generation && result.resourceVersion != "" {
+				objects[objKey.(string)] = persistedEvaluationResult{
+					ResourceVersion: result.resourceVersion,
+					Compliant:       result.compliant,
+					Message:         result.msg,
+					ReasonCode:      result.reasonCode,
+				}
+			}
+
+			return true
+		})
+
+		if len(objects) != 0 {
+			cache.Policies[uid] = persistedPolicyEvaluation{Generation: generation, Objects: objects}
+		}
+
+		return true
+	})
+
+	data, err := encodeEvaluationCache(cache)
+	if err != nil {
+		return err
+	}
+
+	hash := sha256.Sum256(data)
+
+	r.evaluationCache.lock.RLock()
+	unchanged := hash == r.evaluationCache.lastSavedHash &&
+		slices.Equal(activePartitions, r.evaluationCache.lastSavedPartitions)
+	r.evaluationCache.lock.RUnlock()
+
+	if unchanged {
+		return nil
+	}
+
+	if err := r.EvaluationCacheStore.Save(ctx, r.evaluationCachePartition(), data, activePartitions); err != nil {
+		return fmt.Errorf("failed to save the evaluation cache: %w", err)
+	}
+
+	r.evaluationCache.lock.Lock()
+	r.evaluationCache.lastSavedHash = hash
+	r.evaluationCache.lastSavedPartitions = activePartitions
+	r.evaluationCache.lock.Unlock()
+
+	return nil
+}
+
+// saveEvaluationCachePeriodically saves the evaluation cache every EvaluationCacheSaveInterval and when
+// the controller stops. It's a manager runnable, so it only runs on the leader, or on every replica when
+// the policies are sharded.
+func (r *ConfigurationPolicyReconciler) saveEvaluationCachePeriodically(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("evaluation-cache")
+
+	interval := r.EvaluationCacheSaveInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.saveEvaluationCache(ctx); err != nil {
+				log.Error(err, "Failed to save the evaluation cache, will try again")
+			}
+		case <-ctx.Done():
+			// Save the evaluation cache a final time with a new context since the input context is canceled
+			saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+			defer cancel()
+
+			if err := r.saveEvaluationCache(saveCtx); err != nil {
+				log.Error(err, "Failed to save the evaluation cache before stopping")
+			}
+
+			return nil
+		}
+	}
+}
+
+func encodeEvaluationCache(cache persistedEvaluationCache) ([]byte, error) {
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+
+	if err := json.NewEncoder(gzipWriter).Encode(cache); err != nil {
+		return nil, fmt.Errorf("failed to encode the evaluation cache: %w", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress the evaluation cache: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func decodeEvaluationCache(data []byte) (persistedEvaluationCache, error) {
+	cache := persistedEvaluationCache{}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return cache, fmt.Errorf("failed to decompress the evaluation cache: %w", err)
+	}
+
+	decoded, err := io.ReadAll(gzipReader)
+	if err != nil {
+		return cache, fmt.Errorf("failed to decompress the evaluation cache: %w", err)
+	}
+
+	if err := json.Unmarshal(decoded, &cache); err != nil {
+		return cache, fmt.Errorf("failed to decode the evaluation cache: %w", err)
+	}
+
+	if cache.Version != evaluationCacheVersion {
+		return persistedEvaluationCache{}, nil
+	}
+
+	return cache, nil
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	fakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
+)
+
+func TestEvaluationCacheRestore(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, policyv1.AddToScheme(scheme))
+
+	newPolicy := func(name string, uid types.UID, generation int64) *policyv1.ConfigurationPolicy {
+		return &policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "managed", UID: uid, Generation: generation},
+		}
+	}
+
+	unchanged := newPolicy("unchanged", "uid-unchanged", 1)
+	updated := newPolicy("updated", "uid-updated", 1)
+	deleted := newPolicy("deleted", "uid-deleted", 1)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetUID("uid-object")
+	obj.SetResourceVersion("10")
+
+	objectT := &policyv1.ObjectTemplate{ComplianceType: policyv1.MustHave}
+
+	stores := map[string]EvaluationCacheStore{
+		"file":      &FileEvaluationCacheStore{Path: filepath.Join(t.TempDir(), "evaluation-cache.json.gz")},
+		"configmap": &ConfigMapEvaluationCacheStore{Client: fake.NewClientset(), Namespace: "ns", Name: "cache"},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			before := &ConfigurationPolicyReconciler{
+				Client: fakeclient.NewClientBuilder().WithScheme(scheme).
+					WithObjects(unchanged, updated).Build(),
+				EvaluationCacheStore: store,
+			}
+
+			// Nothing is stored yet
+			assert.NoError(t, before.RestoreEvaluationCache(t.Context()))
+
+			before.setEvaluatedObject(unchanged, obj, objectT, false, "not compliant", policyv1.ReasonCodeUpdateFailed)
+			before.setEvaluatedObject(updated, obj, objectT, true, "", "")
+			before.setEvaluatedObject(deleted, obj, objectT, true, "", "")
+
+			assert.NoError(t, before.saveEvaluationCache(t.Context()))
+
+			updatedV2 := newPolicy("updated", "uid-updated", 2)
+			created := newPolicy("created", "uid-created", 1)
+
+			after := &ConfigurationPolicyReconciler{
+				Client: fakeclient.NewClientBuilder().WithScheme(scheme).
+					WithObjects(unchanged, updatedV2, created).Build(),
+				EvaluationCacheStore: store,
+			}
+
+			assert.NoError(t, after.RestoreEvaluationCache(t.Context()))
+
+			evaluated, compliant, msg, reasonCode := after.alreadyEvaluated(unchanged, obj, objectT)
+			assert.True(t, evaluated)
+			assert.False(t, compliant)
+			assert.Equal(t, "not compliant", msg)
+			assert.Equal(t, policyv1.ReasonCodeUpdateFailed, reasonCode)
+
+			evaluated, _, _, _ = after.alreadyEvaluated(updatedV2, obj, objectT)
+			assert.False(t, evaluated, "the results of a previous generation must not be used")
+
+			changedObj := obj.DeepCopy()
+			changedObj.SetResourceVersion("11")
+
+			evaluated, _, _, _ = after.alreadyEvaluated(unchanged, changedObj, objectT)
+			assert.False(t, evaluated, "the results of a previous resourceVersion must not be used")
+
+			_, loaded := after.processedPolicyCache.Load(deleted.GetUID())
+			assert.False(t, loaded, "the results of deleted policies must be pruned")
+
+			assert.False(t, after.evaluationCacheStale(unchanged))
+			assert.True(t, after.evaluationCacheStale(updatedV2))
+			assert.True(t, after.evaluationCacheStale(created))
+		})
+	}
+}
+
+func TestEvaluationCacheNotRestored(t *testing.T) {
+	t.Parallel()
+
+	r := &ConfigurationPolicyReconciler{}
+
+	assert.NoError(t, r.RestoreEvaluationCache(t.Context()))
+	assert.False(t, r.evaluationCacheStale(&policyv1.ConfigurationPolicy{}))
+}
+
+func TestEvaluationCacheCorruptPartition(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, policyv1.AddToScheme(scheme))
+
+	saved := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "saved", Namespace: "managed", UID: "uid-saved", Generation: 1},
+	}
+	created := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "created", Namespace: "managed", UID: "uid-created", Generation: 1},
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetUID("uid-object")
+	obj.SetResourceVersion("10")
+
+	objectT := &policyv1.ObjectTemplate{ComplianceType: policyv1.MustHave}
+	store := &ConfigMapEvaluationCacheStore{Client: fake.NewClientset(), Namespace: "ns", Name: "cache"}
+
+	before := &ConfigurationPolicyReconciler{
+		Client:               fakeclient.NewClientBuilder().WithScheme(scheme).WithObjects(saved).Build(),
+		EvaluationCacheStore: store,
+	}
+
+	before.setEvaluatedObject(saved, obj, objectT, true, "", "")
+	assert.NoError(t, before.saveEvaluationCache(t.Context()))
+	assert.NoError(t, store.Save(t.Context(), "replica-b", []byte("corrupt"), []string{"", "replica-b"}))
+
+	after := &ConfigurationPolicyReconciler{EvaluationCacheStore: store}
+	assert.NoError(t, after.RestoreEvaluationCache(t.Context()))
+
+	evaluated, compliant, _, _ := after.alreadyEvaluated(saved, obj, objectT)
+	assert.True(t, evaluated, "the results of the valid partition are restored")
+	assert.True(t, compliant)
+	assert.False(t, after.evaluationCacheStale(saved))
+	assert.True(t, after.evaluationCacheStale(created))
+}
+
+func TestEvaluationCacheSharded(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, policyv1.AddToScheme(scheme))
+
+	policies := make([]client.Object, 20)
+	for i := range policies {
+		policies[i] = &policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("policy-%d", i), Namespace: "managed", UID: types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+		}
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetUID("uid-object")
+	obj.SetResourceVersion("10")
+
+	objectT := &policyv1.ObjectTemplate{ComplianceType: policyv1.MustHave}
+
+	stores := map[string]EvaluationCacheStore{
+		"file":      &FileEvaluationCacheStore{Path: filepath.Join(t.TempDir(), "evaluation-cache.json.gz")},
+		"configmap": &ConfigMapEvaluationCacheStore{Client: fake.NewClientset(), Namespace: "ns", Name: "cache"},
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			leaseClient := fake.NewClientset()
+
+			startReplica := func(ctx context.Context, identity string) *ConfigurationPolicyReconciler {
+				coordinator := sharding.NewCoordinator(leaseClient, "ns", "test", identity)
+				coordinator.RenewInterval = 10 * time.Millisecond
+
+				go func() { _ = coordinator.Start(ctx) }()
+
+				r := &ConfigurationPolicyReconciler{
+					Client: fakeclient.NewClientBuilder().WithScheme(scheme).
+						WithObjects(policies...).Build(),
+					EvaluationCacheStore: store,
+					Shards:               coordinator,
+				}
+
+				// Each replica has results for every policy, such as for the policies it owned before
+				for _, policy := range policies {
+					r.setEvaluatedObject(policy.(*policyv1.ConfigurationPolicy), obj, objectT, true, "", "")
+				}
+
+				return r
+			}
+
+			ctxB, cancelB := context.WithCancel(t.Context())
+			defer cancelB()
+
+			replicaA := startReplica(t.Context(), "replica-a")
+			replicaB := startReplica(ctxB, "replica-b")
+
+			for _, r := range []*ConfigurationPolicyReconciler{replicaA, replicaB} {
+				assert.Eventually(t, func() bool {
+					return len(r.Shards.Members()) == 2
+				}, 5*time.Second, 10*time.Millisecond)
+
+				assert.NoError(t, r.saveEvaluationCache(t.Context()))
+			}
+
+			caches, err := store.Load(t.Context())
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			assert.Len(t, caches, 2)
+
+			// Each replica only saved the policies it owns
+			for identity, data := range caches {
+				cache, err := decodeEvaluationCache(data)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				assert.NotEmpty(t, cache.Policies)
+
+				for _, policy := range policies {
+					_, saved := cache.Policies[policy.GetUID()]
+					owner := replicaA.Shards.Owner(sharding.Key(policy.GetNamespace(), policy.GetName()))
+					assert.Equal(t, owner == identity, saved, policy.GetName())
+				}
+			}
+
+			// A restarted replica restores the results of every partition
+			restored := &ConfigurationPolicyReconciler{EvaluationCacheStore: store}
+			assert.NoError(t, restored.RestoreEvaluationCache(t.Context()))
+
+			for _, policy := range policies {
+				evaluated, _, _, _ := restored.alreadyEvaluated(policy.(*policyv1.ConfigurationPolicy), obj, objectT)
+				assert.True(t, evaluated, policy.GetName())
+			}
+
+			// The partition of a stopped replica is removed
+			cancelB()
+
+			assert.Eventually(t, func() bool {
+				return len(replicaA.Shards.Members()) == 1
+			}, 5*time.Second, 10*time.Millisecond)
+
+			assert.NoError(t, replicaA.saveEvaluationCache(t.Context()))
+
+			caches, err = store.Load(t.Context())
+			assert.NoError(t, err)
+			assert.Len(t, caches, 1)
+			assert.Contains(t, caches, "replica-a")
+		})
+	}
+}
@@ -0,0 +1,317 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"math/bits"
+	"strings"
+	"sync"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+const (
+	// maxAgeBucket is the highest priority bucket for the age since the last evaluation. The buckets
+	// are powers of two in minutes, so the highest bucket is for policies not evaluated in over ~4 hours
+	// or never evaluated.
+	maxAgeBucket = 9
+	// compliancePriority is added to the priority of policies that are not compliant. It is higher
+	// than any age bucket so that age only orders policies with the same compliance.
+	compliancePriority = maxAgeBucket + 1
+	// severityPriority is the multiplier of the severity rank so that the severity takes precedence
+	// over the compliance and age.
+	severityPriority = 10 * compliancePriority
+	// stalePriority is added to the priority of policies without results in the evaluation cache restored
+	// after a restart until they're evaluated. It's higher than any severity so that policies which changed
+	// while the controller was stopped are evaluated before the policies with cached results.
+	stalePriority = 5 * severityPriority
+)
+
+// policyPriorityInfo is what the evaluation queue orders policies by.
+type policyPriorityInfo struct {
+	severity   policyv1.Severity
+	compliance policyv1.ComplianceState
+	// lastEvaluated is the zero time when it's unknown, in which case the last time the policy left the
+	// queue is used.
+	lastEvaluated time.Time
+	// stale is whether the restored evaluation cache has no results for the current policy generation.
+	stale bool
+}
+
+// priorityInfoFunc returns the priority information of the policy in the request. False is returned
+// when the policy no longer exists.
+type priorityInfoFunc func(ctx context.Context, request reconcile.Request) (policyPriorityInfo, bool)
+
+// configPolicyPriorityInfo returns a priorityInfoFunc that reads the ConfigurationPolicy from the
+// client cache. The optional stale function returns whether the restored evaluation cache is stale for
+// the policy.
+func configPolicyPriorityInfo(
+	c client.Client, stale func(*policyv1.ConfigurationPolicy) bool,
+) priorityInfoFunc {
+	return func(ctx context.Context, request reconcile.Request) (policyPriorityInfo, bool) {
+		policy := &policyv1.ConfigurationPolicy{}
+
+		if err := c.Get(ctx, request.NamespacedName, policy); err != nil {
+			return policyPriorityInfo{}, !k8serrors.IsNotFound(err)
+		}
+
+		info := policyPriorityInfo{severity: policy.Spec.Severity, compliance: policy.Status.ComplianceState}
+
+		if lastEvaluated, err := time.Parse(time.RFC3339, policy.Status.LastEvaluated); err == nil {
+			info.lastEvaluated = lastEvaluated
+		}
+
+		if stale != nil {
+			info.stale = stale(policy)
+		}
+
+		return info, true
+	}
+}
+
+// operatorPolicyPriorityInfo returns a priorityInfoFunc that reads the OperatorPolicy from the client
+// cache. The OperatorPolicy status doesn't record the last evaluation, so the evaluation queue tracks it.
+func operatorPolicyPriorityInfo(c client.Client) priorityInfoFunc {
+	return func(ctx context.Context, request reconcile.Request) (policyPriorityInfo, bool) {
+		policy := &policyv1beta1.OperatorPolicy{}
+
+		if err := c.Get(ctx, request.NamespacedName, policy); err != nil {
+			return policyPriorityInfo{}, !k8serrors.IsNotFound(err)
+		}
+
+		return policyPriorityInfo{severity: policy.Spec.Severity, compliance: policy.Status.ComplianceState}, true
+	}
+}
+
+// severityRank returns a higher value for a higher severity. An unset or unknown severity is ranked
+// the lowest.
+func severityRank(severity policyv1.Severity) int {
+	switch strings.ToLower(string(severity)) {
+	case "critical":
+		return 4
+	case "high":
+		return 3
+	case "medium":
+		return 2
+	case "low":
+		return 1
+	}
+
+	return 0
+}
+
+// priorityClass returns the metric label for the severity and compliance of the policy.
+func (info policyPriorityInfo) priorityClass() string {
+	severity := strings.ToLower(string(info.severity))
+	if severityRank(info.severity) == 0 {
+		severity = "none"
+	}
+
+	if info.compliance == policyv1.Compliant {
+		return severity + "-compliant"
+	}
+
+	return severity + "-noncompliant"
+}
+
+// priority returns the queue priority of the policy. Policies are ordered by severity, then
+// noncompliant (including not yet evaluated) before compliant, then by the age since the last
+// evaluation in power of two buckets of minutes so that the number of distinct priorities is small.
+// Stale policies that haven't been reconciled since the controller started come first.
+func (info policyPriorityInfo) priority(lastDone time.Time, now time.Time) int {
+	priority := severityRank(info.severity) * severityPriority
+
+	if info.stale && lastDone.IsZero() {
+		priority += stalePriority
+	}
+
+	if info.compliance != policyv1.Compliant {
+		priority += compliancePriority
+	}
+
+	lastEvaluated := info.lastEvaluated
+	if lastEvaluated.IsZero() {
+		lastEvaluated = lastDone
+	}
+
+	if lastEvaluated.IsZero() {
+		return priority + maxAgeBucket
+	}
+
+	ageMinutes := max(now.Sub(lastEvaluated), 0) / time.Minute
+
+	return priority + min(bits.Len64(uint64(ageMinutes)), maxAgeBucket)
+}
+
+// queuedPolicy tracks a policy in the evaluation queue for the metrics.
+type queuedPolicy struct {
+	readyAt  time.Time
+	priority int
+	class    string
+}
+
+// evaluationQueue is a priority queue of policy reconcile requests. It wraps the controller-runtime
+// priority queue and overrides the priority of every added request based on the policy, and records
+// the depth and wait time metrics by priority class.
+type evaluationQueue struct {
+	priorityqueue.PriorityQueue[reconcile.Request]
+	controllerName string
+	rateLimiter    workqueue.TypedRateLimiter[reconcile.Request]
+	getInfo        priorityInfoFunc
+	now            func() time.Time
+	lock           sync.Mutex
+	queued         map[reconcile.Request]queuedPolicy
+	// lastDone is the last time each policy finished reconciling and is used for the age when the policy
+	// doesn't record its last evaluation.
+	lastDone map[reconcile.Request]time.Time
+}
+
+// newEvaluationQueue returns a function for the NewQueue controller option that creates an
+// evaluationQueue with the input priorityInfoFunc.
+func newEvaluationQueue(getInfo priorityInfoFunc) func(
+	string, workqueue.TypedRateLimiter[reconcile.Request],
+) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return func(
+		controllerName string, rateLimiter workqueue.TypedRateLimiter[reconcile.Request],
+	) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return &evaluationQueue{
+			PriorityQueue: priorityqueue.New(controllerName, func(o *priorityqueue.Opts[reconcile.Request]) {
+				o.RateLimiter = rateLimiter
+			}),
+			controllerName: controllerName,
+			rateLimiter:    rateLimiter,
+			getInfo:        getInfo,
+			now:            time.Now,
+			queued:         map[reconcile.Request]queuedPolicy{},
+			lastDone:       map[reconcile.Request]time.Time{},
+		}
+	}
+}
+
+func (q *evaluationQueue) Add(item reconcile.Request) {
+	q.AddWithOpts(priorityqueue.AddOpts{}, item)
+}
+
+func (q *evaluationQueue) AddAfter(item reconcile.Request, duration time.Duration) {
+	q.AddWithOpts(priorityqueue.AddOpts{After: duration}, item)
+}
+
+func (q *evaluationQueue) AddRateLimited(item reconcile.Request) {
+	q.AddWithOpts(priorityqueue.AddOpts{RateLimited: true}, item)
+}
+
+// AddWithOpts adds the requests with the priority of the policy, ignoring the input priority. The
+// rate limiting delay is determined here rather than by the wrapped queue so that the time the
+// request is ready is known for the wait time metric.
+func (q *evaluationQueue) AddWithOpts(opts priorityqueue.AddOpts, items ...reconcile.Request) {
+	if q.ShuttingDown() {
+		return
+	}
+
+	for _, item := range items {
+		itemOpts := opts
+
+		if itemOpts.RateLimited {
+			itemOpts.RateLimited = false
+
+			if rlAfter := q.rateLimiter.When(item); itemOpts.After == 0 || rlAfter < itemOpts.After {
+				itemOpts.After = rlAfter
+			}
+		}
+
+		info, exists := q.getInfo(context.TODO(), item)
+		now := q.now()
+
+		q.lock.Lock()
+
+		if !exists {
+			delete(q.lastDone, item)
+		}
+
+		priority := info.priority(q.lastDone[item], now)
+		itemOpts.Priority = &priority
+
+		tracked := queuedPolicy{
+			readyAt:  now.Add(max(itemOpts.After, 0)),
+			priority: priority,
+			class:    info.priorityClass(),
+		}
+
+		if existing, ok := q.queued[item]; ok {
+			if existing.readyAt.Before(tracked.readyAt) {
+				tracked.readyAt = existing.readyAt
+			}
+
+			// The wrapped queue keeps the highest priority of the additions
+			if existing.priority >= priority {
+				tracked.priority = existing.priority
+				tracked.class = existing.class
+			} else {
+				evaluationQueueDepthGauge.WithLabelValues(q.controllerName, existing.class).Dec()
+				evaluationQueueDepthGauge.WithLabelValues(q.controllerName, tracked.class).Inc()
+			}
+		} else {
+			evaluationQueueDepthGauge.WithLabelValues(q.controllerName, tracked.class).Inc()
+		}
+
+		q.queued[item] = tracked
+
+		q.lock.Unlock()
+
+		q.PriorityQueue.AddWithOpts(itemOpts, item)
+	}
+}
+
+func (q *evaluationQueue) Get() (reconcile.Request, bool) {
+	item, _, shutdown := q.GetWithPriority()
+
+	return item, shutdown
+}
+
+func (q *evaluationQueue) GetWithPriority() (reconcile.Request, int, bool) {
+	item, priority, shutdown := q.PriorityQueue.GetWithPriority()
+	if shutdown {
+		return item, priority, shutdown
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if tracked, ok := q.queued[item]; ok {
+		delete(q.queued, item)
+
+		evaluationQueueDepthGauge.WithLabelValues(q.controllerName, tracked.class).Dec()
+		evaluationQueueWaitSeconds.WithLabelValues(q.controllerName, tracked.class).Observe(
+			max(q.now().Sub(tracked.readyAt), 0).Seconds(),
+		)
+	}
+
+	return item, priority, shutdown
+}
+
+// Done records when the policy finished reconciling. The time isn't recorded when the policy no longer
+// exists so that deleted policies aren't tracked indefinitely.
+func (q *evaluationQueue) Done(item reconcile.Request) {
+	_, exists := q.getInfo(context.TODO(), item)
+
+	q.lock.Lock()
+
+	if exists {
+		q.lastDone[item] = q.now()
+	} else {
+		delete(q.lastDone, item)
+	}
+
+	q.lock.Unlock()
+
+	q.PriorityQueue.Done(item)
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestPolicyPriority(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+
+	tests := map[string]struct {
+		info          policyPriorityInfo
+		lastDone      time.Time
+		expected      int
+		expectedClass string
+	}{
+		"critical never evaluated": {
+			info:          policyPriorityInfo{severity: "critical"},
+			expected:      4*severityPriority + compliancePriority + maxAgeBucket,
+			expectedClass: "critical-noncompliant",
+		},
+		"high compliant evaluated recently": {
+			info:          policyPriorityInfo{severity: "High", compliance: policyv1.Compliant, lastEvaluated: now},
+			expected:      3 * severityPriority,
+			expectedClass: "high-compliant",
+		},
+		"low noncompliant evaluated 5 minutes ago": {
+			info: policyPriorityInfo{
+				severity: "low", compliance: policyv1.NonCompliant, lastEvaluated: now.Add(-5 * time.Minute),
+			},
+			expected:      severityPriority + compliancePriority + 3,
+			expectedClass: "low-noncompliant",
+		},
+		"unset severity uses the last time the policy left the queue": {
+			info:          policyPriorityInfo{compliance: policyv1.Compliant},
+			lastDone:      now.Add(-time.Minute),
+			expected:      1,
+			expectedClass: "none-compliant",
+		},
+		"age is capped": {
+			info:          policyPriorityInfo{severity: "medium", lastEvaluated: now.Add(-240 * time.Hour)},
+			expected:      2*severityPriority + compliancePriority + maxAgeBucket,
+			expectedClass: "medium-noncompliant",
+		},
+		"stale policy before any severity": {
+			info:          policyPriorityInfo{compliance: policyv1.Compliant, lastEvaluated: now, stale: true},
+			expected:      stalePriority,
+			expectedClass: "none-compliant",
+		},
+		"stale policy already reconciled": {
+			info:          policyPriorityInfo{compliance: policyv1.Compliant, lastEvaluated: now, stale: true},
+			lastDone:      now,
+			expected:      0,
+			expectedClass: "none-compliant",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, test.info.priority(test.lastDone, now))
+			assert.Equal(t, test.expectedClass, test.info.priorityClass())
+		})
+	}
+}
+
+func TestEvaluationQueueOrder(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, policyv1.AddToScheme(scheme))
+
+	lastEvaluated := func(ago time.Duration) string {
+		return time.Now().Add(-ago).UTC().Format(time.RFC3339)
+	}
+
+	policies := []client.Object{
+		&policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "low-noncompliant", Namespace: "managed"},
+			Spec:       policyv1.ConfigurationPolicySpec{Severity: "low"},
+			Status: policyv1.ConfigurationPolicyStatus{
+				ComplianceState: policyv1.NonCompliant, LastEvaluated: lastEvaluated(time.Hour),
+			},
+		},
+		&policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "critical-compliant", Namespace: "managed"},
+			Spec:       policyv1.ConfigurationPolicySpec{Severity: "critical"},
+			Status: policyv1.ConfigurationPolicyStatus{
+				ComplianceState: policyv1.Compliant, LastEvaluated: lastEvaluated(time.Hour),
+			},
+		},
+		&policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "critical-noncompliant-recent", Namespace: "managed"},
+			Spec:       policyv1.ConfigurationPolicySpec{Severity: "critical"},
+			Status: policyv1.ConfigurationPolicyStatus{
+				ComplianceState: policyv1.NonCompliant, LastEvaluated: lastEvaluated(0),
+			},
+		},
+		&policyv1.ConfigurationPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "critical-noncompliant-old", Namespace: "managed"},
+			Spec:       policyv1.ConfigurationPolicySpec{Severity: "critical"},
+			Status: policyv1.ConfigurationPolicyStatus{
+				ComplianceState: policyv1.NonCompliant, LastEvaluated: lastEvaluated(time.Hour),
+			},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(policies...).Build()
+
+	queue := newEvaluationQueue(configPolicyPriorityInfo(fakeClient, nil))(
+		"test-evaluation-queue", workqueue.DefaultTypedControllerRateLimiter[reconcile.Request](),
+	)
+	defer queue.ShutDown()
+
+	for _, policy := range policies {
+		queue.Add(reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: "managed", Name: policy.GetName()},
+		})
+	}
+
+	assert.Eventually(t, func() bool { return queue.Len() == len(policies) }, 5*time.Second, 10*time.Millisecond)
+
+	expected := []string{
+		"critical-noncompliant-old", "critical-noncompliant-recent", "critical-compliant", "low-noncompliant",
+	}
+
+	for _, name := range expected {
+		item, shutdown := queue.Get()
+		assert.False(t, shutdown)
+		assert.Equal(t, name, item.Name)
+
+		queue.Done(item)
+	}
+}
+
+func TestEvaluationQueueDoneDeleted(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, policyv1.AddToScheme(scheme))
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "managed"}}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(policy).Build()
+
+	queue := newEvaluationQueue(configPolicyPriorityInfo(fakeClient, nil))(
+		"test-evaluation-queue-deleted", workqueue.DefaultTypedControllerRateLimiter[reconcile.Request](),
+	)
+	defer queue.ShutDown()
+
+	existing := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "managed", Name: "existing"}}
+	deleted := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "managed", Name: "deleted"}}
+
+	for _, item := range []reconcile.Request{existing, deleted} {
+		queue.Add(item)
+	}
+
+	assert.Eventually(t, func() bool { return queue.Len() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	for range 2 {
+		item, shutdown := queue.Get()
+		assert.False(t, shutdown)
+
+		queue.Done(item)
+	}
+
+	lastDone := queue.(*evaluationQueue).lastDone
+
+	// The deleted policy isn't tracked after its reconcile finishes
+	assert.Contains(t, lastDone, existing)
+	assert.NotContains(t, lastDone, deleted)
+}
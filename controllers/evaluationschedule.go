@@ -0,0 +1,83 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"hash/fnv"
+	"time"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// getNextEvaluation returns the time of the next scheduled evaluation of the policy after the last
+// evaluation, based on the evaluation interval for the current compliance state of the policy. The
+// noncompliant interval is used when the compliance state is not compliant. A jitter of up to the
+// configured percentage of the interval is added so that policies with the same interval aren't all
+// evaluated at the same time, such as after the controller restarts. ErrIsNever and ErrIsWatch are
+// returned when the policy is not scheduled for evaluation.
+func (r *ConfigurationPolicyReconciler) getNextEvaluation(
+	policy *policyv1.ConfigurationPolicy, lastEvaluated time.Time,
+) (time.Time, error) {
+	getNext := policy.Spec.EvaluationInterval.GetNonCompliantNextEvaluation
+	if policy.Status.ComplianceState == policyv1.Compliant {
+		getNext = policy.Spec.EvaluationInterval.GetCompliantNextEvaluation
+	}
+
+	next, err := getNext(lastEvaluated)
+	if err != nil {
+		return next, err
+	}
+
+	jitterPercent := int32(r.EvaluationJitterPercent)
+	if policy.Spec.EvaluationInterval.JitterPercent != nil {
+		jitterPercent = *policy.Spec.EvaluationInterval.JitterPercent
+	}
+
+	if jitterPercent <= 0 {
+		return next, nil
+	}
+
+	// Use the interval after the next evaluation so that the jitter is relative to the period of a cron
+	// expression rather than how close the last evaluation was to the next scheduled time.
+	following, err := getNext(next)
+	if err != nil {
+		return next, nil //nolint:nilerr
+	}
+
+	interval := following.Sub(next)
+	if interval <= 0 {
+		return next, nil
+	}
+
+	maxJitter := interval * time.Duration(min(jitterPercent, 100)) / 100
+
+	return next.Add(time.Duration(jitterFraction(policy, lastEvaluated) * float64(maxJitter))), nil
+}
+
+// jitterFraction returns a number between 0 and 1 derived from the policy UID and the last
+// evaluation time, so that the jitter is consistent between the requeue after the evaluation and
+// the check for whether the policy is ready to be evaluated, but varies between policies.
+func jitterFraction(policy *policyv1.ConfigurationPolicy, lastEvaluated time.Time) float64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(policy.GetUID()))
+	_, _ = hash.Write([]byte(lastEvaluated.UTC().Format(time.RFC3339)))
+
+	return float64(hash.Sum64()%10000) / 10000
+}
+
+// getNextEvaluationStatus returns the value of the status.nextEvaluation field based on the
+// status.lastEvaluated field. An empty string is returned when the policy is not scheduled for
+// evaluation.
+func (r *ConfigurationPolicyReconciler) getNextEvaluationStatus(policy *policyv1.ConfigurationPolicy) string {
+	lastEvaluated, err := time.Parse(time.RFC3339, policy.Status.LastEvaluated)
+	if err != nil {
+		return ""
+	}
+
+	next, err := r.getNextEvaluation(policy, lastEvaluated)
+	if err != nil {
+		return ""
+	}
+
+	return next.UTC().Format(time.RFC3339)
+}
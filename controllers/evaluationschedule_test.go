@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestGetNextEvaluation(t *testing.T) {
+	t.Parallel()
+
+	lastEvaluated := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+	fiftyPercent := int32(50)
+	noJitter := int32(0)
+
+	tests := map[string]struct {
+		interval            policyv1.EvaluationInterval
+		complianceState     policyv1.ComplianceState
+		controllerJitter    uint8
+		expectedEarliest    time.Time
+		expectedLatest      time.Time
+		expectedErr         error
+		expectedStatusEmpty bool
+	}{
+		"duration without jitter": {
+			interval:         policyv1.EvaluationInterval{Compliant: "1h", NonCompliant: "10m"},
+			complianceState:  policyv1.Compliant,
+			expectedEarliest: lastEvaluated.Add(time.Hour),
+			expectedLatest:   lastEvaluated.Add(time.Hour),
+		},
+		"noncompliant interval when the compliance is unknown": {
+			interval:         policyv1.EvaluationInterval{Compliant: "1h", NonCompliant: "10m"},
+			expectedEarliest: lastEvaluated.Add(10 * time.Minute),
+			expectedLatest:   lastEvaluated.Add(10 * time.Minute),
+		},
+		"duration with the controller jitter": {
+			interval:         policyv1.EvaluationInterval{Compliant: "1h"},
+			complianceState:  policyv1.Compliant,
+			controllerJitter: 10,
+			expectedEarliest: lastEvaluated.Add(time.Hour),
+			expectedLatest:   lastEvaluated.Add(66 * time.Minute),
+		},
+		"the policy jitter overrides the controller jitter": {
+			interval:         policyv1.EvaluationInterval{Compliant: "1h", JitterPercent: &noJitter},
+			complianceState:  policyv1.Compliant,
+			controllerJitter: 10,
+			expectedEarliest: lastEvaluated.Add(time.Hour),
+			expectedLatest:   lastEvaluated.Add(time.Hour),
+		},
+		"cron with jitter relative to the cron period": {
+			interval:         policyv1.EvaluationInterval{NonCompliant: "cron:0 */6 * * *", JitterPercent: &fiftyPercent},
+			complianceState:  policyv1.NonCompliant,
+			expectedEarliest: time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC),
+			expectedLatest:   time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		},
+		"watch": {
+			interval:            policyv1.EvaluationInterval{Compliant: "watch"},
+			complianceState:     policyv1.Compliant,
+			controllerJitter:    10,
+			expectedErr:         policyv1.ErrIsWatch,
+			expectedStatusEmpty: true,
+		},
+		"never": {
+			interval:            policyv1.EvaluationInterval{NonCompliant: "never"},
+			complianceState:     policyv1.NonCompliant,
+			expectedErr:         policyv1.ErrIsNever,
+			expectedStatusEmpty: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &ConfigurationPolicyReconciler{EvaluationJitterPercent: test.controllerJitter}
+			policy := &policyv1.ConfigurationPolicy{
+				ObjectMeta: metav1.ObjectMeta{UID: types.UID("policy-" + name)},
+				Spec:       policyv1.ConfigurationPolicySpec{EvaluationInterval: test.interval},
+				Status: policyv1.ConfigurationPolicyStatus{
+					ComplianceState: test.complianceState,
+					LastEvaluated:   lastEvaluated.Format(time.RFC3339),
+				},
+			}
+
+			next, err := r.getNextEvaluation(policy, lastEvaluated)
+			status := r.getNextEvaluationStatus(policy)
+
+			if test.expectedErr != nil {
+				assert.ErrorIs(t, err, test.expectedErr)
+				assert.Empty(t, status)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.False(t, next.Before(test.expectedEarliest), "expected %s to not be before %s",
+				next, test.expectedEarliest)
+			assert.False(t, next.After(test.expectedLatest), "expected %s to not be after %s",
+				next, test.expectedLatest)
+			assert.Equal(t, next.Truncate(time.Second).Format(time.RFC3339), status)
+
+			// The jitter must be consistent so that the requeue matches the check before the evaluation
+			again, _ := r.getNextEvaluation(policy, lastEvaluated)
+			assert.True(t, next.Equal(again))
+		})
+	}
+}
+
+func TestJitterFractionVariesByPolicy(t *testing.T) {
+	t.Parallel()
+
+	lastEvaluated := time.Date(2024, 1, 1, 5, 30, 0, 0, time.UTC)
+	fractions := map[float64]bool{}
+
+	for _, uid := range []types.UID{"a", "b", "c", "d", "e"} {
+		policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{UID: uid}}
+		fraction := jitterFraction(policy, lastEvaluated)
+
+		assert.GreaterOrEqual(t, fraction, 0.0)
+		assert.Less(t, fraction, 1.0)
+
+		fractions[fraction] = true
+	}
+
+	assert.Greater(t, len(fractions), 1)
+}
@@ -0,0 +1,114 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	argoCDTrackingIDAnnotation  = "argocd.argoproj.io/tracking-id"
+	argoCDInstanceLabel         = "argocd.argoproj.io/instance"
+	fluxKustomizeNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizeNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	fluxHelmNameLabel           = "helm.toolkit.fluxcd.io/name"
+	fluxHelmNamespaceLabel      = "helm.toolkit.fluxcd.io/namespace"
+	fluxReconcileAnnotation     = "kustomize.toolkit.fluxcd.io/reconcile"
+)
+
+// gitOpsFieldManagers maps the field managers used by the GitOps tools to the name of the tool.
+var gitOpsFieldManagers = map[string]string{
+	"argocd-controller":             "Argo CD",
+	"argocd-application-controller": "Argo CD",
+	"kustomize-controller":          "Flux",
+	"helm-controller":               "Flux",
+}
+
+// gitOpsOwner identifies the GitOps tool, and the resource of that tool when known, that manages an
+// object on the cluster.
+type gitOpsOwner struct {
+	// tool is the name of the GitOps tool, such as "Argo CD" or "Flux".
+	tool string
+	// kind is the kind of the GitOps resource managing the object, such as "Application". It is empty
+	// when the owner was only detected from the field managers.
+	kind      string
+	name      string
+	namespace string
+	// fieldManager is set when the owner was only detected from the field managers.
+	fieldManager string
+}
+
+// String returns a description of the GitOps owner for status messages, such as
+// "Argo CD Application openshift-gitops/my-app".
+func (o gitOpsOwner) String() string {
+	if o.kind == "" {
+		return o.tool + " (field manager " + o.fieldManager + ")"
+	}
+
+	name := o.name
+	if o.namespace != "" {
+		name = o.namespace + "/" + o.name
+	}
+
+	return o.tool + " " + o.kind + " " + name
+}
+
+// getGitOpsOwner returns the GitOps tool managing the object based on the well-known tracking
+// annotations and labels of Argo CD and Flux, and then on the field managers of the object. The
+// `app.kubernetes.io/instance` label used by the legacy Argo CD tracking method is ignored since it
+// is commonly set by other tools such as Helm. Note that the field managers are not available on
+// objects from the watch cache. If the object is not managed by a GitOps tool, nil is returned.
+func getGitOpsOwner(obj *unstructured.Unstructured) *gitOpsOwner {
+	if obj == nil {
+		return nil
+	}
+
+	annotations := obj.GetAnnotations()
+	labels := obj.GetLabels()
+
+	// The tracking ID has the format of <app>:<group>/<kind>:<namespace>/<name>, where <app> is prefixed with
+	// <namespace>_ when the Application is outside of the Argo CD control plane namespace.
+	if trackingID := annotations[argoCDTrackingIDAnnotation]; trackingID != "" {
+		app, _, _ := strings.Cut(trackingID, ":")
+		owner := &gitOpsOwner{tool: "Argo CD", kind: "Application", name: app}
+
+		if ns, name, found := strings.Cut(app, "_"); found {
+			owner.namespace = ns
+			owner.name = name
+		}
+
+		return owner
+	}
+
+	if app := labels[argoCDInstanceLabel]; app != "" {
+		return &gitOpsOwner{tool: "Argo CD", kind: "Application", name: app}
+	}
+
+	// Flux doesn't reconcile objects with this annotation, so they aren't considered to be managed by Flux.
+	fluxDisabled := annotations[fluxReconcileAnnotation] == "disabled"
+
+	if name := labels[fluxKustomizeNameLabel]; name != "" && !fluxDisabled {
+		return &gitOpsOwner{
+			tool: "Flux", kind: "Kustomization", name: name, namespace: labels[fluxKustomizeNamespaceLabel],
+		}
+	}
+
+	if name := labels[fluxHelmNameLabel]; name != "" && !fluxDisabled {
+		return &gitOpsOwner{
+			tool: "Flux", kind: "HelmRelease", name: name, namespace: labels[fluxHelmNamespaceLabel],
+		}
+	}
+
+	for _, entry := range obj.GetManagedFields() {
+		tool, ok := gitOpsFieldManagers[entry.Manager]
+		if !ok || (tool == "Flux" && fluxDisabled) {
+			continue
+		}
+
+		return &gitOpsOwner{tool: tool, fieldManager: entry.Manager}
+	}
+
+	return nil
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestGetGitOpsOwner(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		annotations   map[string]string
+		labels        map[string]string
+		managers      []string
+		expectedOwner string
+	}{
+		"no markers": {
+			labels:   map[string]string{"app.kubernetes.io/instance": "my-release"},
+			managers: []string{"kubectl-edit"},
+		},
+		"Argo CD tracking ID": {
+			annotations:   map[string]string{argoCDTrackingIDAnnotation: "my-app:/ConfigMap:default/my-cm"},
+			expectedOwner: "Argo CD Application my-app",
+		},
+		"Argo CD tracking ID with an Application namespace": {
+			annotations:   map[string]string{argoCDTrackingIDAnnotation: "team-a_my-app:/ConfigMap:default/my-cm"},
+			expectedOwner: "Argo CD Application team-a/my-app",
+		},
+		"Argo CD instance label": {
+			labels:        map[string]string{argoCDInstanceLabel: "my-app"},
+			expectedOwner: "Argo CD Application my-app",
+		},
+		"Flux Kustomization": {
+			labels: map[string]string{
+				fluxKustomizeNameLabel:      "apps",
+				fluxKustomizeNamespaceLabel: "flux-system",
+			},
+			expectedOwner: "Flux Kustomization flux-system/apps",
+		},
+		"Flux HelmRelease": {
+			labels: map[string]string{
+				fluxHelmNameLabel:      "podinfo",
+				fluxHelmNamespaceLabel: "default",
+			},
+			expectedOwner: "Flux HelmRelease default/podinfo",
+		},
+		"Flux reconciliation disabled": {
+			annotations: map[string]string{fluxReconcileAnnotation: "disabled"},
+			labels: map[string]string{
+				fluxKustomizeNameLabel:      "apps",
+				fluxKustomizeNamespaceLabel: "flux-system",
+			},
+			managers: []string{"kustomize-controller"},
+		},
+		"field manager": {
+			managers:      []string{"kubectl-edit", "argocd-controller"},
+			expectedOwner: "Argo CD (field manager argocd-controller)",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			obj := &unstructured.Unstructured{Object: map[string]any{
+				"apiVersion": "v1",
+				"kind":       "ConfigMap",
+				"metadata":   map[string]any{"name": "my-cm", "namespace": "default"},
+			}}
+			obj.SetAnnotations(test.annotations)
+			obj.SetLabels(test.labels)
+
+			managedFields := []metav1.ManagedFieldsEntry{}
+			for _, manager := range test.managers {
+				managedFields = append(managedFields, metav1.ManagedFieldsEntry{
+					Manager:   manager,
+					Operation: metav1.ManagedFieldsOperationApply,
+				})
+			}
+
+			obj.SetManagedFields(managedFields)
+
+			owner := getGitOpsOwner(obj)
+			if test.expectedOwner == "" {
+				assert.Nil(t, owner)
+
+				return
+			}
+
+			if assert.NotNil(t, owner) {
+				assert.Equal(t, test.expectedOwner, owner.String())
+			}
+		})
+	}
+}
+
+func TestCreateStatusGitOpsOwner(t *testing.T) {
+	t.Parallel()
+
+	_, reason, msg, reasonCode := createStatus("configmaps", map[string]*objectTmplEvalResultWithEvent{
+		"default/my-cm": {
+			result: objectTmplEvalResult{
+				objectNames: []string{"my-cm"},
+				namespace:   "default",
+				gitOpsOwner: "Argo CD Application my-app",
+			},
+			event: objectTmplEvalEvent{false, reasonWantFoundNoMatch, policyv1.ReasonCodeMismatch, ""},
+		},
+	})
+
+	assert.Equal(t, "K8s does not have a `must have` object", reason)
+	assert.Equal(t, policyv1.ReasonCodeMismatch, reasonCode)
+	assert.Equal(t,
+		"configmaps [my-cm] found but not as specified in namespace default, "+
+			"not enforced since it is managed by Argo CD Application my-app",
+		msg,
+	)
+
+	compliant, reason, msg, reasonCode := createStatus("configmaps", map[string]*objectTmplEvalResultWithEvent{
+		"default/my-cm": {
+			result: objectTmplEvalResult{
+				objectNames: []string{"my-cm"},
+				namespace:   "default",
+				gitOpsOwner: "Argo CD Application my-app",
+			},
+			event: objectTmplEvalEvent{
+				true,
+				reasonGitOpsSkipped,
+				policyv1.ReasonCodeExempted,
+				"configmaps [my-cm] in namespace default is managed by Argo CD Application my-app and was not evaluated",
+			},
+		},
+	})
+
+	assert.True(t, compliant)
+	assert.Equal(t, reasonGitOpsSkipped, reason)
+	assert.Equal(t, policyv1.ReasonCodeExempted, reasonCode)
+	assert.Equal(t,
+		"configmaps [my-cm] in namespace default is managed by Argo CD Application my-app and was not evaluated",
+		msg,
+	)
+}
+
+func TestHandleSingleObjGitOpsSkip(t *testing.T) {
+	t.Parallel()
+
+	existing := &unstructured.Unstructured{}
+	existing.SetAPIVersion("v1")
+	existing.SetKind("ConfigMap")
+	existing.SetName("my-cm")
+	existing.SetNamespace("default")
+	existing.SetAnnotations(map[string]string{argoCDTrackingIDAnnotation: "my-app:/ConfigMap:default/my-cm"})
+
+	tests := map[string]struct {
+		shouldExist       bool
+		remediation       policyv1.RemediationAction
+		expectedCompliant bool
+		expectedReason    string
+		expectedOwner     string
+	}{
+		"musthave is skipped": {
+			shouldExist:       true,
+			remediation:       policyv1.Enforce,
+			expectedCompliant: true,
+			expectedReason:    reasonGitOpsSkipped,
+			expectedOwner:     "Argo CD Application my-app",
+		},
+		"mustnothave is reported but not deleted": {
+			remediation:    policyv1.Enforce,
+			expectedReason: reasonWantNotFoundExists,
+			expectedOwner:  "Argo CD Application my-app",
+		},
+		"mustnothave is reported with inform": {
+			remediation:    policyv1.Inform,
+			expectedReason: reasonWantNotFoundExists,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &ConfigurationPolicyReconciler{}
+
+			result, _ := r.handleSingleObj(context.TODO(), singleObject{
+				policy:      &policyv1.ConfigurationPolicy{},
+				existingObj: existing.DeepCopy(),
+				name:        "my-cm",
+				namespace:   "default",
+				shouldExist: test.shouldExist,
+			}, test.remediation, true, &policyv1.ObjectTemplate{GitOpsManaged: policyv1.GitOpsManagedSkip})
+
+			if assert.Len(t, result.events, 1) {
+				assert.Equal(t, test.expectedCompliant, result.events[0].compliant)
+				assert.Equal(t, test.expectedReason, result.events[0].reason)
+			}
+
+			assert.Equal(t, test.expectedOwner, result.gitOpsOwner)
+		})
+	}
+}
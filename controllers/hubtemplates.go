@@ -29,7 +29,7 @@ type hubResolver interface {
 	getHubClient() *kubernetes.Clientset
 }
 
-func resolveHubTemplates(ctx context.Context, r hubResolver, policyCopy client.Object) error {
+func resolveHubTemplates(ctx context.Context, r hubResolver, policyCopy client.Object) (err error) {
 	log := ctrl.LoggerFrom(ctx)
 
 	jsonBytes, err := json.Marshal(policyCopy)
@@ -41,6 +41,9 @@ func resolveHubTemplates(ctx context.Context, r hubResolver, policyCopy client.O
 		return nil
 	}
 
+	ctx, span := startSpan(ctx, "resolveHubTemplates")
+	defer func() { endSpan(span, err) }()
+
 	hubDynamicWatcher := r.getHubDynamicWatcher()
 
 	if hubDynamicWatcher == nil {
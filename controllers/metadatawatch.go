@@ -0,0 +1,313 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// NewMetadataDynamicWatcher returns a DynamicWatcher that lists and watches objects with the metadata API, so that
+// the cached objects only have their apiVersion, kind, and metadata. This is used for the object templates that
+// only need the metadata of the objects, which greatly reduces the memory used to cache objects such as Secrets
+// and ConfigMaps.
+func NewMetadataDynamicWatcher(
+	config *rest.Config, reconciler depclient.Reconciler, options *depclient.Options,
+) (depclient.DynamicWatcher, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a dynamic Kubernetes client: %w", err)
+	}
+
+	metadataClient, err := metadata.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a metadata Kubernetes client: %w", err)
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize a discovery Kubernetes client: %w", err)
+	}
+
+	client := &metadataDynamicClient{
+		dynamic:  dynamicClient,
+		metadata: metadataClient,
+		kinds:    &resourceKinds{discovery: discoveryClient},
+	}
+
+	return newAPIGroupDynamicWatcher(client, discoveryClient, reconciler, options, client.kinds), nil
+}
+
+// resourceKinds determines the kinds of resources with discovery, since the metadata API only returns
+// PartialObjectMetadata. The kinds are cached per group version and fetched again when a resource isn't found.
+type resourceKinds struct {
+	discovery discovery.DiscoveryInterface
+	// kinds has the group versions as keys and the values are maps of the resource names to the kinds
+	kinds sync.Map
+}
+
+func (k *resourceKinds) kindFor(gvr schema.GroupVersionResource) (schema.GroupVersionKind, error) {
+	groupVersion := gvr.GroupVersion()
+
+	if cached, ok := k.kinds.Load(groupVersion); ok {
+		if kind, ok := cached.(map[string]string)[gvr.Resource]; ok {
+			return groupVersion.WithKind(kind), nil
+		}
+	}
+
+	resources, err := k.discovery.ServerResourcesForGroupVersion(groupVersion.String())
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to determine the kind of %s: %w", gvr, err)
+	}
+
+	kinds := make(map[string]string, len(resources.APIResources))
+	for _, resource := range resources.APIResources {
+		kinds[resource.Name] = resource.Kind
+	}
+
+	k.kinds.Store(groupVersion, kinds)
+
+	kind, ok := kinds[gvr.Resource]
+	if !ok {
+		return schema.GroupVersionKind{}, fmt.Errorf("failed to determine the kind of %s: %w",
+			gvr, depclient.ErrNoVersionedResource)
+	}
+
+	return groupVersion.WithKind(kind), nil
+}
+
+// invalidate drops the cached kinds of the API group.
+func (k *resourceKinds) invalidate(group string) {
+	k.kinds.Range(func(key, _ any) bool {
+		if key.(schema.GroupVersion).Group == group {
+			k.kinds.Delete(key)
+		}
+
+		return true
+	})
+}
+
+// metadataDynamicClient is a dynamic client that gets, lists, and watches objects with the metadata API and
+// returns them as unstructured objects with only the apiVersion, kind, and metadata. The other requests are
+// sent with the dynamic client.
+type metadataDynamicClient struct {
+	dynamic  dynamic.Interface
+	metadata metadata.Interface
+	kinds    *resourceKinds
+}
+
+func (c *metadataDynamicClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &metadataResource{
+		ResourceInterface: c.dynamic.Resource(gvr),
+		metadata:          c.metadata.Resource(gvr),
+		client:            c,
+		gvr:               gvr,
+	}
+}
+
+type metadataResource struct {
+	dynamic.ResourceInterface
+	metadata  metadata.ResourceInterface
+	client    *metadataDynamicClient
+	gvr       schema.GroupVersionResource
+	namespace string
+}
+
+func (r *metadataResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &metadataResource{
+		ResourceInterface: r.client.dynamic.Resource(r.gvr).Namespace(namespace),
+		metadata:          r.client.metadata.Resource(r.gvr).Namespace(namespace),
+		client:            r.client,
+		gvr:               r.gvr,
+		namespace:         namespace,
+	}
+}
+
+// kind returns the GroupVersionKind of the resource, since the metadata API only returns PartialObjectMetadata.
+func (r *metadataResource) kind() (schema.GroupVersionKind, error) {
+	return r.client.kinds.kindFor(r.gvr)
+}
+
+func (r *metadataResource) Get(
+	ctx context.Context, name string, options metav1.GetOptions, subresources ...string,
+) (*unstructured.Unstructured, error) {
+	if len(subresources) != 0 {
+		return r.ResourceInterface.Get(ctx, name, options, subresources...)
+	}
+
+	gvk, err := r.kind()
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := r.metadata.Get(ctx, name, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return metadataToUnstructured(obj, gvk)
+}
+
+func (r *metadataResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	gvk, err := r.kind()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := r.metadata.List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &unstructured.UnstructuredList{Items: make([]unstructured.Unstructured, 0, len(list.Items))}
+	result.SetResourceVersion(list.ResourceVersion)
+	result.SetContinue(list.Continue)
+
+	for i := range list.Items {
+		obj, err := metadataToUnstructured(&list.Items[i], gvk)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Items = append(result.Items, *obj)
+	}
+
+	return result, nil
+}
+
+func (r *metadataResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	gvk, err := r.kind()
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := r.metadata.Watch(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return watch.Filter(w, func(event watch.Event) (watch.Event, bool) {
+		obj, ok := event.Object.(*metav1.PartialObjectMetadata)
+		if !ok {
+			// Error events have a Status object that is passed as is
+			return event, true
+		}
+
+		converted, err := metadataToUnstructured(obj, gvk)
+		if err != nil {
+			return watch.Event{Type: watch.Error, Object: &metav1.Status{
+				Status:  metav1.StatusFailure,
+				Message: err.Error(),
+			}}, true
+		}
+
+		event.Object = converted
+
+		return event, true
+	}), nil
+}
+
+// metadataToUnstructured converts the PartialObjectMetadata to an unstructured object of the input kind.
+func metadataToUnstructured(
+	obj *metav1.PartialObjectMetadata, gvk schema.GroupVersionKind,
+) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&obj.ObjectMeta)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := &unstructured.Unstructured{Object: map[string]any{"metadata": content}}
+	converted.SetGroupVersionKind(gvk)
+
+	return converted, nil
+}
+
+// metadataOnlyFields are the top-level fields of an object definition that are available when only the
+// metadata of the objects is watched.
+var metadataOnlyFields = map[string]bool{"apiVersion": true, "kind": true, "metadata": true}
+
+// usesMetadataOnly returns true when the object template only asserts the existence, labels, annotations, or
+// other metadata of objects, in which case the objects are watched with the metadata API. The objects are fully
+// watched when enforcing something other than mustnothave, since updates need the full object, and when the
+// full objects are available in templates or custom compliance messages.
+func (r *ConfigurationPolicyReconciler) usesMetadataOnly(
+	plc *policyv1.ConfigurationPolicy, objectT *policyv1.ObjectTemplate,
+) bool {
+	if r.MetadataDynamicWatcher == nil || objectT == nil {
+		return false
+	}
+
+	if !plc.Spec.RemediationAction.IsInform() && !objectT.ComplianceType.IsMustNotHave() {
+		return false
+	}
+
+	if plc.Spec.CustomMessage.Compliant != "" || plc.Spec.CustomMessage.NonCompliant != "" {
+		return false
+	}
+
+	if templateHasObjectRegex.Match(objectT.ObjectDefinition.Raw) {
+		return false
+	}
+
+	definition := map[string]json.RawMessage{}
+	if err := json.Unmarshal(objectT.ObjectDefinition.Raw, &definition); err != nil {
+		return false
+	}
+
+	for field := range definition {
+		if !metadataOnlyFields[field] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// templateWatcher returns the DynamicWatcher to get and list the objects of the object template with.
+func (r *ConfigurationPolicyReconciler) templateWatcher(
+	plc *policyv1.ConfigurationPolicy, objectT *policyv1.ObjectTemplate,
+) depclient.DynamicWatcher {
+	if r.usesMetadataOnly(plc, objectT) {
+		return r.MetadataDynamicWatcher
+	}
+
+	return r.DynamicWatcher
+}
+
+// policyWatchers returns the DynamicWatchers of the objects of the policies.
+func (r *ConfigurationPolicyReconciler) policyWatchers() []depclient.DynamicWatcher {
+	if r.MetadataDynamicWatcher == nil {
+		return []depclient.DynamicWatcher{r.DynamicWatcher}
+	}
+
+	return []depclient.DynamicWatcher{r.DynamicWatcher, r.MetadataDynamicWatcher}
+}
+
+// removePolicyWatches removes the watches of the objects of the policy from all of the DynamicWatchers.
+func (r *ConfigurationPolicyReconciler) removePolicyWatches(watcher depclient.ObjectIdentifier) error {
+	var errs []error
+
+	for _, dynamicWatcher := range r.policyWatchers() {
+		if err := dynamicWatcher.RemoveWatcher(watcher); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
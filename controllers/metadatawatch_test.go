@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	discoveryfake "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestUsesMetadataOnly(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		definition     string
+		complianceType policyv1.ComplianceType
+		remediation    policyv1.RemediationAction
+		customMessage  string
+		expected       bool
+	}{
+		"labels": {
+			definition: `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a","labels":{"app":"b"}}}`,
+			expected:   true,
+		},
+		"existence": {
+			definition: `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"}}`,
+			expected:   true,
+		},
+		"data": {
+			definition: `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"},"data":{"key":"dmFsdWU="}}`,
+		},
+		"templated object": {
+			definition: `{"apiVersion":"v1","kind":"Secret","metadata":` +
+				`{"name":"a","labels":{"app":"{{ .Object.metadata.name }}"}}}`,
+		},
+		"enforce musthave": {
+			definition:  `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"}}`,
+			remediation: policyv1.Enforce,
+		},
+		"enforce mustnothave": {
+			definition:     `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"}}`,
+			complianceType: policyv1.MustNotHave,
+			remediation:    policyv1.Enforce,
+			expected:       true,
+		},
+		"custom message": {
+			definition:    `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"a"}}`,
+			customMessage: "{{ .DefaultMessage }}",
+		},
+	}
+
+	r := &ConfigurationPolicyReconciler{MetadataDynamicWatcher: newFakeWatcher(0)}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			remediation := test.remediation
+			if remediation == "" {
+				remediation = policyv1.Inform
+			}
+
+			complianceType := test.complianceType
+			if complianceType == "" {
+				complianceType = policyv1.MustHave
+			}
+
+			policy := &policyv1.ConfigurationPolicy{
+				Spec: policyv1.ConfigurationPolicySpec{
+					RemediationAction: remediation,
+					CustomMessage:     policyv1.CustomMessage{NonCompliant: test.customMessage},
+				},
+			}
+			objectT := &policyv1.ObjectTemplate{
+				ComplianceType:   complianceType,
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(test.definition)},
+			}
+
+			assert.Equal(t, test.expected, r.usesMetadataOnly(policy, objectT))
+		})
+	}
+
+	// Without a metadata watcher, the full objects are always watched
+	noMetadataWatcher := &ConfigurationPolicyReconciler{DynamicWatcher: newFakeWatcher(0)}
+	objectT := &policyv1.ObjectTemplate{
+		ComplianceType:   policyv1.MustHave,
+		ObjectDefinition: runtime.RawExtension{Raw: []byte(tests["labels"].definition)},
+	}
+	policy := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{RemediationAction: policyv1.Inform},
+	}
+
+	assert.False(t, noMetadataWatcher.usesMetadataOnly(policy, objectT))
+	assert.Equal(t, noMetadataWatcher.DynamicWatcher, noMetadataWatcher.templateWatcher(policy, objectT))
+}
+
+func TestMetadataDynamicClient(t *testing.T) {
+	t.Parallel()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(t, metav1.AddMetaToScheme(scheme))
+
+	secret := &metav1.PartialObjectMetadata{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "secret", Namespace: "default", Labels: map[string]string{"app": "test"}, ResourceVersion: "5",
+		},
+	}
+
+	secretsGVR := schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+	secretGVK := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+
+	discoveryClient := &discoveryfake.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	discoveryClient.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "secrets", Kind: "Secret", Namespaced: true}},
+	}}
+
+	client := &metadataDynamicClient{
+		dynamic:  dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		metadata: metadatafake.NewSimpleMetadataClient(scheme, secret),
+		kinds:    &resourceKinds{discovery: discoveryClient},
+	}
+
+	resource := client.Resource(secretsGVR).Namespace("default")
+
+	list, err := resource.List(t.Context(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, secretGVK, list.Items[0].GroupVersionKind())
+	assert.Equal(t, map[string]string{"app": "test"}, list.Items[0].GetLabels())
+	assert.Equal(t, "5", list.Items[0].GetResourceVersion())
+	assert.ElementsMatch(t, []string{"apiVersion", "kind", "metadata"}, mapKeys(list.Items[0].Object))
+
+	obj, err := resource.Get(t.Context(), "secret", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, list.Items[0].Object, obj.Object)
+
+	// The kinds of an API group are fetched again once invalidated
+	_, cached := client.kinds.kinds.Load(secretGVK.GroupVersion())
+	assert.True(t, cached)
+
+	client.kinds.invalidate("")
+
+	_, cached = client.kinds.kinds.Load(secretGVK.GroupVersion())
+	assert.False(t, cached)
+
+	w, err := resource.Watch(t.Context(), metav1.ListOptions{})
+	assert.NoError(t, err)
+
+	defer w.Stop()
+
+	err = client.metadata.Resource(secretsGVR).Namespace("default").Delete(
+		t.Context(), "secret", metav1.DeleteOptions{},
+	)
+	assert.NoError(t, err)
+
+	for {
+		select {
+		case event := <-w.ResultChan():
+			converted, ok := event.Object.(*unstructured.Unstructured)
+			assert.True(t, ok, "the watch events have unstructured objects")
+			assert.Equal(t, secretGVK, converted.GroupVersionKind())
+			assert.Equal(t, "secret", converted.GetName())
+
+			if event.Type == watch.Deleted {
+				return
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for the watch event")
+		}
+	}
+}
+
+func mapKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
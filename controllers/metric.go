@@ -2,12 +2,18 @@ package controllers
 
 import (
 	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	v1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
 )
 
 var (
@@ -40,6 +46,14 @@ var (
 		},
 		[]string{"name"},
 	)
+	policyStatusWritesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_policy_status_writes_total",
+			Help: "The total number of status writes of the configuration policy at the end of its evaluations. " +
+				"The result is written, unchanged when the write was skipped, or failed.",
+		},
+		[]string{"name", "result"},
+	)
 	compareObjSecondsCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "compare_objects_seconds_total",
@@ -78,16 +92,199 @@ var (
 			"type",
 		},
 	)
+	evaluationQueueDepthGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_policy_evaluation_queue_depth",
+			Help: "The number of policies waiting in the evaluation queue, including those waiting for " +
+				"a requeue delay to pass, by priority class",
+		},
+		[]string{"controller", "priority_class"},
+	)
+	evaluationQueueWaitSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "config_policy_evaluation_queue_wait_seconds",
+			Help: "The seconds a policy waited in the evaluation queue after it was ready to be evaluated, " +
+				"by priority class",
+			Buckets: prometheus.ExponentialBuckets(0.005, 4, 10),
+		},
+		[]string{"controller", "priority_class"},
+	)
+	watchCacheObjectsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_policy_watch_cache_objects",
+			Help: "The number of objects cached for the watches of the configuration policies, by watched " +
+				"group, version, and kind, and whether the full objects or only their metadata are watched",
+		},
+		[]string{"group", "version", "kind", "watch"},
+	)
+	watchCacheBytesGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_policy_watch_cache_bytes",
+			Help: "The approximate memory used by the objects cached for the watches of the configuration " +
+				"policies, based on the JSON size of the objects. " +
+				"Use this alongside config_policy_watch_cache_objects.",
+		},
+		[]string{"group", "version", "kind", "watch"},
+	)
+	policyShardGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_shard_assignment_info",
+			Help: "The policies handled by this controller replica when the policies are sharded across replicas. " +
+				"The value is always 1.",
+		},
+		[]string{
+			"kind",             // The kind of the policy
+			"policy",           // The name of the policy
+			"policy_namespace", // The namespace where the policy is defined
+			"replica",          // The controller replica that owns the policy
+		},
+	)
+	notificationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_compliance_notifications_total",
+			Help: "The total number of compliance notifications sent to each sink, by whether they were " +
+				"delivered or failed after all of the attempts",
+		},
+		[]string{"sink", "result"},
+	)
+	notificationRetriesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_compliance_notification_retries_total",
+			Help: "The total number of times a compliance notification was sent again to a sink after a failure",
+		},
+		[]string{"sink"},
+	)
+	notificationDeliverySeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "policy_compliance_notification_delivery_seconds",
+			Help:    "The seconds taken to deliver a compliance notification to a sink, including the retries",
+			Buckets: prometheus.ExponentialBuckets(0.01, 4, 9),
+		},
+		[]string{"sink"},
+	)
+	notificationsDroppedCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "policy_compliance_notifications_dropped_total",
+			Help: "The total number of compliance notifications dropped because the notification queue was full",
+		},
+	)
+	notificationQueueGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "policy_compliance_notification_queue_length",
+			Help: "The number of compliance notifications waiting to be sent",
+		},
+	)
+	enforcementActionsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "policy_enforcement_actions_total",
+			Help: "The total number of objects created, updated, recreated, or deleted when enforcing the policy, " +
+				"by the kind of the object and whether the action succeeded",
+		},
+		[]string{
+			"kind",             // The kind of the policy
+			"policy",           // The name of the policy
+			"policy_namespace", // The namespace where the policy is defined
+			"object_kind",      // The kind of the object
+			"action",           // create, update, recreate, or delete
+			"result",           // success or failure
+		},
+	)
+	timeToRemediateSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "policy_time_to_remediate_seconds",
+			Help:    "The seconds from when a policy became noncompliant until it was compliant again",
+			Buckets: prometheus.ExponentialBuckets(1, 4, 10),
+		},
+		[]string{"kind"},
+	)
+	noncompliantObjectsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "policy_noncompliant_objects",
+			Help: "The number of related objects of the policy which are noncompliant",
+		},
+		[]string{
+			"kind",             // The kind of the policy
+			"policy",           // The name of the policy
+			"policy_namespace", // The namespace where the policy is defined
+		},
+	)
+	operatorInstalledCSVGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "operator_policy_installed_csv_info",
+			Help: "The ClusterServiceVersions installed for the operator policy, with the version from the name " +
+				"of the ClusterServiceVersion. The value is always 1.",
+		},
+		[]string{"policy", "policy_namespace", "csv", "version"},
+	)
+	operatorUpgradePendingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "operator_policy_upgrade_approval_pending",
+			Help: "Whether an InstallPlan to upgrade the operator of the operator policy is awaiting approval. " +
+				"1 == awaiting approval. 0 == none.",
+		},
+		[]string{"policy", "policy_namespace"},
+	)
+	operatorCatalogSourceHealthGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "operator_policy_catalog_source_health",
+			Help: "The health of the CatalogSource of the operator policy. " +
+				"1 == healthy. 0 == unhealthy or missing. -1 == unknown.",
+		},
+		[]string{"policy", "policy_namespace"},
+	)
+	operatorDeprecationGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "operator_policy_deprecation_present",
+			Help: "Whether the package, channel, or bundle requested by the operator policy is deprecated. " +
+				"1 == deprecated. 0 == not deprecated.",
+		},
+		[]string{"policy", "policy_namespace"},
+	)
+	operatorMinorChannelUpgradeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "operator_policy_minor_channel_upgrade_available",
+			Help: "Whether a channel with a newer minor version of the operator of the operator policy is " +
+				"available. 1 == available. 0 == not available or not checked.",
+		},
+		[]string{"policy", "policy_namespace"},
+	)
 )
 
+// csvVersionRegexp matches the version at the end of the name of a ClusterServiceVersion, which bundles name
+// <package>.v<version>.
+var csvVersionRegexp = regexp.MustCompile(`\.v(\d+\.\d+\.\d+\S*)$`)
+
+// noncompliantSince has the time each policy became noncompliant, by policyMetricKey, to measure the time to
+// remediate the policy.
+var noncompliantSince sync.Map
+
 func init() {
 	// Register custom metrics with the global Prometheus registry
 	metrics.Registry.MustRegister(
 		policyStatusGauge,
 		policyEvalSecondsCounter,
 		policyEvalCounter,
+		policyStatusWritesCounter,
 		compareObjSecondsCounter,
 		compareObjEvalCounter,
+		evaluationQueueDepthGauge,
+		evaluationQueueWaitSeconds,
+		watchCacheObjectsGauge,
+		watchCacheBytesGauge,
+		policyShardGauge,
+		notificationsCounter,
+		notificationRetriesCounter,
+		notificationDeliverySeconds,
+		notificationsDroppedCounter,
+		notificationQueueGauge,
+		enforcementActionsCounter,
+		timeToRemediateSeconds,
+		noncompliantObjectsGauge,
+		operatorInstalledCSVGauge,
+		operatorUpgradePendingGauge,
+		operatorCatalogSourceHealthGauge,
+		operatorDeprecationGauge,
+		operatorMinorChannelUpgradeGauge,
 	)
 	// Error metrics may already be registered by template sync
 	alreadyReg := &prometheus.AlreadyRegisteredError{}
@@ -113,25 +310,192 @@ func getStatusValue(complianceState v1.ComplianceState) float64 {
 	return -1
 }
 
-func removeOperatorPolicyMetrics(request ctrl.Request) {
-	_ = policyStatusGauge.DeletePartialMatch(prometheus.Labels{
-		"kind":             "OperatorPolicy",
-		"policy":           request.Name,
-		"policy_namespace": request.Namespace,
-	})
+// policyMetricKey identifies the policy in the metric state kept by the controller.
+func policyMetricKey(kind string, namespace string, name string) string {
+	return kind + "/" + namespace + "/" + name
 }
 
-func removeConfigPolicyMetrics(request ctrl.Request) {
+// recordComplianceTransition observes the time to remediate the policy when it becomes compliant after being
+// noncompliant. When the controller didn't see the policy become noncompliant, such as after a restart, the start
+// of the latest noncompliant streak in the status history is used.
+func recordComplianceTransition(
+	kind string, policy metav1.Object, previous v1.ComplianceState, current v1.ComplianceState,
+	history []v1.HistoryEvent, now time.Time,
+) {
+	key := policyMetricKey(kind, policy.GetNamespace(), policy.GetName())
+
+	switch current {
+	case v1.NonCompliant:
+		if _, ok := noncompliantSince.Load(key); ok {
+			return
+		}
+
+		since := now
+
+		if previous == v1.NonCompliant {
+			if streakStart := noncompliantStreakStart(history); !streakStart.IsZero() {
+				since = streakStart
+			}
+		}
+
+		noncompliantSince.Store(key, since)
+	case v1.Compliant:
+		var since time.Time
+
+		if tracked, ok := noncompliantSince.LoadAndDelete(key); ok {
+			since = tracked.(time.Time)
+		} else if previous == v1.NonCompliant {
+			since = noncompliantStreakStart(history)
+		}
+
+		if !since.IsZero() {
+			timeToRemediateSeconds.WithLabelValues(kind).Observe(now.Sub(since).Seconds())
+		}
+	default:
+	}
+}
+
+// noncompliantStreakStart returns the time of the oldest event of the latest consecutive noncompliant events in
+// the status history, which is ordered from the newest event. It returns the zero time when there are none.
+func noncompliantStreakStart(history []v1.HistoryEvent) time.Time {
+	var start time.Time
+
+	for _, event := range history {
+		if strings.HasPrefix(event.Message, string(v1.NonCompliant)+";") {
+			start = event.LastTimestamp.Time
+		} else if !start.IsZero() {
+			break
+		}
+	}
+
+	return start
+}
+
+// setNoncompliantObjectsMetric sets the number of noncompliant related objects of the policy.
+func setNoncompliantObjectsMetric(kind string, policy metav1.Object, relatedObjects []v1.RelatedObject) {
+	noncompliant := 0
+
+	for _, related := range relatedObjects {
+		if related.Compliant == string(v1.NonCompliant) {
+			noncompliant++
+		}
+	}
+
+	noncompliantObjectsGauge.WithLabelValues(kind, policy.GetName(), policy.GetNamespace()).Set(float64(noncompliant))
+}
+
+// removePolicyMetrics removes the metrics common to all kinds of policies.
+func removePolicyMetrics(kind string, request ctrl.Request) {
+	labels := prometheus.Labels{"kind": kind, "policy": request.Name, "policy_namespace": request.Namespace}
+
 	// If a metric has an error while deleting, that means the policy was never evaluated so it can be ignored.
-	_ = policyStatusGauge.DeletePartialMatch(prometheus.Labels{
-		"kind":             "ConfigurationPolicy",
-		"policy":           request.Name,
-		"policy_namespace": request.Namespace,
+	_ = policyStatusGauge.DeletePartialMatch(labels)
+	_ = enforcementActionsCounter.DeletePartialMatch(labels)
+	_ = noncompliantObjectsGauge.DeletePartialMatch(labels)
+
+	noncompliantSince.Delete(policyMetricKey(kind, request.Namespace, request.Name))
+}
+
+// setOperatorInventoryMetrics sets the metrics of the operator installed by the OperatorPolicy from the
+// conditions and related objects in its status. The metrics of the conditions not in the status are removed.
+func setOperatorInventoryMetrics(policy *policyv1beta1.OperatorPolicy) {
+	labels := prometheus.Labels{"policy": policy.Name, "policy_namespace": policy.Namespace}
+
+	_ = operatorInstalledCSVGauge.DeletePartialMatch(labels)
+
+	for _, related := range policy.Status.RelatedObjsOfKind(clusterServiceVersionGVK.Kind) {
+		// Only the ClusterServiceVersions found on the cluster have a UID
+		if related.Properties == nil || related.Properties.UID == "" {
+			continue
+		}
+
+		version := ""
+		if match := csvVersionRegexp.FindStringSubmatch(related.Object.Metadata.Name); match != nil {
+			version = match[1]
+		}
+
+		operatorInstalledCSVGauge.WithLabelValues(
+			policy.Name, policy.Namespace, related.Object.Metadata.Name, version,
+		).Set(1)
+	}
+
+	setConditionMetric(operatorUpgradePendingGauge, policy, installPlanConditionType, func(reason string) bool {
+		return reason == "InstallPlanRequiresApproval"
 	})
+
+	setConditionMetric(operatorCatalogSourceHealthGauge, policy, catalogSrcConditionType, func(reason string) bool {
+		return reason == "CatalogSourcesFound"
+	})
+
+	// The CatalogSource condition is replaced by one of another type when its state is unknown
+	if idx, _ := policy.Status.GetCondition(catalogSourceUnknownCond.Type); idx != -1 {
+		operatorCatalogSourceHealthGauge.With(labels).Set(-1)
+	}
+
+	setConditionMetric(operatorDeprecationGauge, policy, deprecationType, func(reason string) bool {
+		return strings.HasSuffix(reason, "Deprecated")
+	})
+
+	setConditionMetric(operatorMinorChannelUpgradeGauge, policy, minorChannelConditionType, func(reason string) bool {
+		return reason == "UpgradeAvailable"
+	})
+}
+
+// setConditionMetric sets the gauge of the OperatorPolicy to 1 when the reason of its condition of the type matches,
+// and to 0 otherwise. The gauge is removed when the policy doesn't have the condition. The reason is used rather than
+// the status of the condition, since the status depends on the compliance configuration of the policy.
+func setConditionMetric(
+	gauge *prometheus.GaugeVec,
+	policy *policyv1beta1.OperatorPolicy,
+	condType string,
+	matches func(reason string) bool,
+) {
+	idx, cond := policy.Status.GetCondition(condType)
+	if idx == -1 {
+		_ = gauge.DeleteLabelValues(policy.Name, policy.Namespace)
+
+		return
+	}
+
+	gauge.WithLabelValues(policy.Name, policy.Namespace).Set(boolMetricValue(matches(cond.Reason)))
+}
+
+func boolMetricValue(value bool) float64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+func removeOperatorPolicyMetrics(request ctrl.Request) {
+	removePolicyMetrics("OperatorPolicy", request)
+
+	labels := prometheus.Labels{"policy": request.Name, "policy_namespace": request.Namespace}
+
+	_ = operatorInstalledCSVGauge.DeletePartialMatch(labels)
+	_ = operatorUpgradePendingGauge.DeletePartialMatch(labels)
+	_ = operatorCatalogSourceHealthGauge.DeletePartialMatch(labels)
+	_ = operatorDeprecationGauge.DeletePartialMatch(labels)
+	_ = operatorMinorChannelUpgradeGauge.DeletePartialMatch(labels)
+}
+
+func removeConfigPolicyMetrics(request ctrl.Request) {
+	removePolicyMetrics("ConfigurationPolicy", request)
+
 	_ = policyEvalSecondsCounter.DeleteLabelValues(request.Name)
 	_ = policyEvalCounter.DeleteLabelValues(request.Name)
+	_ = policyStatusWritesCounter.DeletePartialMatch(prometheus.Labels{"name": request.Name})
 	_ = compareObjEvalCounter.DeletePartialMatch(prometheus.Labels{"config_policy_name": request.Name})
 	_ = compareObjSecondsCounter.DeletePartialMatch(prometheus.Labels{"config_policy_name": request.Name})
 	_ = policyUserErrorsCounter.DeletePartialMatch(prometheus.Labels{"template": request.Name})
 	_ = policySystemErrorsCounter.DeletePartialMatch(prometheus.Labels{"template": request.Name})
 }
+
+func removePolicyShardMetric(kind string, request ctrl.Request) {
+	_ = policyShardGauge.DeletePartialMatch(prometheus.Labels{
+		"kind":             kind,
+		"policy":           request.Name,
+		"policy_namespace": request.Namespace,
+	})
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	operatorv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+func historyEvent(message string, at time.Time) policyv1.HistoryEvent {
+	return policyv1.HistoryEvent{LastTimestamp: metav1.NewMicroTime(at), Message: message}
+}
+
+func TestNoncompliantStreakStart(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		history  []policyv1.HistoryEvent
+		expected time.Time
+	}{
+		"no history": {},
+		"never noncompliant": {
+			history: []policyv1.HistoryEvent{historyEvent("Compliant; notification - found", start)},
+		},
+		"latest streak": {
+			history: []policyv1.HistoryEvent{
+				historyEvent("NonCompliant; violation - missing b", start.Add(2*time.Minute)),
+				historyEvent("NonCompliant; violation - missing a", start.Add(time.Minute)),
+				historyEvent("Compliant; notification - found", start),
+				historyEvent("NonCompliant; violation - missing", start.Add(-time.Hour)),
+			},
+			expected: start.Add(time.Minute),
+		},
+		"compliant after the streak": {
+			history: []policyv1.HistoryEvent{
+				historyEvent("Compliant; notification - found", start.Add(time.Hour)),
+				historyEvent("NonCompliant; violation - missing", start),
+			},
+			expected: start,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.True(t, test.expected.Equal(noncompliantStreakStart(test.history)))
+		})
+	}
+}
+
+func histogramCount(t *testing.T, kind string) uint64 {
+	t.Helper()
+
+	metric := &dto.Metric{}
+	assert.NoError(t, timeToRemediateSeconds.WithLabelValues(kind).(prometheus.Metric).Write(metric))
+
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestRecordComplianceTransition(t *testing.T) {
+	t.Parallel()
+
+	// A kind unique to the test so that the histogram isn't shared with other tests
+	kind := "TestRecordComplianceTransition"
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "remediated"}}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Becoming noncompliant starts the measurement, which isn't reset by more noncompliant evaluations
+	recordComplianceTransition(kind, policy, policyv1.Compliant, policyv1.NonCompliant, nil, start)
+	recordComplianceTransition(kind, policy, policyv1.NonCompliant, policyv1.NonCompliant, nil, start.Add(time.Hour))
+	assert.Equal(t, uint64(0), histogramCount(t, kind))
+
+	recordComplianceTransition(kind, policy, policyv1.NonCompliant, policyv1.Compliant, nil, start.Add(2*time.Hour))
+	assert.Equal(t, uint64(1), histogramCount(t, kind))
+
+	metric := &dto.Metric{}
+	assert.NoError(t, timeToRemediateSeconds.WithLabelValues(kind).(prometheus.Metric).Write(metric))
+	assert.InDelta(t, (2 * time.Hour).Seconds(), metric.GetHistogram().GetSampleSum(), 0)
+
+	// Staying compliant isn't observed
+	recordComplianceTransition(kind, policy, policyv1.Compliant, policyv1.Compliant, nil, start.Add(3*time.Hour))
+	assert.Equal(t, uint64(1), histogramCount(t, kind))
+
+	// Without a tracked start, such as after a restart, the status history is used
+	history := []policyv1.HistoryEvent{historyEvent("NonCompliant; violation - missing", start)}
+
+	recordComplianceTransition(kind, policy, policyv1.NonCompliant, policyv1.Compliant, history, start.Add(time.Hour))
+	assert.Equal(t, uint64(2), histogramCount(t, kind))
+
+	// Deleting the policy forgets when it became noncompliant
+	recordComplianceTransition(kind, policy, policyv1.Compliant, policyv1.NonCompliant, nil, start)
+	removePolicyMetrics(kind, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+
+	recordComplianceTransition(kind, policy, policyv1.UnknownCompliancy, policyv1.Compliant, nil, start.Add(time.Hour))
+	assert.Equal(t, uint64(2), histogramCount(t, kind))
+}
+
+func TestPolicyEnforcementMetrics(t *testing.T) {
+	t.Parallel()
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "enforced"}}
+
+	countEnforcementAction("ConfigurationPolicy", policy, "ConfigMap", auditActionCreate, nil)
+	countEnforcementAction("ConfigurationPolicy", policy, "ConfigMap", auditActionUpdate, errors.New("conflict"))
+
+	created := enforcementActionsCounter.WithLabelValues(
+		"ConfigurationPolicy", "enforced", "managed", "ConfigMap", "create", "success",
+	)
+	failed := enforcementActionsCounter.WithLabelValues(
+		"ConfigurationPolicy", "enforced", "managed", "ConfigMap", "update", "failure",
+	)
+
+	assert.InDelta(t, 1, counterValue(t, created), 0)
+	assert.InDelta(t, 1, counterValue(t, failed), 0)
+
+	setNoncompliantObjectsMetric("ConfigurationPolicy", policy, []policyv1.RelatedObject{
+		{Compliant: string(policyv1.NonCompliant)},
+		{Compliant: string(policyv1.Compliant)},
+		{Compliant: string(policyv1.NonCompliant)},
+	})
+
+	assert.InDelta(t, 2, gaugeValue(t, noncompliantObjectsGauge.WithLabelValues(
+		"ConfigurationPolicy", "enforced", "managed",
+	)), 0)
+}
+
+func TestOperatorInventoryMetrics(t *testing.T) {
+	t.Parallel()
+
+	policy := &policyv1beta1.OperatorPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "inventory"}}
+	csv := &operatorv1alpha1.ClusterServiceVersion{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: clusterServiceVersionGVK.GroupVersion().String(), Kind: clusterServiceVersionGVK.Kind,
+		},
+		ObjectMeta: metav1.ObjectMeta{Namespace: "operators", Name: "quay-operator.v3.10.2", UID: "1234"},
+	}
+
+	policy.Status.RelatedObjects = []policyv1.RelatedObject{
+		existingCSVObj(csv),
+		missingCSVObj("quay-operator.v3.11.0", "operators"),
+	}
+	policy.Status.Conditions = []metav1.Condition{
+		installPlanUpgradeCond("NonCompliant", []string{"quay-operator.v3.11.0"}, nil),
+		catalogSourceFindCond("NonCompliant", true, false, "redhat-operators"),
+		deprecationCond("stable-3.10", Channel, ""),
+		minorChannelOKCond("Recommended", "the channel is the latest minor version"),
+	}
+
+	setOperatorInventoryMetrics(policy)
+
+	assert.InDelta(t, 1, gaugeValue(t, operatorInstalledCSVGauge.WithLabelValues(
+		"inventory", "managed", "quay-operator.v3.10.2", "3.10.2",
+	)), 0)
+	assert.False(t, operatorInstalledCSVGauge.DeleteLabelValues(
+		"inventory", "managed", "quay-operator.v3.11.0", "3.11.0",
+	))
+	assert.InDelta(t, 1, gaugeValue(t, operatorUpgradePendingGauge.WithLabelValues("inventory", "managed")), 0)
+	assert.InDelta(t, 0, gaugeValue(t, operatorCatalogSourceHealthGauge.WithLabelValues("inventory", "managed")), 0)
+	assert.InDelta(t, 1, gaugeValue(t, operatorDeprecationGauge.WithLabelValues("inventory", "managed")), 0)
+	assert.InDelta(t, 0, gaugeValue(t, operatorMinorChannelUpgradeGauge.WithLabelValues("inventory", "managed")), 0)
+
+	// The upgrade was approved, and the state of the CatalogSource is no longer known
+	csv.Name = "quay-operator.v3.11.0"
+	policy.Status.RelatedObjects = []policyv1.RelatedObject{existingCSVObj(csv)}
+	policy.Status.Conditions = []metav1.Condition{
+		installPlanApprovedCond("quay-operator.v3.11.0"),
+		catalogSourceUnknownCond,
+	}
+
+	setOperatorInventoryMetrics(policy)
+
+	assert.InDelta(t, 1, gaugeValue(t, operatorInstalledCSVGauge.WithLabelValues(
+		"inventory", "managed", "quay-operator.v3.11.0", "3.11.0",
+	)), 0)
+	assert.False(t, operatorInstalledCSVGauge.DeleteLabelValues(
+		"inventory", "managed", "quay-operator.v3.10.2", "3.10.2",
+	))
+	assert.InDelta(t, 0, gaugeValue(t, operatorUpgradePendingGauge.WithLabelValues("inventory", "managed")), 0)
+	assert.InDelta(t, -1, gaugeValue(t, operatorCatalogSourceHealthGauge.WithLabelValues("inventory", "managed")), 0)
+	assert.False(t, operatorDeprecationGauge.DeleteLabelValues("inventory", "managed"))
+	assert.False(t, operatorMinorChannelUpgradeGauge.DeleteLabelValues("inventory", "managed"))
+
+	removeOperatorPolicyMetrics(ctrl.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+
+	assert.False(t, operatorInstalledCSVGauge.DeleteLabelValues(
+		"inventory", "managed", "quay-operator.v3.11.0", "3.11.0",
+	))
+	assert.False(t, operatorUpgradePendingGauge.DeleteLabelValues("inventory", "managed"))
+}
@@ -0,0 +1,158 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// cachedObjectResult is the result of evaluating an object template against a single existing object
+// in watch mode. It's stored in processedPolicyCache so that when a watch event is for one of the many
+// objects selected by an object template, only the changed object is evaluated again and the cached
+// results of the other objects are merged into the status.
+type cachedObjectResult struct {
+	// generation is the policy generation that was evaluated since any change to the spec can change the
+	// result
+	generation int64
+	// resourceVersion is the resourceVersion of the object that was evaluated
+	resourceVersion string
+	// desiredHash is the hash of the desired object after templates were resolved
+	desiredHash    string
+	relatedObjects []policyv1.RelatedObject
+	result         objectTmplEvalResult
+}
+
+// getObjectResultKey returns the processedPolicyCache key of the result of the object template at the
+// index for the object with the namespace and name.
+func getObjectResultKey(index int, namespace string, name string) string {
+	return fmt.Sprintf("result/%d/%s/%s", index, namespace, name)
+}
+
+// getDesiredHash returns a hash of the desired object to detect when the resolved object template for
+// the object changed. An empty string is returned if the object can't be marshaled, in which case the
+// result isn't cached.
+func getDesiredHash(desiredObj *unstructured.Unstructured) string {
+	desiredBytes, err := json.Marshal(desiredObj.Object)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(desiredBytes)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// setObjectResult caches the result of evaluating the object template against the object at the
+// resourceVersion. Only results that depend solely on the comparison of the object are cached, so
+// enforcement results, API errors, and time dependent results such as a deletion in progress are
+// determined again in the next evaluation.
+func (r *ConfigurationPolicyReconciler) setObjectResult(
+	policy *policyv1.ConfigurationPolicy,
+	key string,
+	resourceVersion string,
+	desiredHash string,
+	relatedObjects []policyv1.RelatedObject,
+	result objectTmplEvalResult,
+	remediation policyv1.RemediationAction,
+) {
+	if resourceVersion == "" || desiredHash == "" || !isComparisonResult(result, remediation) {
+		return
+	}
+
+	policyMap := &sync.Map{}
+
+	loadedPolicyMap, loaded := r.processedPolicyCache.LoadOrStore(policy.GetUID(), policyMap)
+	if loaded {
+		policyMap = loadedPolicyMap.(*sync.Map)
+	}
+
+	policyMap.Store(key, cachedObjectResult{
+		generation:      policy.GetGeneration(),
+		resourceVersion: resourceVersion,
+		desiredHash:     desiredHash,
+		relatedObjects:  copyRelatedObjects(relatedObjects),
+		result:          copyObjectTmplEvalResult(result),
+	})
+}
+
+// isComparisonResult returns whether the result only depends on the comparison of the object with the
+// object template. That is the case when the object is compliant, or when it's noncompliant and nothing
+// was enforced.
+func isComparisonResult(result objectTmplEvalResult, remediation policyv1.RemediationAction) bool {
+	if result.apiErr != nil {
+		return false
+	}
+
+	for _, event := range result.events {
+		switch event.reason {
+		case reasonWantFoundExists, reasonGitOpsSkipped:
+			if !event.compliant {
+				return false
+			}
+		case reasonWantFoundNoMatch, reasonWantNotFoundExists:
+			if remediation.IsEnforce() {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// getObjectResult returns the cached result of evaluating the object template against the object if
+// neither the policy, the object, nor the resolved object template have changed since.
+func (r *ConfigurationPolicyReconciler) getObjectResult(
+	policy *policyv1.ConfigurationPolicy, key string, resourceVersion string, desiredHash string,
+) (relatedObjects []policyv1.RelatedObject, result objectTmplEvalResult, found bool) {
+	if resourceVersion == "" || desiredHash == "" {
+		return nil, objectTmplEvalResult{}, false
+	}
+
+	loadedPolicyMap, loaded := r.processedPolicyCache.Load(policy.GetUID())
+	if !loaded {
+		return nil, objectTmplEvalResult{}, false
+	}
+
+	cached, loaded := loadedPolicyMap.(*sync.Map).Load(key)
+	if !loaded {
+		return nil, objectTmplEvalResult{}, false
+	}
+
+	cachedTyped, ok := cached.(cachedObjectResult)
+	if !ok || cachedTyped.generation != policy.GetGeneration() ||
+		cachedTyped.resourceVersion != resourceVersion || cachedTyped.desiredHash != desiredHash {
+		return nil, objectTmplEvalResult{}, false
+	}
+
+	// Copy the cached values since the related objects are modified when the status is updated
+	return copyRelatedObjects(cachedTyped.relatedObjects), copyObjectTmplEvalResult(cachedTyped.result), true
+}
+
+func copyRelatedObjects(relatedObjects []policyv1.RelatedObject) []policyv1.RelatedObject {
+	copied := make([]policyv1.RelatedObject, len(relatedObjects))
+
+	for i := range relatedObjects {
+		relatedObjects[i].DeepCopyInto(&copied[i])
+	}
+
+	return copied
+}
+
+func copyObjectTmplEvalResult(result objectTmplEvalResult) objectTmplEvalResult {
+	result.objectNames = slices.Clone(result.objectNames)
+	result.events = slices.Clone(result.events)
+	result.changedBy = slices.Clone(result.changedBy)
+
+	return result
+}
@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// fakeWatcher is a DynamicWatcher backed by a map of ConfigMaps for evaluating policies in watch mode
+// without an API server. Only the methods used by handleObjectTemplates are implemented.
+type fakeWatcher struct {
+	depclient.DynamicWatcher
+	objects map[string]*unstructured.Unstructured
+	names   []string
+}
+
+func newFakeWatcher(count int) *fakeWatcher {
+	watcher := &fakeWatcher{objects: make(map[string]*unstructured.Unstructured, count)}
+
+	for i := range count {
+		name := fmt.Sprintf("configmap-%05d", i)
+
+		watcher.objects[name] = &unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]any{
+				"name":            name,
+				"namespace":       "bench",
+				"uid":             "uid-" + name,
+				"resourceVersion": "1",
+				"labels":          map[string]any{"app": "bench"},
+			},
+			"data": map[string]any{"setting": "enabled"},
+		}}
+		watcher.names = append(watcher.names, name)
+	}
+
+	return watcher
+}
+
+// update simulates a watch event by changing the data and resourceVersion of the object.
+func (w *fakeWatcher) update(name string, setting string) {
+	obj := w.objects[name]
+
+	rv, _ := strconv.Atoi(obj.GetResourceVersion())
+	obj.SetResourceVersion(strconv.Itoa(rv + 1))
+
+	_ = unstructured.SetNestedField(obj.Object, setting, "data", "setting")
+}
+
+func (w *fakeWatcher) StartQueryBatch(depclient.ObjectIdentifier) error {
+	return nil
+}
+
+func (w *fakeWatcher) EndQueryBatch(depclient.ObjectIdentifier) error {
+	return nil
+}
+
+func (w *fakeWatcher) GVKToGVR(gvk schema.GroupVersionKind) (depclient.ScopedGVR, error) {
+	return depclient.ScopedGVR{
+		GroupVersionResource: gvk.GroupVersion().WithResource("configmaps"), Namespaced: true,
+	}, nil
+}
+
+func (w *fakeWatcher) Get(
+	_ depclient.ObjectIdentifier, _ schema.GroupVersionKind, _ string, name string,
+) (*unstructured.Unstructured, error) {
+	obj, ok := w.objects[name]
+	if !ok {
+		return nil, nil
+	}
+
+	return obj.DeepCopy(), nil
+}
+
+func (w *fakeWatcher) List(
+	_ depclient.ObjectIdentifier, _ schema.GroupVersionKind, _ string, selector labels.Selector,
+) ([]unstructured.Unstructured, error) {
+	list := make([]unstructured.Unstructured, 0, len(w.names))
+
+	for _, name := range w.names {
+		if selector.Matches(labels.Set(w.objects[name].GetLabels())) {
+			list = append(list, *w.objects[name].DeepCopy())
+		}
+	}
+
+	return list, nil
+}
+
+func (w *fakeWatcher) ListWatchedFromCache(depclient.ObjectIdentifier) ([]unstructured.Unstructured, error) {
+	return w.List(depclient.ObjectIdentifier{}, schema.GroupVersionKind{}, "", labels.Everything())
+}
+
+// getSelectorPolicySetup returns a reconciler and a watch mode policy with an objectSelector that
+// selects all of the ConfigMaps of the fakeWatcher.
+func getSelectorPolicySetup(
+	tb testing.TB, watcher *fakeWatcher,
+) (*ConfigurationPolicyReconciler, *policyv1.ConfigurationPolicy) {
+	tb.Helper()
+
+	scheme := runtime.NewScheme()
+	assert.NoError(tb, policyv1.AddToScheme(scheme))
+
+	policy := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "selector-policy", Namespace: "managed", UID: types.UID("policy-uid"), Generation: 1,
+		},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{{
+				ComplianceType: policyv1.MustHave,
+				ObjectSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "bench"}},
+				ObjectDefinition: runtime.RawExtension{
+					Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"namespace":"bench"},` +
+						`"data":{"setting":"enabled"}}`),
+				},
+			}},
+		},
+	}
+
+	fakeClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(policy).
+		WithStatusSubresource(policy).
+		Build()
+
+	objects := make([]runtime.Object, 0, len(watcher.names))
+	for _, name := range watcher.names {
+		objects = append(objects, watcher.objects[name].DeepCopy())
+	}
+
+	r := &ConfigurationPolicyReconciler{
+		Client:                 fakeClient,
+		DynamicWatcher:         watcher,
+		TargetK8sDynamicClient: dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...),
+		Recorder:               &events.FakeRecorder{},
+	}
+
+	return r, policy
+}
+
+func TestHandleObjectTemplatesIncremental(t *testing.T) {
+	t.Parallel()
+
+	watcher := newFakeWatcher(20)
+	r, policy := getSelectorPolicySetup(t, watcher)
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, policyv1.Compliant, policy.Status.ComplianceState)
+	assert.Len(t, policy.Status.RelatedObjects, 20)
+
+	cachedResults := 0
+
+	policyMap, _ := r.processedPolicyCache.Load(policy.GetUID())
+	policyMap.(*sync.Map).Range(func(_, value any) bool {
+		if _, ok := value.(cachedObjectResult); ok {
+			cachedResults++
+		}
+
+		return true
+	})
+
+	assert.Equal(t, 20, cachedResults)
+
+	// Only the changed object is reevaluated and the result is merged with the cached results
+	watcher.update("configmap-00007", "disabled")
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, policyv1.NonCompliant, policy.Status.ComplianceState)
+	assert.Len(t, policy.Status.RelatedObjects, 20)
+
+	for _, related := range policy.Status.RelatedObjects {
+		expected := string(policyv1.Compliant)
+		if related.Object.Metadata.Name == "configmap-00007" {
+			expected = string(policyv1.NonCompliant)
+		}
+
+		assert.Equal(t, expected, related.Compliant, related.Object.Metadata.Name)
+	}
+
+	assert.Contains(
+		t,
+		policy.Status.CompliancyDetails[0].Conditions[0].Message,
+		"configmaps [configmap-00007] found but not as specified",
+	)
+
+	// A changed resourceVersion without a change in compliance is reevaluated too
+	watcher.update("configmap-00007", "enabled")
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, policyv1.Compliant, policy.Status.ComplianceState)
+
+	// A change to the policy invalidates the cached results
+	policy.Generation++
+	policy.Spec.ObjectTemplates[0].ComplianceType = policyv1.MustNotHave
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, policyv1.NonCompliant, policy.Status.ComplianceState)
+}
+
+func TestCachedObjectResult(t *testing.T) {
+	t.Parallel()
+
+	r := &ConfigurationPolicyReconciler{}
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{UID: "policy-uid", Generation: 1}}
+	desiredHash := getDesiredHash(&unstructured.Unstructured{Object: map[string]any{"data": map[string]any{"a": "b"}}})
+	created := false
+	related := []policyv1.RelatedObject{{Properties: &policyv1.ObjectProperties{CreatedByPolicy: &created}}}
+
+	r.setObjectResult(
+		policy, "key", "1", desiredHash, related, objectTmplEvalResult{objectNames: []string{"a"}}, policyv1.Inform,
+	)
+
+	// Modifying the input related objects must not modify the cache
+	*related[0].Properties.CreatedByPolicy = true
+
+	cachedRelated, cachedResult, found := r.getObjectResult(policy, "key", "1", desiredHash)
+	assert.True(t, found)
+	assert.False(t, *cachedRelated[0].Properties.CreatedByPolicy)
+	assert.Equal(t, []string{"a"}, cachedResult.objectNames)
+
+	// Modifying the returned related objects must not modify the cache
+	*cachedRelated[0].Properties.CreatedByPolicy = true
+
+	cachedRelated, _, _ = r.getObjectResult(policy, "key", "1", desiredHash)
+	assert.False(t, *cachedRelated[0].Properties.CreatedByPolicy)
+
+	_, _, found = r.getObjectResult(policy, "key", "2", desiredHash)
+	assert.False(t, found, "a different resourceVersion")
+
+	_, _, found = r.getObjectResult(policy, "key", "1", "other")
+	assert.False(t, found, "a different resolved object template")
+
+	policy.Generation++
+
+	_, _, found = r.getObjectResult(policy, "key", "1", desiredHash)
+	assert.False(t, found, "a different policy generation")
+
+	apiErrResult := objectTmplEvalResult{apiErr: errors.New("oops")}
+	r.setObjectResult(policy, "api-error", "1", desiredHash, related, apiErrResult, policyv1.Inform)
+
+	_, _, found = r.getObjectResult(policy, "api-error", "1", desiredHash)
+	assert.False(t, found, "API errors are not cached")
+
+	tests := map[string]struct {
+		event       objectTmplEvalEvent
+		remediation policyv1.RemediationAction
+		cached      bool
+	}{
+		"compliant": {
+			event:       objectTmplEvalEvent{true, reasonWantFoundExists, policyv1.ReasonCodeFound, ""},
+			remediation: policyv1.Enforce, cached: true,
+		},
+		"inform noncompliant": {
+			event:       objectTmplEvalEvent{false, reasonWantFoundNoMatch, policyv1.ReasonCodeMismatch, ""},
+			remediation: policyv1.Inform, cached: true,
+		},
+		"enforce noncompliant": {
+			event:       objectTmplEvalEvent{false, reasonWantNotFoundExists, policyv1.ReasonCodeFoundNotWanted, ""},
+			remediation: policyv1.Enforce,
+		},
+		"enforced update": {
+			event:       objectTmplEvalEvent{true, reasonUpdateSuccess, policyv1.ReasonCodeUpdated, ""},
+			remediation: policyv1.Enforce,
+		},
+		"deletion in progress": {
+			event:       objectTmplEvalEvent{false, reasonDeleteInProgress, policyv1.ReasonCodeDeleting, ""},
+			remediation: policyv1.Enforce,
+		},
+		"template error": {
+			event: objectTmplEvalEvent{
+				false, "K8s update template error", policyv1.ReasonCodeUpdateFailed, "oops",
+			},
+			remediation: policyv1.Inform,
+		},
+	}
+
+	for name, test := range tests {
+		result := objectTmplEvalResult{events: []objectTmplEvalEvent{test.event}}
+
+		r.setObjectResult(policy, name, "1", desiredHash, related, result, test.remediation)
+
+		_, _, found = r.getObjectResult(policy, name, "1", desiredHash)
+		assert.Equal(t, test.cached, found, name)
+	}
+}
+
+// TestHandleObjectTemplatesDeleteStuck verifies that a deletion in progress isn't reused from the cached
+// results, so the object is reported as stuck once the deletion timeout is reached even though the object
+// didn't change.
+func TestHandleObjectTemplatesDeleteStuck(t *testing.T) {
+	t.Parallel()
+
+	watcher := newFakeWatcher(1)
+	terminating := watcher.objects["configmap-00000"]
+	terminating.SetFinalizers([]string{"example.com/cleanup"})
+	terminating.SetDeletionTimestamp(&metav1.Time{Time: time.Now()})
+
+	r, policy := getSelectorPolicySetup(t, watcher)
+	policy.Spec.RemediationAction = policyv1.Enforce
+	policy.Spec.ObjectTemplates[0] = &policyv1.ObjectTemplate{
+		ComplianceType:  policyv1.MustNotHave,
+		WaitForDeletion: true,
+		DeletionTimeout: "30m",
+		ObjectDefinition: runtime.RawExtension{
+			Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"configmap-00000",` +
+				`"namespace":"bench"}}`),
+		},
+	}
+	assert.NoError(t, r.Update(t.Context(), policy))
+
+	getReason := func() string {
+		if assert.Len(t, policy.Status.RelatedObjects, 1) {
+			return policy.Status.RelatedObjects[0].Reason
+		}
+
+		return ""
+	}
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, reasonDeleteInProgress, getReason())
+
+	_, hasDeadline := r.deletionDeadlines.Load(policy.GetUID())
+	assert.True(t, hasDeadline)
+
+	// Each reconcile clears the deadline, so the evaluation must set it again to be requeued
+	r.deletionDeadlines.Delete(policy.GetUID())
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, reasonDeleteInProgress, getReason())
+
+	_, hasDeadline = r.deletionDeadlines.Load(policy.GetUID())
+	assert.True(t, hasDeadline)
+
+	// Simulate the deletion timeout passing without a change to the object's resourceVersion
+	terminating.SetDeletionTimestamp(&metav1.Time{Time: time.Now().Add(-time.Hour)})
+	r.deletionDeadlines.Delete(policy.GetUID())
+
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+	assert.Equal(t, reasonDeleteStuck, getReason())
+	assert.Equal(t, policyv1.NonCompliant, policy.Status.ComplianceState)
+}
+
+// BenchmarkHandleObjectTemplates measures the evaluation of a watch mode policy with an objectSelector
+// that matches 10k objects. The "full" case clears the cached results before each evaluation as when
+// the policy is evaluated for the first time, and the "watch event" case changes a single object
+// before each evaluation.
+func BenchmarkHandleObjectTemplates(b *testing.B) {
+	watcher := newFakeWatcher(10000)
+
+	b.Run("full", func(b *testing.B) {
+		r, policy := getSelectorPolicySetup(b, watcher)
+
+		for b.Loop() {
+			r.processedPolicyCache.Delete(policy.GetUID())
+
+			if err := r.handleObjectTemplates(b.Context(), policy); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("watch event", func(b *testing.B) {
+		r, policy := getSelectorPolicySetup(b, watcher)
+
+		if err := r.handleObjectTemplates(b.Context(), policy); err != nil {
+			b.Fatal(err)
+		}
+
+		i := 0
+
+		for b.Loop() {
+			watcher.update(watcher.names[i%len(watcher.names)], "enabled")
+			i++
+
+			if err := r.handleObjectTemplates(b.Context(), policy); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
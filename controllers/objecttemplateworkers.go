@@ -0,0 +1,247 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"sync"
+
+	templates "github.com/stolostron/go-template-utils/v7/pkg/templates"
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// clusterLookupFuncs are the template functions that read objects from the cluster.
+var clusterLookupFuncs = [][]byte{
+	[]byte("lookup"),
+	[]byte("fromSecret"),
+	[]byte("fromConfigMap"),
+	[]byte("fromClusterClaim"),
+	[]byte("copySecretData"),
+	[]byte("copyConfigMapData"),
+	[]byte("NodesWithExactRoles"),
+}
+
+// mustHandleInOrder returns whether each object template must be resolved and handled before the next
+// object template is resolved. That's the case when an object template finds objects on the cluster,
+// with an objectSelector or with template lookups, since handling a previous object template can change
+// what is found.
+func mustHandleInOrder(objectTemplates []*policyv1.ObjectTemplate, templatesEnabled bool) bool {
+	for _, objectT := range objectTemplates {
+		if objectT == nil {
+			continue
+		}
+
+		if objectT.ObjectSelector != nil {
+			return true
+		}
+
+		if !templatesEnabled || !templates.HasTemplate(objectT.ObjectDefinition.Raw, "", true) {
+			continue
+		}
+
+		for _, lookupFunc := range clusterLookupFuncs {
+			if bytes.Contains(objectT.ObjectDefinition.Raw, lookupFunc) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// objectTemplateEvaluation is the evaluation of an object template of a policy. When object templates
+// are handled concurrently, the desired objects are determined for every object template in order first,
+// and then the desired objects of independent object templates are handled concurrently.
+type objectTemplateEvaluation struct {
+	desiredObjects []*unstructured.Unstructured
+	scopedGVR      *depclient.ScopedGVR
+	// relatedObjects are the related objects returned when determining the desired objects
+	relatedObjects []policyv1.RelatedObject
+	errEvent       *objectTmplEvalEvent
+	err            error
+	// results has the result of handling each desired object, in the same order as desiredObjects
+	results []desiredObjectResult
+}
+
+type desiredObjectResult struct {
+	relatedObjects []policyv1.RelatedObject
+	result         objectTmplEvalResult
+}
+
+// skipped returns whether the desired objects must not be handled because determining them failed
+// without an event to report.
+func (e *objectTemplateEvaluation) skipped() bool {
+	return e.errEvent == nil && e.err != nil
+}
+
+// objectTemplateGroups groups the object templates which can touch the same object, so that the object
+// templates of a group are handled in order by the same worker and enforcement on an object is in the
+// order of the object templates. Object templates with an unnamed desired object, such as with an
+// objectSelector that matched nothing, can touch any object of the resource, so all the object templates
+// of that resource are in the same group. The groups are ordered by their first object template.
+func objectTemplateGroups(evaluations []objectTemplateEvaluation) [][]int {
+	// parents is a union-find of the object template indexes
+	parents := make([]int, len(evaluations))
+	for i := range parents {
+		parents[i] = i
+	}
+
+	var find func(int) int
+
+	find = func(i int) int {
+		if parents[i] != i {
+			parents[i] = find(parents[i])
+		}
+
+		return parents[i]
+	}
+
+	union := func(i, j int) {
+		rootI, rootJ := find(i), find(j)
+		if rootI < rootJ {
+			parents[rootJ] = rootI
+		} else {
+			parents[rootI] = rootJ
+		}
+	}
+
+	groupResource := func(e *objectTemplateEvaluation) string {
+		return e.scopedGVR.Group + "/" + e.scopedGVR.Resource
+	}
+
+	// Different versions of a resource are the same objects, so only the group and resource are compared
+	wildcardResources := map[string]bool{}
+
+	for i := range evaluations {
+		e := &evaluations[i]
+		if e.skipped() || e.scopedGVR == nil {
+			continue
+		}
+
+		for _, desiredObj := range e.desiredObjects {
+			if desiredObj.GetName() == "" || (e.scopedGVR.Namespaced && desiredObj.GetNamespace() == "") {
+				wildcardResources[groupResource(e)] = true
+			}
+		}
+	}
+
+	owners := map[string]int{}
+
+	for i := range evaluations {
+		e := &evaluations[i]
+		if e.skipped() || e.scopedGVR == nil {
+			continue
+		}
+
+		for _, desiredObj := range e.desiredObjects {
+			key := groupResource(e)
+			if !wildcardResources[key] {
+				key += "/" + desiredObj.GetNamespace() + "/" + desiredObj.GetName()
+			}
+
+			if owner, ok := owners[key]; ok {
+				union(owner, i)
+			} else {
+				owners[key] = i
+			}
+		}
+	}
+
+	groupIndexes := map[int]int{}
+	groups := [][]int{}
+
+	for i := range evaluations {
+		root := find(i)
+
+		groupIndex, ok := groupIndexes[root]
+		if !ok {
+			groupIndex = len(groups)
+			groupIndexes[root] = groupIndex
+			groups = append(groups, nil)
+		}
+
+		groups[groupIndex] = append(groups[groupIndex], i)
+	}
+
+	return groups
+}
+
+// handleDesiredObjects handles the desired objects of the object templates and sets the results on the
+// evaluations. Independent object templates are handled concurrently with up to ObjectTemplateConcurrency
+// workers for the policy, and the desired objects of an object template are always handled in order.
+func (r *ConfigurationPolicyReconciler) handleDesiredObjects(
+	ctx context.Context,
+	plc *policyv1.ConfigurationPolicy,
+	evaluations []objectTemplateEvaluation,
+	usingWatch bool,
+) {
+	groups := objectTemplateGroups(evaluations)
+
+	handleGroup := func(group []int) {
+		for _, index := range group {
+			r.handleTemplateDesiredObjects(ctx, plc, index, &evaluations[index], usingWatch)
+		}
+	}
+
+	workers := min(int(r.ObjectTemplateConcurrency), len(groups))
+	if workers <= 1 {
+		for _, group := range groups {
+			handleGroup(group)
+		}
+
+		return
+	}
+
+	groupsChan := make(chan []int, len(groups))
+	for _, group := range groups {
+		groupsChan <- group
+	}
+
+	close(groupsChan)
+
+	var wg sync.WaitGroup
+
+	for range workers {
+		wg.Go(func() {
+			for group := range groupsChan {
+				handleGroup(group)
+			}
+		})
+	}
+
+	wg.Wait()
+}
+
+// handleTemplateDesiredObjects handles the desired objects of the object template at the index in order.
+func (r *ConfigurationPolicyReconciler) handleTemplateDesiredObjects(
+	ctx context.Context,
+	plc *policyv1.ConfigurationPolicy,
+	index int,
+	evaluation *objectTemplateEvaluation,
+	usingWatch bool,
+) {
+	if evaluation.skipped() {
+		return
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+	objectT := plc.Spec.ObjectTemplates[index]
+
+	evaluation.results = make([]desiredObjectResult, 0, len(evaluation.desiredObjects))
+
+	for _, desiredObj := range evaluation.desiredObjects {
+		log.V(1).Info("Handling the object template for the relevant namespace",
+			"namespace", desiredObj.GetNamespace(), "desiredName", desiredObj.GetName(), "index", index)
+
+		related, result := r.handleObjects(
+			ctx, objectT, desiredObj, index, plc, *evaluation.scopedGVR, usingWatch,
+		)
+
+		evaluation.results = append(evaluation.results, desiredObjectResult{relatedObjects: related, result: result})
+	}
+}
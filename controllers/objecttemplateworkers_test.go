@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestObjectTemplateGroups(t *testing.T) {
+	t.Parallel()
+
+	configMaps := &depclient.ScopedGVR{
+		GroupVersionResource: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}, Namespaced: true,
+	}
+	deploymentsV1 := &depclient.ScopedGVR{
+		GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		Namespaced:           true,
+	}
+	deploymentsV2 := &depclient.ScopedGVR{
+		GroupVersionResource: schema.GroupVersionResource{Group: "apps", Version: "v2", Resource: "deployments"},
+		Namespaced:           true,
+	}
+
+	desired := func(namespace, name string) []*unstructured.Unstructured {
+		obj := &unstructured.Unstructured{}
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+
+		return []*unstructured.Unstructured{obj}
+	}
+
+	tests := map[string]struct {
+		evaluations []objectTemplateEvaluation
+		expected    [][]int
+	}{
+		"independent objects": {
+			evaluations: []objectTemplateEvaluation{
+				{scopedGVR: configMaps, desiredObjects: desired("a", "one")},
+				{scopedGVR: configMaps, desiredObjects: desired("a", "two")},
+				{scopedGVR: deploymentsV1, desiredObjects: desired("a", "one")},
+			},
+			expected: [][]int{{0}, {1}, {2}},
+		},
+		"same object": {
+			evaluations: []objectTemplateEvaluation{
+				{scopedGVR: configMaps, desiredObjects: desired("a", "one")},
+				{scopedGVR: configMaps, desiredObjects: desired("b", "one")},
+				{scopedGVR: configMaps, desiredObjects: append(desired("c", "one"), desired("a", "one")...)},
+				{scopedGVR: configMaps, desiredObjects: desired("c", "one")},
+			},
+			expected: [][]int{{0, 2, 3}, {1}},
+		},
+		"different versions of the same object": {
+			evaluations: []objectTemplateEvaluation{
+				{scopedGVR: deploymentsV1, desiredObjects: desired("a", "one")},
+				{scopedGVR: configMaps, desiredObjects: desired("a", "one")},
+				{scopedGVR: deploymentsV2, desiredObjects: desired("a", "one")},
+			},
+			expected: [][]int{{0, 2}, {1}},
+		},
+		"unnamed object": {
+			evaluations: []objectTemplateEvaluation{
+				{scopedGVR: configMaps, desiredObjects: desired("a", "one")},
+				{scopedGVR: deploymentsV1, desiredObjects: desired("a", "one")},
+				{scopedGVR: configMaps, desiredObjects: desired("b", "two")},
+				{scopedGVR: configMaps, desiredObjects: desired("c", "")},
+			},
+			expected: [][]int{{0, 2, 3}, {1}},
+		},
+		"failed object templates": {
+			evaluations: []objectTemplateEvaluation{
+				{scopedGVR: configMaps, desiredObjects: desired("a", "one")},
+				{err: errors.New("no mapping")},
+				{scopedGVR: configMaps, desiredObjects: desired("a", "one"), err: errors.New("oops")},
+			},
+			expected: [][]int{{0}, {1}, {2}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, objectTemplateGroups(test.evaluations))
+		})
+	}
+}
+
+func TestHandleObjectTemplatesConcurrently(t *testing.T) {
+	t.Parallel()
+
+	watcher := newFakeWatcher(20)
+
+	objectTemplates := func() []*policyv1.ObjectTemplate {
+		objectTemplates := []*policyv1.ObjectTemplate{}
+
+		for i, name := range watcher.names {
+			setting := "enabled"
+			if i%3 == 0 {
+				setting = "disabled"
+			}
+
+			objectTemplates = append(objectTemplates, &policyv1.ObjectTemplate{
+				ComplianceType: policyv1.MustHave,
+				ObjectDefinition: runtime.RawExtension{
+					Raw: fmt.Appendf(nil, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"%s",`+
+						`"namespace":"bench"},"data":{"setting":"%s"}}`, name, setting),
+				},
+			})
+		}
+
+		// Object templates on an object that is also in a previous object template
+		return append(objectTemplates, &policyv1.ObjectTemplate{
+			ComplianceType: policyv1.MustNotHave,
+			ObjectDefinition: runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"configmap-00001",` +
+					`"namespace":"bench"}}`),
+			},
+		}, &policyv1.ObjectTemplate{
+			ComplianceType: policyv1.MustHave,
+			ObjectDefinition: runtime.RawExtension{
+				Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"missing",` +
+					`"namespace":"bench"}}`),
+			},
+		})
+	}
+
+	evaluate := func(concurrency uint8) *policyv1.ConfigurationPolicy {
+		r, policy := getSelectorPolicySetup(t, watcher)
+		r.ObjectTemplateConcurrency = concurrency
+		policy.Spec.ObjectTemplates = objectTemplates()
+
+		assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+
+		return policy
+	}
+
+	sequential := evaluate(1)
+	concurrent := evaluate(8)
+
+	assert.Equal(t, policyv1.NonCompliant, concurrent.Status.ComplianceState)
+	assert.Len(t, concurrent.Status.CompliancyDetails, len(watcher.names)+2)
+	assert.Equal(t, sequential.Status.RelatedObjects, concurrent.Status.RelatedObjects)
+
+	for i, details := range concurrent.Status.CompliancyDetails {
+		assert.Equal(t, sequential.Status.CompliancyDetails[i].ComplianceState, details.ComplianceState)
+		assert.Equal(t, sequential.Status.CompliancyDetails[i].Conditions[0].Message, details.Conditions[0].Message)
+	}
+}
+
+func TestMustHandleInOrder(t *testing.T) {
+	t.Parallel()
+
+	objectTemplate := func(definition string) *policyv1.ObjectTemplate {
+		return &policyv1.ObjectTemplate{
+			ComplianceType:   policyv1.MustHave,
+			ObjectDefinition: runtime.RawExtension{Raw: []byte(definition)},
+		}
+	}
+
+	named := objectTemplate(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a","namespace":"b"}}`)
+	templated := objectTemplate(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a","namespace":"b"},` +
+		`"data":{"value":"{{ printf \"%s\" \"a\" }}"}}`)
+	lookup := objectTemplate(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"a","namespace":"b"},` +
+		`"data":{"value":"{{ fromConfigMap \"b\" \"c\" \"d\" }}"}}`)
+	selector := objectTemplate(`{"apiVersion":"v1","kind":"ConfigMap","metadata":{"namespace":"b"}}`)
+	selector.ObjectSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "a"}}
+
+	tests := map[string]struct {
+		objectTemplates  []*policyv1.ObjectTemplate
+		templatesEnabled bool
+		expected         bool
+	}{
+		"named objects": {
+			objectTemplates: []*policyv1.ObjectTemplate{named, templated}, templatesEnabled: true,
+		},
+		"template lookup": {
+			objectTemplates: []*policyv1.ObjectTemplate{named, lookup}, templatesEnabled: true, expected: true,
+		},
+		"template lookup with templates disabled": {
+			objectTemplates: []*policyv1.ObjectTemplate{named, lookup},
+		},
+		"object selector": {
+			objectTemplates: []*policyv1.ObjectTemplate{selector, named}, expected: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.expected, mustHandleInOrder(test.objectTemplates, test.templatesEnabled))
+		})
+	}
+}
@@ -21,6 +21,7 @@ import (
 	operatorv1alpha1 "github.com/operator-framework/api/pkg/operators/v1alpha1"
 	templates "github.com/stolostron/go-template-utils/v7/pkg/templates"
 	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"go.opentelemetry.io/otel/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -39,6 +40,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
@@ -48,6 +50,7 @@ import (
 	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
 	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
 	common "open-cluster-management.io/config-policy-controller/pkg/common"
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
 )
 
 const (
@@ -128,6 +131,16 @@ type OperatorPolicyReconciler struct {
 	// This is a workaround to account for race conditions where the status is updated but the controller-runtime cache
 	// has not updated yet.
 	lastEvaluatedCache sync.Map
+	// When set, the policies are sharded across the active controller replicas and this replica only handles
+	// the policies it owns
+	Shards *sharding.Coordinator
+	shards *shardTracker
+	// When set, the related objects of the policies are reported in PolicyReports and ClusterPolicyReports
+	PolicyReporter *PolicyReporter
+	// When set, the compliance changes of the policies are sent to the configured HTTP sinks
+	ComplianceNotifier *ComplianceNotifier
+	// When set, the changes made to the objects on the cluster are recorded in the audit log
+	AuditLogger *AuditLogger
 }
 
 // SetupWithManager sets up the controller with the Manager and will reconcile when the dynamic watcher
@@ -135,8 +148,11 @@ type OperatorPolicyReconciler struct {
 func (r *OperatorPolicyReconciler) SetupWithManager(
 	mgr ctrl.Manager, depEvents source.TypedSource[reconcile.Request],
 ) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	builder := ctrl.NewControllerManagedBy(mgr).
 		Named(OperatorControllerName).
+		WithOptions(controller.Options{
+			NewQueue: newEvaluationQueue(operatorPolicyPriorityInfo(mgr.GetClient())),
+		}).
 		For(&policyv1beta1.OperatorPolicy{}, builder.WithPredicates(predicate.Funcs{
 			// Skip most pure status/metadata updates
 			UpdateFunc: func(e event.UpdateEvent) bool {
@@ -177,8 +193,29 @@ func (r *OperatorPolicyReconciler) SetupWithManager(
 			&policyv1beta1.OperatorPolicy{},
 			handler.EnqueueRequestsFromMapFunc(overlapMapper)).
 		WatchesRawSource(depEvents).
-		WithLogConstructor(common.LogConstructor(OperatorControllerName, "OperatorPolicy")).
-		Complete(r)
+		WithLogConstructor(common.LogConstructor(OperatorControllerName, "OperatorPolicy"))
+
+	if r.Shards != nil {
+		r.shards = newShardTracker(r.Shards, "OperatorPolicy",
+			func(ctx context.Context) ([]types.NamespacedName, error) {
+				policies := &policyv1beta1.OperatorPolicyList{}
+				if err := r.List(ctx, policies); err != nil {
+					return nil, err
+				}
+
+				names := make([]types.NamespacedName, 0, len(policies.Items))
+				for _, policy := range policies.Items {
+					names = append(names, client.ObjectKeyFromObject(&policy))
+				}
+
+				return names, nil
+			},
+		)
+
+		builder = builder.WatchesRawSource(r.shards.source())
+	}
+
+	return builder.Complete(r)
 }
 
 func overlapMapper(_ context.Context, obj client.Object) []reconcile.Request {
@@ -225,6 +262,17 @@ var _ reconcile.Reconciler = &OperatorPolicyReconciler{}
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.4/pkg/reconcile
 func (r *OperatorPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := startPolicySpan(ctx, "OperatorPolicy.Reconcile", "OperatorPolicy", req.NamespacedName)
+
+	result, err := r.reconcile(ctx, req)
+
+	endSpan(span, err)
+
+	return result, err
+}
+
+// reconcile evaluates the OperatorPolicy, enforcing it when needed, and updates its status.
+func (r *OperatorPolicyReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	opLog := ctrl.LoggerFrom(ctx)
 	policy := &policyv1beta1.OperatorPolicy{}
 	watcher := opPolIdentifier(req.Namespace, req.Name)
@@ -245,6 +293,21 @@ func (r *OperatorPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			opLog.Info("Operator policy could not be found")
 			removeOperatorPolicyMetrics(req)
 
+			owned := true
+
+			if r.shards != nil {
+				owned = r.shards.forget(req)
+			}
+
+			// The reports are only removed by the replica that owned the policy since every replica gets the
+			// deletion
+			if r.PolicyReporter != nil && owned {
+				err = r.PolicyReporter.Remove(ctx, "OperatorPolicy", req.NamespacedName)
+				if err != nil {
+					opLog.Error(err, "Failed to delete the policy reports of the OperatorPolicy. Ignoring the failure.")
+				}
+			}
+
 			err = r.DynamicWatcher.RemoveWatcher(watcher)
 			if err != nil {
 				opLog.Error(err, "Error updating dependency watcher. Ignoring the failure.")
@@ -258,6 +321,27 @@ func (r *OperatorPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		return reconcile.Result{}, err
 	}
 
+	if r.shards != nil {
+		switch action, wait := r.shards.action(req); action {
+		case shardHandle:
+		case shardWait:
+			opLog.V(2).Info(
+				"Waiting for the controller replicas to be known or for the previous replica to release the policy",
+				"requeueAfter", wait,
+			)
+
+			return reconcile.Result{RequeueAfter: wait}, nil
+		case shardRelease:
+			opLog.Info("Releasing the policy to another controller replica",
+				"replica", r.Shards.Owner(sharding.Key(req.Namespace, req.Name)))
+			r.releasePolicy(ctx, policy)
+
+			return reconcile.Result{}, nil
+		case shardSkip:
+			return reconcile.Result{}, nil
+		}
+	}
+
 	if cachedLastEval, ok := r.lastEvaluatedCache.Load(policy.UID); ok {
 		last, cachedConversionErr := strconv.Atoi(cachedLastEval.(string))
 		current, realConversionErr := strconv.Atoi(policy.GetResourceVersion())
@@ -297,7 +381,12 @@ func (r *OperatorPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	errs := make([]error, 0)
 
-	conditionsToEmit, statusChanged, err := r.handleResources(ctx, policy)
+	handleCtx, span := startSpan(ctx, "OperatorPolicy.handleResources")
+
+	conditionsToEmit, statusChanged, err := r.handleResources(handleCtx, policy)
+
+	endSpan(span, err)
+
 	if err != nil {
 		errs = append(errs, err)
 	}
@@ -339,14 +428,31 @@ func (r *OperatorPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 	}
 
+	statusWritten := true
+
 	if statusChanged || !reflect.DeepEqual(policy.Status, originalStatus) {
-		if err := r.Status().Update(ctx, policy); err != nil {
+		statusCtx, span := startSpan(ctx, "OperatorPolicy.updateStatus")
+
+		err := r.Status().Update(statusCtx, policy)
+
+		endSpan(span, err)
+
+		if err != nil {
 			errs = append(errs, err)
+			statusWritten = false
 		} else {
 			r.lastEvaluatedCache.Store(policy.UID, policy.GetResourceVersion())
 		}
 	}
 
+	if statusWritten {
+		r.reportPolicy(ctx, policy)
+
+		if originalStatus.ComplianceState != policy.Status.ComplianceState {
+			r.notifyCompliance(ctx, policy)
+		}
+	}
+
 	result := reconcile.Result{}
 	finalErr := utilerrors.NewAggregate(errs)
 
@@ -363,6 +469,14 @@ func (r *OperatorPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	).Set(
 		getStatusValue(policy.Status.ComplianceState),
 	)
+	setNoncompliantObjectsMetric("OperatorPolicy", policy, policy.Status.RelatedObjects)
+	setOperatorInventoryMetrics(policy)
+	recordComplianceTransition(
+		"OperatorPolicy", policy, originalStatus.ComplianceState, policy.Status.ComplianceState,
+		policy.Status.History, time.Now(),
+	)
+
+	trace.SpanFromContext(ctx).SetAttributes(policyComplianceKey.String(string(policy.Status.ComplianceState)))
 
 	opLog.Info("Reconciling complete", "finalErr", finalErr,
 		"statusChanged", statusChanged, "eventCount", len(conditionsToEmit))
@@ -445,7 +559,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		desiredSubName = desiredSub.Name
 	}
 
-	ogCorrect, earlyConds, changed, err := r.handleOpGroup(ctx, policy, desiredOG, desiredSubName)
+	handlerCtx, span := startSpan(ctx, "OperatorPolicy.handleOpGroup")
+	ogCorrect, earlyConds, changed, err := r.handleOpGroup(handlerCtx, policy, desiredOG, desiredSubName)
+	endSpan(span, err)
+
 	earlyComplianceEvents = append(earlyComplianceEvents, earlyConds...)
 	condChanged = condChanged || changed
 
@@ -455,7 +572,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		return earlyComplianceEvents, condChanged, err
 	}
 
-	subscription, earlyConds, changed, err := r.handleSubscription(ctx, policy, desiredSub, ogCorrect)
+	handlerCtx, span = startSpan(ctx, "OperatorPolicy.handleSubscription")
+	subscription, earlyConds, changed, err := r.handleSubscription(handlerCtx, policy, desiredSub, ogCorrect)
+	endSpan(span, err)
+
 	earlyComplianceEvents = append(earlyComplianceEvents, earlyConds...)
 	condChanged = condChanged || changed
 
@@ -465,7 +585,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		return earlyComplianceEvents, condChanged, err
 	}
 
-	changed, err = r.handleInstallPlan(ctx, policy, subscription)
+	handlerCtx, span = startSpan(ctx, "OperatorPolicy.handleInstallPlan")
+	changed, err = r.handleInstallPlan(handlerCtx, policy, subscription)
+	endSpan(span, err)
+
 	condChanged = condChanged || changed
 
 	if err != nil {
@@ -474,7 +597,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		return earlyComplianceEvents, condChanged, err
 	}
 
-	csv, earlyConds, changed, err := r.handleCSV(ctx, policy, subscription)
+	handlerCtx, span = startSpan(ctx, "OperatorPolicy.handleCSV")
+	csv, earlyConds, changed, err := r.handleCSV(handlerCtx, policy, subscription)
+	endSpan(span, err)
+
 	earlyComplianceEvents = append(earlyComplianceEvents, earlyConds...)
 	condChanged = condChanged || changed
 
@@ -496,7 +622,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		return earlyComplianceEvents, condChanged, err
 	}
 
-	earlyConds, changed, err = r.handleCRDs(ctx, policy, subscription)
+	handlerCtx, span = startSpan(ctx, "OperatorPolicy.handleCRDs")
+	earlyConds, changed, err = r.handleCRDs(handlerCtx, policy, subscription)
+	endSpan(span, err)
+
 	earlyComplianceEvents = append(earlyComplianceEvents, earlyConds...)
 	condChanged = condChanged || changed
 
@@ -506,7 +635,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		return earlyComplianceEvents, condChanged, err
 	}
 
-	changed, err = r.handleDeployment(ctx, policy, csv)
+	handlerCtx, span = startSpan(ctx, "OperatorPolicy.handleDeployment")
+	changed, err = r.handleDeployment(handlerCtx, policy, csv)
+	endSpan(span, err)
+
 	condChanged = condChanged || changed
 
 	if err != nil {
@@ -515,7 +647,10 @@ func (r *OperatorPolicyReconciler) handleResources(ctx context.Context, policy *
 		return earlyComplianceEvents, condChanged, err
 	}
 
+	_, span = startSpan(ctx, "OperatorPolicy.handleCatalogSource")
 	changed, err = r.handleCatalogSource(policy, subscription)
+	endSpan(span, err)
+
 	condChanged = condChanged || changed
 
 	if err != nil {
@@ -1194,7 +1329,7 @@ func (r *OperatorPolicyReconciler) musthaveOpGroup(
 			earlyConds = append(earlyConds, calculateComplianceCondition(policy))
 		}
 
-		err := r.createWithNamespace(ctx, desiredOpGroup)
+		err := r.createWithNamespace(ctx, policy, desiredOpGroup)
 		if err != nil {
 			return false, nil, changed, fmt.Errorf("error creating the OperatorGroup: %w", err)
 		}
@@ -1230,6 +1365,8 @@ func (r *OperatorPolicyReconciler) musthaveOpGroup(
 			return false, nil, updateStatus(policy, mismatchCond("OperatorGroup"), missing, badExisting), nil
 		}
 
+		foundOpGroup := opGroup.DeepCopy()
+
 		updateNeeded, skipUpdate, err := r.mergeOpGroups(ctx, desiredOpGroup, &opGroup)
 		if err != nil {
 			return false, nil, false, fmt.Errorf("error checking if the OperatorGroup needs an update: %w", err)
@@ -1272,6 +1409,8 @@ func (r *OperatorPolicyReconciler) musthaveOpGroup(
 		opLog.Info("Updating OperatorGroup to match desired state", "opGroupName", opGroup.GetName())
 
 		err = r.TargetClient.Update(ctx, &opGroup)
+		r.auditObjectChange(ctx, policy, auditActionUpdate, operatorGroupGVK, foundOpGroup, &opGroup, err)
+
 		if err != nil {
 			return false, nil, changed, fmt.Errorf("error updating the OperatorGroup: %w", err)
 		}
@@ -1289,19 +1428,26 @@ func (r *OperatorPolicyReconciler) musthaveOpGroup(
 }
 
 // createWithNamespace will create the input object and the object's namespace if needed.
-func (r *OperatorPolicyReconciler) createWithNamespace(ctx context.Context, object client.Object) error {
+func (r *OperatorPolicyReconciler) createWithNamespace(
+	ctx context.Context, policy *policyv1beta1.OperatorPolicy, object client.Object,
+) error {
 	opLog := ctrl.LoggerFrom(ctx)
+	gvk := object.GetObjectKind().GroupVersionKind()
 
-	opLog.Info("Creating resource", "resourceGVK", object.GetObjectKind().GroupVersionKind(),
+	opLog.Info("Creating resource", "resourceGVK", gvk,
 		"resourceName", object.GetName(), "resourceNamespace", object.GetNamespace())
 
 	err := r.TargetClient.Create(ctx, object)
 	if err == nil {
+		r.auditObjectChange(ctx, policy, auditActionCreate, gvk, nil, object, nil)
+
 		return nil
 	}
 
 	// If the error is not due to a missing namespace or the namespace is not set on the object, return the error.
 	if !isNamespaceNotFound(err) || object.GetNamespace() == "" {
+		r.auditObjectChange(ctx, policy, auditActionCreate, gvk, nil, object, err)
+
 		return err
 	}
 
@@ -1314,12 +1460,19 @@ func (r *OperatorPolicyReconciler) createWithNamespace(ctx context.Context, obje
 	}
 
 	err = r.TargetClient.Create(ctx, &ns)
+	if !k8serrors.IsAlreadyExists(err) {
+		r.auditObjectChange(ctx, policy, auditActionCreate, namespaceGVK, nil, &ns, err)
+	}
+
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
 		return err
 	}
 
 	// Try creating the object again now that the namespace was created.
-	return r.TargetClient.Create(ctx, object)
+	err = r.TargetClient.Create(ctx, object)
+	r.auditObjectChange(ctx, policy, auditActionCreate, gvk, nil, object, err)
+
+	return err
 }
 
 // isNamespaceNotFound detects if the input error from r.Create failed due to the specified namespace not existing.
@@ -1432,6 +1585,8 @@ func (r *OperatorPolicyReconciler) mustnothaveOpGroup(
 	opLog.Info("Deleting OperatorGroup", "opGroupName", desiredOpGroup.Name)
 
 	err := r.TargetClient.Delete(ctx, desiredOpGroup)
+	r.auditObjectChange(ctx, policy, auditActionDelete, operatorGroupGVK, desiredOpGroup, nil, err)
+
 	if err != nil {
 		return earlyConds, changed, fmt.Errorf("error deleting the OperatorGroup: %w", err)
 	}
@@ -1492,7 +1647,7 @@ func (r *OperatorPolicyReconciler) musthaveSubscription(
 			earlyConds = append(earlyConds, calculateComplianceCondition(policy))
 		}
 
-		err := r.createWithNamespace(ctx, desiredSub)
+		err := r.createWithNamespace(ctx, policy, desiredSub)
 		if err != nil {
 			return nil, nil, changed, fmt.Errorf("error creating the Subscription: %w", err)
 		}
@@ -1505,6 +1660,7 @@ func (r *OperatorPolicyReconciler) musthaveSubscription(
 	}
 
 	// Subscription found; check if specs match
+	originalSub := foundSub.DeepCopy()
 	updateNeeded, skipUpdate, err := r.mergeSubscriptions(ctx, desiredSub, foundSub, policy.Spec.RemediationAction)
 	if err != nil {
 		return nil, nil, false, fmt.Errorf("error checking if the Subscription needs an update: %w", err)
@@ -1585,6 +1741,8 @@ func (r *OperatorPolicyReconciler) musthaveSubscription(
 		"subNamespace", foundSub.GetNamespace())
 
 	err = r.TargetClient.Update(ctx, mergedSub)
+	r.auditObjectChange(ctx, policy, auditActionUpdate, subscriptionGVK, originalSub, mergedSub, err)
+
 	if err != nil {
 		return mergedSub, nil, changed, fmt.Errorf("error updating the Subscription: %w", err)
 	}
@@ -1886,7 +2044,10 @@ func (r *OperatorPolicyReconciler) considerResolutionFailed(
 
 	mergedSub.Status.CurrentCSV = existingCSV.GetName()
 
-	if err := r.TargetClient.Status().Update(ctx, mergedSub); err != nil {
+	err = r.TargetClient.Status().Update(ctx, mergedSub)
+	r.auditObjectChange(ctx, policy, auditActionUpdate, subscriptionGVK, nil, mergedSub, err)
+
+	if err != nil {
 		return mergedSub, nil, changed,
 			fmt.Errorf("error updating the Subscription status to point to the CSV: %w", err)
 	}
@@ -1947,6 +2108,8 @@ func (r *OperatorPolicyReconciler) mustnothaveSubscription(
 		"subNamespace", foundUnstructSub.GetNamespace())
 
 	err := r.TargetClient.Delete(ctx, foundUnstructSub)
+	r.auditObjectChange(ctx, policy, auditActionDelete, subscriptionGVK, foundUnstructSub, nil, err)
+
 	if err != nil {
 		return foundSub, earlyConds, changed, fmt.Errorf("error deleting the Subscription: %w", err)
 	}
@@ -2206,11 +2369,18 @@ func (r *OperatorPolicyReconciler) musthaveInstallPlan(
 	opLog.Info("Approving InstallPlan", "InstallPlanName", latestInstallPlan.Name,
 		"InstallPlanNamespace", latestInstallPlan.Namespace)
 
+	unapprovedInstallPlan := latestInstallPlanUnstruct.DeepCopy()
+
 	if err := unstructured.SetNestedField(latestInstallPlanUnstruct.Object, true, "spec", "approved"); err != nil {
 		return false, fmt.Errorf("error approving InstallPlan: %w", err)
 	}
 
-	if err := r.TargetClient.Update(ctx, latestInstallPlanUnstruct); err != nil {
+	err = r.TargetClient.Update(ctx, latestInstallPlanUnstruct)
+	r.auditObjectChange(
+		ctx, policy, auditActionUpdate, installPlanGVK, unapprovedInstallPlan, latestInstallPlanUnstruct, err,
+	)
+
+	if err != nil {
 		return false, fmt.Errorf("error updating approved InstallPlan: %w", err)
 	}
 
@@ -2556,7 +2726,10 @@ func (r *OperatorPolicyReconciler) cleanupStaleInstallation(
 		opLog.Info("Deleting stale Subscription from previous reconcile",
 			"subName", foundSub.GetName(), "subNamespace", foundSub.GetNamespace())
 
-		if err := r.TargetClient.Delete(ctx, foundSub); err != nil {
+		err := r.TargetClient.Delete(ctx, foundSub)
+		r.auditObjectChange(ctx, policy, auditActionDelete, subscriptionGVK, foundSub, nil, err)
+
+		if err != nil {
 			return fmt.Errorf("error deleting stale Subscription: %w", err)
 		}
 	}
@@ -2701,6 +2874,8 @@ func (r *OperatorPolicyReconciler) mustnothaveCSV(
 			"csvNamespace", csvList[i].GetNamespace())
 
 		err := r.TargetClient.Delete(ctx, &csvList[i])
+		r.auditObjectChange(ctx, policy, auditActionDelete, clusterServiceVersionGVK, &csvList[i], nil, err)
+
 		if err != nil {
 			changed := updateStatus(policy, foundNotWantedCond("ClusterServiceVersion", csvNames...), relatedCSVs...)
 
@@ -2879,6 +3054,8 @@ func (r *OperatorPolicyReconciler) handleCRDs(
 		opLog.Info("Deleting CustomResourceDefinition", "crdName", crdList[i].GetName())
 
 		err := r.TargetClient.Delete(ctx, &crdList[i])
+		r.auditObjectChange(ctx, policy, auditActionDelete, customResourceDefinitionGVK, &crdList[i], nil, err)
+
 		if err != nil {
 			changed := updateStatus(policy, foundNotWantedCond("CustomResourceDefinition"), relatedCRDs...)
 
@@ -83,7 +83,8 @@ func updateStatus(
 				}
 			}
 
-			if prevObj.Compliant != updatedObj.Compliant || prevObj.Reason != updatedObj.Reason {
+			if prevObj.Compliant != updatedObj.Compliant || prevObj.Reason != updatedObj.Reason ||
+				prevObj.ReasonCode != updatedObj.ReasonCode {
 				relObjsChanged = true
 			}
 		}
@@ -991,27 +992,30 @@ var catalogSourceUnknownCond = metav1.Condition{
 // missingWantedObj returns a NonCompliant RelatedObject with reason = 'Resource not found but should exist'
 func missingWantedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    reasonWantFoundDNE,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     reasonWantFoundDNE,
+		ReasonCode: policyv1.ReasonCodeMissing,
 	}
 }
 
 // missingNotWantedObj returns a Compliant RelatedObject with reason = 'Resource not found as expected'
 func missingNotWantedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonWantNotFoundDNE,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonWantNotFoundDNE,
+		ReasonCode: policyv1.ReasonCodeNotFound,
 	}
 }
 
 // foundNotWantedObj returns a NonCompliant RelatedObject with reason = 'Resource found but should not exist'
 func foundNotWantedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    reasonWantNotFoundExists,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     reasonWantNotFoundExists,
+		ReasonCode: policyv1.ReasonCodeFoundNotWanted,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
@@ -1022,9 +1026,10 @@ func foundNotWantedObj(obj client.Object) policyv1.RelatedObject {
 // reason = 'Resource found but will not be handled in mustnothave mode'
 func foundNotApplicableObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonFoundNotApplicable,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonFoundNotApplicable,
+		ReasonCode: policyv1.ReasonCodeNotApplicable,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
@@ -1042,15 +1047,18 @@ func missingObj(
 ) policyv1.RelatedObject {
 	var compliance policyv1.ComplianceState
 	var reason string
+	var reasonCode policyv1.ReasonCode
 
 	if complianceType.IsMustHave() {
 		compliance = policyv1.NonCompliant
 		reason = reasonWantFoundDNE
+		reasonCode = policyv1.ReasonCodeMissing
 	} else {
 		// Non-Applicables are not handled by controller
 		// However if the're not found -> report NA or report not found as expected?
 		compliance = policyv1.Compliant
 		reason = reasonWantNotFoundDNE
+		reasonCode = policyv1.ReasonCodeNotFound
 	}
 
 	return policyv1.RelatedObject{
@@ -1062,8 +1070,9 @@ func missingObj(
 				Namespace: namespace,
 			},
 		},
-		Compliant: string(compliance),
-		Reason:    reason,
+		Compliant:  string(compliance),
+		Reason:     reason,
+		ReasonCode: reasonCode,
 	}
 }
 
@@ -1072,9 +1081,10 @@ func createdObj(obj client.Object) policyv1.RelatedObject {
 	created := true
 
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonWantFoundCreated,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonWantFoundCreated,
+		ReasonCode: policyv1.ReasonCodeCreated,
 		Properties: &policyv1.ObjectProperties{
 			CreatedByPolicy: &created,
 			UID:             string(obj.GetUID()),
@@ -1085,9 +1095,10 @@ func createdObj(obj client.Object) policyv1.RelatedObject {
 // deletedObj returns a Compliant RelatedObject with reason = 'K8s deletion success'
 func deletedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonDeleteSuccess,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonDeleteSuccess,
+		ReasonCode: policyv1.ReasonCodeDeleted,
 	}
 }
 
@@ -1095,18 +1106,20 @@ func deletedObj(obj client.Object) policyv1.RelatedObject {
 // reason = 'The object is being deleted but has not been removed yet'
 func deletingObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    "The object is being deleted but has not been removed yet",
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     "The object is being deleted but has not been removed yet",
+		ReasonCode: policyv1.ReasonCodeDeleting,
 	}
 }
 
 // matchedObj returns a Compliant RelatedObject with reason = 'Resource found as expected'
 func matchedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonWantFoundExists,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonWantFoundExists,
+		ReasonCode: policyv1.ReasonCodeFound,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
@@ -1116,9 +1129,10 @@ func matchedObj(obj client.Object) policyv1.RelatedObject {
 // mismatchedObj returns a NonCompliant RelatedObject with reason = 'Resource found but does not match'
 func mismatchedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    reasonWantFoundNoMatch,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     reasonWantFoundNoMatch,
+		ReasonCode: policyv1.ReasonCodeMismatch,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
@@ -1128,22 +1142,24 @@ func mismatchedObj(obj client.Object) policyv1.RelatedObject {
 // updatedObj returns a Compliant RelatedObject with reason = 'K8s update success'
 func updatedObj(obj client.Object) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonUpdateSuccess,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonUpdateSuccess,
+		ReasonCode: policyv1.ReasonCodeUpdated,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
 	}
 }
 
-// nonCompObj returns a NonCompliant RelatedObject with the given reason.
+// nonCompObj returns a NonCompliant RelatedObject with the given reason and the Error reason code.
 // It includes the UID of the given object.
 func nonCompObj(obj client.Object, reason string) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(obj),
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    reason,
+		Object:     policyv1.ObjectResourceFromObj(obj),
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     reason,
+		ReasonCode: policyv1.ReasonCodeError,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
@@ -1161,7 +1177,8 @@ func leftoverObj(obj client.Object) policyv1.RelatedObject {
 		Properties: &policyv1.ObjectProperties{
 			UID: string(obj.GetUID()),
 		},
-		Reason: "The " + kind + " is attached to a mustnothave policy, but does not need to be removed",
+		Reason:     "The " + kind + " is attached to a mustnothave policy, but does not need to be removed",
+		ReasonCode: policyv1.ReasonCodeExempted,
 	}
 }
 
@@ -1172,9 +1189,10 @@ func opGroupTooManyObjs(opGroups []unstructured.Unstructured) []policyv1.Related
 
 	for i, opGroup := range opGroups {
 		objs = append(objs, policyv1.RelatedObject{
-			Object:    policyv1.ObjectResourceFromObj(&opGroups[i]),
-			Compliant: string(policyv1.NonCompliant),
-			Reason:    "There is more than one OperatorGroup in this namespace",
+			Object:     policyv1.ObjectResourceFromObj(&opGroups[i]),
+			Compliant:  string(policyv1.NonCompliant),
+			Reason:     "There is more than one OperatorGroup in this namespace",
+			ReasonCode: policyv1.ReasonCodeConflict,
 			Properties: &policyv1.ObjectProperties{
 				UID: string(opGroup.GetUID()),
 			},
@@ -1196,8 +1214,9 @@ func noInstallPlansObj(namespace string) policyv1.RelatedObject {
 				Namespace: namespace,
 			},
 		},
-		Compliant: string(policyv1.Compliant),
-		Reason:    "There are no relevant InstallPlans in this namespace",
+		Compliant:  string(policyv1.Compliant),
+		Reason:     "There are no relevant InstallPlans in this namespace",
+		ReasonCode: policyv1.ReasonCodeNotFound,
 	}
 }
 
@@ -1226,6 +1245,8 @@ func existingInstallPlanObj(
 
 	switch phase {
 	case string(operatorv1alpha1.InstallPlanPhaseInstalling):
+		relObj.ReasonCode = policyv1.ReasonCodeInstalling
+
 		// Check policy.spec.statusConfig.upgradesAvailable to determine `compliant`.
 		if complianceConfig != "Compliant" {
 			relObj.Compliant = string(policyv1.NonCompliant)
@@ -1233,6 +1254,8 @@ func existingInstallPlanObj(
 			relObj.Compliant = string(policyv1.Compliant)
 		}
 	case string(operatorv1alpha1.InstallPlanPhaseRequiresApproval):
+		relObj.ReasonCode = policyv1.ReasonCodeApprovalRequired
+
 		// Check policy.spec.statusConfig.upgradesAvailable to determine `compliant`.
 		if complianceConfig != "Compliant" {
 			relObj.Compliant = string(policyv1.NonCompliant)
@@ -1241,8 +1264,10 @@ func existingInstallPlanObj(
 		}
 	case string(operatorv1alpha1.InstallPlanPhaseComplete):
 		relObj.Compliant = string(policyv1.Compliant)
+		relObj.ReasonCode = policyv1.ReasonCodeFound
 	default:
 		relObj.Compliant = string(policyv1.NonCompliant)
+		relObj.ReasonCode = policyv1.ReasonCodeUnhealthy
 	}
 
 	return relObj
@@ -1260,8 +1285,9 @@ func missingCSVObj(name string, namespace string) policyv1.RelatedObject {
 				Namespace: namespace,
 			},
 		},
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    reasonWantFoundDNE,
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     reasonWantFoundDNE,
+		ReasonCode: policyv1.ReasonCodeMissing,
 	}
 }
 
@@ -1277,8 +1303,9 @@ func missingNotWantedCSVObj(namespace string) policyv1.RelatedObject {
 				Namespace: namespace,
 			},
 		},
-		Compliant: string(policyv1.Compliant),
-		Reason:    reasonWantNotFoundDNE,
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reasonWantNotFoundDNE,
+		ReasonCode: policyv1.ReasonCodeNotFound,
 	}
 }
 
@@ -1287,14 +1314,21 @@ func missingNotWantedCSVObj(namespace string) policyv1.RelatedObject {
 // is in the Succeeded phase.
 func existingCSVObj(csv *operatorv1alpha1.ClusterServiceVersion) policyv1.RelatedObject {
 	compliance := policyv1.NonCompliant
-	if csv.Status.Phase == operatorv1alpha1.CSVPhaseSucceeded {
+	reasonCode := policyv1.ReasonCodeInstalling
+
+	switch csv.Status.Phase {
+	case operatorv1alpha1.CSVPhaseSucceeded:
 		compliance = policyv1.Compliant
+		reasonCode = policyv1.ReasonCodeFound
+	case operatorv1alpha1.CSVPhaseFailed:
+		reasonCode = policyv1.ReasonCodeUnhealthy
 	}
 
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(csv),
-		Compliant: string(compliance),
-		Reason:    string(csv.Status.Reason),
+		Object:     policyv1.ObjectResourceFromObj(csv),
+		Compliant:  string(compliance),
+		Reason:     string(csv.Status.Reason),
+		ReasonCode: reasonCode,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(csv.GetUID()),
 		},
@@ -1305,9 +1339,10 @@ func existingCSVObj(csv *operatorv1alpha1.ClusterServiceVersion) policyv1.Relate
 // with Reason 'ClusterServiceVersion (_____) is not an approved version'.
 func disallowedCSVObj(csv *operatorv1alpha1.ClusterServiceVersion) policyv1.RelatedObject {
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(csv),
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    "ClusterServiceVersion (" + csv.Name + ") is not an approved version",
+		Object:     policyv1.ObjectResourceFromObj(csv),
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     "ClusterServiceVersion (" + csv.Name + ") is not an approved version",
+		ReasonCode: policyv1.ReasonCodeNotApproved,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(csv.GetUID()),
 		},
@@ -1323,8 +1358,9 @@ var noExistingCSVObj = policyv1.RelatedObject{
 			Name: "-",
 		},
 	},
-	Compliant: "Inapplicable",
-	Reason:    "No relevant ClusterServiceVersion found",
+	Compliant:  "Inapplicable",
+	Reason:     "No relevant ClusterServiceVersion found",
+	ReasonCode: policyv1.ReasonCodeNotFound,
 }
 
 // noExistingCRDObj is a RelatedObject for CustomResourceDefinitions,
@@ -1339,8 +1375,9 @@ var noExistingCRDObj = policyv1.RelatedObject{
 			Name: "-",
 		},
 	},
-	Compliant: "Inapplicable",
-	Reason:    "No relevant CustomResourceDefinitions found",
+	Compliant:  "Inapplicable",
+	Reason:     "No relevant CustomResourceDefinitions found",
+	ReasonCode: policyv1.ReasonCodeNotFound,
 }
 
 // existingDeploymentObj returns a RelatedObject for a Deployment, which will
@@ -1353,10 +1390,12 @@ func existingDeploymentObj(
 ) policyv1.RelatedObject {
 	compliance := policyv1.NonCompliant
 	reason := "Deployment Unavailable"
+	reasonCode := policyv1.ReasonCodeUnhealthy
 
 	if dep.Status.UnavailableReplicas == 0 {
 		compliance = policyv1.Compliant
 		reason = "Deployment Available"
+		reasonCode = policyv1.ReasonCodeFound
 	} else if complianceConfig == "Compliant" {
 		compliance = policyv1.Compliant
 		// Notify user since complianceConfig was changed from NonCompliant -> Compliant
@@ -1364,9 +1403,10 @@ func existingDeploymentObj(
 	}
 
 	return policyv1.RelatedObject{
-		Object:    policyv1.ObjectResourceFromObj(dep),
-		Compliant: string(compliance),
-		Reason:    reason,
+		Object:     policyv1.ObjectResourceFromObj(dep),
+		Compliant:  string(compliance),
+		Reason:     reason,
+		ReasonCode: reasonCode,
 		Properties: &policyv1.ObjectProperties{
 			UID: string(dep.GetUID()),
 		},
@@ -1385,8 +1425,9 @@ var noExistingDeploymentObj = policyv1.RelatedObject{
 			Name: "-",
 		},
 	},
-	Compliant: "Inapplicable",
-	Reason:    "No relevant deployments found",
+	Compliant:  "Inapplicable",
+	Reason:     "No relevant deployments found",
+	ReasonCode: policyv1.ReasonCodeNotFound,
 }
 
 // catalogSourceObj returns a conditionally compliant RelatedObject with reason
@@ -1402,9 +1443,11 @@ func catalogSourceObj(
 ) policyv1.RelatedObject {
 	compliance := string(policyv1.Compliant)
 	reason := reasonWantFoundExists
+	reasonCode := policyv1.ReasonCodeFound
 
 	if isUnhealthy {
 		reason = reasonWantFoundExists + " but is unhealthy"
+		reasonCode = policyv1.ReasonCodeUnhealthy
 
 		if complianceConfig != "Compliant" {
 			compliance = string(policyv1.NonCompliant)
@@ -1413,6 +1456,7 @@ func catalogSourceObj(
 
 	if isMissing {
 		reason = reasonWantFoundDNE
+		reasonCode = policyv1.ReasonCodeMissing
 
 		if complianceConfig != "Compliant" {
 			compliance = string(policyv1.NonCompliant)
@@ -1428,8 +1472,9 @@ func catalogSourceObj(
 				Namespace: catalogNS,
 			},
 		},
-		Compliant: compliance,
-		Reason:    reason,
+		Compliant:  compliance,
+		Reason:     reason,
+		ReasonCode: reasonCode,
 	}
 }
 
@@ -1445,7 +1490,8 @@ func catalogSrcUnknownObj(catalogName string, catalogNS string) policyv1.Related
 				Namespace: catalogNS,
 			},
 		},
-		Compliant: string(policyv1.NonCompliant),
-		Reason:    "Resource found but current state is unknown",
+		Compliant:  string(policyv1.NonCompliant),
+		Reason:     "Resource found but current state is unknown",
+		ReasonCode: policyv1.ReasonCodeUnknown,
 	}
 }
@@ -0,0 +1,306 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+const (
+	reasonInvalidParameters = "Invalid parameters"
+	reasonParametersIgnored = "ParametersIgnored"
+)
+
+var (
+	configMapGVK  = schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	configMapGVR  = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	parametersGVK = policyv1.GroupVersion.WithKind("ConfigurationPolicyParameters")
+	parametersGVR = policyv1.GroupVersion.WithResource("configurationpolicyparameters")
+)
+
+// resolveParameters returns the values of the policy parameters to expose to the Go templates as
+// `.Params`. The values start from the defaults declared in `spec.parameters` and are overridden by
+// the sources in `spec.parametersFrom`, in order. When parameters are declared, only the declared
+// parameters are returned and their values are validated against the declared types. When using
+// watches, the sources are retrieved with the dynamic watcher so that changes to them cause the
+// policy to be reevaluated. If the policy has no parameters, nil is returned. Errors in the policy
+// spec are wrapped in ErrPolicyInvalid.
+func (r *ConfigurationPolicyReconciler) resolveParameters(
+	ctx context.Context, plc *policyv1.ConfigurationPolicy, usingWatch bool,
+) (map[string]any, error) {
+	if len(plc.Spec.Parameters) == 0 && len(plc.Spec.ParametersFrom) == 0 {
+		return nil, nil
+	}
+
+	declared := make(map[string]policyv1.PolicyParameter, len(plc.Spec.Parameters))
+	params := map[string]any{}
+
+	for _, param := range plc.Spec.Parameters {
+		if _, ok := declared[param.Name]; ok {
+			return nil, fmt.Errorf("%w: the parameter %s is declared more than once", ErrPolicyInvalid, param.Name)
+		}
+
+		declared[param.Name] = param
+
+		if param.Default == nil {
+			continue
+		}
+
+		var defaultValue any
+
+		err := json.Unmarshal(param.Default.Raw, &defaultValue)
+		if err == nil {
+			defaultValue, err = convertParameter(param, defaultValue)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: the default value of the parameter %s is invalid: %w",
+				ErrPolicyInvalid, param.Name, err)
+		}
+
+		params[param.Name] = defaultValue
+	}
+
+	for _, ref := range plc.Spec.ParametersFrom {
+		values, err := r.getParametersFromSource(ctx, plc, ref, usingWatch)
+		if err != nil {
+			return nil, err
+		}
+
+		for name, value := range values {
+			param, isDeclared := declared[name]
+			if len(declared) != 0 && !isDeclared {
+				continue
+			}
+
+			if isDeclared {
+				// Values from a ConfigMap are always strings, so they are parsed as the declared type.
+				if strValue, ok := value.(string); ok && ref.Kind == policyv1.ParametersReferenceConfigMap {
+					value, err = parseParameterString(param.Type, strValue)
+				} else {
+					value, err = convertParameter(param, value)
+				}
+
+				if err != nil {
+					return nil, fmt.Errorf("the value of the parameter %s from the %s %s is invalid: %w",
+						name, parametersRefKind(ref), ref.Name, err)
+				}
+			}
+
+			params[name] = value
+		}
+	}
+
+	for _, param := range plc.Spec.Parameters {
+		if _, ok := params[param.Name]; param.Required && !ok {
+			return nil, fmt.Errorf("the required parameter %s is not set", param.Name)
+		}
+	}
+
+	return params, nil
+}
+
+// getParametersFromSource returns the parameter values from the ConfigurationPolicyParameters or
+// ConfigMap referenced in `spec.parametersFrom`. If the source doesn't exist and is optional, nil is
+// returned.
+func (r *ConfigurationPolicyReconciler) getParametersFromSource(
+	ctx context.Context, plc *policyv1.ConfigurationPolicy, ref policyv1.ParametersReference, usingWatch bool,
+) (map[string]any, error) {
+	kind := parametersRefKind(ref)
+
+	gvk := parametersGVK
+	gvr := parametersGVR
+
+	if kind == policyv1.ParametersReferenceConfigMap {
+		gvk = configMapGVK
+		gvr = configMapGVR
+	}
+
+	var source *unstructured.Unstructured
+	var err error
+
+	if usingWatch && r.DynamicWatcher != nil {
+		source, err = r.getObjectFromCache(plc, ctrl.LoggerFrom(ctx), plc.GetNamespace(), ref.Name, gvk)
+	} else {
+		source, err = getObject(
+			ctx,
+			plc.GetNamespace(),
+			ref.Name,
+			depclient.ScopedGVR{GroupVersionResource: gvr, Namespaced: true},
+			r.TargetK8sDynamicClient,
+		)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the %s %s referenced in parametersFrom: %w", kind, ref.Name, err)
+	}
+
+	if source == nil {
+		if ref.Optional {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("the %s %s referenced in parametersFrom was not found", kind, ref.Name)
+	}
+
+	var values map[string]any
+
+	if kind == policyv1.ParametersReferenceConfigMap {
+		data, _, err := unstructured.NestedStringMap(source.Object, "data")
+		if err != nil {
+			return nil, fmt.Errorf("the ConfigMap %s referenced in parametersFrom is invalid: %w", ref.Name, err)
+		}
+
+		values = make(map[string]any, len(data))
+
+		for key, value := range data {
+			values[key] = value
+		}
+	} else {
+		values, _, err = unstructured.NestedMap(source.Object, "spec", "parameters")
+		if err != nil {
+			return nil, fmt.Errorf(
+				"the ConfigurationPolicyParameters %s referenced in parametersFrom is invalid: %w", ref.Name, err,
+			)
+		}
+	}
+
+	return values, nil
+}
+
+// parametersRefKind returns the kind of the parameters source with the default applied.
+func parametersRefKind(ref policyv1.ParametersReference) policyv1.ParametersReferenceKind {
+	if ref.Kind == "" {
+		return policyv1.ParametersReferenceConfigurationPolicyParameters
+	}
+
+	return ref.Kind
+}
+
+// convertParameter validates that the value matches the declared type of the parameter. Whole
+// numbers are returned as int64 for the `integer` type since JSON decoding produces float64 values.
+func convertParameter(param policyv1.PolicyParameter, value any) (any, error) {
+	paramType := param.Type
+	if paramType == "" {
+		paramType = policyv1.ParameterTypeString
+	}
+
+	var ok bool
+
+	switch paramType {
+	case policyv1.ParameterTypeString:
+		_, ok = value.(string)
+	case policyv1.ParameterTypeInteger:
+		switch typedValue := value.(type) {
+		case int64:
+			ok = true
+		case int:
+			return int64(typedValue), nil
+		case float64:
+			if typedValue == math.Trunc(typedValue) {
+				return int64(typedValue), nil
+			}
+		}
+	case policyv1.ParameterTypeNumber:
+		switch value.(type) {
+		case int, int64, float64:
+			ok = true
+		}
+	case policyv1.ParameterTypeBoolean:
+		_, ok = value.(bool)
+	case policyv1.ParameterTypeArray:
+		_, ok = value.([]any)
+	case policyv1.ParameterTypeObject:
+		_, ok = value.(map[string]any)
+	default:
+		return nil, fmt.Errorf("the type %s is not supported", paramType)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("expected a value of type %s but got %T", paramType, value)
+	}
+
+	return value, nil
+}
+
+// parseParameterString parses a string value, such as from a ConfigMap, as the parameter type.
+func parseParameterString(paramType policyv1.ParameterType, value string) (any, error) {
+	switch paramType {
+	case "", policyv1.ParameterTypeString:
+		return value, nil
+	case policyv1.ParameterTypeInteger:
+		return strconv.ParseInt(value, 10, 64)
+	case policyv1.ParameterTypeNumber:
+		return strconv.ParseFloat(value, 64)
+	case policyv1.ParameterTypeBoolean:
+		return strconv.ParseBool(value)
+	case policyv1.ParameterTypeArray, policyv1.ParameterTypeObject:
+		var parsed any
+
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			return nil, fmt.Errorf("the value is not valid JSON: %w", err)
+		}
+
+		return convertParameter(policyv1.PolicyParameter{Type: paramType}, parsed)
+	}
+
+	return nil, fmt.Errorf("the type %s is not supported", paramType)
+}
+
+// warnParametersIgnored emits a warning event on the policy when it has parameters but templates are
+// disabled with the disable-templates annotation, since the parameters are then not used.
+func (r *ConfigurationPolicyReconciler) warnParametersIgnored(
+	ctx context.Context, plc *policyv1.ConfigurationPolicy,
+) {
+	if len(plc.Spec.Parameters) == 0 && len(plc.Spec.ParametersFrom) == 0 {
+		return
+	}
+
+	msg := "The policy parameters are ignored since templates are disabled with the " +
+		disableTemplatesAnnotation + " annotation"
+
+	ctrl.LoggerFrom(ctx).Info(msg)
+
+	r.Recorder.Eventf(plc, nil, corev1.EventTypeWarning, reasonParametersIgnored, "policy: "+plc.GetName(), msg)
+}
+
+// handleParametersErr sets the status of the policy when the parameters could not be resolved. If
+// the error is due to the policy spec, the error is returned so that the policy isn't requeued.
+// Otherwise, nil is returned so that the policy is reevaluated based on the evaluation interval or
+// when a watched parameters source changes.
+func (r *ConfigurationPolicyReconciler) handleParametersErr(
+	ctx context.Context, plc *policyv1.ConfigurationPolicy, err error,
+) error {
+	msg := strings.TrimPrefix(err.Error(), ErrPolicyInvalid.Error()+": ")
+
+	statusChanged := addConditionToStatus(plc, -1, false, reasonInvalidParameters, policyv1.ReasonCodeInvalidSpec, msg)
+	if statusChanged {
+		r.recordInfoEvent(plc, true)
+	}
+
+	r.updatedRelatedObjects(plc, []policyv1.RelatedObject{})
+
+	// Note: don't clean up child objects when the parameters are invalid
+
+	r.addForUpdate(ctx, plc, statusChanged)
+
+	if errors.Is(err, ErrPolicyInvalid) {
+		return err
+	}
+
+	return nil
+}
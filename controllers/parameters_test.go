@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/events"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// getParametersClient returns a fake dynamic client with the parameters sources. The
+// ConfigurationPolicyParameters is created through the client since the fake client can't guess the
+// plural of the kind.
+func getParametersClient(t *testing.T) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			configMapGVR:  "ConfigMapList",
+			parametersGVR: "ConfigurationPolicyParametersList",
+		},
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]any{"name": "team-a", "namespace": "managed"},
+			"data": map[string]any{
+				"replicas": "3",
+				"enabled":  "true",
+				"labels":   `{"team":"a"}`,
+				"extra":    "value",
+			},
+		}},
+	)
+
+	_, err := dynamicClient.Resource(parametersGVR).Namespace("managed").Create(
+		context.TODO(),
+		&unstructured.Unstructured{Object: map[string]any{
+			"apiVersion": "policy.open-cluster-management.io/v1",
+			"kind":       "ConfigurationPolicyParameters",
+			"metadata":   map[string]any{"name": "overrides", "namespace": "managed"},
+			"spec": map[string]any{
+				"parameters": map[string]any{"replicas": int64(5), "prefix": "override"},
+			},
+		}},
+		metav1.CreateOptions{},
+	)
+	assert.NoError(t, err)
+
+	return dynamicClient
+}
+
+func TestResolveParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		parameters     []policyv1.PolicyParameter
+		parametersFrom []policyv1.ParametersReference
+		expected       map[string]any
+		expectedErr    string
+		invalid        bool
+	}{
+		"no parameters": {},
+		"defaults only": {
+			parameters: []policyv1.PolicyParameter{
+				{Name: "prefix", Default: &apiextensionsv1.JSON{Raw: []byte(`"default"`)}},
+				{Name: "replicas", Type: policyv1.ParameterTypeInteger, Default: &apiextensionsv1.JSON{Raw: []byte(`1`)}},
+				{Name: "unset", Type: policyv1.ParameterTypeBoolean},
+			},
+			expected: map[string]any{"prefix": "default", "replicas": int64(1)},
+		},
+		"ConfigMap values are converted to the declared types": {
+			parameters: []policyv1.PolicyParameter{
+				{Name: "replicas", Type: policyv1.ParameterTypeInteger},
+				{Name: "enabled", Type: policyv1.ParameterTypeBoolean},
+				{Name: "labels", Type: policyv1.ParameterTypeObject},
+			},
+			parametersFrom: []policyv1.ParametersReference{
+				{Kind: policyv1.ParametersReferenceConfigMap, Name: "team-a"},
+			},
+			expected: map[string]any{
+				"replicas": int64(3), "enabled": true, "labels": map[string]any{"team": "a"},
+			},
+		},
+		"without a schema all values are exposed": {
+			parametersFrom: []policyv1.ParametersReference{
+				{Kind: policyv1.ParametersReferenceConfigMap, Name: "team-a"},
+			},
+			expected: map[string]any{
+				"replicas": "3", "enabled": "true", "labels": `{"team":"a"}`, "extra": "value",
+			},
+		},
+		"later sources take precedence": {
+			parameters: []policyv1.PolicyParameter{
+				{Name: "prefix", Default: &apiextensionsv1.JSON{Raw: []byte(`"default"`)}},
+				{Name: "replicas", Type: policyv1.ParameterTypeInteger},
+			},
+			parametersFrom: []policyv1.ParametersReference{
+				{Kind: policyv1.ParametersReferenceConfigMap, Name: "team-a"},
+				{Name: "overrides"},
+				{Name: "missing", Optional: true},
+			},
+			expected: map[string]any{"prefix": "override", "replicas": int64(5)},
+		},
+		"missing source": {
+			parametersFrom: []policyv1.ParametersReference{{Name: "missing"}},
+			expectedErr:    "the ConfigurationPolicyParameters missing referenced in parametersFrom was not found",
+		},
+		"missing required parameter": {
+			parameters:  []policyv1.PolicyParameter{{Name: "prefix", Required: true}},
+			expectedErr: "the required parameter prefix is not set",
+		},
+		"value of the wrong type": {
+			parameters: []policyv1.PolicyParameter{{Name: "prefix", Type: policyv1.ParameterTypeInteger}},
+			parametersFrom: []policyv1.ParametersReference{
+				{Kind: policyv1.ParametersReferenceConfigurationPolicyParameters, Name: "overrides"},
+			},
+			expectedErr: "the value of the parameter prefix from the ConfigurationPolicyParameters overrides is " +
+				"invalid: expected a value of type integer but got string",
+		},
+		"default of the wrong type": {
+			parameters: []policyv1.PolicyParameter{
+				{Name: "replicas", Type: policyv1.ParameterTypeInteger, Default: &apiextensionsv1.JSON{Raw: []byte(`1.5`)}},
+			},
+			expectedErr: "the default value of the parameter replicas is invalid: " +
+				"expected a value of type integer but got float64",
+			invalid: true,
+		},
+		"duplicate parameter": {
+			parameters:  []policyv1.PolicyParameter{{Name: "prefix"}, {Name: "prefix"}},
+			expectedErr: "the parameter prefix is declared more than once",
+			invalid:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			r := &ConfigurationPolicyReconciler{TargetK8sDynamicClient: getParametersClient(t)}
+
+			policy := &policyv1.ConfigurationPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "managed"},
+				Spec: policyv1.ConfigurationPolicySpec{
+					Parameters:     test.parameters,
+					ParametersFrom: test.parametersFrom,
+				},
+			}
+
+			params, err := r.resolveParameters(context.TODO(), policy, false)
+
+			if test.expectedErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), test.expectedErr)
+					assert.Equal(t, test.invalid, errors.Is(err, ErrPolicyInvalid))
+				}
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, params)
+		})
+	}
+}
+
+func TestParseParameterString(t *testing.T) {
+	t.Parallel()
+
+	value, err := parseParameterString(policyv1.ParameterTypeNumber, "1.5")
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 1.5, value, 0)
+
+	value, err = parseParameterString(policyv1.ParameterTypeArray, `["a","b"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"a", "b"}, value)
+
+	_, err = parseParameterString(policyv1.ParameterTypeArray, `{"a":"b"}`)
+	assert.ErrorContains(t, err, "expected a value of type array but got map[string]interface {}")
+
+	_, err = parseParameterString(policyv1.ParameterTypeBoolean, "maybe")
+	assert.Error(t, err)
+}
+
+func TestWarnParametersIgnored(t *testing.T) {
+	t.Parallel()
+
+	recorder := events.NewFakeRecorder(1)
+	r := &ConfigurationPolicyReconciler{Recorder: recorder}
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "my-policy", Namespace: "managed"}}
+
+	r.warnParametersIgnored(context.TODO(), policy)
+	assert.Empty(t, recorder.Events)
+
+	policy.Spec.Parameters = []policyv1.PolicyParameter{{Name: "replicas"}}
+
+	r.warnParametersIgnored(context.TODO(), policy)
+
+	if assert.Len(t, recorder.Events, 1) {
+		assert.Equal(t,
+			"Warning ParametersIgnored The policy parameters are ignored since templates are disabled with the "+
+				"policy.open-cluster-management.io/disable-templates annotation",
+			<-recorder.Events,
+		)
+	}
+}
@@ -0,0 +1,395 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+)
+
+const (
+	// policyReportLabel is set on the reports of a policy to a hash of the kind, namespace, and name of the policy,
+	// since these don't always fit in label values.
+	policyReportLabel = "policy.open-cluster-management.io/policy-report-of"
+	// policyReportSource is the identifier of the policy engine in the report results.
+	policyReportSource = "open-cluster-management"
+	// policyReportFieldManager is the field manager of the server-side applied reports.
+	policyReportFieldManager = "config-policy-controller"
+)
+
+var (
+	policyReportGVR = schema.GroupVersionResource{
+		Group: "wgpolicyk8s.io", Version: "v1alpha2", Resource: "policyreports",
+	}
+	clusterPolicyReportGVR = schema.GroupVersionResource{
+		Group: "wgpolicyk8s.io", Version: "v1alpha2", Resource: "clusterpolicyreports",
+	}
+)
+
+// PolicyReporter projects the related objects of policies into Kubernetes Policy WG PolicyReports for the objects
+// in a namespace and ClusterPolicyReports for the cluster-scoped objects. Each policy has its own reports, which
+// are named after the policy and updated when the results of the policy change.
+type PolicyReporter struct {
+	client dynamic.Interface
+	// reported has the policyReportKey of the policies as keys and the values are policyReportState objects
+	reported sync.Map
+	now      func() time.Time
+}
+
+// NewPolicyReporter returns a PolicyReporter that writes the reports with the input client.
+func NewPolicyReporter(client dynamic.Interface) *PolicyReporter {
+	return &PolicyReporter{client: client, now: time.Now}
+}
+
+type policyReportKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// policyReportState is what was last reported for a policy.
+type policyReportState struct {
+	// hash is the hash of the results of the policy, excluding their timestamps
+	hash string
+	// scopes has the namespaces with a PolicyReport, and an empty string if there is a ClusterPolicyReport
+	scopes map[string]bool
+}
+
+// Sync makes the reports of the policy match its related objects, creating, updating, and deleting the reports
+// as needed. Nothing is written when the results haven't changed since the last sync.
+func (p *PolicyReporter) Sync(
+	ctx context.Context,
+	kind string,
+	policy metav1.Object,
+	severity policyv1.Severity,
+	relatedObjects []policyv1.RelatedObject,
+) error {
+	key := policyReportKey{kind: kind, namespace: policy.GetNamespace(), name: policy.GetName()}
+
+	results := map[string][]map[string]any{}
+
+	for _, related := range relatedObjects {
+		scope := related.Object.Metadata.Namespace
+		results[scope] = append(results[scope], policyReportResult(kind, policy, severity, related))
+	}
+
+	hash, err := policyReportHash(results)
+	if err != nil {
+		return err
+	}
+
+	var previousScopes map[string]bool
+
+	if loaded, ok := p.reported.Load(key); ok {
+		state := loaded.(*policyReportState)
+		if state.hash == hash {
+			return nil
+		}
+
+		previousScopes = state.scopes
+	} else {
+		// After a restart, find the reports of the policy to delete the ones that are no longer needed
+		previousScopes, err = p.existingScopes(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Forget the state until the reports are successfully synced so that everything is retried next time
+	p.reported.Delete(key)
+
+	timestamp := p.now()
+
+	var errs []error
+
+	for scope, scopeResults := range results {
+		for _, result := range scopeResults {
+			result["timestamp"] = map[string]any{"seconds": timestamp.Unix(), "nanos": int64(timestamp.Nanosecond())}
+		}
+
+		if err := p.apply(ctx, key, scope, scopeResults); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for scope := range previousScopes {
+		if _, ok := results[scope]; ok {
+			continue
+		}
+
+		if err := p.delete(ctx, key, scope); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) != 0 {
+		return errors.Join(errs...)
+	}
+
+	scopes := make(map[string]bool, len(results))
+	for scope := range results {
+		scopes[scope] = true
+	}
+
+	p.reported.Store(key, &policyReportState{hash: hash, scopes: scopes})
+
+	ctrl.LoggerFrom(ctx).V(1).Info("Updated the policy reports", "reports", len(scopes))
+
+	return nil
+}
+
+// Remove deletes the reports of a policy that was deleted. The reports of the scopes of the last sync are deleted,
+// and the reports are only found with the label of the policy when it wasn't synced since a restart.
+func (p *PolicyReporter) Remove(ctx context.Context, kind string, policy types.NamespacedName) error {
+	key := policyReportKey{kind: kind, namespace: policy.Namespace, name: policy.Name}
+
+	var scopes map[string]bool
+
+	if loaded, ok := p.reported.LoadAndDelete(key); ok {
+		scopes = loaded.(*policyReportState).scopes
+	} else {
+		var err error
+
+		scopes, err = p.existingScopes(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	var errs []error
+
+	for scope := range scopes {
+		if err := p.delete(ctx, key, scope); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// existingScopes lists the reports of the policy to return their namespaces, and an empty string if there is a
+// ClusterPolicyReport.
+func (p *PolicyReporter) existingScopes(ctx context.Context, key policyReportKey) (map[string]bool, error) {
+	listOpts := metav1.ListOptions{LabelSelector: policyReportLabel + "=" + key.labelValue()}
+	scopes := map[string]bool{}
+
+	reports, err := p.client.Resource(policyReportGVR).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the PolicyReports of the policy: %w", err)
+	}
+
+	for _, report := range reports.Items {
+		scopes[report.GetNamespace()] = true
+	}
+
+	clusterReports, err := p.client.Resource(clusterPolicyReportGVR).List(ctx, listOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the ClusterPolicyReports of the policy: %w", err)
+	}
+
+	if len(clusterReports.Items) != 0 {
+		scopes[""] = true
+	}
+
+	return scopes, nil
+}
+
+// apply creates or updates the report of the policy in the namespace, or the ClusterPolicyReport when the
+// namespace is empty.
+func (p *PolicyReporter) apply(
+	ctx context.Context, key policyReportKey, namespace string, results []map[string]any,
+) error {
+	summary := map[string]any{"pass": int64(0), "fail": int64(0), "warn": int64(0), "error": int64(0), "skip": int64(0)}
+	items := make([]any, 0, len(results))
+
+	for _, result := range results {
+		summary[result["result"].(string)] = summary[result["result"].(string)].(int64) + 1
+		items = append(items, result)
+	}
+
+	report := &unstructured.Unstructured{Object: map[string]any{
+		"metadata": map[string]any{
+			"name": key.reportName(),
+			"labels": map[string]any{
+				"app.kubernetes.io/managed-by": policyReportFieldManager,
+				policyReportLabel:              key.labelValue(),
+			},
+			"annotations": map[string]any{
+				"policy.open-cluster-management.io/policy": key.namespace + "/" + key.name,
+				"policy.open-cluster-management.io/kind":   key.kind,
+			},
+		},
+		"summary": summary,
+		"results": items,
+	}}
+
+	var resource dynamic.ResourceInterface = p.client.Resource(clusterPolicyReportGVR)
+
+	report.SetAPIVersion(clusterPolicyReportGVR.GroupVersion().String())
+	report.SetKind("ClusterPolicyReport")
+
+	if namespace != "" {
+		resource = p.client.Resource(policyReportGVR).Namespace(namespace)
+
+		report.SetKind("PolicyReport")
+		report.SetNamespace(namespace)
+	}
+
+	_, err := resource.Apply(
+		ctx, report.GetName(), report, metav1.ApplyOptions{FieldManager: policyReportFieldManager, Force: true},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to apply the %s %s in namespace %q: %w", report.GetKind(), report.GetName(),
+			namespace, err)
+	}
+
+	return nil
+}
+
+// delete deletes the report of the policy in the namespace, or the ClusterPolicyReport when the namespace is
+// empty.
+func (p *PolicyReporter) delete(ctx context.Context, key policyReportKey, namespace string) error {
+	var err error
+
+	if namespace != "" {
+		err = p.client.Resource(policyReportGVR).Namespace(namespace).Delete(
+			ctx, key.reportName(), metav1.DeleteOptions{},
+		)
+	} else {
+		err = p.client.Resource(clusterPolicyReportGVR).Delete(ctx, key.reportName(), metav1.DeleteOptions{})
+	}
+
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the report %s in namespace %q: %w", key.reportName(), namespace, err)
+	}
+
+	return nil
+}
+
+// reportName returns the name of the reports of the policy, which is truncated with a hash suffix when it's
+// longer than allowed.
+func (k policyReportKey) reportName() string {
+	name := strings.ToLower(k.kind) + "-" + k.namespace + "." + k.name
+
+	const maxNameLength = 253
+
+	if len(name) > maxNameLength {
+		suffix := "-" + k.labelValue()
+		name = strings.TrimRight(name[:maxNameLength-len(suffix)], "-.") + suffix
+	}
+
+	return name
+}
+
+// labelValue returns a hash of the policy to select its reports with.
+func (k policyReportKey) labelValue() string {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(k.kind + "/" + k.namespace + "/" + k.name))
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// policyReportResult converts a related object of the policy to a PolicyReport result.
+func policyReportResult(
+	kind string, policy metav1.Object, severity policyv1.Severity, related policyv1.RelatedObject,
+) map[string]any {
+	result := "error"
+
+	switch policyv1.ComplianceState(related.Compliant) {
+	case policyv1.Compliant:
+		result = "pass"
+	case policyv1.NonCompliant:
+		result = "fail"
+	case policyv1.UnknownCompliancy, policyv1.Terminating:
+	}
+
+	resource := map[string]any{
+		"apiVersion": related.Object.APIVersion,
+		"kind":       related.Object.Kind,
+		"name":       related.Object.Metadata.Name,
+	}
+
+	if related.Object.Metadata.Namespace != "" {
+		resource["namespace"] = related.Object.Metadata.Namespace
+	}
+
+	if related.Properties != nil && related.Properties.UID != "" {
+		resource["uid"] = related.Properties.UID
+	}
+
+	reportResult := map[string]any{
+		"source":    policyReportSource,
+		"category":  kind,
+		"policy":    policy.GetNamespace() + "/" + policy.GetName(),
+		"result":    result,
+		"message":   related.Reason,
+		"resources": []any{resource},
+	}
+
+	if reportSeverity := strings.ToLower(string(severity)); slices.Contains(
+		[]string{"critical", "high", "medium", "low"}, reportSeverity,
+	) {
+		reportResult["severity"] = reportSeverity
+	}
+
+	return reportResult
+}
+
+// policyReportHash returns a hash of the results of all the reports of a policy.
+func policyReportHash(results map[string][]map[string]any) (string, error) {
+	// Maps are encoded with sorted keys, so the hash is deterministic
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(encoded)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// reportPolicy syncs the reports of the policy when the PolicyReporter is set. Failures are only logged since the
+// reports are updated again on the next evaluation.
+func (r *ConfigurationPolicyReconciler) reportPolicy(ctx context.Context, policy *policyv1.ConfigurationPolicy) {
+	if r.PolicyReporter == nil {
+		return
+	}
+
+	err := r.PolicyReporter.Sync(ctx, "ConfigurationPolicy", policy, policy.Spec.Severity, policy.Status.RelatedObjects)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to update the policy reports of the policy")
+	}
+}
+
+// reportPolicy syncs the reports of the policy when the PolicyReporter is set. Failures are only logged since the
+// reports are updated again on the next evaluation.
+func (r *OperatorPolicyReconciler) reportPolicy(ctx context.Context, policy *policyv1beta1.OperatorPolicy) {
+	if r.PolicyReporter == nil {
+		return
+	}
+
+	err := r.PolicyReporter.Sync(ctx, "OperatorPolicy", policy, policy.Spec.Severity, policy.Status.RelatedObjects)
+	if err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Failed to update the policy reports of the policy")
+	}
+}
@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// newFakeReportClient returns a fake dynamic client that handles apply patches of reports by creating or replacing
+// them, since the fake client doesn't create objects on apply.
+func newFakeReportClient() *dynamicfake.FakeDynamicClient {
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			policyReportGVR:        "PolicyReportList",
+			clusterPolicyReportGVR: "ClusterPolicyReportList",
+		},
+	)
+
+	client.PrependReactor("patch", "*", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(clienttesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON(patch.GetPatch()); err != nil {
+			return true, nil, err
+		}
+
+		tracker := client.Tracker()
+
+		_, err := tracker.Get(patch.GetResource(), patch.GetNamespace(), patch.GetName())
+		if err != nil {
+			err = tracker.Create(patch.GetResource(), obj, patch.GetNamespace())
+		} else {
+			err = tracker.Update(patch.GetResource(), obj, patch.GetNamespace())
+		}
+
+		return true, obj, err
+	})
+
+	return client
+}
+
+func reportRelatedObject(kind, namespace, name string, compliant policyv1.ComplianceState) policyv1.RelatedObject {
+	return policyv1.RelatedObject{
+		Object: policyv1.ObjectResource{
+			APIVersion: "v1",
+			Kind:       kind,
+			Metadata:   policyv1.ObjectMetadata{Name: name, Namespace: namespace},
+		},
+		Compliant:  string(compliant),
+		Reason:     "Resource found as expected",
+		Properties: &policyv1.ObjectProperties{UID: "uid-" + name},
+	}
+}
+
+func countActions(client *dynamicfake.FakeDynamicClient, verb string) int {
+	count := 0
+
+	for _, action := range client.Actions() {
+		if action.GetVerb() == verb {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestPolicyReporterSync(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := newFakeReportClient()
+	reporter := NewPolicyReporter(client)
+	reporter.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "my-policy"}}
+	related := []policyv1.RelatedObject{
+		reportRelatedObject("ConfigMap", "default", "good", policyv1.Compliant),
+		reportRelatedObject("ConfigMap", "default", "bad", policyv1.NonCompliant),
+		reportRelatedObject("Namespace", "", "default", policyv1.Compliant),
+	}
+
+	err := reporter.Sync(ctx, "ConfigurationPolicy", policy, "High", related)
+	assert.NoError(t, err)
+
+	report, err := client.Resource(policyReportGVR).Namespace("default").Get(
+		ctx, "configurationpolicy-managed.my-policy", metav1.GetOptions{},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "PolicyReport", report.GetKind())
+	assert.Equal(t, "managed/my-policy", report.GetAnnotations()["policy.open-cluster-management.io/policy"])
+
+	results, _, _ := unstructured.NestedSlice(report.Object, "results")
+	if assert.Len(t, results, 2) {
+		first := results[0].(map[string]any)
+		assert.Equal(t, "pass", first["result"])
+		assert.Equal(t, "high", first["severity"])
+		assert.Equal(t, "managed/my-policy", first["policy"])
+		assert.Equal(t, "Resource found as expected", first["message"])
+		assert.Equal(t, []any{map[string]any{
+			"apiVersion": "v1", "kind": "ConfigMap", "namespace": "default", "name": "good", "uid": "uid-good",
+		}}, first["resources"])
+		assert.Equal(t, "fail", results[1].(map[string]any)["result"])
+	}
+
+	fails, _, _ := unstructured.NestedInt64(report.Object, "summary", "fail")
+	assert.Equal(t, int64(1), fails)
+
+	clusterReport, err := client.Resource(clusterPolicyReportGVR).Get(
+		ctx, "configurationpolicy-managed.my-policy", metav1.GetOptions{},
+	)
+	if assert.NoError(t, err) {
+		clusterResults, _, _ := unstructured.NestedSlice(clusterReport.Object, "results")
+		assert.Len(t, clusterResults, 1)
+	}
+
+	// The same results don't write the reports again
+	applies := countActions(client, "patch")
+
+	err = reporter.Sync(ctx, "ConfigurationPolicy", policy, "High", related)
+	assert.NoError(t, err)
+	assert.Equal(t, applies, countActions(client, "patch"))
+
+	// The cluster-scoped object is no longer related, so the ClusterPolicyReport is deleted
+	err = reporter.Sync(ctx, "ConfigurationPolicy", policy, "High", related[:2])
+	assert.NoError(t, err)
+
+	_, err = client.Resource(clusterPolicyReportGVR).Get(
+		ctx, "configurationpolicy-managed.my-policy", metav1.GetOptions{},
+	)
+	assert.True(t, err != nil && strings.Contains(err.Error(), "not found"))
+
+	// The reports of the scopes of the last sync are removed without listing the reports
+	lists := countActions(client, "list")
+
+	err = reporter.Remove(ctx, "ConfigurationPolicy", types.NamespacedName{Namespace: "managed", Name: "my-policy"})
+	assert.NoError(t, err)
+	assert.Equal(t, lists, countActions(client, "list"))
+
+	reports, err := client.Resource(policyReportGVR).List(ctx, metav1.ListOptions{})
+	if assert.NoError(t, err) {
+		assert.Empty(t, reports.Items)
+	}
+
+	err = NewPolicyReporter(client).Sync(ctx, "ConfigurationPolicy", policy, "High", related[:2])
+	assert.NoError(t, err)
+
+	// A new reporter, as after a restart, finds the existing reports to remove
+	restarted := NewPolicyReporter(client)
+
+	err = restarted.Remove(ctx, "ConfigurationPolicy", types.NamespacedName{Namespace: "managed", Name: "my-policy"})
+	assert.NoError(t, err)
+
+	reports, err = client.Resource(policyReportGVR).List(ctx, metav1.ListOptions{})
+	if assert.NoError(t, err) {
+		assert.Empty(t, reports.Items)
+	}
+}
+
+func TestPolicyReporterSyncRetriesFailures(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client := newFakeReportClient()
+	reporter := NewPolicyReporter(client)
+
+	failing := true
+
+	client.PrependReactor("patch", "policyreports", func(clienttesting.Action) (bool, runtime.Object, error) {
+		if failing {
+			return true, nil, assert.AnError
+		}
+
+		return false, nil, nil
+	})
+
+	policy := &policyv1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "managed", Name: "my-policy"}}
+	related := []policyv1.RelatedObject{reportRelatedObject("ConfigMap", "default", "good", policyv1.Compliant)}
+
+	err := reporter.Sync(ctx, "ConfigurationPolicy", policy, "", related)
+	assert.ErrorIs(t, err, assert.AnError)
+
+	failing = false
+
+	err = reporter.Sync(ctx, "ConfigurationPolicy", policy, "", related)
+	assert.NoError(t, err)
+
+	report, err := client.Resource(policyReportGVR).Namespace("default").Get(
+		ctx, "configurationpolicy-managed.my-policy", metav1.GetOptions{},
+	)
+	if assert.NoError(t, err) {
+		results, _, _ := unstructured.NestedSlice(report.Object, "results")
+		if assert.Len(t, results, 1) {
+			assert.NotContains(t, results[0], "severity")
+		}
+	}
+}
+
+func TestPolicyReportName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		key      policyReportKey
+		expected string
+	}{
+		"short": {
+			key:      policyReportKey{kind: "OperatorPolicy", namespace: "managed", name: "install"},
+			expected: "operatorpolicy-managed.install",
+		},
+		"truncated": {
+			key: policyReportKey{kind: "ConfigurationPolicy", namespace: "managed", name: strings.Repeat("a", 250)},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			reportName := test.key.reportName()
+
+			if test.expected != "" {
+				assert.Equal(t, test.expected, reportName)
+
+				return
+			}
+
+			assert.Len(t, reportName, 253)
+			assert.True(t, strings.HasSuffix(reportName, "-"+test.key.labelValue()))
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+const (
+	provenanceUIDLabel             = "policy.open-cluster-management.io/configuration-policy-uid"
+	provenanceNameAnnotation       = "policy.open-cluster-management.io/configuration-policy-name"
+	provenanceNamespaceAnnotation  = "policy.open-cluster-management.io/configuration-policy-namespace"
+	provenanceGenerationAnnotation = "policy.open-cluster-management.io/configuration-policy-generation"
+	provenanceIndexAnnotation      = "policy.open-cluster-management.io/object-template-index"
+	// provenanceCreatedByAnnotation is only set when the object is created by the policy, and its value is the
+	// UID of the policy. It's used to determine whether the policy created the object when the status is lost.
+	provenanceCreatedByAnnotation = "policy.open-cluster-management.io/created-by-configuration-policy-uid"
+)
+
+var provenanceAnnotations = []string{
+	provenanceNameAnnotation,
+	provenanceNamespaceAnnotation,
+	provenanceGenerationAnnotation,
+	provenanceIndexAnnotation,
+	provenanceCreatedByAnnotation,
+}
+
+// stampProvenance sets the labels and annotations on the object that identify the configuration
+// policy managing it, based on the provenance setting of the policy. The object should be about to be
+// created or updated by the policy. When the provenance is set to OwnerReference and the object is in
+// the namespace of the policy, an owner reference to the policy is also added.
+func stampProvenance(
+	plc *policyv1.ConfigurationPolicy, obj *unstructured.Unstructured, index int, namespaced bool, created bool,
+) {
+	if plc == nil || obj == nil {
+		return
+	}
+
+	if plc.Spec.Provenance != policyv1.ProvenanceMetadata && plc.Spec.Provenance != policyv1.ProvenanceOwnerReference {
+		return
+	}
+
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	labels[provenanceUIDLabel] = string(plc.GetUID())
+	obj.SetLabels(labels)
+
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[provenanceNameAnnotation] = plc.GetName()
+	annotations[provenanceNamespaceAnnotation] = plc.GetNamespace()
+	annotations[provenanceGenerationAnnotation] = strconv.FormatInt(plc.GetGeneration(), 10)
+	annotations[provenanceIndexAnnotation] = strconv.Itoa(index)
+
+	if created {
+		annotations[provenanceCreatedByAnnotation] = string(plc.GetUID())
+	}
+
+	obj.SetAnnotations(annotations)
+
+	// Owner references across namespaces or from cluster scoped objects aren't valid.
+	if plc.Spec.Provenance != policyv1.ProvenanceOwnerReference || !namespaced ||
+		obj.GetNamespace() != plc.GetNamespace() || plc.GetUID() == "" {
+		return
+	}
+
+	ownerRefs := obj.GetOwnerReferences()
+
+	for _, ownerRef := range ownerRefs {
+		if ownerRef.UID == plc.GetUID() {
+			return
+		}
+	}
+
+	obj.SetOwnerReferences(append(ownerRefs, metav1.OwnerReference{
+		APIVersion: policyv1.GroupVersion.String(),
+		Kind:       "ConfigurationPolicy",
+		Name:       plc.GetName(),
+		UID:        plc.GetUID(),
+	}))
+}
+
+// removeProvenance removes the labels and annotations set by stampProvenance so that they aren't
+// considered when comparing the object with the object template.
+func removeProvenance(obj *unstructured.Unstructured) {
+	if labels := obj.GetLabels(); labels != nil {
+		if _, ok := labels[provenanceUIDLabel]; ok {
+			delete(labels, provenanceUIDLabel)
+
+			if len(labels) == 0 {
+				unstructured.RemoveNestedField(obj.Object, "metadata", "labels")
+			} else {
+				obj.SetLabels(labels)
+			}
+		}
+	}
+
+	if annotations := obj.GetAnnotations(); annotations != nil {
+		removed := false
+
+		for _, annotation := range provenanceAnnotations {
+			if _, ok := annotations[annotation]; ok {
+				delete(annotations, annotation)
+
+				removed = true
+			}
+		}
+
+		if removed {
+			if len(annotations) == 0 {
+				unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+			} else {
+				obj.SetAnnotations(annotations)
+			}
+		}
+	}
+}
+
+// createdByPolicyStamp reports whether the object annotations indicate that the object was created
+// by the configuration policy.
+func createdByPolicyStamp(plc *policyv1.ConfigurationPolicy, annotations map[string]string) bool {
+	return plc.GetUID() != "" && annotations[provenanceCreatedByAnnotation] == string(plc.GetUID())
+}
+
+// provenanceRelatedObjects lists the objects labeled with the UID of the configuration policy for the
+// kinds in its object templates. It's used to find the objects to prune when the status doesn't list
+// them, such as when the status was lost.
+func (r *ConfigurationPolicyReconciler) provenanceRelatedObjects(
+	ctx context.Context, plc *policyv1.ConfigurationPolicy,
+) []policyv1.RelatedObject {
+	if plc.GetUID() == "" {
+		return nil
+	}
+
+	if plc.Spec.Provenance != policyv1.ProvenanceMetadata && plc.Spec.Provenance != policyv1.ProvenanceOwnerReference {
+		return nil
+	}
+
+	log := ctrl.LoggerFrom(ctx)
+	listOpts := metav1.ListOptions{LabelSelector: provenanceUIDLabel + "=" + string(plc.GetUID())}
+	seen := map[schema.GroupVersionKind]bool{}
+	related := []policyv1.RelatedObject{}
+
+	for _, objectT := range plc.Spec.ObjectTemplates {
+		if objectT == nil {
+			continue
+		}
+
+		var unstruct unstructured.Unstructured
+
+		if err := json.Unmarshal(objectT.ObjectDefinition.Raw, &unstruct.Object); err != nil {
+			continue
+		}
+
+		gvk := unstruct.GroupVersionKind()
+		if gvk.Kind == "" || seen[gvk] {
+			continue
+		}
+
+		seen[gvk] = true
+
+		scopedGVR, err := r.DynamicWatcher.GVKToGVR(gvk)
+		if err != nil {
+			log.V(1).Info("Could not get the resource mapping to list the objects with provenance",
+				"groupVersionKind", gvk.String(), "error", err.Error())
+
+			continue
+		}
+
+		list, err := r.TargetK8sDynamicClient.Resource(scopedGVR.GroupVersionResource).List(ctx, listOpts)
+		if err != nil {
+			log.Error(err, "Failed to list the objects with provenance", "groupVersionKind", gvk.String())
+
+			continue
+		}
+
+		for i := range list.Items {
+			related = append(related, policyv1.RelatedObject{
+				Object: policyv1.ObjectResourceFromObj(&list.Items[i]),
+			})
+		}
+	}
+
+	return related
+}
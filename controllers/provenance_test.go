@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func getProvenancePolicy(provenance policyv1.Provenance) *policyv1.ConfigurationPolicy {
+	return &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "my-policy",
+			Namespace:  "managed",
+			UID:        "9b1f6c2e-3a0f-4c55-8a4e-7f0d6b1c2d3e",
+			Generation: 3,
+		},
+		Spec: policyv1.ConfigurationPolicySpec{Provenance: provenance},
+	}
+}
+
+func getProvenanceConfigMap(namespace string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "my-cm",
+			"namespace": namespace,
+			"labels":    map[string]interface{}{"app": "test"},
+		},
+	}}
+}
+
+func TestStampProvenance(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		provenance        policyv1.Provenance
+		namespace         string
+		created           bool
+		expectedStamps    bool
+		expectedOwnerRefs int
+	}{
+		"none": {
+			provenance: policyv1.ProvenanceNone,
+			namespace:  "managed",
+			created:    true,
+		},
+		"metadata": {
+			provenance:     policyv1.ProvenanceMetadata,
+			namespace:      "managed",
+			created:        true,
+			expectedStamps: true,
+		},
+		"owner reference in the same namespace": {
+			provenance:        policyv1.ProvenanceOwnerReference,
+			namespace:         "managed",
+			expectedStamps:    true,
+			expectedOwnerRefs: 1,
+		},
+		"owner reference in another namespace": {
+			provenance:     policyv1.ProvenanceOwnerReference,
+			namespace:      "other",
+			expectedStamps: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			policy := getProvenancePolicy(test.provenance)
+			obj := getProvenanceConfigMap(test.namespace)
+
+			stampProvenance(policy, obj, 2, true, test.created)
+			// Stamping twice shouldn't duplicate the owner reference
+			stampProvenance(policy, obj, 2, true, test.created)
+
+			assert.Len(t, obj.GetOwnerReferences(), test.expectedOwnerRefs)
+
+			if !test.expectedStamps {
+				assert.Equal(t, map[string]string{"app": "test"}, obj.GetLabels())
+				assert.Nil(t, obj.GetAnnotations())
+
+				return
+			}
+
+			assert.Equal(t, string(policy.UID), obj.GetLabels()[provenanceUIDLabel])
+			assert.Equal(t, "my-policy", obj.GetAnnotations()[provenanceNameAnnotation])
+			assert.Equal(t, "managed", obj.GetAnnotations()[provenanceNamespaceAnnotation])
+			assert.Equal(t, "3", obj.GetAnnotations()[provenanceGenerationAnnotation])
+			assert.Equal(t, "2", obj.GetAnnotations()[provenanceIndexAnnotation])
+			assert.Equal(t, test.created, createdByPolicyStamp(policy, obj.GetAnnotations()))
+
+			removeProvenance(obj)
+
+			assert.Equal(t, map[string]string{"app": "test"}, obj.GetLabels())
+			assert.Nil(t, obj.GetAnnotations())
+		})
+	}
+}
+
+func TestUIDWasCreatedByPolicyStamp(t *testing.T) {
+	t.Parallel()
+
+	policy := getProvenancePolicy(policyv1.ProvenanceMetadata)
+	annotations := map[string]string{provenanceCreatedByAnnotation: string(policy.UID)}
+
+	// The status was lost, so the annotation is used
+	assert.True(t, uidWasCreatedByPolicy(policy, "object-uid", annotations))
+	assert.False(t, uidWasCreatedByPolicy(policy, "object-uid", nil))
+
+	// The annotation must be from this policy
+	otherPolicy := getProvenancePolicy(policyv1.ProvenanceMetadata)
+	otherPolicy.UID = "other-uid"
+	assert.False(t, uidWasCreatedByPolicy(otherPolicy, "object-uid", annotations))
+
+	created := true
+	policy.Status.RelatedObjects = []policyv1.RelatedObject{
+		{Properties: &policyv1.ObjectProperties{CreatedByPolicy: &created, UID: "object-uid"}},
+	}
+	assert.True(t, uidWasCreatedByPolicy(policy, "object-uid", nil))
+}
+
+func TestEnforceByCreatingProvenance(t *testing.T) {
+	t.Parallel()
+
+	policy := getProvenancePolicy(policyv1.ProvenanceOwnerReference)
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme)
+	r := &ConfigurationPolicyReconciler{TargetK8sDynamicClient: dynamicClient}
+	configMapGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	desiredObj := getProvenanceConfigMap("managed")
+
+	completed, reason, reasonCode, _, _, err := r.enforceByCreating(context.TODO(), singleObject{
+		policy:     policy,
+		scopedGVR:  depclient.ScopedGVR{GroupVersionResource: configMapGVR, Namespaced: true},
+		name:       "my-cm",
+		namespace:  "managed",
+		index:      1,
+		desiredObj: desiredObj,
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, completed)
+	assert.Equal(t, reasonWantFoundCreated, reason)
+	assert.Equal(t, policyv1.ReasonCodeCreated, reasonCode)
+
+	// The desired object from the policy is not modified
+	assert.Nil(t, desiredObj.GetAnnotations())
+
+	createdObj, err := dynamicClient.Resource(configMapGVR).Namespace("managed").Get(
+		context.TODO(), "my-cm", metav1.GetOptions{},
+	)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, createdByPolicyStamp(policy, createdObj.GetAnnotations()))
+	assert.Equal(t, "1", createdObj.GetAnnotations()[provenanceIndexAnnotation])
+
+	if assert.Len(t, createdObj.GetOwnerReferences(), 1) {
+		assert.Equal(t, policy.UID, createdObj.GetOwnerReferences()[0].UID)
+		assert.Equal(t, "ConfigurationPolicy", createdObj.GetOwnerReferences()[0].Kind)
+	}
+}
+
+func TestCleanUpChildObjectsProvenance(t *testing.T) {
+	t.Parallel()
+
+	policy := getProvenancePolicy(policyv1.ProvenanceMetadata)
+	policy.Spec.RemediationAction = policyv1.Enforce
+	policy.Spec.PruneObjectBehavior = "DeleteIfCreated"
+	policy.Spec.ObjectTemplates = []*policyv1.ObjectTemplate{{
+		ComplianceType:   policyv1.MustHave,
+		ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion":"v1","kind":"ConfigMap"}`)},
+	}}
+
+	created := getProvenanceConfigMap("managed")
+	stampProvenance(policy, created, 0, true, true)
+
+	updated := getProvenanceConfigMap("managed")
+	updated.SetName("my-updated-cm")
+	stampProvenance(policy, updated, 0, true, false)
+
+	other := getProvenanceConfigMap("managed")
+	other.SetName("my-other-cm")
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(scheme.Scheme, created, updated, other)
+	r := &ConfigurationPolicyReconciler{TargetK8sDynamicClient: dynamicClient, DynamicWatcher: newFakeWatcher(0)}
+	configMapGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+
+	// The status was lost, so the objects are found by the provenance label
+	failures := r.cleanUpChildObjects(context.TODO(), policy, nil, false)
+	assert.Empty(t, failures)
+
+	list, err := dynamicClient.Resource(configMapGVR).Namespace("managed").List(context.TODO(), metav1.ListOptions{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+
+	assert.ElementsMatch(t, []string{"my-updated-cm", "my-other-cm"}, names)
+}
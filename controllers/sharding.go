@@ -0,0 +1,200 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
+)
+
+// shardTracker tracks the policies of a controller that this replica handles when the policies are
+// sharded across replicas, and requeues the policies that moved to or from this replica when the
+// replicas are rebalanced, so that gained policies are evaluated and lost policies are released.
+type shardTracker struct {
+	shards *sharding.Coordinator
+	kind   string
+	// listPolicies returns the policies of the controller from the cache
+	listPolicies func(ctx context.Context) ([]types.NamespacedName, error)
+	// owned has the requests of the policies this replica handled as their owner
+	owned sync.Map
+	// queue is the queue of the controller, which is set once the controller starts
+	queue atomic.Pointer[workqueue.TypedRateLimitingInterface[reconcile.Request]]
+}
+
+func newShardTracker(
+	shards *sharding.Coordinator,
+	kind string,
+	listPolicies func(ctx context.Context) ([]types.NamespacedName, error),
+) *shardTracker {
+	tracker := &shardTracker{
+		shards:       shards,
+		kind:         kind,
+		listPolicies: listPolicies,
+	}
+
+	shards.OnRebalance(tracker.rebalance)
+
+	return tracker
+}
+
+// source returns the source of the requests for the policies that moved to or from this replica, which
+// keeps the queue of the controller so that the requests are added without blocking the rebalance.
+func (t *shardTracker) source() source.Source {
+	return source.Func(func(_ context.Context, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) error {
+		t.queue.Store(&queue)
+
+		return nil
+	})
+}
+
+// rebalance enqueues the policies for which this replica became or stopped being the owner.
+func (t *shardTracker) rebalance(ctx context.Context) {
+	log := ctrl.LoggerFrom(ctx).WithName("sharding").WithValues("kind", t.kind)
+
+	queue := t.queue.Load()
+	if queue == nil {
+		// Every policy is evaluated when the controller starts
+		return
+	}
+
+	policies, err := t.listPolicies(ctx)
+	if err != nil {
+		// The policies are still moved on their next evaluation or watch event
+		log.Error(err, "Failed to list the policies to rebalance")
+
+		return
+	}
+
+	moved := 0
+
+	for _, policy := range policies {
+		_, wasOwned := t.owned.Load(reconcile.Request{NamespacedName: policy})
+		if wasOwned == t.shards.Owns(sharding.Key(policy.Namespace, policy.Name)) {
+			continue
+		}
+
+		(*queue).Add(reconcile.Request{NamespacedName: policy})
+		moved++
+	}
+
+	log.Info("Rebalanced the policies", "moved", moved, "replicas", len(t.shards.Members()))
+}
+
+// shardAction is what a reconciler does with a policy when the policies are sharded.
+type shardAction int
+
+const (
+	// shardHandle means this replica owns the policy and handles it.
+	shardHandle shardAction = iota
+	// shardWait means the ring isn't known yet, or this replica just gained the policy from a replica
+	// that might still be handling it, so the policy must be requeued.
+	shardWait
+	// shardRelease means another replica owns the policy, which this replica handled until now, so the
+	// watches and cached state of the policy must be released.
+	shardRelease
+	// shardSkip means another replica owns the policy, which this replica doesn't handle.
+	shardSkip
+)
+
+// action returns what the reconciler must do with the policy in the request, and records whether the
+// policy is handled by this replica. For shardWait, it also returns when to requeue the policy.
+func (t *shardTracker) action(request reconcile.Request) (shardAction, time.Duration) {
+	if !t.shards.IsReady() {
+		return shardWait, time.Second
+	}
+
+	key := sharding.Key(request.Namespace, request.Name)
+
+	if t.shards.Owns(key) {
+		if _, wasOwned := t.owned.Load(request); !wasOwned {
+			if remaining := t.shards.HandoffRemaining(key); remaining > 0 {
+				return shardWait, remaining
+			}
+		}
+
+		t.owned.Store(request, true)
+		policyShardGauge.WithLabelValues(t.kind, request.Name, request.Namespace, t.shards.Identity()).Set(1)
+
+		return shardHandle, 0
+	}
+
+	if _, wasOwned := t.owned.LoadAndDelete(request); wasOwned {
+		removePolicyShardMetric(t.kind, request)
+
+		return shardRelease, 0
+	}
+
+	return shardSkip, 0
+}
+
+// forget stops tracking a deleted policy and returns true if this replica handled the policy or owns it, so that
+// the state of the policy shared by the replicas is only cleaned up by its owner.
+func (t *shardTracker) forget(request reconcile.Request) bool {
+	_, wasOwned := t.owned.LoadAndDelete(request)
+	removePolicyShardMetric(t.kind, request)
+
+	return wasOwned || t.shards.Owns(sharding.Key(request.Namespace, request.Name))
+}
+
+// releasePolicy stops the watches and drops the cached state of a policy now owned by another replica.
+func (r *ConfigurationPolicyReconciler) releasePolicy(ctx context.Context, policy *policyv1.ConfigurationPolicy) {
+	log := ctrl.LoggerFrom(ctx)
+
+	removeConfigPolicyMetrics(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+	r.SelectorReconciler.Stop(policy.Namespace, policy.Name)
+
+	if err := r.removePolicyWatches(policy.ObjectIdentifier()); err != nil {
+		log.Error(err, "Failed to remove the watches of the released policy. Will ignore.")
+	}
+
+	if r.HubDynamicWatcher != nil {
+		if err := r.HubDynamicWatcher.RemoveWatcher(policy.ObjectIdentifier()); err != nil {
+			log.Error(err, "Failed to remove the hub watches of the released policy. Will ignore.")
+		}
+	}
+
+	r.lastEvaluatedCache.Delete(policy.GetUID())
+	r.processedPolicyCache.Delete(policy.GetUID())
+	r.lastCompliantCache.Delete(policy.GetUID())
+	r.deletionDeadlines.Delete(policy.GetUID())
+
+	if r.missingAPIs != nil {
+		r.missingAPIs.forget(client.ObjectKeyFromObject(policy))
+	}
+
+	r.DecisionTracer.forget(client.ObjectKeyFromObject(policy))
+}
+
+// releasePolicy stops the watches and drops the cached state of a policy now owned by another replica.
+func (r *OperatorPolicyReconciler) releasePolicy(ctx context.Context, policy *policyv1beta1.OperatorPolicy) {
+	log := ctrl.LoggerFrom(ctx)
+
+	removeOperatorPolicyMetrics(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(policy)})
+
+	watcher := opPolIdentifier(policy.Namespace, policy.Name)
+
+	if err := r.DynamicWatcher.RemoveWatcher(watcher); err != nil {
+		log.Error(err, "Failed to remove the watches of the released policy. Will ignore.")
+	}
+
+	if r.HubDynamicWatcher != nil {
+		if err := r.HubDynamicWatcher.RemoveWatcher(watcher); err != nil {
+			log.Error(err, "Failed to remove the hub watches of the released policy. Will ignore.")
+		}
+	}
+
+	r.lastEvaluatedCache.Delete(policy.GetUID())
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
+)
+
+func TestShardTracker(t *testing.T) {
+	t.Parallel()
+
+	leaseClient := fake.NewClientset()
+
+	startReplica := func(identity string) *sharding.Coordinator {
+		coordinator := sharding.NewCoordinator(leaseClient, "ns", "test", identity)
+		coordinator.RenewInterval = 10 * time.Millisecond
+
+		ctx, cancel := context.WithCancel(t.Context())
+		t.Cleanup(cancel)
+
+		go func() { _ = coordinator.Start(ctx) }()
+
+		return coordinator
+	}
+
+	policies := make([]types.NamespacedName, 50)
+	for i := range policies {
+		policies[i] = types.NamespacedName{Namespace: "managed", Name: fmt.Sprintf("policy-%d", i)}
+	}
+
+	replicaA := sharding.NewCoordinator(leaseClient, "ns", "test", "replica-a")
+	replicaA.RenewInterval = 10 * time.Millisecond
+
+	tracker := newShardTracker(replicaA, "ConfigurationPolicy",
+		func(context.Context) ([]types.NamespacedName, error) { return policies, nil },
+	)
+
+	queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+
+	assert.NoError(t, tracker.source().Start(t.Context(), queue))
+
+	request := func(policy types.NamespacedName) reconcile.Request {
+		return reconcile.Request{NamespacedName: policy}
+	}
+
+	action := func(tracker *shardTracker, policy types.NamespacedName) shardAction {
+		action, _ := tracker.action(request(policy))
+
+		return action
+	}
+
+	assert.Equal(t, shardWait, action(tracker, policies[0]))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	go func() { _ = replicaA.Start(ctx) }()
+
+	<-replicaA.Ready()
+
+	// The first rebalance enqueues every policy since replica-a is alone
+	assert.Eventually(t, func() bool { return queue.Len() == len(policies) }, 5*time.Second, 10*time.Millisecond)
+
+	for range policies {
+		item, _ := queue.Get()
+		queue.Done(item)
+	}
+
+	for _, policy := range policies {
+		assert.Equal(t, shardHandle, action(tracker, policy))
+	}
+
+	replicaB := startReplica("replica-b")
+
+	assert.Eventually(t, func() bool { return len(replicaA.Members()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	<-replicaB.Ready()
+
+	ownedByB := 0
+
+	for _, policy := range policies {
+		if replicaA.Owner(sharding.Key(policy.Namespace, policy.Name)) == "replica-b" {
+			ownedByB++
+		}
+	}
+
+	assert.Positive(t, ownedByB)
+	assert.Less(t, ownedByB, len(policies))
+
+	assert.Eventually(t, func() bool { return queue.Len() >= ownedByB }, 5*time.Second, 10*time.Millisecond)
+
+	moved := map[string]bool{}
+
+	for queue.Len() > 0 {
+		item, _ := queue.Get()
+		moved[item.Name] = true
+		queue.Done(item)
+	}
+
+	// replica-b waits for replica-a to release the policies it gained
+	trackerB := &shardTracker{shards: replicaB, kind: "ConfigurationPolicy"}
+
+	for _, policy := range policies {
+		owner := replicaA.Owner(sharding.Key(policy.Namespace, policy.Name))
+		assert.Equal(t, owner == "replica-b", moved[policy.Name], policy.Name)
+
+		if owner == "replica-b" {
+			actionB, wait := trackerB.action(request(policy))
+			assert.Equal(t, shardWait, actionB)
+			assert.Positive(t, wait)
+
+			assert.Equal(t, shardRelease, action(tracker, policy))
+			assert.Equal(t, shardSkip, action(tracker, policy), "a released policy is only released once")
+		} else {
+			assert.Equal(t, shardHandle, action(tracker, policy))
+		}
+
+		// Only the owner of a deleted policy cleans up its shared state
+		assert.Equal(t, owner == "replica-a", tracker.forget(request(policy)), policy.Name)
+	}
+}
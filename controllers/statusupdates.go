@@ -0,0 +1,106 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// pendingStatus tracks the status updates of a policy during an evaluation, so that the status is written at
+// most once per evaluation.
+type pendingStatus struct {
+	// persisted is the status of the policy when the evaluation started
+	persisted policyv1.ConfigurationPolicyStatus
+	// updated is true when the status was updated during the evaluation
+	updated bool
+	// failed is true when an update couldn't be recorded, in which case the status isn't written so that
+	// everything is retried on the next evaluation
+	failed bool
+}
+
+// beginStatusUpdates starts deferring the status writes of the policy until flushStatusUpdates is called.
+func (r *ConfigurationPolicyReconciler) beginStatusUpdates(policy *policyv1.ConfigurationPolicy) {
+	r.pendingStatuses.Store(policy.GetUID(), &pendingStatus{persisted: *policy.Status.DeepCopy()})
+}
+
+// deferStatusWrite returns true when the policy is being evaluated, in which case its status is written by
+// flushStatusUpdates instead.
+func (r *ConfigurationPolicyReconciler) deferStatusWrite(policy *policyv1.ConfigurationPolicy) bool {
+	pending, ok := r.pendingStatuses.Load(policy.GetUID())
+	if !ok {
+		return false
+	}
+
+	pending.(*pendingStatus).updated = true
+
+	return true
+}
+
+// markStatusUpdateFailed marks the pending status of the policy so that it isn't written at the end of the evaluation.
+func (r *ConfigurationPolicyReconciler) markStatusUpdateFailed(policy *policyv1.ConfigurationPolicy) {
+	if pending, ok := r.pendingStatuses.Load(policy.GetUID()); ok {
+		pending.(*pendingStatus).failed = true
+	}
+}
+
+// flushStatusUpdates writes the status of the policy if it was updated during the evaluation, unless it's
+// semantically unchanged from the status when the evaluation started. A compliance notification is sent once the
+// status is written if the compliance changed during the evaluation.
+func (r *ConfigurationPolicyReconciler) flushStatusUpdates(ctx context.Context, policy *policyv1.ConfigurationPolicy) {
+	loaded, ok := r.pendingStatuses.LoadAndDelete(policy.GetUID())
+	if !ok {
+		return
+	}
+
+	pending := loaded.(*pendingStatus)
+	if !pending.updated || pending.failed {
+		return
+	}
+
+	if statusUnchanged(&pending.persisted, &policy.Status) {
+		policyStatusWritesCounter.WithLabelValues(policy.Name, "unchanged").Inc()
+		r.reportPolicy(ctx, policy)
+
+		return
+	}
+
+	if err := r.writePolicyStatus(ctx, policy); err != nil {
+		r.statusUpdateFailed(ctx, policy, err)
+
+		return
+	}
+
+	if pending.persisted.ComplianceState != policy.Status.ComplianceState {
+		r.notifyCompliance(ctx, policy)
+	}
+}
+
+// maxSkippedStatusAge bounds how long the status of a policy isn't written when it's semantically unchanged, so
+// that the status.lastEvaluated field reported by the compliance API and the policy reports is never older than
+// this.
+const maxSkippedStatusAge = 5 * time.Minute
+
+// statusUnchanged returns true when the statuses only differ by when the policy was last and will next be
+// evaluated, and the persisted status was evaluated less than maxSkippedStatusAge before the current status.
+func statusUnchanged(persisted, current *policyv1.ConfigurationPolicyStatus) bool {
+	persistedEvaluated, err := time.Parse(time.RFC3339, persisted.LastEvaluated)
+	if err != nil {
+		return false
+	}
+
+	currentEvaluated, err := time.Parse(time.RFC3339, current.LastEvaluated)
+	if err != nil || currentEvaluated.Sub(persistedEvaluated) >= maxSkippedStatusAge {
+		return false
+	}
+
+	persistedCopy := persisted.DeepCopy()
+	persistedCopy.LastEvaluated = current.LastEvaluated
+	persistedCopy.NextEvaluation = current.NextEvaluation
+
+	return equality.Semantic.DeepEqual(persistedCopy, current)
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+func TestStatusUpdatesCoalesced(t *testing.T) {
+	t.Parallel()
+
+	watcher := newFakeWatcher(20)
+	r, policy := getSelectorPolicySetup(t, watcher)
+
+	// The notifier isn't started, so the queued notifications are counted
+	r.ComplianceNotifier = NewComplianceNotifier(fake.NewClientset(), "ocm", "sinks", "test", 10)
+	r.ComplianceNotifier.sinks.Store(&[]notificationSink{{Name: "sink", URL: "http://localhost"}})
+
+	writes := atomic.Int32{}
+	failWrites := atomic.Bool{}
+	r.Client = interceptor.NewClient(r.Client.(client.WithWatch), interceptor.Funcs{
+		SubResourceUpdate: func(
+			ctx context.Context, c client.Client, subResource string, obj client.Object,
+			opts ...client.SubResourceUpdateOption,
+		) error {
+			if failWrites.Load() {
+				return errors.New("the status can't be written")
+			}
+
+			writes.Add(1)
+
+			return c.SubResource(subResource).Update(ctx, obj, opts...)
+		},
+	})
+
+	evaluate := func() {
+		r.beginStatusUpdates(policy)
+		assert.NoError(t, r.handleObjectTemplates(t.Context(), policy))
+		r.flushStatusUpdates(t.Context(), policy)
+	}
+
+	evaluate()
+	assert.Equal(t, int32(1), writes.Load(), "the status is written once per evaluation")
+	assert.Equal(t, policyv1.Compliant, policy.Status.ComplianceState)
+	assert.Len(t, r.ComplianceNotifier.queue, 1, "the notification is sent once per evaluation")
+
+	_, pending := r.pendingStatuses.Load(policy.GetUID())
+	assert.False(t, pending)
+
+	// Only the last evaluated time changes, so the status isn't written
+	evaluate()
+	assert.Equal(t, int32(1), writes.Load())
+	assert.Len(t, r.ComplianceNotifier.queue, 1)
+
+	// The notification isn't sent when the status can't be written
+	watcher.update("configmap-00003", "disabled")
+	failWrites.Store(true)
+
+	failed := policy.DeepCopy()
+	r.beginStatusUpdates(failed)
+	assert.NoError(t, r.handleObjectTemplates(t.Context(), failed))
+	r.flushStatusUpdates(t.Context(), failed)
+
+	assert.Equal(t, policyv1.NonCompliant, failed.Status.ComplianceState)
+	assert.Len(t, r.ComplianceNotifier.queue, 1)
+
+	failWrites.Store(false)
+
+	evaluate()
+	assert.Equal(t, int32(2), writes.Load())
+	assert.Equal(t, policyv1.NonCompliant, policy.Status.ComplianceState)
+	assert.Len(t, r.ComplianceNotifier.queue, 2)
+
+	stored := &policyv1.ConfigurationPolicy{}
+	assert.NoError(t, r.Get(t.Context(), client.ObjectKeyFromObject(policy), stored))
+	assert.Equal(t, policyv1.NonCompliant, stored.Status.ComplianceState)
+
+	// Only the compliance message changes, so the status is written without a notification
+	message := policy.Status.CompliancyDetails[0].Conditions[0].Message
+
+	watcher.update("configmap-00004", "disabled")
+	evaluate()
+	assert.Equal(t, int32(3), writes.Load())
+	assert.NotEqual(t, message, policy.Status.CompliancyDetails[0].Conditions[0].Message)
+	assert.Len(t, r.ComplianceNotifier.queue, 2)
+
+	// Outside of an evaluation, the status is written right away
+	r.addForUpdate(t.Context(), policy, true)
+	assert.Equal(t, int32(4), writes.Load())
+	assert.Len(t, r.ComplianceNotifier.queue, 2)
+}
+
+func TestStatusUnchanged(t *testing.T) {
+	t.Parallel()
+
+	persisted := &policyv1.ConfigurationPolicyStatus{
+		ComplianceState: policyv1.Compliant,
+		LastEvaluated:   "2026-01-01T00:00:00Z",
+	}
+
+	tests := map[string]struct {
+		update    func(status *policyv1.ConfigurationPolicyStatus)
+		unchanged bool
+	}{
+		"identical": {
+			update:    func(*policyv1.ConfigurationPolicyStatus) {},
+			unchanged: true,
+		},
+		"last evaluated": {
+			update: func(status *policyv1.ConfigurationPolicyStatus) {
+				status.LastEvaluated = "2026-01-01T00:01:00Z"
+			},
+			unchanged: true,
+		},
+		"next evaluation": {
+			update: func(status *policyv1.ConfigurationPolicyStatus) {
+				status.LastEvaluated = "2026-01-01T00:01:00Z"
+				status.NextEvaluation = "2026-01-01T00:02:00Z"
+			},
+			unchanged: true,
+		},
+		"stale last evaluated": {
+			update: func(status *policyv1.ConfigurationPolicyStatus) {
+				status.LastEvaluated = "2026-01-01T00:05:00Z"
+			},
+		},
+		"invalid last evaluated": {
+			update: func(status *policyv1.ConfigurationPolicyStatus) {
+				status.LastEvaluated = "yesterday"
+			},
+		},
+		"compliance": {
+			update: func(status *policyv1.ConfigurationPolicyStatus) {
+				status.ComplianceState = policyv1.NonCompliant
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			current := persisted.DeepCopy()
+			test.update(current)
+
+			assert.Equal(t, test.unchanged, statusUnchanged(persisted, current))
+		})
+	}
+}
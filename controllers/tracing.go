@@ -0,0 +1,77 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// tracer creates the spans of the policy evaluations from the global tracer provider, so nothing is recorded
+// unless tracing is enabled.
+var tracer = otel.Tracer("open-cluster-management.io/config-policy-controller/controllers")
+
+// The attribute keys of the policy evaluation spans.
+const (
+	policyKindKey         = attribute.Key("policy.kind")
+	policyNamespaceKey    = attribute.Key("policy.namespace")
+	policyNameKey         = attribute.Key("policy.name")
+	policyComplianceKey   = attribute.Key("policy.compliance")
+	templateIndexKey      = attribute.Key("policy.object_template.index")
+	objectAPIVersionKey   = attribute.Key("k8s.object.api_version")
+	objectKindKey         = attribute.Key("k8s.object.kind")
+	objectNamespaceKey    = attribute.Key("k8s.object.namespace")
+	objectNameKey         = attribute.Key("k8s.object.name")
+	objectUpdateNeededKey = attribute.Key("k8s.object.update_needed")
+	objectViolationKey    = attribute.Key("k8s.object.violation")
+)
+
+// startPolicySpan starts the root span of the evaluation of a policy. The trace ID is added to the logger in the
+// returned context so that the logs of the evaluation can be correlated with the trace.
+func startPolicySpan(
+	ctx context.Context, name string, kind string, policy types.NamespacedName,
+) (context.Context, trace.Span) {
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(
+		policyKindKey.String(kind),
+		policyNamespaceKey.String(policy.Namespace),
+		policyNameKey.String(policy.Name),
+	))
+
+	if span.SpanContext().IsSampled() {
+		ctx = ctrl.LoggerInto(ctx, ctrl.LoggerFrom(ctx, "traceID", span.SpanContext().TraceID().String()))
+	}
+
+	return ctx, span
+}
+
+// startSpan starts a span for a step of a policy evaluation, as a child of the span in the context.
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// objectSpanAttributes returns the span attributes which identify the object.
+func objectSpanAttributes(gvk schema.GroupVersionKind, namespace string, name string) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		objectAPIVersionKey.String(gvk.GroupVersion().String()),
+		objectKindKey.String(gvk.Kind),
+		objectNamespaceKey.String(namespace),
+		objectNameKey.String(name),
+	}
+}
+
+// endSpan records the error, if any, on the span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestEndSpan(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	testTracer := provider.Tracer("test")
+
+	_, span := testTracer.Start(context.Background(), "succeeded")
+	endSpan(span, nil)
+
+	_, span = testTracer.Start(context.Background(), "failed")
+	endSpan(span, errors.New("the object is immutable"))
+
+	ended := recorder.Ended()
+	if !assert.Len(t, ended, 2) {
+		return
+	}
+
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+	assert.Empty(t, ended[0].Events())
+
+	assert.Equal(t, codes.Error, ended[1].Status().Code)
+	assert.Equal(t, "the object is immutable", ended[1].Status().Description)
+
+	if assert.Len(t, ended[1].Events(), 1) {
+		assert.Equal(t, "exception", ended[1].Events()[0].Name)
+	}
+}
+
+func TestObjectSpanAttributes(t *testing.T) {
+	t.Parallel()
+
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	attrs := objectSpanAttributes(gvk, "ns", "app")
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("k8s.object.api_version", "apps/v1"),
+		attribute.String("k8s.object.kind", "Deployment"),
+		attribute.String("k8s.object.namespace", "ns"),
+		attribute.String("k8s.object.name", "app"),
+	}, attrs)
+}
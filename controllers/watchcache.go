@@ -0,0 +1,171 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	policyv1 "open-cluster-management.io/config-policy-controller/api/v1"
+)
+
+// watchCacheReportInterval is how often the watch cache metrics are updated.
+const watchCacheReportInterval = time.Minute
+
+// watchCacheKey identifies the cached objects of a kind for the full or metadata-only watches.
+type watchCacheKey struct {
+	gvk   schema.GroupVersionKind
+	watch string
+}
+
+// watchCacheUsage is the number and approximate size of the cached objects of a kind.
+type watchCacheUsage struct {
+	objects int
+	bytes   int
+}
+
+// cachedObjectSize is the JSON size of a cached object at a resourceVersion, so that unchanged objects don't have
+// to be encoded each time the metrics are updated.
+type cachedObjectSize struct {
+	resourceVersion string
+	bytes           int
+}
+
+// watchCacheReporter tracks the state of the watch cache metrics between updates.
+type watchCacheReporter struct {
+	// sizes has the keys from watchedObjectKey and the values are cachedObjectSize objects
+	sizes map[string]cachedObjectSize
+	// reported has the label sets that were last reported, so that kinds that are no longer cached are removed
+	reported map[watchCacheKey]bool
+}
+
+// reportWatchCacheUsage updates the watch cache metrics every watchCacheReportInterval until the context is
+// canceled.
+func (r *ConfigurationPolicyReconciler) reportWatchCacheUsage(ctx context.Context) error {
+	reporter := &watchCacheReporter{}
+
+	ticker := time.NewTicker(watchCacheReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.updateWatchCacheMetrics(ctx, reporter)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// updateWatchCacheMetrics sets the number and approximate size of the objects cached for the watches of the
+// policies per watched kind. Objects watched by several policies are only counted once since they share the cache.
+func (r *ConfigurationPolicyReconciler) updateWatchCacheMetrics(ctx context.Context, reporter *watchCacheReporter) {
+	log := ctrl.LoggerFrom(ctx).WithName("watch-cache")
+
+	policies := &policyv1.ConfigurationPolicyList{}
+	if err := r.List(ctx, policies); err != nil {
+		log.Error(err, "Failed to list the policies to report the watch cache usage")
+
+		return
+	}
+
+	usage := map[watchCacheKey]*watchCacheUsage{}
+	sizes := map[string]cachedObjectSize{}
+
+	watchers := []struct {
+		watch   string
+		watcher depclient.DynamicWatcher
+	}{
+		{watch: "full", watcher: r.DynamicWatcher},
+		{watch: "metadata", watcher: r.MetadataDynamicWatcher},
+	}
+
+	for _, dynamicWatcher := range watchers {
+		if dynamicWatcher.watcher == nil {
+			continue
+		}
+
+		watch := dynamicWatcher.watch
+		counted := map[string]bool{}
+
+		for i := range policies.Items {
+			objects, err := dynamicWatcher.watcher.ListWatchedFromCache(policies.Items[i].ObjectIdentifier())
+			if err != nil {
+				log.V(2).Info("Failed to list the cached objects of the policy", "error", err.Error())
+
+				continue
+			}
+
+			for j := range objects {
+				objKey := watch + "/" + watchedObjectKey(objects[j].GroupVersionKind(),
+					objects[j].GetNamespace(), objects[j].GetName())
+				if counted[objKey] {
+					continue
+				}
+
+				counted[objKey] = true
+
+				size, ok := reporter.sizes[objKey]
+				if !ok || size.resourceVersion != objects[j].GetResourceVersion() {
+					encoded, err := json.Marshal(objects[j].Object)
+					if err != nil {
+						continue
+					}
+
+					size = cachedObjectSize{resourceVersion: objects[j].GetResourceVersion(), bytes: len(encoded)}
+				}
+
+				sizes[objKey] = size
+
+				key := watchCacheKey{gvk: objects[j].GroupVersionKind(), watch: watch}
+				if usage[key] == nil {
+					usage[key] = &watchCacheUsage{}
+				}
+
+				usage[key].objects++
+				usage[key].bytes += size.bytes
+			}
+		}
+	}
+
+	reported := make(map[watchCacheKey]bool, len(usage))
+
+	for key, kindUsage := range usage {
+		labels := watchCacheLabels(key)
+
+		watchCacheObjectsGauge.With(labels).Set(float64(kindUsage.objects))
+		watchCacheBytesGauge.With(labels).Set(float64(kindUsage.bytes))
+
+		reported[key] = true
+	}
+
+	for key := range reporter.reported {
+		if !reported[key] {
+			watchCacheObjectsGauge.Delete(watchCacheLabels(key))
+			watchCacheBytesGauge.Delete(watchCacheLabels(key))
+		}
+	}
+
+	reporter.sizes = sizes
+	reporter.reported = reported
+}
+
+func watchCacheLabels(key watchCacheKey) prometheus.Labels {
+	return prometheus.Labels{
+		"group":   key.gvk.Group,
+		"version": key.gvk.Version,
+		"kind":    key.gvk.Kind,
+		"watch":   key.watch,
+	}
+}
+
+// watchedObjectKey returns a key that uniquely identifies a watched object.
+func watchedObjectKey(gvk schema.GroupVersionKind, namespace string, name string) string {
+	return gvk.String() + "/" + namespace + "/" + name
+}
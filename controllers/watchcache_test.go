@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestUpdateWatchCacheMetrics(t *testing.T) {
+	t.Parallel()
+
+	watcher := newFakeWatcher(5)
+	r, _ := getSelectorPolicySetup(t, watcher)
+
+	metadataWatcher := newFakeWatcher(2)
+	for _, obj := range metadataWatcher.objects {
+		unstructured.RemoveNestedField(obj.Object, "data")
+	}
+
+	r.MetadataDynamicWatcher = metadataWatcher
+
+	reporter := &watchCacheReporter{}
+	r.updateWatchCacheMetrics(t.Context(), reporter)
+
+	fullLabels := prometheus.Labels{"group": "", "version": "v1", "kind": "ConfigMap", "watch": "full"}
+	metadataLabels := prometheus.Labels{"group": "", "version": "v1", "kind": "ConfigMap", "watch": "metadata"}
+
+	assert.InDelta(t, 5, gaugeValue(t, watchCacheObjectsGauge.With(fullLabels)), 0)
+	assert.InDelta(t, 2, gaugeValue(t, watchCacheObjectsGauge.With(metadataLabels)), 0)
+
+	fullBytes := gaugeValue(t, watchCacheBytesGauge.With(fullLabels))
+	metadataBytes := gaugeValue(t, watchCacheBytesGauge.With(metadataLabels))
+
+	assert.Greater(t, fullBytes/5, metadataBytes/2, "the metadata-only objects are smaller")
+	assert.Len(t, reporter.sizes, 7)
+
+	// Kinds that are no longer cached are removed from the metrics
+	r.MetadataDynamicWatcher = newFakeWatcher(0)
+	r.updateWatchCacheMetrics(t.Context(), reporter)
+
+	metrics := make(chan prometheus.Metric, 10)
+	watchCacheObjectsGauge.Collect(metrics)
+	assert.Len(t, metrics, 1)
+	assert.Len(t, reporter.sizes, 5)
+}
+
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	t.Helper()
+
+	metric := &dto.Metric{}
+	assert.NoError(t, gauge.Write(metric))
+
+	return metric.GetGauge().GetValue()
+}
+
+var _ depclient.DynamicWatcher = &fakeWatcher{}
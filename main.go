@@ -21,6 +21,8 @@ import (
 	"github.com/spf13/pflag"
 	"github.com/stolostron/go-log-utils/zaputil"
 	depclient "github.com/stolostron/kubernetes-dependency-watches/client"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	extensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
@@ -31,6 +33,7 @@ import (
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/metadata"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -57,6 +60,8 @@ import (
 	policyv1beta1 "open-cluster-management.io/config-policy-controller/api/v1beta1"
 	"open-cluster-management.io/config-policy-controller/controllers"
 	"open-cluster-management.io/config-policy-controller/pkg/common"
+	"open-cluster-management.io/config-policy-controller/pkg/sharding"
+	"open-cluster-management.io/config-policy-controller/pkg/tracing"
 	"open-cluster-management.io/config-policy-controller/pkg/triggeruninstall"
 	"open-cluster-management.io/config-policy-controller/version"
 )
@@ -95,9 +100,29 @@ type ctrlOpts struct {
 	evalBackoffSeconds       uint32
 	decryptionConcurrency    uint8
 	evaluationConcurrency    uint16
+	evaluationJitterPercent  uint8
+	objTemplateConcurrency   uint8
+	evalCachePath            string
+	evalCacheConfigMap       string
+	evalCacheSaveInterval    time.Duration
+	enableSharding           bool
+	enablePolicyReports      bool
+	notifierConfigMap        string
+	notifierQueueSize        uint32
+	auditLogPath             string
+	auditLogMaxSizeMB        uint32
+	auditLogMaxBackups       uint16
+	tracingExporter          string
+	tracingOTLPEndpoint      string
+	tracingOTLPInsecure      bool
+	tracingFile              string
+	tracingSamplingRatio     float64
 	enableLease              bool
 	enableLeaderElection     bool
 	enableMetrics            bool
+	metricsObjectLabels      bool
+	enableComplianceAPI      bool
+	decisionTraceEvals       uint16
 	enableOperatorPolicy     bool
 	enableOcmPolicyNamespace bool
 	tlsMinVersion            string
@@ -166,9 +191,32 @@ func main() {
 		panic("The --evaluation-concurrency option cannot be less than 1")
 	}
 
+	if opts.objTemplateConcurrency < 1 {
+		panic("The --object-template-concurrency option cannot be less than 1")
+	}
+
+	if opts.evalCachePath != "" && opts.evalCacheConfigMap != "" {
+		panic("The --evaluation-cache-path and --evaluation-cache-configmap options cannot both be set")
+	}
+
+	if opts.evaluationJitterPercent > 100 {
+		panic("The --evaluation-jitter-percent option cannot be greater than 100")
+	}
+
+	if opts.enableComplianceAPI && !opts.secureMetrics {
+		panic("The --enable-compliance-api option requires --secure-metrics so that the requests are authenticated")
+	}
+
+	if opts.decisionTraceEvals != 0 && !opts.secureMetrics {
+		panic("The --decision-trace-evaluations option requires --secure-metrics so that the requests are " +
+			"authenticated")
+	}
+
 	log.Info("Using", "OperatorVersion", version.Version, "GoVersion", runtime.Version(),
 		"GOOS", runtime.GOOS, "GOARCH", runtime.GOARCH)
 
+	tracerProvider := tracerProvider(opts)
+
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -291,7 +339,8 @@ func main() {
 		metricsOptions.TLSOpts = []func(*tls.Config){sdktls.ConfigToFunc(tlsCfg)}
 	}
 
-	// Set default manager options
+	// Set default manager options. When sharding, every replica is active and only handles its own policies, so
+	// there is no leader election.
 	options := manager.Options{
 		Metrics: metricsOptions,
 		Scheme:  scheme,
@@ -302,7 +351,7 @@ func main() {
 			},
 		),
 		HealthProbeBindAddress: opts.probeAddr,
-		LeaderElection:         opts.enableLeaderElection,
+		LeaderElection:         opts.enableLeaderElection && !opts.enableSharding,
 		LeaderElectionID:       "config-policy-controller.open-cluster-management.io",
 		Cache: cache.Options{
 			ByObject:   cacheByObject,
@@ -436,10 +485,50 @@ func main() {
 
 	instanceName, _ := os.Hostname() // on an error, instanceName will be empty, which is ok
 
+	var shards *sharding.Coordinator
+
+	if opts.enableSharding {
+		shards = shardCoordinator(cfg, instanceName)
+
+		if err := mgr.Add(shards); err != nil {
+			log.Error(err, "Unable to add the shard coordinator to the manager")
+			os.Exit(1)
+		}
+	}
+
+	var policyReporter *controllers.PolicyReporter
+
+	if opts.enablePolicyReports {
+		policyReporter = controllers.NewPolicyReporter(targetK8sDynamicClient)
+	}
+
+	auditLogger := auditLogger(opts)
+
+	var decisionTracer *controllers.DecisionTracer
+
+	if opts.decisionTraceEvals != 0 {
+		decisionTracer = controllers.NewDecisionTracer(int(opts.decisionTraceEvals))
+
+		if err := mgr.AddMetricsServerExtraHandler(controllers.DecisionTracePath, decisionTracer); err != nil {
+			log.Error(err, "Unable to add the decision traces to the metrics server")
+			os.Exit(1)
+		}
+	}
+
+	notifier := complianceNotifier(cfg, opts)
+	if notifier != nil {
+		if err := mgr.Add(notifier); err != nil {
+			log.Error(err, "Unable to add the compliance notifier to the manager")
+			os.Exit(1)
+		}
+	}
+
 	var nsSelReconciler common.NamespaceSelectorReconciler
 	var nsSelUpdatesSource source.TypedSource[reconcile.Request]
 	var objectTemplatesChannel source.TypedSource[reconcile.Request]
 	var dynamicWatcher depclient.DynamicWatcher
+	var metadataDynamicWatcher depclient.DynamicWatcher
+	var apiChanges *controllers.APIChangeWatcher
 	var standaloneHubCfg *rest.Config
 	var configPolHubDynamicWatcher depclient.DynamicWatcher
 	var hubClient *kubernetes.Clientset
@@ -471,7 +560,7 @@ func main() {
 
 		watcherReconciler, objectTemplatesChannel = depclient.NewControllerRuntimeSource()
 
-		dynamicWatcher, err = depclient.New(
+		dynamicWatcher, err = controllers.NewDynamicWatcher(
 			targetK8sConfig,
 			watcherReconciler,
 			&depclient.Options{DisableInitialReconcile: true, EnableCache: true},
@@ -488,8 +577,36 @@ func main() {
 			}
 		}()
 
-		// Wait until the dynamic watcher has started
+		// The objects of object templates that only need the metadata of the objects are watched separately to
+		// not cache the full objects
+		metadataDynamicWatcher, err = controllers.NewMetadataDynamicWatcher(
+			targetK8sConfig,
+			watcherReconciler,
+			&depclient.Options{DisableInitialReconcile: true, EnableCache: true},
+		)
+		if err != nil {
+			log.Error(err, "Unable to setup the metadata dynamic watcher", "controller", "ConfigurationPolicy")
+			os.Exit(1)
+		}
+
+		go func() {
+			err := metadataDynamicWatcher.Start(terminatingCtx)
+			if err != nil {
+				panic(err)
+			}
+		}()
+
+		// Wait until the dynamic watchers have started
 		<-dynamicWatcher.Started()
+		<-metadataDynamicWatcher.Started()
+
+		// Evaluate the policies that failed on a missing API mapping as soon as the API is available
+		apiChanges = controllers.NewAPIChangeWatcher(metadata.NewForConfigOrDie(targetK8sConfig))
+
+		if err := mgr.Add(apiChanges); err != nil {
+			log.Error(err, "Unable to add the API change watcher to the manager")
+			os.Exit(1)
+		}
 
 		if opts.standaloneHubTemplateKubeConfigPath != "" {
 			standaloneHubCfg, err = clientcmd.BuildConfigFromFlags("", opts.standaloneHubTemplateKubeConfigPath)
@@ -522,24 +639,47 @@ func main() {
 	}
 
 	reconciler := controllers.ConfigurationPolicyReconciler{
-		Client:                 mgr.GetClient(),
-		DecryptionConcurrency:  opts.decryptionConcurrency,
-		DynamicWatcher:         dynamicWatcher,
-		Scheme:                 mgr.GetScheme(),
-		Recorder:               mgr.GetEventRecorder(controllers.ControllerName),
-		InstanceName:           instanceName,
-		TargetK8sClient:        targetK8sClient,
-		TargetK8sDynamicClient: targetK8sDynamicClient,
-		SelectorReconciler:     &nsSelReconciler,
-		EnableMetrics:          opts.enableMetrics,
-		UninstallMode:          beingUninstalled,
-		EvalBackoffSeconds:     opts.evalBackoffSeconds,
-		ItemLimiters:           controllers.NewPerItemRateLimiter[reconcile.Request](opts.evalBackoffSeconds, 1),
-		HubDynamicWatcher:      configPolHubDynamicWatcher,
-		HubClient:              hubClient,
-		ClusterName:            opts.clusterName,
-		FullDiffs:              false,
-		TemplateFuncDenylist:   opts.templateFuncDenylist,
+		Client:                    mgr.GetClient(),
+		DecryptionConcurrency:     opts.decryptionConcurrency,
+		DynamicWatcher:            dynamicWatcher,
+		MetadataDynamicWatcher:    metadataDynamicWatcher,
+		Scheme:                    mgr.GetScheme(),
+		Recorder:                  mgr.GetEventRecorder(controllers.ControllerName),
+		InstanceName:              instanceName,
+		TargetK8sClient:           targetK8sClient,
+		TargetK8sDynamicClient:    targetK8sDynamicClient,
+		SelectorReconciler:        &nsSelReconciler,
+		EnableMetrics:             opts.enableMetrics,
+		UninstallMode:             beingUninstalled,
+		EvalBackoffSeconds:        opts.evalBackoffSeconds,
+		EvaluationJitterPercent:   opts.evaluationJitterPercent,
+		ObjectTemplateConcurrency: opts.objTemplateConcurrency,
+		ItemLimiters:              controllers.NewPerItemRateLimiter[reconcile.Request](opts.evalBackoffSeconds, 1),
+		HubDynamicWatcher:         configPolHubDynamicWatcher,
+		HubClient:                 hubClient,
+		ClusterName:               opts.clusterName,
+		FullDiffs:                 false,
+		TemplateFuncDenylist:      opts.templateFuncDenylist,
+		Shards:                    shards,
+		APIChanges:                apiChanges,
+		PolicyReporter:            policyReporter,
+		ComplianceNotifier:        notifier,
+		AuditLogger:               auditLogger,
+		DropObjectMetricLabels:    !opts.metricsObjectLabels,
+		DecisionTracer:            decisionTracer,
+
+		EvaluationCacheStore:        evaluationCacheStore(cfg, opts),
+		EvaluationCacheSaveInterval: opts.evalCacheSaveInterval,
+	}
+
+	// The RESTMapper of the manager only maps the APIs of the target cluster when it's not hosted
+	if opts.targetKubeConfig == "" {
+		reconciler.TargetRESTMapper = mgr.GetRESTMapper()
+	}
+
+	// Restore the evaluation cache before the controller starts so that unchanged objects aren't compared again
+	if err := reconciler.RestoreEvaluationCache(managerCtx); err != nil {
+		log.Error(err, "Failed to restore the evaluation cache, all objects will be evaluated")
 	}
 
 	if err = reconciler.SetupWithManager(
@@ -598,16 +738,20 @@ func main() {
 		}
 
 		OpReconciler := controllers.OperatorPolicyReconciler{
-			Client:            mgr.GetClient(),
-			DynamicClient:     targetK8sDynamicClient,
-			DynamicWatcher:    watcher,
-			InstanceName:      instanceName,
-			DefaultNamespace:  opts.operatorPolDefaultNS,
-			MaxHistoryLength:  int(opts.operatorPolHistoryLength),
-			TargetClient:      targetClient,
-			HubDynamicWatcher: opPolHubDynamicWatcher,
-			HubClient:         hubClient,
-			ClusterName:       opts.clusterName,
+			Client:             mgr.GetClient(),
+			DynamicClient:      targetK8sDynamicClient,
+			DynamicWatcher:     watcher,
+			InstanceName:       instanceName,
+			DefaultNamespace:   opts.operatorPolDefaultNS,
+			MaxHistoryLength:   int(opts.operatorPolHistoryLength),
+			TargetClient:       targetClient,
+			HubDynamicWatcher:  opPolHubDynamicWatcher,
+			HubClient:          hubClient,
+			ClusterName:        opts.clusterName,
+			Shards:             shards,
+			PolicyReporter:     policyReporter,
+			ComplianceNotifier: notifier,
+			AuditLogger:        auditLogger,
 		}
 
 		if err = OpReconciler.SetupWithManager(mgr, depEvents); err != nil {
@@ -616,6 +760,15 @@ func main() {
 		}
 	}
 
+	if opts.enableComplianceAPI {
+		complianceAPI := controllers.NewComplianceAPI(mgr.GetClient(), opts.enableOperatorPolicy)
+
+		if err := mgr.AddMetricsServerExtraHandler(controllers.ComplianceAPIPath, complianceAPI); err != nil {
+			log.Error(err, "Unable to add the compliance API to the metrics server")
+			os.Exit(1)
+		}
+	}
+
 	// This lease is not related to leader election. This is to report the status of the controller
 	// to the addon framework. This can be seen in the "status" section of the ManagedClusterAddOn
 	// resource objects.
@@ -743,11 +896,149 @@ func main() {
 
 	wg.Wait()
 
+	if tracerProvider != nil {
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "Failed to export the remaining spans")
+		}
+
+		shutdownCancel()
+	}
+
 	if errorExit {
 		os.Exit(1)
 	}
 }
 
+// evaluationCacheStore returns the store of the configuration policy evaluation cache based on the options, or
+// nil when the evaluation cache isn't persisted.
+func evaluationCacheStore(cfg *rest.Config, opts *ctrlOpts) controllers.EvaluationCacheStore {
+	if opts.evalCachePath != "" {
+		return &controllers.FileEvaluationCacheStore{Path: opts.evalCachePath}
+	}
+
+	if opts.evalCacheConfigMap == "" {
+		return nil
+	}
+
+	operatorNs, err := common.GetOperatorNamespace()
+	if err != nil {
+		if errors.Is(err, common.ErrNoNamespace) || errors.Is(err, common.ErrRunLocal) {
+			log.Info("Not persisting the evaluation cache to a ConfigMap; not running in a cluster")
+
+			return nil
+		}
+
+		log.Error(err, "Failed to get operator namespace")
+		os.Exit(1)
+	}
+
+	return &controllers.ConfigMapEvaluationCacheStore{
+		Client:    kubernetes.NewForConfigOrDie(cfg),
+		Namespace: operatorNs,
+		Name:      opts.evalCacheConfigMap,
+	}
+}
+
+// auditLogger returns the logger which records the changes made to the objects on the cluster, or nil when the
+// audit log is disabled. A path of "-" writes the audit log to stdout.
+func auditLogger(opts *ctrlOpts) *controllers.AuditLogger {
+	switch opts.auditLogPath {
+	case "":
+		return nil
+	case "-":
+		return controllers.NewAuditLogger(os.Stdout)
+	}
+
+	file, err := controllers.OpenRotatingFile(
+		opts.auditLogPath, int64(opts.auditLogMaxSizeMB)*1024*1024, int(opts.auditLogMaxBackups),
+	)
+	if err != nil {
+		log.Error(err, "Failed to open the audit log")
+		os.Exit(1)
+	}
+
+	return controllers.NewAuditLogger(file)
+}
+
+// tracerProvider sets the global OpenTelemetry tracer provider which exports the spans of the policy evaluations,
+// and returns it so it's shut down on exit. It returns nil when tracing is disabled.
+func tracerProvider(opts *ctrlOpts) *sdktrace.TracerProvider {
+	provider, err := tracing.NewTracerProvider(context.Background(), tracing.Options{
+		Exporter:       opts.tracingExporter,
+		OTLPEndpoint:   opts.tracingOTLPEndpoint,
+		OTLPInsecure:   opts.tracingOTLPInsecure,
+		FilePath:       opts.tracingFile,
+		SamplingRatio:  opts.tracingSamplingRatio,
+		ServiceName:    "config-policy-controller",
+		ServiceVersion: version.Version,
+	})
+	if err != nil {
+		log.Error(err, "Failed to set up the tracing of the policy evaluations")
+		os.Exit(1)
+	}
+
+	if provider == nil {
+		return nil
+	}
+
+	log.Info("Tracing the policy evaluations", "exporter", opts.tracingExporter,
+		"samplingRatio", opts.tracingSamplingRatio)
+
+	otel.SetLogger(ctrl.Log.WithName("otel"))
+	otel.SetTracerProvider(provider)
+
+	return provider
+}
+
+// complianceNotifier returns the notifier which sends the compliance changes of the policies to the HTTP sinks
+// configured in a ConfigMap in the controller namespace, or nil when it's not configured.
+func complianceNotifier(cfg *rest.Config, opts *ctrlOpts) *controllers.ComplianceNotifier {
+	if opts.notifierConfigMap == "" {
+		return nil
+	}
+
+	operatorNs, err := common.GetOperatorNamespace()
+	if err != nil {
+		if errors.Is(err, common.ErrNoNamespace) || errors.Is(err, common.ErrRunLocal) {
+			log.Info("Not sending compliance notifications; not running in a cluster")
+
+			return nil
+		}
+
+		log.Error(err, "Failed to get operator namespace")
+		os.Exit(1)
+	}
+
+	return controllers.NewComplianceNotifier(
+		kubernetes.NewForConfigOrDie(cfg),
+		operatorNs,
+		opts.notifierConfigMap,
+		"/clusters/"+opts.clusterName+"/config-policy-controller",
+		int(opts.notifierQueueSize),
+	)
+}
+
+// shardCoordinator returns the coordinator which shards the policies across the active controller replicas
+// using Leases in the controller namespace.
+func shardCoordinator(cfg *rest.Config, identity string) *sharding.Coordinator {
+	operatorNs, err := common.GetOperatorNamespace()
+	if err != nil {
+		log.Error(err, "The controller namespace is required to shard the policies across replicas")
+		os.Exit(1)
+	}
+
+	if identity == "" {
+		log.Error(errors.New("the hostname is unknown"), "Failed to shard the policies across replicas")
+		os.Exit(1)
+	}
+
+	log.Info("Sharding the policies across the active controller replicas", "identity", identity)
+
+	return sharding.NewCoordinator(kubernetes.NewForConfigOrDie(cfg), operatorNs, "config-policy-controller", identity)
+}
+
 func handleTriggerUninstall() {
 	triggerUninstallFlagSet := pflag.NewFlagSet("trigger-uninstall", pflag.ExitOnError)
 
@@ -894,6 +1185,136 @@ func parseOpts(flags *pflag.FlagSet, args []string) *ctrlOpts {
 		"The max number of concurrent configuration policy evaluations",
 	)
 
+	flags.Uint8Var(
+		&opts.evaluationJitterPercent,
+		"evaluation-jitter-percent",
+		0,
+		"The default maximum percentage of the evaluation interval that the next evaluation of a configuration "+
+			"policy is delayed by to spread out evaluations. Can be overridden by the policy.",
+	)
+
+	flags.Uint8Var(
+		&opts.objTemplateConcurrency,
+		"object-template-concurrency",
+		1,
+		"The max number of independent object templates of a configuration policy that are evaluated concurrently. "+
+			"Object templates that can affect the same object, and the object templates of policies with an "+
+			"objectSelector or template lookups, are always evaluated in order.",
+	)
+
+	flags.StringVar(
+		&opts.evalCachePath,
+		"evaluation-cache-path",
+		"",
+		"The path of a file on a persistent volume to save the configuration policy evaluation cache to, so that "+
+			"unchanged objects aren't evaluated again after a restart",
+	)
+
+	flags.StringVar(
+		&opts.evalCacheConfigMap,
+		"evaluation-cache-configmap",
+		"",
+		"The name of a ConfigMap in the controller namespace to save the configuration policy evaluation cache to, "+
+			"so that unchanged objects aren't evaluated again after a restart",
+	)
+
+	flags.DurationVar(
+		&opts.evalCacheSaveInterval,
+		"evaluation-cache-save-interval",
+		time.Minute,
+		"How often the configuration policy evaluation cache is saved when it has changed",
+	)
+
+	flags.BoolVar(
+		&opts.enableSharding,
+		"enable-sharding",
+		false,
+		"Shard the policies across all active controller replicas instead of electing a leader. Each replica "+
+			"renews a Lease in the controller namespace and handles the policies assigned to it by consistent hashing.",
+	)
+
+	flags.StringVar(
+		&opts.auditLogPath,
+		"audit-log",
+		"",
+		"The path of a file to record a JSON line in for each object created, updated, recreated, or deleted "+
+			"when enforcing policies, or - for stdout. The audit log is disabled when empty.",
+	)
+
+	flags.Uint32Var(
+		&opts.auditLogMaxSizeMB,
+		"audit-log-max-size",
+		100,
+		"The size in megabytes at which the audit log file is rotated. Set to 0 to disable the rotation.",
+	)
+
+	flags.Uint16Var(
+		&opts.auditLogMaxBackups,
+		"audit-log-max-backups",
+		5,
+		"The number of rotated audit log files to keep",
+	)
+
+	flags.StringVar(
+		&opts.tracingExporter,
+		"tracing-exporter",
+		tracing.ExporterNone,
+		"The exporter of the OpenTelemetry spans of the policy evaluations: none, otlp, or file",
+	)
+
+	flags.StringVar(
+		&opts.tracingOTLPEndpoint,
+		"tracing-otlp-endpoint",
+		"",
+		"The host and port of the OTLP gRPC receiver of the spans when the tracing exporter is otlp. When empty, "+
+			"the OTEL_EXPORTER_OTLP_ENDPOINT environment variable or localhost:4317 is used.",
+	)
+
+	flags.BoolVar(
+		&opts.tracingOTLPInsecure,
+		"tracing-otlp-insecure",
+		false,
+		"Connect to the OTLP receiver of the spans without TLS",
+	)
+
+	flags.StringVar(
+		&opts.tracingFile,
+		"tracing-file",
+		"-",
+		"The path of a file to append the spans to as JSON when the tracing exporter is file, or - for stdout",
+	)
+
+	flags.Float64Var(
+		&opts.tracingSamplingRatio,
+		"tracing-sampling-ratio",
+		1,
+		"The fraction of the policy evaluations to trace, from 0 to 1",
+	)
+
+	flags.StringVar(
+		&opts.notifierConfigMap,
+		"compliance-notifier-configmap",
+		"",
+		"The name of a ConfigMap in the controller namespace with the HTTP sinks to send CloudEvents to when the "+
+			"compliance of a policy changes. Each key is the name of a sink and each value is a YAML object with "+
+			"the url, and optionally the headers and timeout, of the sink.",
+	)
+
+	flags.Uint32Var(
+		&opts.notifierQueueSize,
+		"compliance-notifier-queue-size",
+		controllers.DefaultNotificationQueueSize,
+		"The maximum number of compliance notifications waiting to be sent before new ones are dropped",
+	)
+
+	flags.BoolVar(
+		&opts.enablePolicyReports,
+		"enable-policy-reports",
+		false,
+		"Report the related objects of the policies in wgpolicyk8s.io PolicyReports and ClusterPolicyReports on the "+
+			"managed cluster. The PolicyReport CRDs must be installed.",
+	)
+
 	flags.BoolVar(
 		&opts.enableMetrics,
 		"enable-metrics",
@@ -901,6 +1322,33 @@ func parseOpts(flags *pflag.FlagSet, args []string) *ctrlOpts {
 		"Disable custom metrics collection",
 	)
 
+	flags.BoolVar(
+		&opts.metricsObjectLabels,
+		"metrics-object-labels",
+		true,
+		"Label the compare_objects_* metrics with the namespace and name of each object. When disabled, the "+
+			"metrics only have the resource of the objects, which limits the number of series.",
+	)
+
+	flags.BoolVar(
+		&opts.enableComplianceAPI,
+		"enable-compliance-api",
+		false,
+		"Serve a read-only JSON summary of the compliance of the policies at "+controllers.ComplianceAPIPath+
+			" on the metrics server. Requires --secure-metrics, and the clients need access to the path through "+
+			"the config-policy-controller-compliance-reader ClusterRole.",
+	)
+
+	flags.Uint16Var(
+		&opts.decisionTraceEvals,
+		"decision-trace-evaluations",
+		0,
+		"The number of evaluations of each ConfigurationPolicy to keep a decision trace of, served at "+
+			controllers.DecisionTracePath+" on the metrics server. 0 disables the decision traces. Requires "+
+			"--secure-metrics, and the clients need access to the path through the "+
+			"config-policy-controller-decision-trace-reader ClusterRole.",
+	)
+
 	flags.Float32Var(
 		&opts.clientQPS,
 		"client-max-qps",
@@ -0,0 +1,405 @@
+// Copyright Contributors to the Open Cluster Management project
+
+// Package sharding spreads the policies across active controller replicas. Each replica renews its own
+// Lease, and the replicas with a current Lease form a consistent hash ring which determines the replica
+// that owns each policy.
+package sharding
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// MemberLabel is the label on the Lease of every replica, with the name of the shard group as the value.
+	MemberLabel = "policy.open-cluster-management.io/controller-shard"
+	// DefaultLeaseDuration is the default time after the last renewal before a replica leaves the ring.
+	DefaultLeaseDuration = 30 * time.Second
+	// DefaultRenewInterval is the default time between the renewals of the Lease and the checks of the
+	// members of the ring.
+	DefaultRenewInterval = 5 * time.Second
+	// DefaultHandoffGracePeriod is the default time after a rebalance before a replica handles the keys it
+	// gained from another active replica, which only releases them once it notices the rebalance.
+	DefaultHandoffGracePeriod = 2 * DefaultRenewInterval
+	// expiredLeaseRetention is the number of lease durations after which the Lease of a replica that didn't
+	// remove its Lease when stopping is deleted, since replaced pods don't reuse the name.
+	expiredLeaseRetention = 10
+)
+
+var (
+	shardReplicasGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "policy_controller_shard_replicas",
+			Help: "The number of active controller replicas that the policies are sharded across",
+		},
+	)
+	shardRebalancesCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "policy_controller_shard_rebalances_total",
+			Help: "The number of times the policies were rebalanced because a controller replica joined or left",
+		},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(shardReplicasGauge, shardRebalancesCounter)
+}
+
+// Key returns the sharding key of a policy. Policies of different kinds with the same namespace and name
+// are owned by the same replica.
+func Key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Coordinator maintains the Lease of this replica and the ring of active replicas. It's a manager
+// runnable which runs on every replica regardless of leader election.
+type Coordinator struct {
+	client    kubernetes.Interface
+	namespace string
+	group     string
+	identity  string
+	// LeaseDuration is the time after the last renewal before a replica leaves the ring.
+	LeaseDuration time.Duration
+	// RenewInterval is the time between the renewals of the Lease and the checks of the ring members.
+	RenewInterval time.Duration
+	// HandoffGracePeriod is the time after a rebalance before this replica handles the keys it gained from
+	// another active replica.
+	HandoffGracePeriod time.Duration
+	// now is overridden in the tests.
+	now func() time.Time
+	// observed has the last observed renewal of the Lease of each other replica, by Lease name. It's only
+	// used by sync.
+	observed map[string]observedLease
+
+	lock      sync.RWMutex
+	ring      *Ring
+	previous  *Ring
+	handoffAt time.Time
+	lastRenew time.Time
+	ready     chan struct{}
+	listeners []func(ctx context.Context)
+	// rebalances signals the goroutine calling the listeners, so that slow listeners don't delay renewals
+	rebalances chan struct{}
+}
+
+// NewCoordinator returns a Coordinator for the replica with the identity, which is typically the pod name.
+// The Leases of the replicas of the shard group are in the namespace.
+func NewCoordinator(client kubernetes.Interface, namespace, group, identity string) *Coordinator {
+	return &Coordinator{
+		client:             client,
+		namespace:          namespace,
+		group:              group,
+		identity:           identity,
+		LeaseDuration:      DefaultLeaseDuration,
+		RenewInterval:      DefaultRenewInterval,
+		HandoffGracePeriod: DefaultHandoffGracePeriod,
+		now:                time.Now,
+		observed:           map[string]observedLease{},
+		ring:               NewRing(nil),
+		previous:           NewRing(nil),
+		ready:              make(chan struct{}),
+		rebalances:         make(chan struct{}, 1),
+	}
+}
+
+// observedLease is a renewal of the Lease of another replica, and when this replica observed it.
+type observedLease struct {
+	renewTime       metav1.MicroTime
+	resourceVersion string
+	// observedAt is the local time when the renewal was first observed, since the clocks of the replicas
+	// can differ
+	observedAt time.Time
+}
+
+// Identity returns the identity of this replica.
+func (c *Coordinator) Identity() string {
+	return c.identity
+}
+
+// Ready returns a channel which is closed once the members of the ring are first known. Until then, this
+// replica owns no keys.
+func (c *Coordinator) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// IsReady returns whether the members of the ring are known.
+func (c *Coordinator) IsReady() bool {
+	select {
+	case <-c.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// Owner returns the replica owning the key, or an empty string if the ring has no members.
+func (c *Coordinator) Owner(key string) string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.ring.Owner(key)
+}
+
+// Owns returns whether this replica owns the key.
+func (c *Coordinator) Owns(key string) bool {
+	return c.Owner(key) == c.identity
+}
+
+// HandoffRemaining returns how long this replica must wait before handling a key it owns, because it gained
+// the key in the last rebalance from another active replica, which keeps handling the key until it notices the
+// rebalance. It's zero once the HandoffGracePeriod after the rebalance passed, and for keys gained from a
+// replica that left, since a replica stops handling its keys when it can't renew its Lease.
+func (c *Coordinator) HandoffRemaining(key string) time.Duration {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	remaining := c.handoffAt.Add(c.HandoffGracePeriod).Sub(c.now())
+	if remaining <= 0 {
+		return 0
+	}
+
+	members := c.ring.Members()
+
+	switch previous := c.previous.Owner(key); {
+	case previous == c.identity:
+		return 0
+	case previous == "":
+		// When this replica first joins, the other replicas might own the key
+		if len(members) == 1 {
+			return 0
+		}
+	case !slices.Contains(members, previous):
+		return 0
+	}
+
+	return remaining
+}
+
+// Members returns the sorted identities of the active replicas.
+func (c *Coordinator) Members() []string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.ring.Members()
+}
+
+// OnRebalance registers a function that is called after the members of the ring change, including when
+// they're first known. The calls are serialized, and changes while a listener runs are coalesced into a
+// single call. It must be called before the Coordinator starts.
+func (c *Coordinator) OnRebalance(listener func(ctx context.Context)) {
+	c.listeners = append(c.listeners, listener)
+}
+
+// NeedLeaderElection implements the manager.LeaderElectionRunnable interface so that the Coordinator
+// runs on every replica.
+func (c *Coordinator) NeedLeaderElection() bool {
+	return false
+}
+
+// Start renews the Lease of this replica and updates the ring until the context is canceled, at which
+// point the Lease is deleted so that the other replicas take over this replica's policies right away.
+func (c *Coordinator) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("sharding").WithValues("identity", c.identity)
+
+	ticker := time.NewTicker(c.RenewInterval)
+	defer ticker.Stop()
+
+	go func() {
+		for {
+			select {
+			case <-c.rebalances:
+				for _, listener := range c.listeners {
+					listener(ctx)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		if err := c.renew(ctx); err != nil {
+			log.Error(err, "Failed to renew the shard Lease, will try again")
+		}
+
+		if err := c.sync(ctx); err != nil {
+			log.Error(err, "Failed to determine the active controller replicas, will try again")
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+
+			err := c.client.CoordinationV1().Leases(c.namespace).Delete(
+				releaseCtx, c.leaseName(), metav1.DeleteOptions{},
+			)
+			if err != nil && !k8serrors.IsNotFound(err) {
+				log.Error(err, "Failed to delete the shard Lease before stopping")
+			}
+
+			return nil
+		}
+	}
+}
+
+func (c *Coordinator) leaseName() string {
+	return c.group + "-" + c.identity
+}
+
+// renew creates or renews the Lease of this replica.
+func (c *Coordinator) renew(ctx context.Context) error {
+	leases := c.client.CoordinationV1().Leases(c.namespace)
+	now := metav1.NewMicroTime(c.now())
+
+	lease, err := leases.Get(ctx, c.leaseName(), metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      c.leaseName(),
+				Namespace: c.namespace,
+				Labels:    map[string]string{MemberLabel: c.group},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(c.identity),
+				LeaseDurationSeconds: ptr.To(int32(c.LeaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+
+		_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+	} else if err == nil {
+		lease.Spec.HolderIdentity = ptr.To(c.identity)
+		lease.Spec.LeaseDurationSeconds = ptr.To(int32(c.LeaseDuration.Seconds()))
+		lease.Spec.RenewTime = &now
+
+		_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.lastRenew = now.Time
+	c.lock.Unlock()
+
+	return nil
+}
+
+// sync updates the ring to the replicas with a current Lease and calls the listeners when the members
+// changed. This replica is only a member while its own Lease is current, so that it stops handling
+// policies at the same time as the other replicas take them over when it can't renew its Lease. Like with
+// leader election, the Lease of another replica expires a lease duration after this replica observed its
+// last renewal, so that the expiry doesn't depend on the clocks of the replicas being in sync.
+func (c *Coordinator) sync(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("sharding")
+
+	leaseList, err := c.client.CoordinationV1().Leases(c.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: MemberLabel + "=" + c.group,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := c.now()
+	members := []string{}
+	listed := make(map[string]bool, len(leaseList.Items))
+
+	for _, lease := range leaseList.Items {
+		if lease.Spec.HolderIdentity == nil || lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+			continue
+		}
+
+		listed[lease.Name] = true
+
+		observed, ok := c.observed[lease.Name]
+		if !ok || !observed.renewTime.Equal(lease.Spec.RenewTime) ||
+			observed.resourceVersion != lease.ResourceVersion {
+			observed = observedLease{
+				renewTime:       *lease.Spec.RenewTime,
+				resourceVersion: lease.ResourceVersion,
+				observedAt:      now,
+			}
+			c.observed[lease.Name] = observed
+		}
+
+		expiry := observed.observedAt.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second)
+
+		if now.Before(expiry) {
+			if *lease.Spec.HolderIdentity != c.identity {
+				members = append(members, *lease.Spec.HolderIdentity)
+			}
+
+			continue
+		}
+
+		if now.Sub(expiry) > expiredLeaseRetention*c.LeaseDuration {
+			err := c.client.CoordinationV1().Leases(c.namespace).Delete(ctx, lease.Name, metav1.DeleteOptions{})
+			if err != nil && !k8serrors.IsNotFound(err) {
+				log.Error(err, "Failed to delete an expired shard Lease", "lease", lease.Name)
+			}
+		}
+	}
+
+	for name := range c.observed {
+		if !listed[name] {
+			delete(c.observed, name)
+		}
+	}
+
+	c.lock.Lock()
+
+	if !c.lastRenew.IsZero() && now.Sub(c.lastRenew) < c.LeaseDuration {
+		members = append(members, c.identity)
+	}
+
+	ring := NewRing(members)
+	changed := !slices.Equal(ring.Members(), c.ring.Members())
+	firstSync := !c.IsReady()
+
+	if changed {
+		c.previous = c.ring
+		c.ring = ring
+		c.handoffAt = now
+	}
+
+	if firstSync {
+		close(c.ready)
+	}
+
+	c.lock.Unlock()
+
+	shardReplicasGauge.Set(float64(len(ring.Members())))
+
+	if !changed && !firstSync {
+		return nil
+	}
+
+	log.Info("The active controller replicas changed, rebalancing the policies", "replicas", ring.Members())
+
+	if !firstSync {
+		shardRebalancesCounter.Inc()
+	}
+
+	select {
+	case c.rebalances <- struct{}{}:
+	default:
+		// A rebalance is already pending
+	}
+
+	return nil
+}
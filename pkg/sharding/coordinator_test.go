@@ -0,0 +1,248 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package sharding
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/utils/ptr"
+)
+
+func TestCoordinatorMembership(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	now := time.Now()
+	clock := func() time.Time { return now }
+
+	newCoordinator := func(identity string) *Coordinator {
+		coordinator := NewCoordinator(client, "ns", "test", identity)
+		coordinator.now = clock
+
+		return coordinator
+	}
+
+	replicaA := newCoordinator("replica-a")
+	replicaB := newCoordinator("replica-b")
+
+	rebalances := atomic.Int32{}
+	replicaA.OnRebalance(func(context.Context) { rebalances.Add(1) })
+
+	assert.False(t, replicaA.IsReady())
+	assert.False(t, replicaA.Owns("ns/name"), "nothing is owned before the ring is known")
+
+	sync := func(c *Coordinator) {
+		assert.NoError(t, c.renew(t.Context()))
+		assert.NoError(t, c.sync(t.Context()))
+	}
+
+	sync(replicaA)
+	assert.True(t, replicaA.IsReady())
+	assert.Equal(t, []string{"replica-a"}, replicaA.Members())
+	assert.True(t, replicaA.Owns("ns/name"))
+	assert.Len(t, replicaA.rebalances, 1)
+
+	sync(replicaB)
+	sync(replicaA)
+	assert.Equal(t, []string{"replica-a", "replica-b"}, replicaA.Members())
+	assert.Equal(t, replicaA.Members(), replicaB.Members())
+
+	// The replicas agree on the owner of every key
+	for _, key := range policyKeys(100) {
+		assert.NotEqual(t, replicaA.Owns(key), replicaB.Owns(key), key)
+	}
+
+	// replica-b stops renewing its Lease, so it leaves the ring once the Lease expires
+	now = now.Add(DefaultLeaseDuration / 2)
+	sync(replicaA)
+	assert.Equal(t, []string{"replica-a", "replica-b"}, replicaA.Members())
+
+	now = now.Add(DefaultLeaseDuration)
+	sync(replicaA)
+	assert.Equal(t, []string{"replica-a"}, replicaA.Members())
+
+	// The expired Lease is eventually deleted
+	now = now.Add(expiredLeaseRetention * DefaultLeaseDuration)
+	sync(replicaA)
+
+	leases, err := client.CoordinationV1().Leases("ns").List(t.Context(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, leases.Items, 1)
+
+	// A replica that can't renew its Lease leaves the ring itself
+	now = now.Add(2 * DefaultLeaseDuration)
+	assert.NoError(t, replicaA.sync(t.Context()))
+	assert.Empty(t, replicaA.Members())
+	assert.False(t, replicaA.Owns("ns/name"))
+}
+
+func TestCoordinatorClockSkew(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	now := time.Now()
+
+	replicaA := NewCoordinator(client, "ns", "test", "replica-a")
+	replicaA.now = func() time.Time { return now }
+
+	// The clock of replica-b is an hour behind and the clock of replica-c is an hour ahead
+	putLease := func(identity string, renewTime time.Time) {
+		lease := &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "test-" + identity, Namespace: "ns", Labels: map[string]string{MemberLabel: "test"},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(identity),
+				LeaseDurationSeconds: ptr.To(int32(DefaultLeaseDuration.Seconds())),
+				RenewTime:            &metav1.MicroTime{Time: renewTime},
+			},
+		}
+
+		_, err := client.CoordinationV1().Leases("ns").Update(t.Context(), lease, metav1.UpdateOptions{})
+		if k8serrors.IsNotFound(err) {
+			_, err = client.CoordinationV1().Leases("ns").Create(t.Context(), lease, metav1.CreateOptions{})
+		}
+
+		assert.NoError(t, err)
+	}
+
+	sync := func() {
+		assert.NoError(t, replicaA.renew(t.Context()))
+		assert.NoError(t, replicaA.sync(t.Context()))
+	}
+
+	renewB := now.Add(-time.Hour)
+	renewC := now.Add(time.Hour)
+
+	putLease("replica-b", renewB)
+	putLease("replica-c", renewC)
+	sync()
+	assert.Equal(t, []string{"replica-a", "replica-b", "replica-c"}, replicaA.Members())
+
+	// Both replicas keep renewing their Leases, so they stay in the ring regardless of their clocks
+	for range 3 {
+		now = now.Add(DefaultLeaseDuration * 2 / 3)
+		renewB = renewB.Add(DefaultLeaseDuration * 2 / 3)
+		renewC = renewC.Add(DefaultLeaseDuration * 2 / 3)
+
+		putLease("replica-b", renewB)
+		putLease("replica-c", renewC)
+		sync()
+		assert.Equal(t, []string{"replica-a", "replica-b", "replica-c"}, replicaA.Members())
+	}
+
+	// replica-c stops renewing its Lease, so it leaves the ring a lease duration later on the local clock
+	now = now.Add(DefaultLeaseDuration / 2)
+	renewB = renewB.Add(DefaultLeaseDuration / 2)
+	putLease("replica-b", renewB)
+	sync()
+	assert.Equal(t, []string{"replica-a", "replica-b", "replica-c"}, replicaA.Members())
+
+	now = now.Add(DefaultLeaseDuration / 2)
+	renewB = renewB.Add(DefaultLeaseDuration / 2)
+	putLease("replica-b", renewB)
+	sync()
+	assert.Equal(t, []string{"replica-a", "replica-b"}, replicaA.Members())
+}
+
+func TestCoordinatorHandoff(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	now := time.Now()
+
+	newCoordinator := func(identity string) *Coordinator {
+		coordinator := NewCoordinator(client, "ns", "test", identity)
+		coordinator.now = func() time.Time { return now }
+
+		return coordinator
+	}
+
+	replicaA := newCoordinator("replica-a")
+	replicaB := newCoordinator("replica-b")
+
+	sync := func(c *Coordinator) {
+		assert.NoError(t, c.renew(t.Context()))
+		assert.NoError(t, c.sync(t.Context()))
+	}
+
+	keys := policyKeys(100)
+
+	// A replica alone in the ring handles its keys right away
+	sync(replicaA)
+
+	for _, key := range keys {
+		assert.Zero(t, replicaA.HandoffRemaining(key), key)
+	}
+
+	// replica-b joins, so it waits for replica-a to release the keys it gained
+	sync(replicaB)
+	sync(replicaA)
+
+	gained := 0
+
+	for _, key := range keys {
+		assert.Zero(t, replicaA.HandoffRemaining(key), key)
+
+		if replicaB.Owns(key) {
+			gained++
+
+			assert.Equal(t, DefaultHandoffGracePeriod, replicaB.HandoffRemaining(key), key)
+		}
+	}
+
+	assert.Positive(t, gained)
+
+	now = now.Add(DefaultHandoffGracePeriod)
+
+	for _, key := range keys {
+		assert.Zero(t, replicaB.HandoffRemaining(key), key)
+	}
+
+	// replica-b leaves, so replica-a handles the keys it gained right away since replica-b stopped handling them
+	now = now.Add(2 * DefaultLeaseDuration)
+	sync(replicaA)
+	assert.Equal(t, []string{"replica-a"}, replicaA.Members())
+
+	for _, key := range keys {
+		assert.Zero(t, replicaA.HandoffRemaining(key), key)
+	}
+}
+
+func TestCoordinatorStart(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewClientset()
+	coordinator := NewCoordinator(client, "ns", "test", "replica-a")
+	coordinator.RenewInterval = 10 * time.Millisecond
+
+	rebalanced := make(chan struct{}, 10)
+	coordinator.OnRebalance(func(context.Context) { rebalanced <- struct{}{} })
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error)
+
+	go func() { done <- coordinator.Start(ctx) }()
+
+	<-coordinator.Ready()
+	<-rebalanced
+
+	_, err := client.CoordinationV1().Leases("ns").Get(t.Context(), "test-replica-a", metav1.GetOptions{})
+	assert.NoError(t, err)
+
+	cancel()
+	assert.NoError(t, <-done)
+
+	// The Lease is deleted when stopping so that the other replicas take over right away
+	leases, err := client.CoordinationV1().Leases("ns").List(t.Context(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, leases.Items)
+}
@@ -0,0 +1,88 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package sharding
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is the number of points of each member on the ring. More points spread the keys more evenly
+// across the members.
+const virtualNodes = 128
+
+// Ring is a consistent hash ring of the controller replicas. A key is owned by the member with the first
+// point on the ring at or after the hash of the key, so when a member joins or leaves, only the keys of
+// the points next to the member's points move to another member.
+type Ring struct {
+	members []string
+	points  []uint64
+	owners  map[uint64]string
+}
+
+// NewRing returns a ring of the members. The order of the members doesn't matter.
+func NewRing(members []string) *Ring {
+	ring := &Ring{
+		members: slices.Sorted(slices.Values(members)),
+		points:  make([]uint64, 0, len(members)*virtualNodes),
+		owners:  make(map[uint64]string, len(members)*virtualNodes),
+	}
+
+	ring.members = slices.Compact(ring.members)
+
+	for _, member := range ring.members {
+		for i := range virtualNodes {
+			point := hash(member + "#" + strconv.Itoa(i))
+
+			// On the unlikely collision, the point goes to the lowest member so that every replica agrees
+			if _, ok := ring.owners[point]; ok {
+				continue
+			}
+
+			ring.owners[point] = member
+			ring.points = append(ring.points, point)
+		}
+	}
+
+	slices.Sort(ring.points)
+
+	return ring
+}
+
+// Members returns the sorted members of the ring.
+func (r *Ring) Members() []string {
+	return slices.Clone(r.members)
+}
+
+// Owner returns the member owning the key, or an empty string if the ring has no members.
+func (r *Ring) Owner(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+
+	keyHash := hash(key)
+
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= keyHash })
+	if i == len(r.points) {
+		i = 0
+	}
+
+	return r.owners[r.points[i]]
+}
+
+func hash(value string) uint64 {
+	hasher := fnv.New64a()
+	_, _ = hasher.Write([]byte(value))
+
+	// FNV doesn't spread similar short inputs well, so the bits are mixed with the finalizer of SplitMix64
+	sum := hasher.Sum64()
+	sum ^= sum >> 30
+	sum *= 0xbf58476d1ce4e5b9
+	sum ^= sum >> 27
+	sum *= 0x94d049bb133111eb
+	sum ^= sum >> 31
+
+	return sum
+}
@@ -0,0 +1,71 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package sharding
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func policyKeys(count int) []string {
+	keys := make([]string, count)
+	for i := range keys {
+		keys[i] = Key(fmt.Sprintf("cluster-%d", i%20), fmt.Sprintf("policy-%d", i))
+	}
+
+	return keys
+}
+
+func TestRingBalance(t *testing.T) {
+	t.Parallel()
+
+	members := []string{"replica-a", "replica-b", "replica-c", "replica-d"}
+	ring := NewRing(members)
+	keys := policyKeys(10000)
+
+	counts := map[string]int{}
+	for _, key := range keys {
+		counts[ring.Owner(key)]++
+	}
+
+	assert.Len(t, counts, len(members))
+
+	for member, count := range counts {
+		// Each member has a fourth of the keys, within 30%
+		assert.InDelta(t, len(keys)/len(members), count, 0.3*float64(len(keys)/len(members)), member)
+	}
+}
+
+func TestRingMovement(t *testing.T) {
+	t.Parallel()
+
+	before := NewRing([]string{"replica-a", "replica-b", "replica-c"})
+	after := NewRing([]string{"replica-c", "replica-b", "replica-a", "replica-d"})
+	keys := policyKeys(10000)
+
+	moved := 0
+
+	for _, key := range keys {
+		if before.Owner(key) != after.Owner(key) {
+			moved++
+
+			// Keys only move to the new member
+			assert.Equal(t, "replica-d", after.Owner(key))
+		}
+	}
+
+	// About a fourth of the keys move to the new member
+	assert.InDelta(t, len(keys)/4, moved, 0.3*float64(len(keys)/4))
+}
+
+func TestRingEmpty(t *testing.T) {
+	t.Parallel()
+
+	ring := NewRing(nil)
+
+	assert.Empty(t, ring.Owner("ns/name"))
+	assert.Empty(t, ring.Members())
+	assert.Equal(t, []string{"a"}, NewRing([]string{"a", "a"}).Members())
+}
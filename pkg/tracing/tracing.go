@@ -0,0 +1,136 @@
+// Copyright Contributors to the Open Cluster Management project
+
+// Package tracing configures the OpenTelemetry tracer provider which exports the spans of the policy evaluations,
+// either to an OTLP receiver or to a file.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const (
+	// ExporterNone disables the tracing.
+	ExporterNone = "none"
+	// ExporterOTLP exports the spans to an OTLP receiver over gRPC.
+	ExporterOTLP = "otlp"
+	// ExporterFile writes the spans to a file as JSON, one span per line.
+	ExporterFile = "file"
+)
+
+// Options configure how the spans are exported and sampled.
+type Options struct {
+	// Exporter is one of ExporterNone, ExporterOTLP, or ExporterFile.
+	Exporter string
+	// OTLPEndpoint is the host and port of the OTLP gRPC receiver. When empty, the standard OTEL_EXPORTER_OTLP_*
+	// environment variables are used.
+	OTLPEndpoint string
+	// OTLPInsecure disables TLS when connecting to the OTLP receiver.
+	OTLPInsecure bool
+	// FilePath is the file the spans are appended to with the file exporter, or - for stdout.
+	FilePath string
+	// SamplingRatio is the fraction of the traces that are sampled, from 0 to 1. Spans with a sampled parent are
+	// always sampled.
+	SamplingRatio float64
+	// ServiceName and ServiceVersion identify the controller in the exported spans.
+	ServiceName    string
+	ServiceVersion string
+}
+
+// NewTracerProvider returns a tracer provider which exports the spans based on the options, or nil when the
+// exporter is none. The provider must be shut down to flush the remaining spans.
+func NewTracerProvider(ctx context.Context, opts Options) (*sdktrace.TracerProvider, error) {
+	if opts.SamplingRatio < 0 || opts.SamplingRatio > 1 {
+		return nil, fmt.Errorf("the sampling ratio must be between 0 and 1, got %v", opts.SamplingRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return nil, nil
+	case ExporterOTLP:
+		exporter, err = newOTLPExporter(ctx, opts)
+	case ExporterFile:
+		exporter, err = newFileExporter(opts.FilePath)
+	default:
+		return nil, fmt.Errorf("the exporter must be one of none, otlp, or file, got %s", opts.Exporter)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", opts.ServiceName),
+		attribute.String("service.version", opts.ServiceVersion),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the tracing resource: %w", err)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SamplingRatio))),
+	), nil
+}
+
+func newOTLPExporter(ctx context.Context, opts Options) (sdktrace.SpanExporter, error) {
+	otlpOpts := []otlptracegrpc.Option{}
+
+	if opts.OTLPEndpoint != "" {
+		otlpOpts = append(otlpOpts, otlptracegrpc.WithEndpoint(opts.OTLPEndpoint))
+	}
+
+	if opts.OTLPInsecure {
+		otlpOpts = append(otlpOpts, otlptracegrpc.WithInsecure())
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlpOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", err)
+	}
+
+	return exporter, nil
+}
+
+// fileExporter closes the file it writes to when it's shut down.
+type fileExporter struct {
+	sdktrace.SpanExporter
+	file io.Closer
+}
+
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	return errors.Join(e.SpanExporter.Shutdown(ctx), e.file.Close())
+}
+
+func newFileExporter(path string) (sdktrace.SpanExporter, error) {
+	if path == "" || path == "-" {
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the trace file %s: %w", path, err)
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(file))
+	if err != nil {
+		_ = file.Close()
+
+		return nil, fmt.Errorf("failed to create the file trace exporter: %w", err)
+	}
+
+	return &fileExporter{SpanExporter: exporter, file: file}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright Contributors to the Open Cluster Management project
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTracerProviderFile(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	provider, err := NewTracerProvider(ctx, Options{
+		Exporter:       ExporterFile,
+		FilePath:       path,
+		SamplingRatio:  1,
+		ServiceName:    "config-policy-controller",
+		ServiceVersion: "test",
+	})
+	if !assert.NoError(t, err) || !assert.NotNil(t, provider) {
+		return
+	}
+
+	_, span := provider.Tracer("test").Start(ctx, "ConfigurationPolicy.Reconcile")
+	span.End()
+
+	assert.NoError(t, provider.Shutdown(ctx))
+
+	data, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if !assert.Len(t, lines, 1) {
+		return
+	}
+
+	exported := map[string]any{}
+	if assert.NoError(t, json.Unmarshal([]byte(lines[0]), &exported)) {
+		assert.Equal(t, "ConfigurationPolicy.Reconcile", exported["Name"])
+		assert.Contains(
+			t, lines[0], `"Key":"service.name","Value":{"Type":"STRING","Value":"config-policy-controller"}`,
+		)
+	}
+}
+
+func TestNewTracerProviderSampling(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "traces.json")
+
+	provider, err := NewTracerProvider(ctx, Options{Exporter: ExporterFile, FilePath: path, SamplingRatio: 0})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, span := provider.Tracer("test").Start(ctx, "ConfigurationPolicy.Reconcile")
+	assert.False(t, span.SpanContext().IsSampled())
+	span.End()
+
+	assert.NoError(t, provider.Shutdown(ctx))
+
+	data, err := os.ReadFile(path)
+	if assert.NoError(t, err) {
+		assert.Empty(t, data)
+	}
+}
+
+func TestNewTracerProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		opts          Options
+		expectedError string
+	}{
+		"none": {
+			opts: Options{Exporter: ExporterNone, SamplingRatio: 1},
+		},
+		"unset": {
+			opts: Options{},
+		},
+		"unknown exporter": {
+			opts:          Options{Exporter: "zipkin", SamplingRatio: 1},
+			expectedError: "the exporter must be one of none, otlp, or file, got zipkin",
+		},
+		"invalid sampling ratio": {
+			opts:          Options{Exporter: ExporterFile, SamplingRatio: 1.5},
+			expectedError: "the sampling ratio must be between 0 and 1, got 1.5",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			provider, err := NewTracerProvider(context.Background(), test.opts)
+			assert.Nil(t, provider)
+
+			if test.expectedError == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.expectedError)
+			}
+		})
+	}
+}